@@ -0,0 +1,125 @@
+// Command webhook-replay posts sample provider callbacks to a running
+// server, signed the way the provider would sign them, to exercise the
+// webhook endpoints locally:
+//
+//	go run ./cmd/webhook-replay -message-id fake-whatsapp-1 cmd/webhook-replay/samples/whatsapp_delivered.json
+//
+// The channel is taken from the file name prefix ("whatsapp_" or "sms_")
+// unless -channel is given, and the secret from the channel's environment
+// variable (WHATSAPP_APP_SECRET or SMS_WEBHOOK_SECRET, also read from .env)
+// unless -secret is given. In the payloads {{message_id}}, {{from}},
+// {{timestamp}} and {{time}} are replaced with the flag values and the
+// current Unix and RFC 3339 time.
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"khata-book-backend/pkg/notify"
+
+	"github.com/joho/godotenv"
+)
+
+func main() {
+	baseURL := flag.String("url", "http://localhost:8080", "server base URL")
+	channel := flag.String("channel", "", "provider channel, whatsapp or sms (default from file name)")
+	secret := flag.String("secret", "", "signing secret (default from the channel's environment variable)")
+	messageID := flag.String("message-id", "", "provider message ID to report on, e.g. fake-sms-1")
+	from := flag.String("from", "919876543210", "customer phone number in international form, without +")
+	repeat := flag.Int("repeat", 1, "times to send each payload, to check repeats are harmless")
+	unsigned := flag.Bool("unsigned", false, "send with a wrong signature, to check it is rejected")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: webhook-replay [flags] payload.json...\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	_ = godotenv.Load()
+
+	failed := false
+	for _, path := range flag.Args() {
+		payloadChannel := *channel
+		if payloadChannel == "" {
+			payloadChannel, _, _ = strings.Cut(filepath.Base(path), "_")
+		}
+		header, secretEnv, ok := notify.WebhookSigner(payloadChannel)
+		if !ok {
+			fmt.Fprintf(os.Stderr, "%s: unknown channel %q\n", path, payloadChannel)
+			os.Exit(2)
+		}
+		signingSecret := *secret
+		if signingSecret == "" {
+			signingSecret = os.Getenv(secretEnv)
+		}
+		if signingSecret == "" && !*unsigned {
+			fmt.Fprintf(os.Stderr, "%s: set %s or pass -secret\n", path, secretEnv)
+			os.Exit(2)
+		}
+
+		raw, err := os.ReadFile(path)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		now := time.Now()
+		body := []byte(strings.NewReplacer(
+			"{{message_id}}", *messageID,
+			"{{from}}", strings.TrimPrefix(*from, "+"),
+			"{{timestamp}}", strconv.FormatInt(now.Unix(), 10),
+			"{{time}}", now.Format(time.RFC3339),
+		).Replace(string(raw)))
+
+		signature := "sha256=" + hex.EncodeToString(notify.SignWebhook(signingSecret, body))
+		if *unsigned {
+			signature = "sha256=" + strings.Repeat("0", 64)
+		}
+
+		for i := 0; i < *repeat; i++ {
+			status, response, err := post(strings.TrimSuffix(*baseURL, "/")+"/api/webhooks/"+payloadChannel, header, signature, body)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "%s: %v\n", path, err)
+				os.Exit(1)
+			}
+			fmt.Printf("%s -> %s %s\n", filepath.Base(path), status, strings.TrimSpace(response))
+			if !strings.HasPrefix(status, "2") {
+				failed = true
+			}
+		}
+	}
+
+	if failed {
+		os.Exit(1)
+	}
+}
+
+func post(url, header, signature string, body []byte) (string, string, error) {
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return "", "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(header, signature)
+
+	resp, err := (&http.Client{Timeout: 30 * time.Second}).Do(req)
+	if err != nil {
+		return "", "", err
+	}
+	defer resp.Body.Close()
+
+	response, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	return resp.Status, string(response), nil
+}
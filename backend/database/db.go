@@ -91,6 +91,25 @@ func InitDB() {
 		logger.L.WithField("error", err).Fatal("Error creating customers table")
 	}
 
+	// Suppliers share the customers table and its balance convention
+	err = ensureColumn("customers", "party_type", "ENUM('customer', 'supplier') NOT NULL DEFAULT 'customer' AFTER name")
+	if err == nil {
+		err = ensureIndex("customers", "idx_user_party", "user_id, party_type")
+	}
+	if err != nil {
+		logger.L.WithField("error", err).Fatal("Error adding party_type to customers")
+	}
+
+	// Email reminders need an address to go to, and reminders are written in
+	// the customer's language when a template exists for it
+	err = ensureColumn("customers", "email", "VARCHAR(255) NULL AFTER phone")
+	if err == nil {
+		err = ensureColumn("customers", "language", "VARCHAR(10) NULL AFTER email")
+	}
+	if err != nil {
+		logger.L.WithField("error", err).Fatal("Error adding contact columns to customers")
+	}
+
 	logger.L.Info("Ensured customers table exists")
 
 	// Create ledger_entries table
@@ -98,9 +117,9 @@ func InitDB() {
 		CREATE TABLE IF NOT EXISTS ledger_entries (
 			id INT AUTO_INCREMENT PRIMARY KEY,
 			customer_id INT NOT NULL,
-			type ENUM('credit', 'debit') NOT NULL,
+			type ENUM('credit', 'debit', 'interest', 'late_fee', 'write_off', 'discount') NOT NULL,
 			amount DECIMAL(10,2) NOT NULL,
-			method ENUM('cash', 'upi', 'bank') NOT NULL,
+			method VARCHAR(30) NOT NULL,
 			note TEXT,
 			date DATE NOT NULL,
 			user_id INT NOT NULL,
@@ -109,6 +128,7 @@ func InitDB() {
 			FOREIGN KEY (customer_id) REFERENCES customers(id) ON DELETE CASCADE,
 			FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
 			INDEX idx_user_date (user_id, date),
+			INDEX idx_user_created (user_id, created_at),
 			INDEX idx_customer_date (customer_id, date)
 		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;`
 
@@ -117,6 +137,12 @@ func InitDB() {
 		logger.L.WithField("error", err).Fatal("Error creating ledger_entries table")
 	}
 
+	// Keyset pagination over (created_at, id) needs this on older databases
+	err = ensureIndex("ledger_entries", "idx_user_created", "user_id, created_at")
+	if err != nil {
+		logger.L.WithField("error", err).Fatal("Error adding ledger_entries created_at index")
+	}
+
 	logger.L.Info("Ensured ledger_entries table exists")
 
 	// Create reminders table
@@ -142,5 +168,759 @@ func InitDB() {
 		logger.L.WithField("error", err).Fatal("Error creating reminders table")
 	}
 
+	// Reminders are delivered by a background dispatcher that retries with
+	// backoff and records the outcome of the last attempt
+	err = ensureColumnType("reminders", "status", "enum('pending','sent','snoozed','paid','failed')", "ENUM('pending', 'sent', 'snoozed', 'paid', 'failed') DEFAULT 'pending'")
+	reminderColumns := []struct{ column, definition string }{
+		{"attempts", "INT NOT NULL DEFAULT 0 AFTER status"},
+		{"next_attempt_at", "DATETIME NULL AFTER attempts"},
+		{"last_error", "TEXT NULL AFTER next_attempt_at"},
+		{"sent_at", "TIMESTAMP NULL AFTER last_error"},
+		{"provider_message_id", "VARCHAR(255) NULL AFTER sent_at"},
+	}
+	for _, c := range reminderColumns {
+		if err == nil {
+			err = ensureColumn("reminders", c.column, c.definition)
+		}
+	}
+	if err == nil {
+		err = ensureIndex("reminders", "idx_status_due", "status, due_date")
+	}
+	if err != nil {
+		logger.L.WithField("error", err).Fatal("Error adding delivery columns to reminders")
+	}
+
 	logger.L.Info("Ensured reminders table exists")
+
+	// Create categories table
+	categoriesTableQuery := `
+		CREATE TABLE IF NOT EXISTS categories (
+			id INT AUTO_INCREMENT PRIMARY KEY,
+			name VARCHAR(100) NOT NULL,
+			user_id INT NOT NULL,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+			FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+			UNIQUE KEY unique_category_user (name, user_id)
+		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;`
+
+	_, err = DB.Exec(categoriesTableQuery)
+	if err != nil {
+		logger.L.WithField("error", err).Fatal("Error creating categories table")
+	}
+
+	logger.L.Info("Ensured categories table exists")
+
+	// Ledger entries may optionally be filed under a category
+	err = ensureColumn("ledger_entries", "category_id", "INT NULL AFTER method")
+	if err == nil {
+		err = ensureForeignKey("ledger_entries", "fk_ledger_category", "FOREIGN KEY (category_id) REFERENCES categories(id) ON DELETE SET NULL")
+	}
+	if err != nil {
+		logger.L.WithField("error", err).Fatal("Error adding category to ledger_entries")
+	}
+
+	// Create ledger_entry_tags table
+	ledgerEntryTagsTableQuery := `
+		CREATE TABLE IF NOT EXISTS ledger_entry_tags (
+			entry_id INT NOT NULL,
+			tag VARCHAR(50) NOT NULL,
+			user_id INT NOT NULL,
+			PRIMARY KEY (entry_id, tag),
+			FOREIGN KEY (entry_id) REFERENCES ledger_entries(id) ON DELETE CASCADE,
+			FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+			INDEX idx_user_tag (user_id, tag)
+		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;`
+
+	_, err = DB.Exec(ledgerEntryTagsTableQuery)
+	if err != nil {
+		logger.L.WithField("error", err).Fatal("Error creating ledger_entry_tags table")
+	}
+
+	logger.L.Info("Ensured ledger_entry_tags table exists")
+
+	// Payment methods used to be a fixed ENUM; they now come from a registry
+	err = ensureColumnNotEnum("ledger_entries", "method", "VARCHAR(30) NOT NULL")
+	if err != nil {
+		logger.L.WithField("error", err).Fatal("Error widening ledger_entries method column")
+	}
+
+	// Create payment_methods table for business specific methods
+	paymentMethodsTableQuery := `
+		CREATE TABLE IF NOT EXISTS payment_methods (
+			id INT AUTO_INCREMENT PRIMARY KEY,
+			code VARCHAR(30) NOT NULL,
+			name VARCHAR(100) NOT NULL,
+			kind ENUM('cash', 'upi', 'bank', 'cheque', 'card', 'wallet', 'other') NOT NULL,
+			is_active BOOLEAN NOT NULL DEFAULT TRUE,
+			user_id INT NOT NULL,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+			FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+			UNIQUE KEY unique_method_user (code, user_id)
+		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;`
+
+	_, err = DB.Exec(paymentMethodsTableQuery)
+	if err != nil {
+		logger.L.WithField("error", err).Fatal("Error creating payment_methods table")
+	}
+
+	logger.L.Info("Ensured payment_methods table exists")
+
+	// Create cheque_details table
+	chequeDetailsTableQuery := `
+		CREATE TABLE IF NOT EXISTS cheque_details (
+			entry_id INT PRIMARY KEY,
+			cheque_number VARCHAR(30) NOT NULL,
+			bank_name VARCHAR(100),
+			clearing_date DATE,
+			status ENUM('pending', 'cleared', 'bounced') NOT NULL DEFAULT 'pending',
+			bounce_entry_id INT,
+			user_id INT NOT NULL,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+			FOREIGN KEY (entry_id) REFERENCES ledger_entries(id) ON DELETE CASCADE,
+			FOREIGN KEY (bounce_entry_id) REFERENCES ledger_entries(id) ON DELETE SET NULL,
+			FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+			INDEX idx_user_status (user_id, status)
+		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;`
+
+	_, err = DB.Exec(chequeDetailsTableQuery)
+	if err != nil {
+		logger.L.WithField("error", err).Fatal("Error creating cheque_details table")
+	}
+
+	logger.L.Info("Ensured cheque_details table exists")
+
+	// Create recurring_entries table
+	recurringEntriesTableQuery := `
+		CREATE TABLE IF NOT EXISTS recurring_entries (
+			id INT AUTO_INCREMENT PRIMARY KEY,
+			customer_id INT NOT NULL,
+			type ENUM('credit', 'debit') NOT NULL,
+			amount DECIMAL(10,2) NOT NULL,
+			method VARCHAR(30) NOT NULL,
+			category_id INT,
+			note TEXT,
+			rrule VARCHAR(255) NOT NULL,
+			start_date DATE NOT NULL,
+			end_date DATE,
+			next_run_date DATE,
+			status ENUM('active', 'paused', 'ended') NOT NULL DEFAULT 'active',
+			user_id INT NOT NULL,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+			FOREIGN KEY (customer_id) REFERENCES customers(id) ON DELETE CASCADE,
+			FOREIGN KEY (category_id) REFERENCES categories(id) ON DELETE SET NULL,
+			FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+			INDEX idx_status_next_run (status, next_run_date),
+			INDEX idx_user_customer (user_id, customer_id)
+		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;`
+
+	_, err = DB.Exec(recurringEntriesTableQuery)
+	if err != nil {
+		logger.L.WithField("error", err).Fatal("Error creating recurring_entries table")
+	}
+
+	logger.L.Info("Ensured recurring_entries table exists")
+
+	// One row per occurrence makes generation idempotent: an occurrence can
+	// only ever be posted or skipped once
+	recurringEntryRunsTableQuery := `
+		CREATE TABLE IF NOT EXISTS recurring_entry_runs (
+			recurring_id INT NOT NULL,
+			occurrence_date DATE NOT NULL,
+			entry_id INT,
+			status ENUM('posted', 'skipped') NOT NULL,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			PRIMARY KEY (recurring_id, occurrence_date),
+			FOREIGN KEY (recurring_id) REFERENCES recurring_entries(id) ON DELETE CASCADE,
+			FOREIGN KEY (entry_id) REFERENCES ledger_entries(id) ON DELETE SET NULL
+		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;`
+
+	_, err = DB.Exec(recurringEntryRunsTableQuery)
+	if err != nil {
+		logger.L.WithField("error", err).Fatal("Error creating recurring_entry_runs table")
+	}
+
+	logger.L.Info("Ensured recurring_entry_runs table exists")
+
+	// Accrual and adjustment entries have their own types so reports can
+	// tell interest, fees, write-offs and discounts apart from cash
+	err = ensureColumnType("ledger_entries", "type", "enum('credit','debit','interest','late_fee','write_off','discount')", "ENUM('credit', 'debit', 'interest', 'late_fee', 'write_off', 'discount') NOT NULL")
+	if err != nil {
+		logger.L.WithField("error", err).Fatal("Error widening ledger_entries type column")
+	}
+
+	// Create interest_settings table
+	interestSettingsTableQuery := `
+		CREATE TABLE IF NOT EXISTS interest_settings (
+			customer_id INT PRIMARY KEY,
+			mode ENUM('simple', 'compound') NOT NULL DEFAULT 'simple',
+			monthly_rate DECIMAL(6,3) NOT NULL,
+			grace_days INT NOT NULL DEFAULT 0,
+			late_fee DECIMAL(10,2) NOT NULL DEFAULT 0,
+			is_active BOOLEAN NOT NULL DEFAULT TRUE,
+			accrued_through DATE NOT NULL,
+			user_id INT NOT NULL,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+			FOREIGN KEY (customer_id) REFERENCES customers(id) ON DELETE CASCADE,
+			FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+			INDEX idx_active_accrued (is_active, accrued_through)
+		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;`
+
+	_, err = DB.Exec(interestSettingsTableQuery)
+	if err != nil {
+		logger.L.WithField("error", err).Fatal("Error creating interest_settings table")
+	}
+
+	logger.L.Info("Ensured interest_settings table exists")
+
+	// One row per customer and period keeps accrual idempotent
+	interestAccrualsTableQuery := `
+		CREATE TABLE IF NOT EXISTS interest_accruals (
+			customer_id INT NOT NULL,
+			period_start DATE NOT NULL,
+			period_end DATE NOT NULL,
+			interest DECIMAL(10,2) NOT NULL,
+			late_fee DECIMAL(10,2) NOT NULL,
+			interest_entry_id INT,
+			late_fee_entry_id INT,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			PRIMARY KEY (customer_id, period_end),
+			FOREIGN KEY (customer_id) REFERENCES customers(id) ON DELETE CASCADE,
+			FOREIGN KEY (interest_entry_id) REFERENCES ledger_entries(id) ON DELETE SET NULL,
+			FOREIGN KEY (late_fee_entry_id) REFERENCES ledger_entries(id) ON DELETE SET NULL
+		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;`
+
+	_, err = DB.Exec(interestAccrualsTableQuery)
+	if err != nil {
+		logger.L.WithField("error", err).Fatal("Error creating interest_accruals table")
+	}
+
+	logger.L.Info("Ensured interest_accruals table exists")
+
+	// Create invoice_series table for invoice numbering
+	invoiceSeriesTableQuery := `
+		CREATE TABLE IF NOT EXISTS invoice_series (
+			id INT AUTO_INCREMENT PRIMARY KEY,
+			name VARCHAR(100) NOT NULL,
+			prefix VARCHAR(20) NOT NULL,
+			next_number INT NOT NULL DEFAULT 1,
+			padding INT NOT NULL DEFAULT 4,
+			is_default BOOLEAN NOT NULL DEFAULT FALSE,
+			user_id INT NOT NULL,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+			FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+			UNIQUE KEY uniq_user_prefix (user_id, prefix)
+		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;`
+
+	_, err = DB.Exec(invoiceSeriesTableQuery)
+	if err != nil {
+		logger.L.WithField("error", err).Fatal("Error creating invoice_series table")
+	}
+
+	logger.L.Info("Ensured invoice_series table exists")
+
+	// Create invoices table. Paid invoices are settled through
+	// invoice_allocations; overdue is derived from due_date when read.
+	invoicesTableQuery := `
+		CREATE TABLE IF NOT EXISTS invoices (
+			id INT AUTO_INCREMENT PRIMARY KEY,
+			customer_id INT NOT NULL,
+			series_id INT,
+			invoice_number VARCHAR(40) NOT NULL,
+			invoice_date DATE NOT NULL,
+			due_date DATE NOT NULL,
+			subtotal DECIMAL(12,2) NOT NULL,
+			tax_total DECIMAL(12,2) NOT NULL,
+			total DECIMAL(12,2) NOT NULL,
+			amount_paid DECIMAL(12,2) NOT NULL DEFAULT 0,
+			status ENUM('open', 'partial', 'paid') NOT NULL DEFAULT 'open',
+			note TEXT,
+			entry_id INT,
+			user_id INT NOT NULL,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+			FOREIGN KEY (customer_id) REFERENCES customers(id) ON DELETE CASCADE,
+			FOREIGN KEY (series_id) REFERENCES invoice_series(id) ON DELETE SET NULL,
+			FOREIGN KEY (entry_id) REFERENCES ledger_entries(id) ON DELETE SET NULL,
+			FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+			UNIQUE KEY uniq_user_number (user_id, invoice_number),
+			INDEX idx_customer_status_due (customer_id, status, due_date)
+		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;`
+
+	_, err = DB.Exec(invoicesTableQuery)
+	if err != nil {
+		logger.L.WithField("error", err).Fatal("Error creating invoices table")
+	}
+
+	logger.L.Info("Ensured invoices table exists")
+
+	// Create invoice_lines table
+	invoiceLinesTableQuery := `
+		CREATE TABLE IF NOT EXISTS invoice_lines (
+			id INT AUTO_INCREMENT PRIMARY KEY,
+			invoice_id INT NOT NULL,
+			position INT NOT NULL,
+			item VARCHAR(255) NOT NULL,
+			quantity DECIMAL(12,3) NOT NULL,
+			rate DECIMAL(12,2) NOT NULL,
+			tax_rate DECIMAL(5,2) NOT NULL DEFAULT 0,
+			amount DECIMAL(12,2) NOT NULL,
+			tax_amount DECIMAL(12,2) NOT NULL,
+			total DECIMAL(12,2) NOT NULL,
+			FOREIGN KEY (invoice_id) REFERENCES invoices(id) ON DELETE CASCADE,
+			INDEX idx_invoice (invoice_id, position)
+		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;`
+
+	_, err = DB.Exec(invoiceLinesTableQuery)
+	if err != nil {
+		logger.L.WithField("error", err).Fatal("Error creating invoice_lines table")
+	}
+
+	logger.L.Info("Ensured invoice_lines table exists")
+
+	// Create invoice_allocations table linking payments to the invoices they
+	// settle
+	invoiceAllocationsTableQuery := `
+		CREATE TABLE IF NOT EXISTS invoice_allocations (
+			invoice_id INT NOT NULL,
+			entry_id INT NOT NULL,
+			amount DECIMAL(12,2) NOT NULL,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			PRIMARY KEY (invoice_id, entry_id),
+			FOREIGN KEY (invoice_id) REFERENCES invoices(id) ON DELETE CASCADE,
+			FOREIGN KEY (entry_id) REFERENCES ledger_entries(id) ON DELETE CASCADE,
+			INDEX idx_entry (entry_id)
+		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;`
+
+	_, err = DB.Exec(invoiceAllocationsTableQuery)
+	if err != nil {
+		logger.L.WithField("error", err).Fatal("Error creating invoice_allocations table")
+	}
+
+	logger.L.Info("Ensured invoice_allocations table exists")
+
+	// GST registration numbers identify the business and registered
+	// customers. An unregistered customer's state_code is the place of supply
+	// when it differs from the business's own state.
+	gstColumns := []struct{ table, column, definition string }{
+		{"users", "gstin", "VARCHAR(15) NULL AFTER address"},
+		{"customers", "gstin", "VARCHAR(15) NULL AFTER note"},
+		{"customers", "state_code", "CHAR(2) NULL AFTER gstin"},
+		{"invoices", "supplier_gstin", "VARCHAR(15) NULL AFTER note"},
+		{"invoices", "customer_gstin", "VARCHAR(15) NULL AFTER supplier_gstin"},
+		{"invoices", "place_of_supply", "CHAR(2) NULL AFTER customer_gstin"},
+		{"invoices", "cgst_total", "DECIMAL(12,2) NOT NULL DEFAULT 0 AFTER tax_total"},
+		{"invoices", "sgst_total", "DECIMAL(12,2) NOT NULL DEFAULT 0 AFTER cgst_total"},
+		{"invoices", "igst_total", "DECIMAL(12,2) NOT NULL DEFAULT 0 AFTER sgst_total"},
+		{"invoice_lines", "hsn_code", "VARCHAR(8) NULL AFTER item"},
+		{"invoice_lines", "cgst_amount", "DECIMAL(12,2) NOT NULL DEFAULT 0 AFTER tax_amount"},
+		{"invoice_lines", "sgst_amount", "DECIMAL(12,2) NOT NULL DEFAULT 0 AFTER cgst_amount"},
+		{"invoice_lines", "igst_amount", "DECIMAL(12,2) NOT NULL DEFAULT 0 AFTER sgst_amount"},
+	}
+	for _, c := range gstColumns {
+		if err := ensureColumn(c.table, c.column, c.definition); err != nil {
+			logger.L.WithFields(map[string]interface{}{"error": err, "table": c.table, "column": c.column}).Fatal("Error adding GST column")
+		}
+	}
+
+	// Create tax_rates table for the user's configured GST slabs
+	taxRatesTableQuery := `
+		CREATE TABLE IF NOT EXISTS tax_rates (
+			id INT AUTO_INCREMENT PRIMARY KEY,
+			name VARCHAR(50) NOT NULL,
+			rate DECIMAL(5,2) NOT NULL,
+			hsn_code VARCHAR(8),
+			is_active BOOLEAN NOT NULL DEFAULT TRUE,
+			user_id INT NOT NULL,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+			FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+			UNIQUE KEY uniq_user_name (user_id, name)
+		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;`
+
+	_, err = DB.Exec(taxRatesTableQuery)
+	if err != nil {
+		logger.L.WithField("error", err).Fatal("Error creating tax_rates table")
+	}
+
+	logger.L.Info("Ensured tax_rates table exists")
+
+	// Create cashbook_entries table for income and expenses that belong to
+	// no party, such as rent or counter sales
+	cashbookEntriesTableQuery := `
+		CREATE TABLE IF NOT EXISTS cashbook_entries (
+			id INT AUTO_INCREMENT PRIMARY KEY,
+			type ENUM('income', 'expense') NOT NULL,
+			amount DECIMAL(10,2) NOT NULL,
+			method VARCHAR(30) NOT NULL,
+			category_id INT,
+			note TEXT,
+			date DATE NOT NULL,
+			user_id INT NOT NULL,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+			FOREIGN KEY (category_id) REFERENCES categories(id) ON DELETE SET NULL,
+			FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+			INDEX idx_user_date (user_id, date),
+			INDEX idx_user_method_date (user_id, method, date)
+		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;`
+
+	_, err = DB.Exec(cashbookEntriesTableQuery)
+	if err != nil {
+		logger.L.WithField("error", err).Fatal("Error creating cashbook_entries table")
+	}
+
+	logger.L.Info("Ensured cashbook_entries table exists")
+
+	// Create day_closes table; a closed day locks the entries dated on or
+	// before it until it is reopened
+	dayClosesTableQuery := `
+		CREATE TABLE IF NOT EXISTS day_closes (
+			id INT AUTO_INCREMENT PRIMARY KEY,
+			date DATE NOT NULL,
+			status ENUM('closed', 'reopened') NOT NULL DEFAULT 'closed',
+			expected_total DECIMAL(12,2) NOT NULL DEFAULT 0.00,
+			counted_total DECIMAL(12,2) NOT NULL DEFAULT 0.00,
+			variance_total DECIMAL(12,2) NOT NULL DEFAULT 0.00,
+			note TEXT,
+			reopen_reason TEXT,
+			closed_at TIMESTAMP NULL,
+			reopened_at TIMESTAMP NULL,
+			user_id INT NOT NULL,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+			FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+			UNIQUE KEY unique_user_date (user_id, date),
+			INDEX idx_user_status_date (user_id, status, date)
+		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;`
+
+	_, err = DB.Exec(dayClosesTableQuery)
+	if err != nil {
+		logger.L.WithField("error", err).Fatal("Error creating day_closes table")
+	}
+
+	// Create day_close_counts table with the expected and counted amount per
+	// payment method; counted is NULL for methods that were not counted
+	dayCloseCountsTableQuery := `
+		CREATE TABLE IF NOT EXISTS day_close_counts (
+			day_close_id INT NOT NULL,
+			method VARCHAR(30) NOT NULL,
+			expected DECIMAL(12,2) NOT NULL,
+			counted DECIMAL(12,2),
+			variance DECIMAL(12,2),
+			PRIMARY KEY (day_close_id, method),
+			FOREIGN KEY (day_close_id) REFERENCES day_closes(id) ON DELETE CASCADE
+		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;`
+
+	_, err = DB.Exec(dayCloseCountsTableQuery)
+	if err != nil {
+		logger.L.WithField("error", err).Fatal("Error creating day_close_counts table")
+	}
+
+	logger.L.Info("Ensured day_closes tables exist")
+
+	// Create reminder_templates table; each business may keep several
+	// templates per channel and language, one of which is the default
+	reminderTemplatesTableQuery := `
+		CREATE TABLE IF NOT EXISTS reminder_templates (
+			id INT AUTO_INCREMENT PRIMARY KEY,
+			name VARCHAR(100) NOT NULL,
+			channel ENUM('sms', 'whatsapp', 'email') NOT NULL,
+			language VARCHAR(10) NOT NULL DEFAULT 'en',
+			subject VARCHAR(200),
+			body TEXT NOT NULL,
+			is_default BOOLEAN NOT NULL DEFAULT FALSE,
+			user_id INT NOT NULL,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+			FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+			UNIQUE KEY uniq_user_template_name (user_id, name),
+			INDEX idx_user_channel_language (user_id, channel, language)
+		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;`
+
+	_, err = DB.Exec(reminderTemplatesTableQuery)
+	if err != nil {
+		logger.L.WithField("error", err).Fatal("Error creating reminder_templates table")
+	}
+
+	// A reminder may name the template it should be sent with
+	err = ensureColumn("reminders", "template_id", "INT NULL AFTER channel")
+	if err == nil {
+		err = ensureForeignKey("reminders", "fk_reminder_template", "FOREIGN KEY (template_id) REFERENCES reminder_templates(id) ON DELETE SET NULL")
+	}
+	if err != nil {
+		logger.L.WithField("error", err).Fatal("Error adding template to reminders")
+	}
+
+	// The business's UPI ID is used to build payment links in reminders
+	err = ensureColumn("users", "upi_id", "VARCHAR(100) NULL AFTER gstin")
+	if err != nil {
+		logger.L.WithField("error", err).Fatal("Error adding upi_id to users")
+	}
+
+	// Payments close or reduce open reminders unless the business opts out
+	err = ensureColumn("users", "auto_close_reminders", "BOOLEAN NOT NULL DEFAULT TRUE AFTER upi_id")
+	if err != nil {
+		logger.L.WithField("error", err).Fatal("Error adding auto_close_reminders to users")
+	}
+
+	logger.L.Info("Ensured reminder_templates table exists")
+
+	// Create reminder_events table; every delivery attempt and status change
+	// of a reminder is appended here
+	reminderEventsTableQuery := `
+		CREATE TABLE IF NOT EXISTS reminder_events (
+			id BIGINT AUTO_INCREMENT PRIMARY KEY,
+			reminder_id INT NOT NULL,
+			event VARCHAR(30) NOT NULL,
+			from_status VARCHAR(20),
+			to_status VARCHAR(20),
+			attempt INT,
+			channel VARCHAR(20),
+			provider_message_id VARCHAR(255),
+			detail TEXT,
+			user_id INT NOT NULL,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			FOREIGN KEY (reminder_id) REFERENCES reminders(id) ON DELETE CASCADE,
+			FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+			INDEX idx_reminder_created (reminder_id, created_at),
+			INDEX idx_provider_message (provider_message_id)
+		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;`
+
+	_, err = DB.Exec(reminderEventsTableQuery)
+	if err != nil {
+		logger.L.WithField("error", err).Fatal("Error creating reminder_events table")
+	}
+
+	// A snoozed reminder wakes up and goes back to pending at snoozed_until
+	err = ensureColumn("reminders", "snoozed_until", "DATETIME NULL AFTER status")
+	if err == nil {
+		err = ensureIndex("reminders", "idx_status_snoozed_until", "status, snoozed_until")
+	}
+	if err != nil {
+		logger.L.WithField("error", err).Fatal("Error adding snoozed_until to reminders")
+	}
+
+	logger.L.Info("Ensured reminder_events table exists")
+
+	// Create reminder_rules table; the scheduler creates reminders for
+	// customers matching a rule
+	reminderRulesTableQuery := `
+		CREATE TABLE IF NOT EXISTS reminder_rules (
+			id INT AUTO_INCREMENT PRIMARY KEY,
+			name VARCHAR(100) NOT NULL,
+			min_balance DECIMAL(12,2) NOT NULL DEFAULT 0,
+			days_since_payment INT NOT NULL DEFAULT 0,
+			channel ENUM('sms', 'whatsapp', 'email') NOT NULL,
+			template_id INT,
+			repeat_every_days INT NOT NULL DEFAULT 7,
+			is_active BOOLEAN NOT NULL DEFAULT TRUE,
+			last_run_at TIMESTAMP NULL,
+			user_id INT NOT NULL,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+			FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+			FOREIGN KEY (template_id) REFERENCES reminder_templates(id) ON DELETE SET NULL,
+			UNIQUE KEY uniq_user_rule_name (user_id, name),
+			INDEX idx_active (is_active)
+		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;`
+
+	_, err = DB.Exec(reminderRulesTableQuery)
+	if err != nil {
+		logger.L.WithField("error", err).Fatal("Error creating reminder_rules table")
+	}
+
+	// Reminders created by a rule point back at it so it can space them out
+	err = ensureColumn("reminders", "rule_id", "INT NULL AFTER template_id")
+	if err == nil {
+		err = ensureForeignKey("reminders", "fk_reminder_rule", "FOREIGN KEY (rule_id) REFERENCES reminder_rules(id) ON DELETE SET NULL")
+	}
+	if err == nil {
+		err = ensureIndex("reminders", "idx_customer_rule_created", "customer_id, rule_id, created_at")
+	}
+	if err != nil {
+		logger.L.WithField("error", err).Fatal("Error adding rule_id to reminders")
+	}
+
+	logger.L.Info("Ensured reminder_rules table exists")
+
+	// send_now asks the dispatcher to send a reminder before its due date
+	err = ensureColumn("reminders", "send_now", "BOOLEAN NOT NULL DEFAULT FALSE AFTER snoozed_until")
+	if err != nil {
+		logger.L.WithField("error", err).Fatal("Error adding send_now to reminders")
+	}
+
+	// Due dates are read in the business's time zone and no reminder is sent
+	// during its quiet hours, which are read in the customer's time zone
+	err = ensureColumn("users", "timezone", "VARCHAR(64) NULL AFTER auto_close_reminders")
+	if err == nil {
+		err = ensureColumn("users", "quiet_hours_start", "TIME NULL AFTER timezone")
+	}
+	if err == nil {
+		err = ensureColumn("users", "quiet_hours_end", "TIME NULL AFTER quiet_hours_start")
+	}
+	if err == nil {
+		err = ensureColumn("customers", "timezone", "VARCHAR(64) NULL AFTER language")
+	}
+	if err != nil {
+		logger.L.WithField("error", err).Fatal("Error adding reminder time zones and quiet hours")
+	}
+
+	// Create customer_consents table; the latest opt-in or opt-out of a
+	// customer on each reminder channel
+	customerConsentsTableQuery := `
+		CREATE TABLE IF NOT EXISTS customer_consents (
+			id INT AUTO_INCREMENT PRIMARY KEY,
+			customer_id INT NOT NULL,
+			channel ENUM('sms', 'whatsapp', 'email') NOT NULL,
+			status ENUM('opted_in', 'opted_out') NOT NULL,
+			source VARCHAR(30) NOT NULL,
+			detail VARCHAR(255),
+			user_id INT NOT NULL,
+			changed_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			FOREIGN KEY (customer_id) REFERENCES customers(id) ON DELETE CASCADE,
+			FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+			UNIQUE KEY uniq_customer_channel (customer_id, channel)
+		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;`
+
+	_, err = DB.Exec(customerConsentsTableQuery)
+	if err != nil {
+		logger.L.WithField("error", err).Fatal("Error creating customer_consents table")
+	}
+
+	logger.L.Info("Ensured customer_consents table exists")
+
+	// Providers report whether the last message sent for a reminder reached
+	// the customer
+	err = ensureColumn("reminders", "delivery_status", "VARCHAR(20) NULL AFTER provider_message_id")
+	if err == nil {
+		err = ensureColumn("reminders", "delivery_status_at", "DATETIME NULL AFTER delivery_status")
+	}
+	if err != nil {
+		logger.L.WithField("error", err).Fatal("Error adding delivery_status to reminders")
+	}
+
+	// Create ledger_attachments table
+	ledgerAttachmentsTableQuery := `
+		CREATE TABLE IF NOT EXISTS ledger_attachments (
+			id INT AUTO_INCREMENT PRIMARY KEY,
+			entry_id INT NOT NULL,
+			file_name VARCHAR(255) NOT NULL,
+			content_type VARCHAR(100) NOT NULL,
+			size_bytes BIGINT NOT NULL,
+			storage_key VARCHAR(255) NOT NULL,
+			thumbnail_key VARCHAR(255),
+			user_id INT NOT NULL,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			FOREIGN KEY (entry_id) REFERENCES ledger_entries(id) ON DELETE CASCADE,
+			FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+			INDEX idx_entry (entry_id)
+		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;`
+
+	_, err = DB.Exec(ledgerAttachmentsTableQuery)
+	if err != nil {
+		logger.L.WithField("error", err).Fatal("Error creating ledger_attachments table")
+	}
+
+	logger.L.Info("Ensured ledger_attachments table exists")
+}
+
+// ensureIndex adds an index to an existing table unless it is already there.
+// CREATE TABLE IF NOT EXISTS leaves tables from older releases untouched, so
+// schema additions are applied through helpers like this one.
+func ensureIndex(table, name, columns string) error {
+	var count int
+	err := DB.QueryRow(`
+		SELECT COUNT(*) FROM information_schema.statistics
+		WHERE table_schema = DATABASE() AND table_name = ? AND index_name = ?`,
+		table, name).Scan(&count)
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return nil
+	}
+
+	_, err = DB.Exec(fmt.Sprintf("ALTER TABLE %s ADD INDEX %s (%s)", table, name, columns))
+	return err
+}
+
+// ensureColumn adds a column to an existing table unless it is already there
+func ensureColumn(table, column, definition string) error {
+	var count int
+	err := DB.QueryRow(`
+		SELECT COUNT(*) FROM information_schema.columns
+		WHERE table_schema = DATABASE() AND table_name = ? AND column_name = ?`,
+		table, column).Scan(&count)
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return nil
+	}
+
+	_, err = DB.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, column, definition))
+	return err
+}
+
+// ensureForeignKey adds a named constraint to an existing table unless it is
+// already there
+func ensureForeignKey(table, name, definition string) error {
+	var count int
+	err := DB.QueryRow(`
+		SELECT COUNT(*) FROM information_schema.table_constraints
+		WHERE table_schema = DATABASE() AND table_name = ? AND constraint_name = ?`,
+		table, name).Scan(&count)
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return nil
+	}
+
+	_, err = DB.Exec(fmt.Sprintf("ALTER TABLE %s ADD CONSTRAINT %s %s", table, name, definition))
+	return err
+}
+
+// ensureColumnNotEnum redefines a column that is still an ENUM. It is used
+// when a fixed list of values moves into application managed data.
+func ensureColumnNotEnum(table, column, definition string) error {
+	var dataType string
+	err := DB.QueryRow(`
+		SELECT data_type FROM information_schema.columns
+		WHERE table_schema = DATABASE() AND table_name = ? AND column_name = ?`,
+		table, column).Scan(&dataType)
+	if err != nil {
+		return err
+	}
+	if dataType != "enum" {
+		return nil
+	}
+
+	_, err = DB.Exec(fmt.Sprintf("ALTER TABLE %s MODIFY COLUMN %s %s", table, column, definition))
+	return err
+}
+
+// ensureColumnType redefines a column whose type differs from columnType, as
+// reported by information_schema. It is used to extend ENUM value lists.
+func ensureColumnType(table, column, columnType, definition string) error {
+	var current string
+	err := DB.QueryRow(`
+		SELECT column_type FROM information_schema.columns
+		WHERE table_schema = DATABASE() AND table_name = ? AND column_name = ?`,
+		table, column).Scan(&current)
+	if err != nil {
+		return err
+	}
+	if current == columnType {
+		return nil
+	}
+
+	_, err = DB.Exec(fmt.Sprintf("ALTER TABLE %s MODIFY COLUMN %s %s", table, column, definition))
+	return err
 }
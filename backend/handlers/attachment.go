@@ -0,0 +1,481 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	_ "image/gif"
+	"image/jpeg"
+	_ "image/png"
+	"io"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"khata-book-backend/database"
+	"khata-book-backend/models"
+	"khata-book-backend/pkg/logger"
+	"khata-book-backend/pkg/storage"
+
+	"github.com/gorilla/mux"
+)
+
+const (
+	maxAttachmentSize  = 10 << 20 // 10 MB
+	thumbnailMaxSide   = 320
+	thumbnailMaxPixels = 16_000_000 // about 64 MB decoded; larger images are stored without a thumbnail
+)
+
+// allowedAttachmentTypes maps sniffed content types to stored file extensions
+var allowedAttachmentTypes = map[string]string{
+	"image/jpeg":      ".jpg",
+	"image/png":       ".png",
+	"image/gif":       ".gif",
+	"image/webp":      ".webp",
+	"application/pdf": ".pdf",
+}
+
+// UploadLedgerAttachment stores a receipt or photo against a ledger entry
+func UploadLedgerAttachment(w http.ResponseWriter, r *http.Request) {
+	// Get user ID from JWT token
+	userID, err := getUserIDFromToken(r)
+	if err != nil {
+		writeJSON(w, http.StatusUnauthorized, map[string]interface{}{"success": false, "error": "unauthorized", "message": "Invalid token"})
+		return
+	}
+
+	entryID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"success": false, "error": "invalid_id", "message": "Invalid entry ID"})
+		return
+	}
+
+	if !checkLedgerEntryOwnership(w, entryID, userID) {
+		return
+	}
+
+	// Leave room for the multipart framing around the file itself
+	r.Body = http.MaxBytesReader(w, r.Body, maxAttachmentSize+(1<<20))
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			writeJSON(w, http.StatusRequestEntityTooLarge, map[string]interface{}{"success": false, "error": "file_too_large", "message": "Attachment must be 10 MB or smaller"})
+			return
+		}
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"success": false, "error": "invalid_request", "message": "A file is required in the 'file' field"})
+		return
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(io.LimitReader(file, maxAttachmentSize+1))
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"success": false, "error": "invalid_request", "message": "Could not read uploaded file"})
+		return
+	}
+	if len(data) > maxAttachmentSize {
+		writeJSON(w, http.StatusRequestEntityTooLarge, map[string]interface{}{"success": false, "error": "file_too_large", "message": "Attachment must be 10 MB or smaller"})
+		return
+	}
+	if len(data) == 0 {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"success": false, "error": "empty_file", "message": "Uploaded file is empty"})
+		return
+	}
+
+	// Trust the bytes, not the client supplied Content-Type
+	contentType, _, _ := mime.ParseMediaType(http.DetectContentType(data))
+	ext, ok := allowedAttachmentTypes[contentType]
+	if !ok {
+		writeJSON(w, http.StatusUnsupportedMediaType, map[string]interface{}{"success": false, "error": "unsupported_type", "message": "Only JPEG, PNG, GIF, WebP images and PDF files are allowed"})
+		return
+	}
+
+	fileName := filepath.Base(header.Filename)
+	if fileName == "." || fileName == string(filepath.Separator) {
+		fileName = "attachment" + ext
+	}
+	if len(fileName) > 255 {
+		fileName = fileName[:255]
+	}
+
+	token, err := randomToken()
+	if err != nil {
+		logger.L.WithField("error", err).Error("Error generating attachment key")
+		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "server_error", "message": "Could not store attachment"})
+		return
+	}
+	storageKey := fmt.Sprintf("attachments/%d/%d/%s%s", userID, entryID, token, ext)
+
+	ctx := r.Context()
+	if err := storage.Blobs.Put(ctx, storageKey, data, contentType); err != nil {
+		logger.L.WithField("error", err).Error("Error storing attachment")
+		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "server_error", "message": "Could not store attachment"})
+		return
+	}
+
+	// Thumbnails are best effort; a photo we cannot decode is still kept
+	var thumbnailKey *string
+	if thumb, err := makeThumbnail(data); err == nil {
+		key := fmt.Sprintf("attachments/%d/%d/%s_thumb.jpg", userID, entryID, token)
+		if err := storage.Blobs.Put(ctx, key, thumb, "image/jpeg"); err != nil {
+			logger.L.WithField("error", err).Warn("Error storing attachment thumbnail")
+		} else {
+			thumbnailKey = &key
+		}
+	}
+
+	result, err := database.DB.Exec(`
+		INSERT INTO ledger_attachments (entry_id, file_name, content_type, size_bytes, storage_key, thumbnail_key, user_id)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		entryID, fileName, contentType, len(data), storageKey, thumbnailKey, userID)
+	if err != nil {
+		logger.L.WithField("error", err).Error("Error inserting attachment")
+		deleteAttachmentBlobs(ctx, storageKey, thumbnailKey)
+		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "server_error", "message": "Could not store attachment"})
+		return
+	}
+
+	attachmentID, err := result.LastInsertId()
+	if err != nil {
+		logger.L.WithField("error", err).Error("Error getting inserted attachment ID")
+		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "server_error", "message": "Could not store attachment"})
+		return
+	}
+
+	logger.L.WithFields(map[string]interface{}{
+		"attachment_id": attachmentID,
+		"entry_id":      entryID,
+		"user_id":       userID,
+		"size_bytes":    len(data),
+	}).Info("Ledger attachment uploaded successfully")
+
+	attachment := models.LedgerAttachment{
+		ID:           int(attachmentID),
+		EntryID:      entryID,
+		FileName:     fileName,
+		ContentType:  contentType,
+		SizeBytes:    int64(len(data)),
+		HasThumbnail: thumbnailKey != nil,
+		UserID:       userID,
+		CreatedAt:    time.Now(),
+	}
+
+	writeJSON(w, http.StatusCreated, map[string]interface{}{
+		"success":    true,
+		"message":    "Attachment uploaded successfully",
+		"attachment": attachment,
+	})
+}
+
+// GetLedgerAttachments lists the attachments of a ledger entry
+func GetLedgerAttachments(w http.ResponseWriter, r *http.Request) {
+	// Get user ID from JWT token
+	userID, err := getUserIDFromToken(r)
+	if err != nil {
+		writeJSON(w, http.StatusUnauthorized, map[string]interface{}{"success": false, "error": "unauthorized", "message": "Invalid token"})
+		return
+	}
+
+	entryID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"success": false, "error": "invalid_id", "message": "Invalid entry ID"})
+		return
+	}
+
+	if !checkLedgerEntryOwnership(w, entryID, userID) {
+		return
+	}
+
+	rows, err := database.DB.Query(`
+		SELECT id, entry_id, file_name, content_type, size_bytes, thumbnail_key IS NOT NULL, created_at
+		FROM ledger_attachments
+		WHERE entry_id = ? AND user_id = ?
+		ORDER BY created_at ASC, id ASC`, entryID, userID)
+	if err != nil {
+		logger.L.WithField("error", err).Error("Error querying attachments")
+		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "server_error", "message": "Could not fetch attachments"})
+		return
+	}
+	defer rows.Close()
+
+	attachments := []models.LedgerAttachment{}
+	for rows.Next() {
+		var attachment models.LedgerAttachment
+		var createdAtStr string
+
+		err := rows.Scan(
+			&attachment.ID, &attachment.EntryID, &attachment.FileName, &attachment.ContentType,
+			&attachment.SizeBytes, &attachment.HasThumbnail, &createdAtStr,
+		)
+		if err != nil {
+			logger.L.WithField("error", err).Error("Error scanning attachment")
+			continue
+		}
+
+		attachment.UserID = userID
+		attachment.CreatedAt, _ = time.Parse("2006-01-02 15:04:05", createdAtStr)
+		attachments = append(attachments, attachment)
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"success":     true,
+		"attachments": attachments,
+		"count":       len(attachments),
+	})
+}
+
+// DownloadLedgerAttachment streams an attachment, or its thumbnail when
+// ?thumbnail=true is given
+func DownloadLedgerAttachment(w http.ResponseWriter, r *http.Request) {
+	// Get user ID from JWT token
+	userID, err := getUserIDFromToken(r)
+	if err != nil {
+		writeJSON(w, http.StatusUnauthorized, map[string]interface{}{"success": false, "error": "unauthorized", "message": "Invalid token"})
+		return
+	}
+
+	entryID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"success": false, "error": "invalid_id", "message": "Invalid entry ID"})
+		return
+	}
+	attachmentID, err := strconv.Atoi(mux.Vars(r)["attachmentId"])
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"success": false, "error": "invalid_id", "message": "Invalid attachment ID"})
+		return
+	}
+
+	if !checkLedgerEntryOwnership(w, entryID, userID) {
+		return
+	}
+
+	var fileName, contentType, storageKey string
+	var thumbnailKey sql.NullString
+	err = database.DB.QueryRow(`
+		SELECT file_name, content_type, storage_key, thumbnail_key
+		FROM ledger_attachments
+		WHERE id = ? AND entry_id = ? AND user_id = ?`, attachmentID, entryID, userID).Scan(
+		&fileName, &contentType, &storageKey, &thumbnailKey,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			writeJSON(w, http.StatusNotFound, map[string]interface{}{"success": false, "error": "not_found", "message": "Attachment not found"})
+			return
+		}
+		logger.L.WithField("error", err).Error("Error querying attachment")
+		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "server_error", "message": "Could not fetch attachment"})
+		return
+	}
+
+	key := storageKey
+	if r.URL.Query().Get("thumbnail") == "true" {
+		if !thumbnailKey.Valid {
+			writeJSON(w, http.StatusNotFound, map[string]interface{}{"success": false, "error": "not_found", "message": "Attachment has no thumbnail"})
+			return
+		}
+		key = thumbnailKey.String
+		contentType = "image/jpeg"
+	}
+
+	blob, err := storage.Blobs.Get(r.Context(), key)
+	if err != nil {
+		if err == storage.ErrNotFound {
+			writeJSON(w, http.StatusNotFound, map[string]interface{}{"success": false, "error": "not_found", "message": "Attachment file is missing"})
+			return
+		}
+		logger.L.WithField("error", err).Error("Error reading attachment from store")
+		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "server_error", "message": "Could not fetch attachment"})
+		return
+	}
+	defer blob.Close()
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("inline", map[string]string{"filename": fileName}))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(http.StatusOK)
+	if _, err := io.Copy(w, blob); err != nil {
+		logger.L.WithField("error", err).Warn("Error streaming attachment")
+	}
+}
+
+// DeleteLedgerAttachment removes an attachment and its stored files
+func DeleteLedgerAttachment(w http.ResponseWriter, r *http.Request) {
+	// Get user ID from JWT token
+	userID, err := getUserIDFromToken(r)
+	if err != nil {
+		writeJSON(w, http.StatusUnauthorized, map[string]interface{}{"success": false, "error": "unauthorized", "message": "Invalid token"})
+		return
+	}
+
+	entryID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"success": false, "error": "invalid_id", "message": "Invalid entry ID"})
+		return
+	}
+	attachmentID, err := strconv.Atoi(mux.Vars(r)["attachmentId"])
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"success": false, "error": "invalid_id", "message": "Invalid attachment ID"})
+		return
+	}
+
+	if !checkLedgerEntryOwnership(w, entryID, userID) {
+		return
+	}
+
+	var storageKey string
+	var thumbnailKey sql.NullString
+	err = database.DB.QueryRow(`
+		SELECT storage_key, thumbnail_key
+		FROM ledger_attachments
+		WHERE id = ? AND entry_id = ? AND user_id = ?`, attachmentID, entryID, userID).Scan(&storageKey, &thumbnailKey)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			writeJSON(w, http.StatusNotFound, map[string]interface{}{"success": false, "error": "not_found", "message": "Attachment not found"})
+			return
+		}
+		logger.L.WithField("error", err).Error("Error querying attachment")
+		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "server_error", "message": "Could not delete attachment"})
+		return
+	}
+
+	_, err = database.DB.Exec("DELETE FROM ledger_attachments WHERE id = ?", attachmentID)
+	if err != nil {
+		logger.L.WithField("error", err).Error("Error deleting attachment")
+		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "server_error", "message": "Could not delete attachment"})
+		return
+	}
+
+	var thumbnail *string
+	if thumbnailKey.Valid {
+		thumbnail = &thumbnailKey.String
+	}
+	deleteAttachmentBlobs(r.Context(), storageKey, thumbnail)
+
+	logger.L.WithFields(map[string]interface{}{
+		"attachment_id": attachmentID,
+		"entry_id":      entryID,
+		"user_id":       userID,
+	}).Info("Ledger attachment deleted successfully")
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"message": "Attachment deleted successfully",
+	})
+}
+
+// checkLedgerEntryOwnership applies the same lookup as GetLedgerEntry and
+// writes the error response when the entry is not visible to the user
+func checkLedgerEntryOwnership(w http.ResponseWriter, entryID, userID int) bool {
+	var id int
+	err := database.DB.QueryRow("SELECT id FROM ledger_entries WHERE id = ? AND user_id = ?", entryID, userID).Scan(&id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			writeJSON(w, http.StatusNotFound, map[string]interface{}{"success": false, "error": "not_found", "message": "Ledger entry not found"})
+			return false
+		}
+		logger.L.WithField("error", err).Error("Error querying ledger entry")
+		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "server_error", "message": "Could not fetch ledger entry"})
+		return false
+	}
+	return true
+}
+
+// deleteAttachmentBlobs removes stored files; failures only leave orphans
+// behind, so they are logged rather than returned
+func deleteAttachmentBlobs(ctx context.Context, storageKey string, thumbnailKey *string) {
+	keys := []string{storageKey}
+	if thumbnailKey != nil {
+		keys = append(keys, *thumbnailKey)
+	}
+	for _, key := range keys {
+		if err := storage.Blobs.Delete(ctx, key); err != nil {
+			logger.L.WithFields(map[string]interface{}{"error": err, "key": key}).Warn("Error deleting attachment blob")
+		}
+	}
+}
+
+// makeThumbnail decodes a JPEG, PNG or GIF image and returns a JPEG that fits
+// in a thumbnailMaxSide square. The header is checked first so a small file
+// claiming huge dimensions is refused before any pixels are allocated.
+func makeThumbnail(data []byte) ([]byte, error) {
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Width <= 0 || cfg.Height <= 0 {
+		return nil, errors.New("empty image")
+	}
+	if int64(cfg.Width)*int64(cfg.Height) > thumbnailMaxPixels {
+		return nil, fmt.Errorf("image is %dx%d pixels, too large for a thumbnail", cfg.Width, cfg.Height)
+	}
+
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+
+	bounds := src.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if width == 0 || height == 0 {
+		return nil, errors.New("empty image")
+	}
+
+	scale := float64(thumbnailMaxSide) / float64(max(width, height))
+	if scale > 1 {
+		scale = 1
+	}
+	thumbWidth := max(1, int(float64(width)*scale))
+	thumbHeight := max(1, int(float64(height)*scale))
+
+	// Flatten transparency onto white before sampling, JPEG has no alpha
+	flat := image.NewRGBA(bounds)
+	draw.Draw(flat, bounds, image.NewUniform(color.White), image.Point{}, draw.Src)
+	draw.Draw(flat, bounds, src, bounds.Min, draw.Over)
+
+	// Box filter: average every source pixel that falls into a thumbnail pixel
+	thumb := image.NewRGBA(image.Rect(0, 0, thumbWidth, thumbHeight))
+	for ty := 0; ty < thumbHeight; ty++ {
+		y0 := bounds.Min.Y + ty*height/thumbHeight
+		y1 := max(y0+1, bounds.Min.Y+(ty+1)*height/thumbHeight)
+		for tx := 0; tx < thumbWidth; tx++ {
+			x0 := bounds.Min.X + tx*width/thumbWidth
+			x1 := max(x0+1, bounds.Min.X+(tx+1)*width/thumbWidth)
+
+			var r, g, b, n uint64
+			for y := y0; y < y1; y++ {
+				for x := x0; x < x1; x++ {
+					offset := flat.PixOffset(x, y)
+					r += uint64(flat.Pix[offset])
+					g += uint64(flat.Pix[offset+1])
+					b += uint64(flat.Pix[offset+2])
+					n++
+				}
+			}
+			thumb.SetRGBA(tx, ty, color.RGBA{R: uint8(r / n), G: uint8(g / n), B: uint8(b / n), A: 255})
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, thumb, &jpeg.Options{Quality: 80}); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// randomToken returns 16 random bytes as hex for unguessable blob keys
+func randomToken() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
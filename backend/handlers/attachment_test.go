@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+)
+
+func encodePNG(t *testing.T, width, height int) []byte {
+	t.Helper()
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.Set(x, y, color.NRGBA{R: uint8(x), G: uint8(y), B: 200, A: 255})
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestMakeThumbnail(t *testing.T) {
+	tests := []struct {
+		width, height int
+		wantW, wantH  int
+	}{
+		{640, 480, 320, 240},
+		{100, 1000, 32, 320},
+		{200, 100, 200, 100},
+	}
+	for _, tt := range tests {
+		thumb, err := makeThumbnail(encodePNG(t, tt.width, tt.height))
+		if err != nil {
+			t.Errorf("%dx%d: %v", tt.width, tt.height, err)
+			continue
+		}
+		cfg, err := jpeg.DecodeConfig(bytes.NewReader(thumb))
+		if err != nil {
+			t.Errorf("%dx%d: thumbnail is not a JPEG: %v", tt.width, tt.height, err)
+			continue
+		}
+		if cfg.Width != tt.wantW || cfg.Height != tt.wantH {
+			t.Errorf("%dx%d: thumbnail is %dx%d, want %dx%d", tt.width, tt.height, cfg.Width, cfg.Height, tt.wantW, tt.wantH)
+		}
+	}
+}
+
+func TestMakeThumbnailRejectsHugeImages(t *testing.T) {
+	// Tiny PNGs whose headers claim more pixels than the cap; decoding them
+	// would allocate for the full image before noticing the data is missing
+	for _, size := range [][2]uint32{{60000, 60000}, {5000, 4000}} {
+		data := encodePNG(t, 1, 1)
+		ihdr := data[8+8 : 8+8+13]
+		binary.BigEndian.PutUint32(ihdr[0:4], size[0])
+		binary.BigEndian.PutUint32(ihdr[4:8], size[1])
+		binary.BigEndian.PutUint32(data[8+8+13:], crc32.ChecksumIEEE(data[8+4:8+8+13]))
+
+		cfg, err := png.DecodeConfig(bytes.NewReader(data))
+		if err != nil || cfg.Width != int(size[0]) || cfg.Height != int(size[1]) {
+			t.Fatalf("crafted header did not decode: %v %+v", err, cfg)
+		}
+		if _, err := makeThumbnail(data); err == nil {
+			t.Errorf("thumbnail made for a %dx%d image", size[0], size[1])
+		}
+	}
+}
+
+func TestMakeThumbnailRejectsNonImages(t *testing.T) {
+	if _, err := makeThumbnail([]byte("%PDF-1.7\n")); err == nil {
+		t.Error("thumbnail made for a PDF")
+	}
+}
@@ -11,6 +11,7 @@ import (
 
 	"khata-book-backend/database"
 	"khata-book-backend/models"
+	"khata-book-backend/pkg/gst"
 	"khata-book-backend/pkg/logger"
 
 	"github.com/golang-jwt/jwt/v5"
@@ -289,12 +290,14 @@ func GetProfile(w http.ResponseWriter, r *http.Request) {
 
 	// Get user from database
 	var user models.User
+	var gstin, upiID, timezone, quietStart, quietEnd sql.NullString
 	var createdAtStr string
 	err = database.DB.QueryRow(`
-		SELECT id, name, phone, email, address, created_at 
+		SELECT id, name, phone, email, address, gstin, upi_id, auto_close_reminders, timezone, quiet_hours_start, quiet_hours_end, created_at 
 		FROM users 
 		WHERE id = ?
-	`, userID).Scan(&user.ID, &user.Name, &user.Phone, &user.Email, &user.Address, &createdAtStr)
+	`, userID).Scan(&user.ID, &user.Name, &user.Phone, &user.Email, &user.Address, &gstin, &upiID, &user.AutoCloseReminders,
+		&timezone, &quietStart, &quietEnd, &createdAtStr)
 	if err != nil {
 		logger.L.WithField("error", err).Error("Error getting user profile")
 		writeJSON(w, http.StatusNotFound, map[string]interface{}{"success": false, "error": "not_found", "message": "User not found"})
@@ -308,6 +311,11 @@ func GetProfile(w http.ResponseWriter, r *http.Request) {
 		user.CreatedAt = time.Now()
 	}
 
+	user.GSTIN = gstin.String
+	user.UPIID = upiID.String
+	user.Timezone = timezone.String
+	user.QuietHoursStart, user.QuietHoursEnd = quietHourString(quietStart), quietHourString(quietEnd)
+
 	// Remove password hash from response
 	user.PasswordHash = ""
 
@@ -372,6 +380,48 @@ func UpdateProfile(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	// Validate GSTIN if provided
+	if updateReq.GSTIN != "" {
+		updateReq.GSTIN = gst.Normalize(updateReq.GSTIN)
+		if !gst.ValidGSTIN(updateReq.GSTIN) {
+			writeJSON(w, http.StatusBadRequest, map[string]interface{}{"success": false, "error": "invalid_gstin", "message": "Invalid GSTIN"})
+			return
+		}
+	}
+
+	// Validate UPI ID if provided; it is the payee address in reminder
+	// payment links
+	if updateReq.UPIID != "" {
+		updateReq.UPIID = strings.TrimSpace(updateReq.UPIID)
+		upiIDRegex := regexp.MustCompile(`^[a-zA-Z0-9.\-_]{2,256}@[a-zA-Z]{2,64}$`)
+		if !upiIDRegex.MatchString(updateReq.UPIID) {
+			writeJSON(w, http.StatusBadRequest, map[string]interface{}{"success": false, "error": "invalid_upi_id", "message": "Invalid UPI ID"})
+			return
+		}
+	}
+
+	// Validate the time zone and quiet hours reminders are scheduled by
+	if updateReq.Timezone != "" {
+		updateReq.Timezone = strings.TrimSpace(updateReq.Timezone)
+		if !validTimezone(updateReq.Timezone) {
+			writeJSON(w, http.StatusBadRequest, map[string]interface{}{"success": false, "error": "invalid_timezone", "message": "Timezone must be an IANA time zone such as 'Asia/Kolkata'"})
+			return
+		}
+	}
+	if (updateReq.QuietHoursStart == nil) != (updateReq.QuietHoursEnd == nil) {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"success": false, "error": "invalid_quiet_hours", "message": "quiet_hours_start and quiet_hours_end must be given together"})
+		return
+	}
+	if updateReq.QuietHoursStart != nil {
+		start, end := strings.TrimSpace(*updateReq.QuietHoursStart), strings.TrimSpace(*updateReq.QuietHoursEnd)
+		cleared := start == "" && end == ""
+		if !cleared && (!quietHourRegex.MatchString(start) || !quietHourRegex.MatchString(end) || start == end) {
+			writeJSON(w, http.StatusBadRequest, map[string]interface{}{"success": false, "error": "invalid_quiet_hours", "message": "Quiet hours must be two different times in HH:MM format, or both empty to clear them"})
+			return
+		}
+		*updateReq.QuietHoursStart, *updateReq.QuietHoursEnd = start, end
+	}
+
 	// Build update query dynamically
 	setParts := []string{}
 	args := []interface{}{}
@@ -391,6 +441,31 @@ func UpdateProfile(w http.ResponseWriter, r *http.Request) {
 		args = append(args, updateReq.Address)
 	}
 
+	if updateReq.GSTIN != "" {
+		setParts = append(setParts, "gstin = ?")
+		args = append(args, updateReq.GSTIN)
+	}
+
+	if updateReq.UPIID != "" {
+		setParts = append(setParts, "upi_id = ?")
+		args = append(args, updateReq.UPIID)
+	}
+
+	if updateReq.AutoCloseReminders != nil {
+		setParts = append(setParts, "auto_close_reminders = ?")
+		args = append(args, *updateReq.AutoCloseReminders)
+	}
+
+	if updateReq.Timezone != "" {
+		setParts = append(setParts, "timezone = ?")
+		args = append(args, updateReq.Timezone)
+	}
+
+	if updateReq.QuietHoursStart != nil {
+		setParts = append(setParts, "quiet_hours_start = NULLIF(?, '')", "quiet_hours_end = NULLIF(?, '')")
+		args = append(args, *updateReq.QuietHoursStart, *updateReq.QuietHoursEnd)
+	}
+
 	if len(setParts) == 0 {
 		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"success": false, "error": "no_updates", "message": "No valid fields to update"})
 		return
@@ -409,12 +484,14 @@ func UpdateProfile(w http.ResponseWriter, r *http.Request) {
 
 	// Get updated user data
 	var user models.User
+	var gstin, upiID, timezone, quietStart, quietEnd sql.NullString
 	var createdAtStr string
 	err = database.DB.QueryRow(`
-		SELECT id, name, phone, email, address, created_at 
+		SELECT id, name, phone, email, address, gstin, upi_id, auto_close_reminders, timezone, quiet_hours_start, quiet_hours_end, created_at 
 		FROM users 
 		WHERE id = ?
-	`, userID).Scan(&user.ID, &user.Name, &user.Phone, &user.Email, &user.Address, &createdAtStr)
+	`, userID).Scan(&user.ID, &user.Name, &user.Phone, &user.Email, &user.Address, &gstin, &upiID, &user.AutoCloseReminders,
+		&timezone, &quietStart, &quietEnd, &createdAtStr)
 	if err != nil {
 		logger.L.WithField("error", err).Error("Error getting updated user profile")
 		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "server_error", "message": "Could not retrieve updated profile"})
@@ -428,6 +505,11 @@ func UpdateProfile(w http.ResponseWriter, r *http.Request) {
 		user.CreatedAt = time.Now()
 	}
 
+	user.GSTIN = gstin.String
+	user.UPIID = upiID.String
+	user.Timezone = timezone.String
+	user.QuietHoursStart, user.QuietHoursEnd = quietHourString(quietStart), quietHourString(quietEnd)
+
 	// Remove password hash from response
 	user.PasswordHash = ""
 
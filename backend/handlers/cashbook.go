@@ -0,0 +1,688 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"khata-book-backend/database"
+	"khata-book-backend/models"
+	"khata-book-backend/pkg/logger"
+
+	"github.com/gorilla/mux"
+)
+
+// maxCashBalanceDays caps the range of a daily cash balance request
+const maxCashBalanceDays = 92
+
+const cashbookEntrySelect = `
+	SELECT cb.id, cb.type, cb.amount, cb.method, cb.category_id, cat.name, cb.note, cb.date,
+		   cb.user_id, cb.created_at, cb.updated_at
+	FROM cashbook_entries cb
+	LEFT JOIN categories cat ON cb.category_id = cat.id`
+
+// GetCashbookEntries lists income and expense entries with their totals
+func GetCashbookEntries(w http.ResponseWriter, r *http.Request) {
+	// Get user ID from JWT token
+	userID, err := getUserIDFromToken(r)
+	if err != nil {
+		writeJSON(w, http.StatusUnauthorized, map[string]interface{}{"success": false, "error": "unauthorized", "message": "Invalid token"})
+		return
+	}
+
+	// Parse query parameters
+	entryType := r.URL.Query().Get("type")
+	method := r.URL.Query().Get("method")
+	categoryIDStr := r.URL.Query().Get("category_id")
+	fromStr := r.URL.Query().Get("from")
+	toStr := r.URL.Query().Get("to")
+	limitStr := r.URL.Query().Get("limit")
+	offsetStr := r.URL.Query().Get("offset")
+
+	where := " WHERE cb.user_id = ?"
+	args := []interface{}{userID}
+
+	if entryType == "income" || entryType == "expense" {
+		where += " AND cb.type = ?"
+		args = append(args, entryType)
+	}
+
+	if method != "" {
+		where += " AND cb.method = ?"
+		args = append(args, method)
+	}
+
+	if categoryIDStr != "" {
+		if categoryID, err := strconv.Atoi(categoryIDStr); err == nil {
+			where += " AND cb.category_id = ?"
+			args = append(args, categoryID)
+		}
+	}
+
+	if fromStr != "" {
+		if from, err := time.Parse("2006-01-02", fromStr); err == nil {
+			where += " AND cb.date >= ?"
+			args = append(args, from.Format("2006-01-02"))
+		}
+	}
+
+	if toStr != "" {
+		if to, err := time.Parse("2006-01-02", toStr); err == nil {
+			where += " AND cb.date <= ?"
+			args = append(args, to.Format("2006-01-02"))
+		}
+	}
+
+	// Totals cover every matching entry, not just the current page
+	var totalIncome, totalExpense float64
+	var count int
+	err = database.DB.QueryRow(`
+		SELECT
+			COALESCE(SUM(CASE WHEN cb.type = 'income' THEN cb.amount ELSE 0 END), 0),
+			COALESCE(SUM(CASE WHEN cb.type = 'expense' THEN cb.amount ELSE 0 END), 0),
+			COUNT(*)
+		FROM cashbook_entries cb`+where, args...).Scan(&totalIncome, &totalExpense, &count)
+	if err != nil {
+		logger.L.WithField("error", err).Error("Error totalling cashbook entries")
+		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "server_error", "message": "Could not fetch cashbook entries"})
+		return
+	}
+
+	limit := 50 // default limit
+	if limitStr != "" {
+		if parsedLimit, err := strconv.Atoi(limitStr); err == nil && parsedLimit > 0 && parsedLimit <= 200 {
+			limit = parsedLimit
+		}
+	}
+
+	offset := 0
+	if offsetStr != "" {
+		if parsedOffset, err := strconv.Atoi(offsetStr); err == nil && parsedOffset >= 0 {
+			offset = parsedOffset
+		}
+	}
+
+	query := cashbookEntrySelect + where + " ORDER BY cb.date DESC, cb.id DESC LIMIT ? OFFSET ?"
+	args = append(args, limit, offset)
+
+	rows, err := database.DB.Query(query, args...)
+	if err != nil {
+		logger.L.WithField("error", err).Error("Error querying cashbook entries")
+		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "server_error", "message": "Could not fetch cashbook entries"})
+		return
+	}
+	defer rows.Close()
+
+	entries := []models.CashbookEntry{}
+	for rows.Next() {
+		entry, err := scanCashbookEntry(rows)
+		if err != nil {
+			logger.L.WithField("error", err).Error("Error scanning cashbook entry")
+			continue
+		}
+		entries = append(entries, *entry)
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"entries": entries,
+		"count":   count,
+		"totals": map[string]interface{}{
+			"income":  totalIncome,
+			"expense": totalExpense,
+			"net":     roundMoney(totalIncome - totalExpense),
+		},
+	})
+}
+
+// CreateCashbookEntry records income or an expense that belongs to no party
+func CreateCashbookEntry(w http.ResponseWriter, r *http.Request) {
+	// Get user ID from JWT token
+	userID, err := getUserIDFromToken(r)
+	if err != nil {
+		writeJSON(w, http.StatusUnauthorized, map[string]interface{}{"success": false, "error": "unauthorized", "message": "Invalid token"})
+		return
+	}
+
+	var entryReq models.CashbookEntryRequest
+	err = json.NewDecoder(r.Body).Decode(&entryReq)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"success": false, "error": "invalid_request", "message": "Invalid request body"})
+		return
+	}
+
+	// Set default date if not provided
+	entryDate := calendarDate(time.Now())
+	if !entryReq.Date.IsZero() {
+		entryDate = entryReq.Date
+	}
+
+	entry := models.CashbookEntry{
+		Type:       entryReq.Type,
+		Amount:     entryReq.Amount,
+		Method:     entryReq.Method,
+		CategoryID: entryReq.CategoryID,
+		Note:       entryReq.Note,
+		Date:       entryDate,
+		UserID:     userID,
+	}
+	if !validateCashbookEntry(w, &entry) || !checkDayOpen(w, userID, entry.Date) {
+		return
+	}
+
+	tx, err := database.DB.Begin()
+	if err != nil {
+		logger.L.WithField("error", err).Error("Could not start transaction")
+		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "server_error", "message": "Could not process request"})
+		return
+	}
+	defer tx.Rollback()
+
+	if !checkDayOpenTx(w, tx, userID, entry.Date) {
+		return
+	}
+
+	result, err := tx.Exec(`
+		INSERT INTO cashbook_entries (type, amount, method, category_id, note, date, user_id)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		entry.Type, entry.Amount, entry.Method, entry.CategoryID, entry.Note, entry.Date.Format("2006-01-02"), userID)
+	if err != nil {
+		logger.L.WithField("error", err).Error("Error inserting cashbook entry")
+		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "server_error", "message": "Could not create cashbook entry"})
+		return
+	}
+
+	entryID, err := result.LastInsertId()
+	if err != nil {
+		logger.L.WithField("error", err).Error("Error getting inserted cashbook entry ID")
+		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "server_error", "message": "Could not create cashbook entry"})
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		logger.L.WithField("error", err).Error("Error committing transaction")
+		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "server_error", "message": "Could not create cashbook entry"})
+		return
+	}
+
+	created, err := getCashbookEntry(int(entryID), userID)
+	if err != nil {
+		logger.L.WithField("error", err).Error("Error loading created cashbook entry")
+		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "server_error", "message": "Could not create cashbook entry"})
+		return
+	}
+
+	logger.L.WithFields(map[string]interface{}{
+		"cashbook_entry_id": entryID,
+		"user_id":           userID,
+		"type":              entry.Type,
+		"amount":            entry.Amount,
+	}).Info("Cashbook entry created successfully")
+
+	writeJSON(w, http.StatusCreated, map[string]interface{}{
+		"success": true,
+		"message": "Cashbook entry created successfully",
+		"entry":   created,
+	})
+}
+
+// GetCashbookEntry returns a single cashbook entry
+func GetCashbookEntry(w http.ResponseWriter, r *http.Request) {
+	// Get user ID from JWT token
+	userID, err := getUserIDFromToken(r)
+	if err != nil {
+		writeJSON(w, http.StatusUnauthorized, map[string]interface{}{"success": false, "error": "unauthorized", "message": "Invalid token"})
+		return
+	}
+
+	entryID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"success": false, "error": "invalid_id", "message": "Invalid cashbook entry ID"})
+		return
+	}
+
+	entry, err := getCashbookEntry(entryID, userID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			writeJSON(w, http.StatusNotFound, map[string]interface{}{"success": false, "error": "not_found", "message": "Cashbook entry not found"})
+			return
+		}
+		logger.L.WithField("error", err).Error("Error querying cashbook entry")
+		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "server_error", "message": "Could not fetch cashbook entry"})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{"success": true, "entry": entry})
+}
+
+// UpdateCashbookEntry changes the fields given in the request body
+func UpdateCashbookEntry(w http.ResponseWriter, r *http.Request) {
+	// Get user ID from JWT token
+	userID, err := getUserIDFromToken(r)
+	if err != nil {
+		writeJSON(w, http.StatusUnauthorized, map[string]interface{}{"success": false, "error": "unauthorized", "message": "Invalid token"})
+		return
+	}
+
+	entryID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"success": false, "error": "invalid_id", "message": "Invalid cashbook entry ID"})
+		return
+	}
+
+	var entryReq models.CashbookEntryRequest
+	err = json.NewDecoder(r.Body).Decode(&entryReq)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"success": false, "error": "invalid_request", "message": "Invalid request body"})
+		return
+	}
+
+	entry, err := getCashbookEntry(entryID, userID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			writeJSON(w, http.StatusNotFound, map[string]interface{}{"success": false, "error": "not_found", "message": "Cashbook entry not found"})
+			return
+		}
+		logger.L.WithField("error", err).Error("Error querying cashbook entry")
+		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "server_error", "message": "Could not update cashbook entry"})
+		return
+	}
+
+	// Both the day the entry was on and the day it moves to must be open
+	originalDate := entry.Date
+	if !checkDayOpen(w, userID, originalDate) {
+		return
+	}
+
+	if entryReq.Type != "" {
+		entry.Type = entryReq.Type
+	}
+	if entryReq.Amount != 0 {
+		entry.Amount = entryReq.Amount
+	}
+	if entryReq.Method != "" {
+		entry.Method = entryReq.Method
+	}
+	if entryReq.CategoryID != nil {
+		entry.CategoryID = entryReq.CategoryID
+	}
+	if entryReq.Note != nil {
+		entry.Note = entryReq.Note
+	}
+	if !entryReq.Date.IsZero() {
+		entry.Date = entryReq.Date
+	}
+	if !validateCashbookEntry(w, entry) || !checkDayOpen(w, userID, entry.Date) {
+		return
+	}
+
+	tx, err := database.DB.Begin()
+	if err != nil {
+		logger.L.WithField("error", err).Error("Could not start transaction")
+		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "server_error", "message": "Could not process request"})
+		return
+	}
+	defer tx.Rollback()
+
+	// The entry is locked and its date read again, so a concurrent move
+	// cannot take it out from under the check
+	if !checkCashbookEntryDayOpenTx(w, tx, entryID, userID) || !checkDayOpenTx(w, tx, userID, entry.Date) {
+		return
+	}
+
+	_, err = tx.Exec(`
+		UPDATE cashbook_entries
+		SET type = ?, amount = ?, method = ?, category_id = ?, note = ?, date = ?, updated_at = CURRENT_TIMESTAMP
+		WHERE id = ? AND user_id = ?`,
+		entry.Type, entry.Amount, entry.Method, entry.CategoryID, entry.Note, entry.Date.Format("2006-01-02"), entryID, userID)
+	if err != nil {
+		logger.L.WithField("error", err).Error("Error updating cashbook entry")
+		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "server_error", "message": "Could not update cashbook entry"})
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		logger.L.WithField("error", err).Error("Error committing transaction")
+		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "server_error", "message": "Could not update cashbook entry"})
+		return
+	}
+
+	updated, err := getCashbookEntry(entryID, userID)
+	if err != nil {
+		logger.L.WithField("error", err).Error("Error loading updated cashbook entry")
+		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "server_error", "message": "Could not update cashbook entry"})
+		return
+	}
+
+	logger.L.WithFields(map[string]interface{}{
+		"cashbook_entry_id": entryID,
+		"user_id":           userID,
+	}).Info("Cashbook entry updated successfully")
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"message": "Cashbook entry updated successfully",
+		"entry":   updated,
+	})
+}
+
+// DeleteCashbookEntry removes a cashbook entry
+func DeleteCashbookEntry(w http.ResponseWriter, r *http.Request) {
+	// Get user ID from JWT token
+	userID, err := getUserIDFromToken(r)
+	if err != nil {
+		writeJSON(w, http.StatusUnauthorized, map[string]interface{}{"success": false, "error": "unauthorized", "message": "Invalid token"})
+		return
+	}
+
+	entryID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"success": false, "error": "invalid_id", "message": "Invalid cashbook entry ID"})
+		return
+	}
+
+	entry, err := getCashbookEntry(entryID, userID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			writeJSON(w, http.StatusNotFound, map[string]interface{}{"success": false, "error": "not_found", "message": "Cashbook entry not found"})
+			return
+		}
+		logger.L.WithField("error", err).Error("Error querying cashbook entry")
+		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "server_error", "message": "Could not delete cashbook entry"})
+		return
+	}
+
+	if !checkDayOpen(w, userID, entry.Date) {
+		return
+	}
+
+	tx, err := database.DB.Begin()
+	if err != nil {
+		logger.L.WithField("error", err).Error("Could not start transaction")
+		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "server_error", "message": "Could not process request"})
+		return
+	}
+	defer tx.Rollback()
+
+	if !checkCashbookEntryDayOpenTx(w, tx, entryID, userID) {
+		return
+	}
+
+	_, err = tx.Exec("DELETE FROM cashbook_entries WHERE id = ? AND user_id = ?", entryID, userID)
+	if err != nil {
+		logger.L.WithField("error", err).Error("Error deleting cashbook entry")
+		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "server_error", "message": "Could not delete cashbook entry"})
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		logger.L.WithField("error", err).Error("Error committing transaction")
+		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "server_error", "message": "Could not delete cashbook entry"})
+		return
+	}
+
+	logger.L.WithFields(map[string]interface{}{
+		"cashbook_entry_id": entryID,
+		"user_id":           userID,
+	}).Info("Cashbook entry deleted successfully")
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{"success": true, "message": "Cashbook entry deleted successfully"})
+}
+
+// GetCashBalances returns the opening and closing balance of every payment
+// method for each day between ?from and ?to, both defaulting to today
+func GetCashBalances(w http.ResponseWriter, r *http.Request) {
+	// Get user ID from JWT token
+	userID, err := getUserIDFromToken(r)
+	if err != nil {
+		writeJSON(w, http.StatusUnauthorized, map[string]interface{}{"success": false, "error": "unauthorized", "message": "Invalid token"})
+		return
+	}
+
+	from := calendarDate(time.Now())
+	if fromStr := r.URL.Query().Get("from"); fromStr != "" {
+		from, err = time.Parse("2006-01-02", fromStr)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]interface{}{"success": false, "error": "invalid_date", "message": "from must be a date in YYYY-MM-DD format"})
+			return
+		}
+	}
+
+	to := from
+	if toStr := r.URL.Query().Get("to"); toStr != "" {
+		to, err = time.Parse("2006-01-02", toStr)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]interface{}{"success": false, "error": "invalid_date", "message": "to must be a date in YYYY-MM-DD format"})
+			return
+		}
+	}
+
+	if to.Before(from) || to.Sub(from).Hours()/24 >= maxCashBalanceDays {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"success": false, "error": "invalid_range", "message": "to must be on or after from and within 92 days of it"})
+		return
+	}
+
+	days, err := getCashBalances(database.DB, userID, from, to)
+	if err != nil {
+		logger.L.WithField("error", err).Error("Error computing cash balances")
+		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "server_error", "message": "Could not fetch cash balances"})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"from":    from.Format("2006-01-02"),
+		"to":      to.Format("2006-01-02"),
+		"days":    days,
+	})
+}
+
+// validateCashbookEntry checks an entry before it is written. Cheques are
+// tracked through the khata, so they cannot be used for cashbook entries.
+func validateCashbookEntry(w http.ResponseWriter, entry *models.CashbookEntry) bool {
+	if entry.Type != "income" && entry.Type != "expense" {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"success": false, "error": "invalid_type", "message": "Type must be 'income' or 'expense'"})
+		return false
+	}
+
+	if entry.Amount <= 0 {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"success": false, "error": "invalid_amount", "message": "Amount must be greater than 0"})
+		return false
+	}
+
+	method, err := lookupPaymentMethod(entry.UserID, strings.TrimSpace(entry.Method))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			writeJSON(w, http.StatusBadRequest, map[string]interface{}{"success": false, "error": "invalid_method", "message": "Method must be one of your active payment methods"})
+			return false
+		}
+		logger.L.WithField("error", err).Error("Error looking up payment method")
+		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "server_error", "message": "Could not verify payment method"})
+		return false
+	}
+	if method.Kind == "cheque" {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"success": false, "error": "invalid_method", "message": "Cheque payments must be recorded against a party"})
+		return false
+	}
+	entry.Method = method.Code
+
+	if entry.CategoryID != nil && !checkCategoryOwnership(w, *entry.CategoryID, entry.UserID) {
+		return false
+	}
+
+	return true
+}
+
+// cashMovements selects every movement of money as (method, date, cash_in,
+// cash_out): payments received from customers and made to suppliers in the
+// khata, and cashbook income and expenses. Credit given to a customer and
+// goods bought on credit from a supplier move no money, and a cheque only
+// counts once it has cleared, on the day it cleared. cond is applied to both
+// halves, so its arguments are given twice, each time after the user ID.
+func cashMovements(cond string) string {
+	return `
+		SELECT method, date, cash_in, cash_out
+		FROM (
+			SELECT le.method, COALESCE(cd.clearing_date, le.date) AS date,
+				CASE WHEN le.type = 'debit' THEN le.amount ELSE 0 END AS cash_in,
+				CASE WHEN le.type = 'credit' THEN le.amount ELSE 0 END AS cash_out
+			FROM ledger_entries le
+			JOIN customers c ON le.customer_id = c.id
+			LEFT JOIN cheque_details cd ON cd.entry_id = le.id
+			WHERE le.user_id = ? AND ` + paymentEntryCond + `
+			  AND (cd.entry_id IS NULL OR cd.status = 'cleared')
+		) payments
+		WHERE ` + cond + `
+		UNION ALL
+		SELECT method, date,
+			CASE WHEN type = 'income' THEN amount ELSE 0 END AS cash_in,
+			CASE WHEN type = 'expense' THEN amount ELSE 0 END AS cash_out
+		FROM cashbook_entries
+		WHERE user_id = ? AND ` + cond
+}
+
+// getCashBalances walks each day from from to to and reports every payment
+// method's opening balance, money in, money out and closing balance
+func getCashBalances(q queryer, userID int, from, to time.Time) ([]models.CashDay, error) {
+	fromStr := from.Format("2006-01-02")
+	toStr := to.Format("2006-01-02")
+
+	running := map[string]float64{}
+	openingRows, err := q.Query(`
+		SELECT method, SUM(cash_in) - SUM(cash_out)
+		FROM (`+cashMovements("date < ?")+`) movements
+		GROUP BY method`, userID, fromStr, userID, fromStr)
+	if err != nil {
+		return nil, err
+	}
+	defer openingRows.Close()
+
+	for openingRows.Next() {
+		var method string
+		var opening float64
+		if err := openingRows.Scan(&method, &opening); err != nil {
+			return nil, err
+		}
+		running[method] = opening
+	}
+	if err := openingRows.Err(); err != nil {
+		return nil, err
+	}
+
+	type flow struct{ in, out float64 }
+	flows := map[string]map[string]flow{}
+	rows, err := q.Query(`
+		SELECT date, method, SUM(cash_in), SUM(cash_out)
+		FROM (`+cashMovements("date BETWEEN ? AND ?")+`) movements
+		GROUP BY date, method`, userID, fromStr, toStr, userID, fromStr, toStr)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var date, method string
+		var f flow
+		if err := rows.Scan(&date, &method, &f.in, &f.out); err != nil {
+			return nil, err
+		}
+		if flows[date] == nil {
+			flows[date] = map[string]flow{}
+		}
+		flows[date][method] = f
+		if _, ok := running[method]; !ok {
+			running[method] = 0
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	methods := make([]string, 0, len(running))
+	for method := range running {
+		methods = append(methods, method)
+	}
+	sort.Strings(methods)
+
+	days := []models.CashDay{}
+	for day := from; !day.After(to); day = day.AddDate(0, 0, 1) {
+		date := day.Format("2006-01-02")
+		cashDay := models.CashDay{Date: date, Methods: []models.CashBalance{}}
+		for _, method := range methods {
+			f := flows[date][method]
+			balance := models.CashBalance{
+				Method:  method,
+				Opening: roundMoney(running[method]),
+				In:      roundMoney(f.in),
+				Out:     roundMoney(f.out),
+			}
+			balance.Closing = roundMoney(balance.Opening + balance.In - balance.Out)
+			running[method] = balance.Closing
+
+			cashDay.Methods = append(cashDay.Methods, balance)
+			cashDay.Opening += balance.Opening
+			cashDay.In += balance.In
+			cashDay.Out += balance.Out
+			cashDay.Closing += balance.Closing
+		}
+		cashDay.Opening = roundMoney(cashDay.Opening)
+		cashDay.In = roundMoney(cashDay.In)
+		cashDay.Out = roundMoney(cashDay.Out)
+		cashDay.Closing = roundMoney(cashDay.Closing)
+		days = append(days, cashDay)
+	}
+
+	return days, nil
+}
+
+// getCashbookTotals sums cashbook income and expenses
+func getCashbookTotals(userID int) (map[string]interface{}, error) {
+	var income, expense float64
+	err := database.DB.QueryRow(`
+		SELECT
+			COALESCE(SUM(CASE WHEN type = 'income' THEN amount ELSE 0 END), 0),
+			COALESCE(SUM(CASE WHEN type = 'expense' THEN amount ELSE 0 END), 0)
+		FROM cashbook_entries
+		WHERE user_id = ?`, userID).Scan(&income, &expense)
+	if err != nil {
+		return nil, err
+	}
+
+	return map[string]interface{}{
+		"total_income":  income,
+		"total_expense": expense,
+		"net":           roundMoney(income - expense),
+	}, nil
+}
+
+func getCashbookEntry(entryID, userID int) (*models.CashbookEntry, error) {
+	return scanCashbookEntry(database.DB.QueryRow(cashbookEntrySelect+" WHERE cb.id = ? AND cb.user_id = ?", entryID, userID))
+}
+
+func scanCashbookEntry(row rowScanner) (*models.CashbookEntry, error) {
+	var entry models.CashbookEntry
+	var categoryID sql.NullInt64
+	var categoryName, note sql.NullString
+	var dateStr, createdAtStr, updatedAtStr string
+
+	err := row.Scan(
+		&entry.ID, &entry.Type, &entry.Amount, &entry.Method, &categoryID, &categoryName, &note, &dateStr,
+		&entry.UserID, &createdAtStr, &updatedAtStr,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	entry.CategoryID = nullIntPtr(categoryID)
+	entry.CategoryName = nullStringPtr(categoryName)
+	entry.Note = nullStringPtr(note)
+	entry.Date, _ = time.Parse("2006-01-02", dateStr)
+	entry.CreatedAt, _ = time.Parse("2006-01-02 15:04:05", createdAtStr)
+	entry.UpdatedAt, _ = time.Parse("2006-01-02 15:04:05", updatedAtStr)
+
+	return &entry, nil
+}
@@ -0,0 +1,480 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"khata-book-backend/database"
+	"khata-book-backend/models"
+	"khata-book-backend/pkg/logger"
+
+	"github.com/gorilla/mux"
+)
+
+const (
+	maxTagsPerEntry = 20
+	maxTagLength    = 50
+)
+
+var errInvalidTags = errors.New("invalid tags")
+
+// GetCategories lists the user's categories with how many entries use each
+func GetCategories(w http.ResponseWriter, r *http.Request) {
+	// Get user ID from JWT token
+	userID, err := getUserIDFromToken(r)
+	if err != nil {
+		writeJSON(w, http.StatusUnauthorized, map[string]interface{}{"success": false, "error": "unauthorized", "message": "Invalid token"})
+		return
+	}
+
+	rows, err := database.DB.Query(`
+		SELECT cat.id, cat.name, cat.created_at, cat.updated_at, COUNT(le.id) as entry_count
+		FROM categories cat
+		LEFT JOIN ledger_entries le ON le.category_id = cat.id
+		WHERE cat.user_id = ?
+		GROUP BY cat.id, cat.name, cat.created_at, cat.updated_at
+		ORDER BY cat.name ASC`, userID)
+	if err != nil {
+		logger.L.WithField("error", err).Error("Error querying categories")
+		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "server_error", "message": "Could not fetch categories"})
+		return
+	}
+	defer rows.Close()
+
+	categories := []models.Category{}
+	for rows.Next() {
+		var category models.Category
+		var createdAtStr, updatedAtStr string
+
+		err := rows.Scan(&category.ID, &category.Name, &createdAtStr, &updatedAtStr, &category.EntryCount)
+		if err != nil {
+			logger.L.WithField("error", err).Error("Error scanning category")
+			continue
+		}
+
+		category.UserID = userID
+		category.CreatedAt, _ = time.Parse("2006-01-02 15:04:05", createdAtStr)
+		category.UpdatedAt, _ = time.Parse("2006-01-02 15:04:05", updatedAtStr)
+		categories = append(categories, category)
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"success":    true,
+		"categories": categories,
+	})
+}
+
+// CreateCategory creates a new category for the authenticated user
+func CreateCategory(w http.ResponseWriter, r *http.Request) {
+	// Get user ID from JWT token
+	userID, err := getUserIDFromToken(r)
+	if err != nil {
+		writeJSON(w, http.StatusUnauthorized, map[string]interface{}{"success": false, "error": "unauthorized", "message": "Invalid token"})
+		return
+	}
+
+	var categoryReq models.CategoryRequest
+	err = json.NewDecoder(r.Body).Decode(&categoryReq)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"success": false, "error": "invalid_request", "message": "Invalid request body"})
+		return
+	}
+
+	categoryReq.Name = strings.TrimSpace(categoryReq.Name)
+	if categoryReq.Name == "" || len(categoryReq.Name) > 100 {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"success": false, "error": "invalid_name", "message": "Category name is required and must be at most 100 characters"})
+		return
+	}
+
+	result, err := database.DB.Exec(`INSERT INTO categories (name, user_id) VALUES (?, ?)`, categoryReq.Name, userID)
+	if err != nil {
+		if strings.Contains(err.Error(), "Duplicate entry") {
+			writeJSON(w, http.StatusConflict, map[string]interface{}{"success": false, "error": "category_exists", "message": "A category with this name already exists"})
+			return
+		}
+		logger.L.WithField("error", err).Error("Error inserting category")
+		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "server_error", "message": "Could not create category"})
+		return
+	}
+
+	categoryID, err := result.LastInsertId()
+	if err != nil {
+		logger.L.WithField("error", err).Error("Error getting inserted category ID")
+		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "server_error", "message": "Could not create category"})
+		return
+	}
+
+	logger.L.WithFields(map[string]interface{}{
+		"category_id": categoryID,
+		"user_id":     userID,
+		"name":        categoryReq.Name,
+	}).Info("Category created successfully")
+
+	category := models.Category{
+		ID:        int(categoryID),
+		Name:      categoryReq.Name,
+		UserID:    userID,
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+
+	writeJSON(w, http.StatusCreated, map[string]interface{}{
+		"success":  true,
+		"message":  "Category created successfully",
+		"category": category,
+	})
+}
+
+// UpdateCategory renames a category
+func UpdateCategory(w http.ResponseWriter, r *http.Request) {
+	// Get user ID from JWT token
+	userID, err := getUserIDFromToken(r)
+	if err != nil {
+		writeJSON(w, http.StatusUnauthorized, map[string]interface{}{"success": false, "error": "unauthorized", "message": "Invalid token"})
+		return
+	}
+
+	categoryID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"success": false, "error": "invalid_id", "message": "Invalid category ID"})
+		return
+	}
+
+	var categoryReq models.CategoryRequest
+	err = json.NewDecoder(r.Body).Decode(&categoryReq)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"success": false, "error": "invalid_request", "message": "Invalid request body"})
+		return
+	}
+
+	categoryReq.Name = strings.TrimSpace(categoryReq.Name)
+	if categoryReq.Name == "" || len(categoryReq.Name) > 100 {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"success": false, "error": "invalid_name", "message": "Category name is required and must be at most 100 characters"})
+		return
+	}
+
+	if !checkCategoryOwnership(w, categoryID, userID) {
+		return
+	}
+
+	_, err = database.DB.Exec(`
+		UPDATE categories SET name = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ?`,
+		categoryReq.Name, categoryID)
+	if err != nil {
+		if strings.Contains(err.Error(), "Duplicate entry") {
+			writeJSON(w, http.StatusConflict, map[string]interface{}{"success": false, "error": "category_exists", "message": "A category with this name already exists"})
+			return
+		}
+		logger.L.WithField("error", err).Error("Error updating category")
+		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "server_error", "message": "Could not update category"})
+		return
+	}
+
+	logger.L.WithFields(map[string]interface{}{
+		"category_id": categoryID,
+		"user_id":     userID,
+	}).Info("Category updated successfully")
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"message": "Category updated successfully",
+	})
+}
+
+// DeleteCategory deletes a category; its entries become uncategorized
+func DeleteCategory(w http.ResponseWriter, r *http.Request) {
+	// Get user ID from JWT token
+	userID, err := getUserIDFromToken(r)
+	if err != nil {
+		writeJSON(w, http.StatusUnauthorized, map[string]interface{}{"success": false, "error": "unauthorized", "message": "Invalid token"})
+		return
+	}
+
+	categoryID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"success": false, "error": "invalid_id", "message": "Invalid category ID"})
+		return
+	}
+
+	if !checkCategoryOwnership(w, categoryID, userID) {
+		return
+	}
+
+	_, err = database.DB.Exec("DELETE FROM categories WHERE id = ?", categoryID)
+	if err != nil {
+		logger.L.WithField("error", err).Error("Error deleting category")
+		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "server_error", "message": "Could not delete category"})
+		return
+	}
+
+	logger.L.WithFields(map[string]interface{}{
+		"category_id": categoryID,
+		"user_id":     userID,
+	}).Info("Category deleted successfully")
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"message": "Category deleted successfully",
+	})
+}
+
+// GetTags lists every tag the user has applied, with usage counts
+func GetTags(w http.ResponseWriter, r *http.Request) {
+	// Get user ID from JWT token
+	userID, err := getUserIDFromToken(r)
+	if err != nil {
+		writeJSON(w, http.StatusUnauthorized, map[string]interface{}{"success": false, "error": "unauthorized", "message": "Invalid token"})
+		return
+	}
+
+	rows, err := database.DB.Query(`
+		SELECT tag, COUNT(*) as entry_count
+		FROM ledger_entry_tags
+		WHERE user_id = ?
+		GROUP BY tag
+		ORDER BY entry_count DESC, tag ASC`, userID)
+	if err != nil {
+		logger.L.WithField("error", err).Error("Error querying tags")
+		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "server_error", "message": "Could not fetch tags"})
+		return
+	}
+	defer rows.Close()
+
+	tags := []map[string]interface{}{}
+	for rows.Next() {
+		var tag string
+		var entryCount int
+		if err := rows.Scan(&tag, &entryCount); err != nil {
+			logger.L.WithField("error", err).Error("Error scanning tag")
+			continue
+		}
+		tags = append(tags, map[string]interface{}{"tag": tag, "entry_count": entryCount})
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"tags":    tags,
+	})
+}
+
+// UpdateLedgerEntryCategory moves a ledger entry into a category, or clears
+// it when category_id is null
+func UpdateLedgerEntryCategory(w http.ResponseWriter, r *http.Request) {
+	// Get user ID from JWT token
+	userID, err := getUserIDFromToken(r)
+	if err != nil {
+		writeJSON(w, http.StatusUnauthorized, map[string]interface{}{"success": false, "error": "unauthorized", "message": "Invalid token"})
+		return
+	}
+
+	entryID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"success": false, "error": "invalid_id", "message": "Invalid entry ID"})
+		return
+	}
+
+	var updateReq struct {
+		CategoryID *int `json:"category_id"`
+	}
+	err = json.NewDecoder(r.Body).Decode(&updateReq)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"success": false, "error": "invalid_request", "message": "Invalid request body"})
+		return
+	}
+
+	if !checkLedgerEntryOwnership(w, entryID, userID) || !checkEntryDayOpen(w, entryID, userID) {
+		return
+	}
+
+	if updateReq.CategoryID != nil && !checkCategoryOwnership(w, *updateReq.CategoryID, userID) {
+		return
+	}
+
+	tx, err := database.DB.Begin()
+	if err != nil {
+		logger.L.WithField("error", err).Error("Could not start transaction")
+		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "server_error", "message": "Could not process request"})
+		return
+	}
+	defer tx.Rollback()
+
+	if !checkEntryDayOpenTx(w, tx, entryID, userID) {
+		return
+	}
+
+	_, err = tx.Exec(`
+		UPDATE ledger_entries SET category_id = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ?`,
+		updateReq.CategoryID, entryID)
+	if err != nil {
+		logger.L.WithField("error", err).Error("Error updating ledger entry category")
+		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "server_error", "message": "Could not update ledger entry"})
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		logger.L.WithField("error", err).Error("Error committing transaction")
+		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "server_error", "message": "Could not update ledger entry"})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"message": "Ledger entry category updated successfully",
+	})
+}
+
+// UpdateLedgerEntryTags replaces the tags on a ledger entry
+func UpdateLedgerEntryTags(w http.ResponseWriter, r *http.Request) {
+	// Get user ID from JWT token
+	userID, err := getUserIDFromToken(r)
+	if err != nil {
+		writeJSON(w, http.StatusUnauthorized, map[string]interface{}{"success": false, "error": "unauthorized", "message": "Invalid token"})
+		return
+	}
+
+	entryID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"success": false, "error": "invalid_id", "message": "Invalid entry ID"})
+		return
+	}
+
+	var updateReq struct {
+		Tags []string `json:"tags"`
+	}
+	err = json.NewDecoder(r.Body).Decode(&updateReq)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"success": false, "error": "invalid_request", "message": "Invalid request body"})
+		return
+	}
+
+	tags, err := normalizeTags(updateReq.Tags)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"success": false, "error": "invalid_tags", "message": "At most 20 tags of up to 50 characters are allowed"})
+		return
+	}
+
+	if !checkLedgerEntryOwnership(w, entryID, userID) || !checkEntryDayOpen(w, entryID, userID) {
+		return
+	}
+
+	tx, err := database.DB.Begin()
+	if err != nil {
+		logger.L.WithField("error", err).Error("Could not start transaction")
+		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "server_error", "message": "Could not process request"})
+		return
+	}
+
+	if !checkEntryDayOpenTx(w, tx, entryID, userID) {
+		tx.Rollback()
+		return
+	}
+
+	if err := replaceEntryTags(tx, entryID, userID, tags); err != nil {
+		tx.Rollback()
+		logger.L.WithField("error", err).Error("Error replacing ledger entry tags")
+		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "server_error", "message": "Could not update tags"})
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		logger.L.WithField("error", err).Error("Error committing transaction")
+		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "server_error", "message": "Could not update tags"})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"message": "Ledger entry tags updated successfully",
+		"tags":    tags,
+	})
+}
+
+// checkCategoryOwnership writes the error response and returns false when the
+// category does not exist for this user
+func checkCategoryOwnership(w http.ResponseWriter, categoryID, userID int) bool {
+	var id int
+	err := database.DB.QueryRow("SELECT id FROM categories WHERE id = ? AND user_id = ?", categoryID, userID).Scan(&id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			writeJSON(w, http.StatusNotFound, map[string]interface{}{"success": false, "error": "category_not_found", "message": "Category not found"})
+			return false
+		}
+		logger.L.WithField("error", err).Error("Error checking category ownership")
+		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "server_error", "message": "Could not verify category"})
+		return false
+	}
+	return true
+}
+
+// normalizeTags trims, lowercases and de-duplicates free-form tags
+func normalizeTags(raw []string) ([]string, error) {
+	seen := map[string]bool{}
+	tags := []string{}
+	for _, tag := range raw {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" || seen[tag] {
+			continue
+		}
+		if len(tag) > maxTagLength {
+			return nil, errInvalidTags
+		}
+		seen[tag] = true
+		tags = append(tags, tag)
+	}
+	if len(tags) > maxTagsPerEntry {
+		return nil, errInvalidTags
+	}
+	sort.Strings(tags)
+	return tags, nil
+}
+
+// replaceEntryTags swaps the tags stored for an entry inside a transaction
+func replaceEntryTags(tx *sql.Tx, entryID, userID int, tags []string) error {
+	if _, err := tx.Exec("DELETE FROM ledger_entry_tags WHERE entry_id = ?", entryID); err != nil {
+		return err
+	}
+	for _, tag := range tags {
+		_, err := tx.Exec(`INSERT INTO ledger_entry_tags (entry_id, tag, user_id) VALUES (?, ?, ?)`, entryID, tag, userID)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// getEntryTags loads tags for a set of ledger entries keyed by entry ID
+func getEntryTags(userID int, entryIDs []int) (map[int][]string, error) {
+	tags := map[int][]string{}
+	if len(entryIDs) == 0 {
+		return tags, nil
+	}
+
+	query := "SELECT entry_id, tag FROM ledger_entry_tags WHERE user_id = ? AND entry_id IN (?" +
+		strings.Repeat(", ?", len(entryIDs)-1) + ") ORDER BY tag"
+	args := []interface{}{userID}
+	for _, id := range entryIDs {
+		args = append(args, id)
+	}
+
+	rows, err := database.DB.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var entryID int
+		var tag string
+		if err := rows.Scan(&entryID, &tag); err != nil {
+			return nil, err
+		}
+		tags[entryID] = append(tags[entryID], tag)
+	}
+	return tags, rows.Err()
+}
@@ -0,0 +1,198 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"khata-book-backend/database"
+	"khata-book-backend/models"
+	"khata-book-backend/pkg/logger"
+	"khata-book-backend/pkg/notify"
+
+	"github.com/gorilla/mux"
+)
+
+// consentSourceRegex matches where a consent came from, e.g. "manual" or
+// "signup_form"
+var consentSourceRegex = regexp.MustCompile(`^[a-z][a-z0-9_]{0,29}$`)
+
+// GetCustomerConsent returns the customer's consent on every reminder
+// channel, "unknown" where they have neither opted in nor out
+func GetCustomerConsent(w http.ResponseWriter, r *http.Request) {
+	// Get user ID from JWT token
+	userID, err := getUserIDFromToken(r)
+	if err != nil {
+		writeJSON(w, http.StatusUnauthorized, map[string]interface{}{"success": false, "error": "unauthorized", "message": "Invalid token"})
+		return
+	}
+
+	customerID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"success": false, "error": "invalid_id", "message": "Invalid customer ID"})
+		return
+	}
+
+	if !checkCustomerOwnership(w, customerID, userID) {
+		return
+	}
+
+	rows, err := database.DB.Query(`
+		SELECT channel, status, source, detail, changed_at
+		FROM customer_consents
+		WHERE customer_id = ?`, customerID)
+	if err != nil {
+		logger.L.WithField("error", err).Error("Error querying customer consent")
+		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "server_error", "message": "Could not fetch consent"})
+		return
+	}
+	defer rows.Close()
+
+	recorded := map[string]models.CustomerConsent{}
+	for rows.Next() {
+		var consent models.CustomerConsent
+		var source, detail, changedAt sql.NullString
+		if err := rows.Scan(&consent.Channel, &consent.Status, &source, &detail, &changedAt); err != nil {
+			logger.L.WithField("error", err).Error("Error scanning customer consent")
+			continue
+		}
+		consent.Source = nullStringPtr(source)
+		consent.Detail = nullStringPtr(detail)
+		consent.ChangedAt = parseNullTimestamp(changedAt)
+		recorded[consent.Channel] = consent
+	}
+
+	consents := []models.CustomerConsent{}
+	for _, channel := range []string{notify.ChannelSMS, notify.ChannelWhatsApp, notify.ChannelEmail} {
+		consent, ok := recorded[channel]
+		if !ok {
+			consent = models.CustomerConsent{Channel: channel, Status: "unknown"}
+		}
+		consents = append(consents, consent)
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"success":     true,
+		"customer_id": customerID,
+		"consents":    consents,
+	})
+}
+
+// UpdateCustomerConsent records that a customer opted in to or out of
+// reminders on one channel, for example when they say so in person
+func UpdateCustomerConsent(w http.ResponseWriter, r *http.Request) {
+	// Get user ID from JWT token
+	userID, err := getUserIDFromToken(r)
+	if err != nil {
+		writeJSON(w, http.StatusUnauthorized, map[string]interface{}{"success": false, "error": "unauthorized", "message": "Invalid token"})
+		return
+	}
+
+	customerID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"success": false, "error": "invalid_id", "message": "Invalid customer ID"})
+		return
+	}
+
+	channel := mux.Vars(r)["channel"]
+	if channel != notify.ChannelSMS && channel != notify.ChannelWhatsApp && channel != notify.ChannelEmail {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"success": false, "error": "invalid_channel", "message": "Channel must be 'sms', 'whatsapp', or 'email'"})
+		return
+	}
+
+	var consentReq models.ConsentRequest
+	err = json.NewDecoder(r.Body).Decode(&consentReq)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"success": false, "error": "invalid_request", "message": "Invalid request body"})
+		return
+	}
+
+	if consentReq.Status != "opted_in" && consentReq.Status != "opted_out" {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"success": false, "error": "invalid_status", "message": "Status must be 'opted_in' or 'opted_out'"})
+		return
+	}
+
+	consentReq.Source = strings.TrimSpace(consentReq.Source)
+	if consentReq.Source == "" {
+		consentReq.Source = "manual"
+	}
+	if !consentSourceRegex.MatchString(consentReq.Source) {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"success": false, "error": "invalid_source", "message": "Source must be a short lowercase name such as 'manual'"})
+		return
+	}
+
+	consentReq.Detail = strings.TrimSpace(consentReq.Detail)
+	if len(consentReq.Detail) > 255 {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"success": false, "error": "invalid_detail", "message": "Detail must be at most 255 characters"})
+		return
+	}
+
+	if !checkCustomerOwnership(w, customerID, userID) {
+		return
+	}
+
+	err = setCustomerConsent(database.DB, customerID, userID, channel, consentReq.Status, consentReq.Source, consentReq.Detail)
+	if err != nil {
+		logger.L.WithField("error", err).Error("Error updating customer consent")
+		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "server_error", "message": "Could not update consent"})
+		return
+	}
+
+	logger.L.WithFields(map[string]interface{}{
+		"customer_id": customerID,
+		"user_id":     userID,
+		"channel":     channel,
+		"status":      consentReq.Status,
+		"source":      consentReq.Source,
+	}).Info("Customer consent updated successfully")
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"success":     true,
+		"message":     "Consent updated successfully",
+		"customer_id": customerID,
+		"channel":     channel,
+		"status":      consentReq.Status,
+		"source":      consentReq.Source,
+	})
+}
+
+// setCustomerConsent replaces the customer's consent on a channel
+func setCustomerConsent(q execer, customerID, userID int, channel, status, source, detail string) error {
+	_, err := q.Exec(`
+		INSERT INTO customer_consents (customer_id, channel, status, source, detail, user_id)
+		VALUES (?, ?, ?, ?, NULLIF(?, ''), ?)
+		ON DUPLICATE KEY UPDATE status = VALUES(status), source = VALUES(source), detail = VALUES(detail),
+			changed_at = CURRENT_TIMESTAMP`,
+		customerID, channel, status, source, detail, userID)
+	return err
+}
+
+// customerOptedOut reports whether the customer asked not to be sent
+// reminders on channel
+func customerOptedOut(q queryRower, customerID int, channel string) (bool, error) {
+	var status string
+	err := q.QueryRow("SELECT status FROM customer_consents WHERE customer_id = ? AND channel = ?", customerID, channel).Scan(&status)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	return status == "opted_out", err
+}
+
+// checkCustomerConsent writes the error response and returns false when the
+// customer opted out of reminders on channel
+func checkCustomerConsent(w http.ResponseWriter, customerID int, channel string) bool {
+	optedOut, err := customerOptedOut(database.DB, customerID, channel)
+	if err != nil {
+		logger.L.WithField("error", err).Error("Error checking customer consent")
+		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "server_error", "message": "Could not verify customer consent"})
+		return false
+	}
+	if optedOut {
+		writeJSON(w, http.StatusConflict, map[string]interface{}{"success": false, "error": "customer_opted_out", "message": "Customer has opted out of " + channel + " reminders"})
+		return false
+	}
+	return true
+}
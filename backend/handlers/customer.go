@@ -4,16 +4,61 @@ import (
 	"database/sql"
 	"encoding/json"
 	"net/http"
+	"regexp"
 	"strconv"
+	"strings"
 	"time"
 
 	"khata-book-backend/database"
 	"khata-book-backend/models"
+	"khata-book-backend/pkg/gst"
 	"khata-book-backend/pkg/logger"
+
+	"github.com/gorilla/mux"
 )
 
 // GetCustomers retrieves all customers for the authenticated user
 func GetCustomers(w http.ResponseWriter, r *http.Request) {
+	listParties(w, r, partyCustomer)
+}
+
+// GetSuppliers retrieves all suppliers for the authenticated user
+func GetSuppliers(w http.ResponseWriter, r *http.Request) {
+	listParties(w, r, partySupplier)
+}
+
+// CreateCustomer creates a new customer for the authenticated user
+func CreateCustomer(w http.ResponseWriter, r *http.Request) {
+	createParty(w, r, partyCustomer)
+}
+
+// CreateSupplier creates a new supplier for the authenticated user
+func CreateSupplier(w http.ResponseWriter, r *http.Request) {
+	createParty(w, r, partySupplier)
+}
+
+// GetCustomer retrieves a specific customer
+func GetCustomer(w http.ResponseWriter, r *http.Request) {
+	getParty(w, r, partyCustomer)
+}
+
+// GetSupplier retrieves a specific supplier
+func GetSupplier(w http.ResponseWriter, r *http.Request) {
+	getParty(w, r, partySupplier)
+}
+
+// UpdateCustomer changes a customer's details. The balance is not editable;
+// it only moves through ledger entries.
+func UpdateCustomer(w http.ResponseWriter, r *http.Request) {
+	updateParty(w, r, partyCustomer)
+}
+
+// UpdateSupplier changes a supplier's details
+func UpdateSupplier(w http.ResponseWriter, r *http.Request) {
+	updateParty(w, r, partySupplier)
+}
+
+func listParties(w http.ResponseWriter, r *http.Request, partyType string) {
 	// Get user ID from JWT token
 	userID, err := getUserIDFromToken(r)
 	if err != nil {
@@ -21,32 +66,33 @@ func GetCustomers(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Query customers
+	// Query parties
 	rows, err := database.DB.Query(`
-		SELECT id, name, phone, note, balance, created_at, updated_at
+		SELECT id, name, phone, email, language, timezone, note, gstin, state_code, balance, created_at, updated_at
 		FROM customers
-		WHERE user_id = ?
-		ORDER BY name ASC`, userID)
+		WHERE user_id = ? AND party_type = ?
+		ORDER BY name ASC`, userID, partyType)
 	if err != nil {
-		logger.L.WithField("error", err).Error("Error querying customers")
-		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "server_error", "message": "Could not fetch customers"})
+		logger.L.WithFields(map[string]interface{}{"error": err, "party_type": partyType}).Error("Error querying parties")
+		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "server_error", "message": "Could not fetch " + partyType + "s"})
 		return
 	}
 	defer rows.Close()
 
-	var customers []map[string]interface{}
+	var parties []map[string]interface{}
 	for rows.Next() {
 		var customer models.Customer
-		var phone sql.NullString
+		var phone, email, language, timezone sql.NullString
 		var note sql.NullString
+		var gstin, stateCode sql.NullString
 		var createdAtStr, updatedAtStr string
 
 		err := rows.Scan(
-			&customer.ID, &customer.Name, &phone, &note,
+			&customer.ID, &customer.Name, &phone, &email, &language, &timezone, &note, &gstin, &stateCode,
 			&customer.Balance, &createdAtStr, &updatedAtStr,
 		)
 		if err != nil {
-			logger.L.WithField("error", err).Error("Error scanning customer")
+			logger.L.WithField("error", err).Error("Error scanning party")
 			continue
 		}
 
@@ -57,29 +103,33 @@ func GetCustomers(w http.ResponseWriter, r *http.Request) {
 			customer.Note = &note.String
 		}
 
-		customer.CreatedAt, _ = time.Parse("2006-01-02 15:04:05", createdAtStr)
-		customer.UpdatedAt, _ = time.Parse("2006-01-02 15:04:05", updatedAtStr)
-
 		customerMap := map[string]interface{}{
-			"id":         customer.ID,
-			"name":       customer.Name,
-			"phone":      customer.Phone,
-			"note":       customer.Note,
-			"balance":    customer.Balance,
-			"created_at": createdAtStr,
-			"updated_at": updatedAtStr,
+			"id":                customer.ID,
+			"name":              customer.Name,
+			"party_type":        partyType,
+			"phone":             customer.Phone,
+			"email":             nullStringPtr(email),
+			"language":          nullStringPtr(language),
+			"timezone":          nullStringPtr(timezone),
+			"note":              customer.Note,
+			"gstin":             nullStringPtr(gstin),
+			"state_code":        nullStringPtr(stateCode),
+			"balance":           customer.Balance,
+			"balance_direction": balanceDirection(customer.Balance),
+			"balance_label":     balanceLabel(partyType, customer.Balance),
+			"created_at":        createdAtStr,
+			"updated_at":        updatedAtStr,
 		}
-		customers = append(customers, customerMap)
+		parties = append(parties, customerMap)
 	}
 
 	writeJSON(w, http.StatusOK, map[string]interface{}{
-		"success":   true,
-		"customers": customers,
+		"success":       true,
+		partyType + "s": parties,
 	})
 }
 
-// CreateCustomer creates a new customer for the authenticated user
-func CreateCustomer(w http.ResponseWriter, r *http.Request) {
+func createParty(w http.ResponseWriter, r *http.Request, partyType string) {
 	// Get user ID from JWT token
 	userID, err := getUserIDFromToken(r)
 	if err != nil {
@@ -96,40 +146,56 @@ func CreateCustomer(w http.ResponseWriter, r *http.Request) {
 
 	// Validate required fields
 	if customerReq.Name == "" {
-		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"success": false, "error": "invalid_name", "message": "Customer name is required"})
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"success": false, "error": "invalid_name", "message": partyNoun(partyType) + " name is required"})
+		return
+	}
+
+	if !normalizeCustomerGST(w, &customerReq) || !normalizeCustomerEmail(w, &customerReq) || !normalizeCustomerLanguage(w, &customerReq) ||
+		!normalizeCustomerTimezone(w, &customerReq) {
 		return
 	}
 
-	// Insert customer
+	// Insert party
 	result, err := database.DB.Exec(`
-		INSERT INTO customers (name, phone, note, balance, user_id)
-		VALUES (?, ?, ?, ?, ?)`,
-		customerReq.Name, customerReq.Phone, customerReq.Note, customerReq.Balance, userID)
+		INSERT INTO customers (name, party_type, phone, email, language, timezone, note, gstin, state_code, balance, user_id)
+		VALUES (?, ?, ?, NULLIF(?, ''), NULLIF(?, ''), NULLIF(?, ''), ?, NULLIF(?, ''), NULLIF(?, ''), ?, ?)`,
+		customerReq.Name, partyType, customerReq.Phone, customerReq.Email, customerReq.Language, customerReq.Timezone, customerReq.Note, customerReq.GSTIN, customerReq.StateCode, customerReq.Balance, userID)
 	if err != nil {
-		logger.L.WithField("error", err).Error("Error inserting customer")
-		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "server_error", "message": "Could not create customer"})
+		if strings.Contains(err.Error(), "Duplicate entry") {
+			writeJSON(w, http.StatusConflict, map[string]interface{}{"success": false, "error": partyType + "_exists", "message": "A customer or supplier with this name already exists"})
+			return
+		}
+		logger.L.WithField("error", err).Error("Error inserting party")
+		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "server_error", "message": "Could not create " + partyType})
 		return
 	}
 
 	customerID, err := result.LastInsertId()
 	if err != nil {
-		logger.L.WithField("error", err).Error("Error getting inserted customer ID")
-		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "server_error", "message": "Could not create customer"})
+		logger.L.WithField("error", err).Error("Error getting inserted party ID")
+		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "server_error", "message": "Could not create " + partyType})
 		return
 	}
 
 	logger.L.WithFields(map[string]interface{}{
 		"customer_id": customerID,
 		"user_id":     userID,
+		"party_type":  partyType,
 		"name":        customerReq.Name,
-	}).Info("Customer created successfully")
+	}).Info(partyNoun(partyType) + " created successfully")
 
-	// Create response customer
+	// Create response party
 	customer := models.Customer{
 		ID:        int(customerID),
 		Name:      customerReq.Name,
+		PartyType: partyType,
 		Phone:     customerReq.Phone,
+		Email:     customerReq.Email,
+		Language:  customerReq.Language,
+		Timezone:  customerReq.Timezone,
 		Note:      customerReq.Note,
+		GSTIN:     customerReq.GSTIN,
+		StateCode: customerReq.StateCode,
 		Balance:   customerReq.Balance,
 		UserID:    userID,
 		CreatedAt: time.Now(),
@@ -137,14 +203,13 @@ func CreateCustomer(w http.ResponseWriter, r *http.Request) {
 	}
 
 	writeJSON(w, http.StatusCreated, map[string]interface{}{
-		"success":  true,
-		"message":  "Customer created successfully",
-		"customer": customer,
+		"success": true,
+		"message": partyNoun(partyType) + " created successfully",
+		partyType: customer,
 	})
 }
 
-// GetCustomer retrieves a specific customer
-func GetCustomer(w http.ResponseWriter, r *http.Request) {
+func getParty(w http.ResponseWriter, r *http.Request, partyType string) {
 	// Get user ID from JWT token
 	userID, err := getUserIDFromToken(r)
 	if err != nil {
@@ -152,35 +217,34 @@ func GetCustomer(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Extract customer ID from URL path
-	customerIDStr := r.URL.Path[len("/api/customers/"):]
-	customerID, err := strconv.Atoi(customerIDStr)
+	customerID, err := strconv.Atoi(mux.Vars(r)["id"])
 	if err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"success": false, "error": "invalid_id", "message": "Invalid customer ID"})
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"success": false, "error": "invalid_id", "message": "Invalid " + partyType + " ID"})
 		return
 	}
 
-	// Query the customer
+	// Query the party
 	var customer models.Customer
-	var phone sql.NullString
+	var phone, email, language, timezone sql.NullString
 	var note sql.NullString
+	var gstin, stateCode sql.NullString
 	var createdAtStr, updatedAtStr string
 
 	err = database.DB.QueryRow(`
-		SELECT id, name, phone, note, balance, created_at, updated_at
+		SELECT id, name, phone, email, language, timezone, note, gstin, state_code, balance, created_at, updated_at
 		FROM customers
-		WHERE id = ? AND user_id = ?`, customerID, userID).Scan(
-		&customer.ID, &customer.Name, &phone, &note,
+		WHERE id = ? AND user_id = ? AND party_type = ?`, customerID, userID, partyType).Scan(
+		&customer.ID, &customer.Name, &phone, &email, &language, &timezone, &note, &gstin, &stateCode,
 		&customer.Balance, &createdAtStr, &updatedAtStr,
 	)
 
 	if err != nil {
 		if err == sql.ErrNoRows {
-			writeJSON(w, http.StatusNotFound, map[string]interface{}{"success": false, "error": "not_found", "message": "Customer not found"})
+			writeJSON(w, http.StatusNotFound, map[string]interface{}{"success": false, "error": "not_found", "message": partyNoun(partyType) + " not found"})
 			return
 		}
-		logger.L.WithField("error", err).Error("Error querying customer")
-		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "server_error", "message": "Could not fetch customer"})
+		logger.L.WithField("error", err).Error("Error querying party")
+		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "server_error", "message": "Could not fetch " + partyType})
 		return
 	}
 
@@ -191,21 +255,234 @@ func GetCustomer(w http.ResponseWriter, r *http.Request) {
 		customer.Note = &note.String
 	}
 
-	customer.CreatedAt, _ = time.Parse("2006-01-02 15:04:05", createdAtStr)
-	customer.UpdatedAt, _ = time.Parse("2006-01-02 15:04:05", updatedAtStr)
-
 	response := map[string]interface{}{
-		"id":         customer.ID,
-		"name":       customer.Name,
-		"phone":      customer.Phone,
-		"note":       customer.Note,
-		"balance":    customer.Balance,
-		"created_at": createdAtStr,
-		"updated_at": updatedAtStr,
+		"id":                customer.ID,
+		"name":              customer.Name,
+		"party_type":        partyType,
+		"phone":             customer.Phone,
+		"email":             nullStringPtr(email),
+		"language":          nullStringPtr(language),
+		"timezone":          nullStringPtr(timezone),
+		"note":              customer.Note,
+		"gstin":             nullStringPtr(gstin),
+		"state_code":        nullStringPtr(stateCode),
+		"balance":           customer.Balance,
+		"balance_direction": balanceDirection(customer.Balance),
+		"balance_label":     balanceLabel(partyType, customer.Balance),
+		"created_at":        createdAtStr,
+		"updated_at":        updatedAtStr,
 	}
 
 	writeJSON(w, http.StatusOK, map[string]interface{}{
-		"success":  true,
-		"customer": response,
+		"success": true,
+		partyType: response,
 	})
 }
+
+func updateParty(w http.ResponseWriter, r *http.Request, partyType string) {
+	// Get user ID from JWT token
+	userID, err := getUserIDFromToken(r)
+	if err != nil {
+		writeJSON(w, http.StatusUnauthorized, map[string]interface{}{"success": false, "error": "unauthorized", "message": "Invalid token"})
+		return
+	}
+
+	customerID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"success": false, "error": "invalid_id", "message": "Invalid " + partyType + " ID"})
+		return
+	}
+
+	var customerReq models.CustomerRequest
+	err = json.NewDecoder(r.Body).Decode(&customerReq)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"success": false, "error": "invalid_request", "message": "Invalid request body"})
+		return
+	}
+
+	if !normalizeCustomerGST(w, &customerReq) || !normalizeCustomerEmail(w, &customerReq) || !normalizeCustomerLanguage(w, &customerReq) ||
+		!normalizeCustomerTimezone(w, &customerReq) {
+		return
+	}
+
+	// Build update query dynamically
+	setParts := []string{}
+	args := []interface{}{}
+
+	if strings.TrimSpace(customerReq.Name) != "" {
+		setParts = append(setParts, "name = ?")
+		args = append(args, strings.TrimSpace(customerReq.Name))
+	}
+	if customerReq.Phone != nil {
+		setParts = append(setParts, "phone = ?")
+		args = append(args, customerReq.Phone)
+	}
+	if customerReq.Email != nil {
+		setParts = append(setParts, "email = NULLIF(?, '')")
+		args = append(args, customerReq.Email)
+	}
+	if customerReq.Language != nil {
+		setParts = append(setParts, "language = NULLIF(?, '')")
+		args = append(args, customerReq.Language)
+	}
+	if customerReq.Timezone != nil {
+		setParts = append(setParts, "timezone = NULLIF(?, '')")
+		args = append(args, customerReq.Timezone)
+	}
+	if customerReq.Note != nil {
+		setParts = append(setParts, "note = ?")
+		args = append(args, customerReq.Note)
+	}
+	if customerReq.GSTIN != nil {
+		setParts = append(setParts, "gstin = NULLIF(?, '')")
+		args = append(args, customerReq.GSTIN)
+	}
+	if customerReq.StateCode != nil {
+		setParts = append(setParts, "state_code = NULLIF(?, '')")
+		args = append(args, customerReq.StateCode)
+	}
+
+	if len(setParts) == 0 {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"success": false, "error": "no_updates", "message": "No valid fields to update"})
+		return
+	}
+
+	var exists int
+	err = database.DB.QueryRow("SELECT COUNT(*) FROM customers WHERE id = ? AND user_id = ? AND party_type = ?", customerID, userID, partyType).Scan(&exists)
+	if err != nil {
+		logger.L.WithField("error", err).Error("Error checking party ownership")
+		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "server_error", "message": "Could not update " + partyType})
+		return
+	}
+	if exists == 0 {
+		writeJSON(w, http.StatusNotFound, map[string]interface{}{"success": false, "error": "not_found", "message": partyNoun(partyType) + " not found"})
+		return
+	}
+
+	setParts = append(setParts, "updated_at = CURRENT_TIMESTAMP")
+	query := "UPDATE customers SET " + strings.Join(setParts, ", ") + " WHERE id = ? AND user_id = ?"
+	args = append(args, customerID, userID)
+
+	_, err = database.DB.Exec(query, args...)
+	if err != nil {
+		if strings.Contains(err.Error(), "Duplicate entry") {
+			writeJSON(w, http.StatusConflict, map[string]interface{}{"success": false, "error": partyType + "_exists", "message": "A customer or supplier with this name already exists"})
+			return
+		}
+		logger.L.WithField("error", err).Error("Error updating party")
+		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "server_error", "message": "Could not update " + partyType})
+		return
+	}
+
+	logger.L.WithFields(map[string]interface{}{
+		"customer_id": customerID,
+		"user_id":     userID,
+		"party_type":  partyType,
+	}).Info(partyNoun(partyType) + " updated successfully")
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"message": partyNoun(partyType) + " updated successfully",
+	})
+}
+
+// normalizeCustomerGST validates a customer's GSTIN and state code, filling
+// the state in from the GSTIN when it is not given. An empty string clears a
+// field. It writes the error response and returns false on invalid input.
+func normalizeCustomerGST(w http.ResponseWriter, req *models.CustomerRequest) bool {
+	if req.GSTIN != nil {
+		*req.GSTIN = gst.Normalize(*req.GSTIN)
+		if *req.GSTIN != "" && !gst.ValidGSTIN(*req.GSTIN) {
+			writeJSON(w, http.StatusBadRequest, map[string]interface{}{"success": false, "error": "invalid_gstin", "message": "Invalid GSTIN"})
+			return false
+		}
+	}
+	if req.StateCode != nil {
+		*req.StateCode = strings.TrimSpace(*req.StateCode)
+		if *req.StateCode != "" && !gst.ValidStateCode(*req.StateCode) {
+			writeJSON(w, http.StatusBadRequest, map[string]interface{}{"success": false, "error": "invalid_state_code", "message": "State code must be a two digit GST state code"})
+			return false
+		}
+	}
+
+	if req.GSTIN != nil && *req.GSTIN != "" {
+		state := gst.StateCode(*req.GSTIN)
+		if req.StateCode == nil || *req.StateCode == "" {
+			req.StateCode = &state
+		} else if *req.StateCode != state {
+			writeJSON(w, http.StatusBadRequest, map[string]interface{}{"success": false, "error": "invalid_state_code", "message": "State code must match the GSTIN"})
+			return false
+		}
+	}
+	return true
+}
+
+var customerEmailRegex = regexp.MustCompile(`^[a-zA-Z0-9._%+-]+@[a-zA-Z0-9.-]+\.[a-zA-Z]{2,}$`)
+
+// normalizeCustomerEmail trims a customer's email address and checks its
+// format. An empty string clears it.
+func normalizeCustomerEmail(w http.ResponseWriter, req *models.CustomerRequest) bool {
+	if req.Email == nil {
+		return true
+	}
+	*req.Email = strings.TrimSpace(*req.Email)
+	if *req.Email != "" && !customerEmailRegex.MatchString(*req.Email) {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"success": false, "error": "invalid_email", "message": "Invalid email format"})
+		return false
+	}
+	return true
+}
+
+// normalizeCustomerLanguage trims the language reminders are sent to a
+// customer in and checks it is a language tag such as "hi" or "en-IN". An
+// empty string clears it.
+func normalizeCustomerLanguage(w http.ResponseWriter, req *models.CustomerRequest) bool {
+	if req.Language == nil {
+		return true
+	}
+	*req.Language = strings.TrimSpace(*req.Language)
+	if *req.Language != "" && !languageTagRegex.MatchString(*req.Language) {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"success": false, "error": "invalid_language", "message": "Language must be a code such as 'en', 'hi' or 'en-IN'"})
+		return false
+	}
+	return true
+}
+
+// normalizeCustomerTimezone trims the customer's time zone, used to keep
+// reminders out of quiet hours where they live, and checks it is an IANA
+// zone such as "America/New_York". An empty string clears it.
+func normalizeCustomerTimezone(w http.ResponseWriter, req *models.CustomerRequest) bool {
+	if req.Timezone == nil {
+		return true
+	}
+	*req.Timezone = strings.TrimSpace(*req.Timezone)
+	if *req.Timezone != "" && !validTimezone(*req.Timezone) {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"success": false, "error": "invalid_timezone", "message": "Timezone must be an IANA time zone such as 'Asia/Kolkata'"})
+		return false
+	}
+	return true
+}
+
+// checkCustomerOwnership writes the error response and returns false when the
+// party, customer or supplier, does not exist for this user
+func checkCustomerOwnership(w http.ResponseWriter, customerID, userID int) bool {
+	_, ok := lookupPartyType(w, customerID, userID)
+	return ok
+}
+
+// lookupPartyType is checkCustomerOwnership for callers that treat customers
+// and suppliers differently
+func lookupPartyType(w http.ResponseWriter, customerID, userID int) (string, bool) {
+	var partyType string
+	err := database.DB.QueryRow("SELECT party_type FROM customers WHERE id = ? AND user_id = ?", customerID, userID).Scan(&partyType)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			writeJSON(w, http.StatusNotFound, map[string]interface{}{"success": false, "error": "customer_not_found", "message": "Customer not found"})
+			return "", false
+		}
+		logger.L.WithField("error", err).Error("Error checking customer ownership")
+		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "server_error", "message": "Could not verify customer"})
+		return "", false
+	}
+	return partyType, true
+}
@@ -3,7 +3,9 @@ package handlers
 import (
 	"database/sql"
 	"net/http"
+	"sort"
 	"strconv"
+	"strings"
 	"time"
 
 	"khata-book-backend/database"
@@ -18,7 +20,9 @@ type DashboardEntry struct {
 	ID           int     `json:"id"`
 	CustomerID   int     `json:"customer_id"`
 	CustomerName string  `json:"customer_name"`
+	PartyType    string  `json:"party_type"`
 	Type         string  `json:"type"`
+	TypeLabel    string  `json:"type_label"`
 	Amount       float64 `json:"amount"`
 	Method       string  `json:"method"`
 	Note         *string `json:"note,omitempty"`
@@ -42,6 +46,22 @@ func GetDashboardSummary(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Outstanding balances, split by which side owes
+	partyTotals, err := getPartyTotals(userID)
+	if err != nil {
+		logger.L.WithField("error", err).Error("Error getting receivables and payables")
+		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "server_error", "message": "Could not fetch dashboard data"})
+		return
+	}
+
+	// Business income and expenses kept outside the khata
+	cashbook, err := getCashbookTotals(userID)
+	if err != nil {
+		logger.L.WithField("error", err).Error("Error getting cashbook totals")
+		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "server_error", "message": "Could not fetch dashboard data"})
+		return
+	}
+
 	// Get latest entries
 	latestEntries, err := getLatestEntriesWithNames(userID, 5)
 	if err != nil {
@@ -51,10 +71,20 @@ func GetDashboardSummary(w http.ResponseWriter, r *http.Request) {
 	}
 
 	response := map[string]interface{}{
-		"total_credit":   summary.TotalCredit,
-		"total_debit":    summary.TotalDebit,
-		"balance":        summary.Balance,
-		"latest_entries": latestEntries,
+		"total_credit":       summary.TotalCredit,
+		"total_debit":        summary.TotalDebit,
+		"total_interest":     summary.TotalInterest,
+		"total_late_fees":    summary.TotalLateFees,
+		"total_write_offs":   summary.TotalWriteOffs,
+		"total_discounts":    summary.TotalDiscounts,
+		"supplier_purchases": summary.SupplierPurchases,
+		"supplier_payments":  summary.SupplierPayments,
+		"balance":            summary.Balance,
+		"receivables":        partyTotals["receivables"],
+		"payables":           partyTotals["payables"],
+		"by_party_type":      partyTotals["by_party_type"],
+		"cashbook":           cashbook,
+		"latest_entries":     latestEntries,
 	}
 
 	writeJSON(w, http.StatusOK, map[string]interface{}{"success": true, "data": response})
@@ -129,8 +159,13 @@ func GetCategoryReports(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	// Get category reports
-	reports, err := getCategoryReports(userID, startDate, endDate)
+	// Reports are grouped by customer unless ?group_by=category is given
+	var reports []map[string]interface{}
+	if r.URL.Query().Get("group_by") == "category" {
+		reports, err = getEntryCategoryReports(userID, startDate, endDate)
+	} else {
+		reports, err = getCategoryReports(userID, startDate, endDate)
+	}
 	if err != nil {
 		logger.L.WithField("error", err).Error("Error getting category reports")
 		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "server_error", "message": "Could not fetch category reports"})
@@ -178,24 +213,99 @@ func GetPaymentMethodReports(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	cheques, err := getChequeReport(userID, startDate, endDate)
+	if err != nil {
+		logger.L.WithField("error", err).Error("Error getting cheque report")
+		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "server_error", "message": "Could not fetch payment method reports"})
+		return
+	}
+
 	writeJSON(w, http.StatusOK, map[string]interface{}{
 		"success": true,
 		"reports": reports,
+		"cheques": cheques,
+	})
+}
+
+// GetWriteOffReports returns write-offs and discounts per month, or per year
+// with ?group_by=year
+func GetWriteOffReports(w http.ResponseWriter, r *http.Request) {
+	// Get user ID from JWT token
+	userID, err := getUserIDFromToken(r)
+	if err != nil {
+		writeJSON(w, http.StatusUnauthorized, map[string]interface{}{"success": false, "error": "unauthorized", "message": "Invalid token"})
+		return
+	}
+
+	// Parse query parameters
+	startDateStr := r.URL.Query().Get("start_date")
+	endDateStr := r.URL.Query().Get("end_date")
+	groupBy := r.URL.Query().Get("group_by")
+
+	var startDate, endDate *time.Time
+
+	if startDateStr != "" {
+		if parsedDate, err := time.Parse("2006-01-02", startDateStr); err == nil {
+			startDate = &parsedDate
+		}
+	}
+
+	if endDateStr != "" {
+		if parsedDate, err := time.Parse("2006-01-02", endDateStr); err == nil {
+			endDate = &parsedDate
+		}
+	}
+
+	if groupBy != "year" {
+		groupBy = "month"
+	}
+
+	reports, err := getWriteOffReports(userID, groupBy, startDate, endDate)
+	if err != nil {
+		logger.L.WithField("error", err).Error("Error getting write-off reports")
+		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "server_error", "message": "Could not fetch write-off reports"})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"success":  true,
+		"group_by": groupBy,
+		"reports":  reports,
 	})
 }
 
+// ledgerTotalsColumns sums ledger entries joined to their party as c.
+// Credits and debits are counted for customers only, where a debit is money
+// received; a supplier's debit is a purchase on credit and its credit a
+// payment made, so those are summed apart. balance is the net change across
+// both sides.
+const ledgerTotalsColumns = `
+	COALESCE(SUM(CASE WHEN c.party_type = 'customer' AND le.type = 'credit' THEN le.amount ELSE 0 END), 0) as total_credit,
+	COALESCE(SUM(CASE WHEN c.party_type = 'customer' AND le.type = 'debit' THEN le.amount ELSE 0 END), 0) as total_debit,
+	COALESCE(SUM(CASE WHEN le.type = 'interest' THEN le.amount ELSE 0 END), 0) as total_interest,
+	COALESCE(SUM(CASE WHEN le.type = 'late_fee' THEN le.amount ELSE 0 END), 0) as total_late_fees,
+	COALESCE(SUM(CASE WHEN le.type = 'write_off' THEN le.amount ELSE 0 END), 0) as total_write_offs,
+	COALESCE(SUM(CASE WHEN le.type = 'discount' THEN le.amount ELSE 0 END), 0) as total_discounts,
+	COALESCE(SUM(CASE WHEN c.party_type = 'supplier' AND le.type = 'debit' THEN le.amount ELSE 0 END), 0) as supplier_purchases,
+	COALESCE(SUM(CASE WHEN c.party_type = 'supplier' AND le.type = 'credit' THEN le.amount ELSE 0 END), 0) as supplier_payments,
+	COALESCE(SUM(CASE WHEN le.type IN ('debit', 'write_off', 'discount') THEN -le.amount ELSE le.amount END), 0) as balance`
+
+// paymentEntryCond matches ledger entries, joined to their party as c, that
+// move money: payments received from customers and made to suppliers. Entries
+// posted with adjustmentMethod, such as invoices, never do.
+const paymentEntryCond = `le.method != '` + adjustmentMethod + `' AND ((c.party_type = '` + partyCustomer + `' AND le.type = 'debit') OR (c.party_type = '` + partySupplier + `' AND le.type = 'credit'))`
+
 // getDashboardSummary calculates total credits, debits, and balance for a user
 func getDashboardSummary(userID int) (*models.ReportSummary, error) {
 	query := `
-		SELECT
-			COALESCE(SUM(CASE WHEN type = 'credit' THEN amount ELSE 0 END), 0) as total_credit,
-			COALESCE(SUM(CASE WHEN type = 'debit' THEN amount ELSE 0 END), 0) as total_debit,
-			COALESCE(SUM(CASE WHEN type = 'credit' THEN amount ELSE -amount END), 0) as balance
-		FROM ledger_entries
-		WHERE user_id = ?`
+		SELECT` + ledgerTotalsColumns + `
+		FROM ledger_entries le
+		JOIN customers c ON le.customer_id = c.id
+		WHERE le.user_id = ?`
 
 	var summary models.ReportSummary
-	err := database.DB.QueryRow(query, userID).Scan(&summary.TotalCredit, &summary.TotalDebit, &summary.Balance)
+	err := database.DB.QueryRow(query, userID).Scan(&summary.TotalCredit, &summary.TotalDebit, &summary.TotalInterest, &summary.TotalLateFees,
+		&summary.TotalWriteOffs, &summary.TotalDiscounts, &summary.SupplierPurchases, &summary.SupplierPayments, &summary.Balance)
 	if err != nil {
 		return nil, err
 	}
@@ -203,11 +313,54 @@ func getDashboardSummary(userID int) (*models.ReportSummary, error) {
 	return &summary, nil
 }
 
+// getPartyTotals sums what parties owe the business (receivables) and what
+// the business owes them (payables), overall and per party type
+func getPartyTotals(userID int) (map[string]interface{}, error) {
+	rows, err := database.DB.Query(`
+		SELECT party_type,
+			COUNT(*) as party_count,
+			COALESCE(SUM(CASE WHEN balance > 0 THEN balance ELSE 0 END), 0) as receivable,
+			COALESCE(SUM(CASE WHEN balance < 0 THEN -balance ELSE 0 END), 0) as payable
+		FROM customers
+		WHERE user_id = ?
+		GROUP BY party_type`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var receivables, payables float64
+	byPartyType := map[string]interface{}{}
+	for _, partyType := range []string{partyCustomer, partySupplier} {
+		byPartyType[partyType] = map[string]interface{}{"count": 0, "receivable": 0.0, "payable": 0.0}
+	}
+	for rows.Next() {
+		var partyType string
+		var count int
+		var receivable, payable float64
+		if err := rows.Scan(&partyType, &count, &receivable, &payable); err != nil {
+			return nil, err
+		}
+		receivables += receivable
+		payables += payable
+		byPartyType[partyType] = map[string]interface{}{"count": count, "receivable": receivable, "payable": payable}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return map[string]interface{}{
+		"receivables":   roundMoney(receivables),
+		"payables":      roundMoney(payables),
+		"by_party_type": byPartyType,
+	}, nil
+}
+
 // getLatestEntriesWithNames returns the most recent ledger entries with customer names
 func getLatestEntriesWithNames(userID int, limit int) ([]DashboardEntry, error) {
 	query := `
 		SELECT le.id, le.customer_id, le.type, le.amount, le.method, le.note, le.date,
-			   c.name as customer_name
+			   c.name as customer_name, c.party_type
 		FROM ledger_entries le
 		JOIN customers c ON le.customer_id = c.id
 		WHERE le.user_id = ?
@@ -227,11 +380,12 @@ func getLatestEntriesWithNames(userID int, limit int) ([]DashboardEntry, error)
 
 		err := rows.Scan(
 			&entry.ID, &entry.CustomerID, &entry.Type, &entry.Amount,
-			&entry.Method, &note, &entry.Date, &entry.CustomerName,
+			&entry.Method, &note, &entry.Date, &entry.CustomerName, &entry.PartyType,
 		)
 		if err != nil {
 			return nil, err
 		}
+		entry.TypeLabel = entryTypeLabel(entry.PartyType, entry.Type)
 
 		if note.Valid {
 			entry.Note = &note.String
@@ -274,49 +428,26 @@ func getUserIDFromToken(r *http.Request) (int, error) {
 
 // getMonthlyReports returns monthly analytics for the user
 func getMonthlyReports(userID int, year int, month int) ([]models.ReportSummary, error) {
-	var query string
-	var args []interface{}
+	query := `
+		SELECT DATE_FORMAT(le.date, '%Y-%m') as month,` + ledgerTotalsColumns + `
+		FROM ledger_entries le
+		JOIN customers c ON le.customer_id = c.id
+		WHERE le.user_id = ?`
+	args := []interface{}{userID}
 
 	if year > 0 && month > 0 {
 		// Specific month
-		query = `
-			SELECT
-				DATE_FORMAT(date, '%Y-%m') as month,
-				COALESCE(SUM(CASE WHEN type = 'credit' THEN amount ELSE 0 END), 0) as total_credit,
-				COALESCE(SUM(CASE WHEN type = 'debit' THEN amount ELSE 0 END), 0) as total_debit,
-				COALESCE(SUM(CASE WHEN type = 'credit' THEN amount ELSE -amount END), 0) as balance
-			FROM ledger_entries
-			WHERE user_id = ? AND YEAR(date) = ? AND MONTH(date) = ?
-			GROUP BY DATE_FORMAT(date, '%Y-%m')
-			ORDER BY month DESC`
-		args = []interface{}{userID, year, month}
+		query += " AND YEAR(le.date) = ? AND MONTH(le.date) = ?"
+		args = append(args, year, month)
 	} else if year > 0 {
 		// Specific year
-		query = `
-			SELECT
-				DATE_FORMAT(date, '%Y-%m') as month,
-				COALESCE(SUM(CASE WHEN type = 'credit' THEN amount ELSE 0 END), 0) as total_credit,
-				COALESCE(SUM(CASE WHEN type = 'debit' THEN amount ELSE 0 END), 0) as total_debit,
-				COALESCE(SUM(CASE WHEN type = 'credit' THEN amount ELSE -amount END), 0) as balance
-			FROM ledger_entries
-			WHERE user_id = ? AND YEAR(date) = ?
-			GROUP BY DATE_FORMAT(date, '%Y-%m')
-			ORDER BY month DESC`
-		args = []interface{}{userID, year}
+		query += " AND YEAR(le.date) = ?"
+		args = append(args, year)
 	} else {
 		// Last 12 months
-		query = `
-			SELECT
-				DATE_FORMAT(date, '%Y-%m') as month,
-				COALESCE(SUM(CASE WHEN type = 'credit' THEN amount ELSE 0 END), 0) as total_credit,
-				COALESCE(SUM(CASE WHEN type = 'debit' THEN amount ELSE 0 END), 0) as total_debit,
-				COALESCE(SUM(CASE WHEN type = 'credit' THEN amount ELSE -amount END), 0) as balance
-			FROM ledger_entries
-			WHERE user_id = ? AND date >= DATE_SUB(CURDATE(), INTERVAL 12 MONTH)
-			GROUP BY DATE_FORMAT(date, '%Y-%m')
-			ORDER BY month DESC`
-		args = []interface{}{userID}
+		query += " AND le.date >= DATE_SUB(CURDATE(), INTERVAL 12 MONTH)"
 	}
+	query += " GROUP BY month ORDER BY month DESC"
 
 	rows, err := database.DB.Query(query, args...)
 	if err != nil {
@@ -327,16 +458,161 @@ func getMonthlyReports(userID int, year int, month int) ([]models.ReportSummary,
 	var reports []models.ReportSummary
 	for rows.Next() {
 		var report models.ReportSummary
-		err := rows.Scan(&report.Month, &report.TotalCredit, &report.TotalDebit, &report.Balance)
+		err := rows.Scan(&report.Month, &report.TotalCredit, &report.TotalDebit, &report.TotalInterest, &report.TotalLateFees,
+			&report.TotalWriteOffs, &report.TotalDiscounts, &report.SupplierPurchases, &report.SupplierPayments, &report.Balance)
 		if err != nil {
 			return nil, err
 		}
+		report.ByCategory = map[string]float64{}
+		report.ByMethod = map[string]float64{}
 		reports = append(reports, report)
 	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	reports, err = addMonthlyCashbook(userID, year, month, reports)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := fillMonthlyBreakdowns(userID, reports); err != nil {
+		return nil, err
+	}
 
 	return reports, nil
 }
 
+// addMonthlyCashbook adds cashbook income and expenses to the monthly
+// reports, covering the same period. Months with cashbook entries but no
+// khata entries get a report of their own.
+func addMonthlyCashbook(userID int, year int, month int, reports []models.ReportSummary) ([]models.ReportSummary, error) {
+	query := `
+		SELECT
+			DATE_FORMAT(date, '%Y-%m') as month,
+			COALESCE(SUM(CASE WHEN type = 'income' THEN amount ELSE 0 END), 0) as income,
+			COALESCE(SUM(CASE WHEN type = 'expense' THEN amount ELSE 0 END), 0) as expense
+		FROM cashbook_entries
+		WHERE user_id = ?`
+	args := []interface{}{userID}
+
+	if year > 0 && month > 0 {
+		query += " AND YEAR(date) = ? AND MONTH(date) = ?"
+		args = append(args, year, month)
+	} else if year > 0 {
+		query += " AND YEAR(date) = ?"
+		args = append(args, year)
+	} else {
+		query += " AND date >= DATE_SUB(CURDATE(), INTERVAL 12 MONTH)"
+	}
+	query += " GROUP BY DATE_FORMAT(date, '%Y-%m')"
+
+	rows, err := database.DB.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	byMonth := map[string]int{}
+	for i := range reports {
+		byMonth[reports[i].Month] = i
+	}
+	for rows.Next() {
+		var reportMonth string
+		var income, expense float64
+		if err := rows.Scan(&reportMonth, &income, &expense); err != nil {
+			return nil, err
+		}
+		i, ok := byMonth[reportMonth]
+		if !ok {
+			reports = append(reports, models.ReportSummary{
+				Month:      reportMonth,
+				ByCategory: map[string]float64{},
+				ByMethod:   map[string]float64{},
+			})
+			i = len(reports) - 1
+		}
+		reports[i].CashbookIncome = income
+		reports[i].CashbookExpense = expense
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	sort.Slice(reports, func(i, j int) bool { return reports[i].Month > reports[j].Month })
+	return reports, nil
+}
+
+// fillMonthlyBreakdowns adds each category's net effect on balances and the
+// amount paid per payment method to each monthly report. A category's amount
+// is signed like balance: credit and charges add, payments and adjustments
+// subtract. Entries without a category are counted under "uncategorized";
+// only payments count towards a method. Cashbook entries stay out of
+// ByCategory as they stay out of Balance.
+func fillMonthlyBreakdowns(userID int, reports []models.ReportSummary) error {
+	if len(reports) == 0 {
+		return nil
+	}
+
+	byMonth := map[string]*models.ReportSummary{}
+	months := []interface{}{}
+	for i := range reports {
+		byMonth[reports[i].Month] = &reports[i]
+		months = append(months, reports[i].Month)
+	}
+	placeholders := "?" + strings.Repeat(", ?", len(months)-1)
+
+	rows, err := database.DB.Query(`
+		SELECT DATE_FORMAT(le.date, '%Y-%m') as month, COALESCE(cat.name, 'uncategorized') as category,
+			SUM(CASE WHEN le.type IN ('debit', 'write_off', 'discount') THEN -le.amount ELSE le.amount END)
+		FROM ledger_entries le
+		LEFT JOIN categories cat ON le.category_id = cat.id
+		WHERE le.user_id = ? AND DATE_FORMAT(le.date, '%Y-%m') IN (`+placeholders+`)
+		GROUP BY month, category`, append([]interface{}{userID}, months...)...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var month, category string
+		var total float64
+		if err := rows.Scan(&month, &category, &total); err != nil {
+			return err
+		}
+		if report, ok := byMonth[month]; ok {
+			report.ByCategory[category] = total
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	methodRows, err := database.DB.Query(`
+		SELECT DATE_FORMAT(le.date, '%Y-%m') as month, le.method, SUM(le.amount)
+		FROM ledger_entries le
+		JOIN customers c ON le.customer_id = c.id
+		WHERE le.user_id = ? AND `+paymentEntryCond+` AND DATE_FORMAT(le.date, '%Y-%m') IN (`+placeholders+`)
+		GROUP BY month, le.method`, append([]interface{}{userID}, months...)...)
+	if err != nil {
+		return err
+	}
+	defer methodRows.Close()
+
+	for methodRows.Next() {
+		var month, method string
+		var total float64
+		if err := methodRows.Scan(&month, &method, &total); err != nil {
+			return err
+		}
+		if report, ok := byMonth[month]; ok {
+			report.ByMethod[method] = total
+		}
+	}
+
+	return methodRows.Err()
+}
+
 // getCategoryReports returns category-wise analytics (by customer)
 func getCategoryReports(userID int, startDate, endDate *time.Time) ([]map[string]interface{}, error) {
 	var query string
@@ -348,7 +624,7 @@ func getCategoryReports(userID int, startDate, endDate *time.Time) ([]map[string
 			c.id as customer_id,
 			COALESCE(SUM(CASE WHEN le.type = 'credit' THEN le.amount ELSE 0 END), 0) as total_credit,
 			COALESCE(SUM(CASE WHEN le.type = 'debit' THEN le.amount ELSE 0 END), 0) as total_debit,
-			COALESCE(SUM(CASE WHEN le.type = 'credit' THEN le.amount ELSE -le.amount END), 0) as balance,
+			COALESCE(SUM(CASE WHEN le.type IN ('debit', 'write_off', 'discount') THEN -le.amount ELSE le.amount END), 0) as balance,
 			COUNT(le.id) as transaction_count
 		FROM customers c
 		LEFT JOIN ledger_entries le ON c.id = le.customer_id AND le.user_id = ?
@@ -400,33 +676,104 @@ func getCategoryReports(userID int, startDate, endDate *time.Time) ([]map[string
 	return reports, nil
 }
 
-// getPaymentMethodReports returns payment method analytics
+// getEntryCategoryReports returns analytics grouped by ledger entry category.
+// net_amount is the category's net effect on balances, signed like balance.
+// Cashbook entries are reported by the cashbook, not here.
+func getEntryCategoryReports(userID int, startDate, endDate *time.Time) ([]map[string]interface{}, error) {
+	query := `
+		SELECT
+			le.category_id,
+			COALESCE(cat.name, 'uncategorized') as category_name,
+			COALESCE(SUM(CASE WHEN le.type = 'credit' THEN le.amount ELSE 0 END), 0) as total_credit,
+			COALESCE(SUM(CASE WHEN le.type = 'debit' THEN le.amount ELSE 0 END), 0) as total_debit,
+			COALESCE(SUM(CASE WHEN le.type IN ('debit', 'write_off', 'discount') THEN -le.amount ELSE le.amount END), 0) as net_amount,
+			COUNT(le.id) as transaction_count
+		FROM ledger_entries le
+		LEFT JOIN categories cat ON le.category_id = cat.id
+		WHERE le.user_id = ?`
+
+	args := []interface{}{userID}
+
+	if startDate != nil {
+		query += " AND le.date >= ?"
+		args = append(args, startDate.Format("2006-01-02"))
+	}
+
+	if endDate != nil {
+		query += " AND le.date <= ?"
+		args = append(args, endDate.Format("2006-01-02"))
+	}
+
+	query += " GROUP BY le.category_id, cat.name ORDER BY net_amount DESC"
+
+	rows, err := database.DB.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var reports []map[string]interface{}
+	for rows.Next() {
+		var categoryID sql.NullInt64
+		var categoryName string
+		var totalCredit, totalDebit, netAmount float64
+		var transactionCount int
+
+		err := rows.Scan(&categoryID, &categoryName, &totalCredit, &totalDebit, &netAmount, &transactionCount)
+		if err != nil {
+			return nil, err
+		}
+
+		report := map[string]interface{}{
+			"category_id":       nullIntPtr(categoryID),
+			"category_name":     categoryName,
+			"total_credit":      totalCredit,
+			"total_debit":       totalDebit,
+			"net_amount":        netAmount,
+			"transaction_count": transactionCount,
+		}
+		reports = append(reports, report)
+	}
+
+	return reports, nil
+}
+
+// getPaymentMethodReports returns payment method analytics. Only payments
+// count: money received from customers and paid to suppliers.
 func getPaymentMethodReports(userID int, startDate, endDate *time.Time) ([]map[string]interface{}, error) {
 	var query string
 	var args []interface{}
 
 	query = `
 		SELECT
-			method,
+			le.method,
 			COUNT(*) as transaction_count,
-			SUM(amount) as total_amount,
-			AVG(amount) as average_amount
-		FROM ledger_entries
-		WHERE user_id = ?`
+			SUM(CASE WHEN le.type = 'debit' THEN le.amount ELSE 0 END) as money_in,
+			SUM(CASE WHEN le.type = 'credit' THEN le.amount ELSE 0 END) as money_out,
+			SUM(le.amount) as total_amount,
+			AVG(le.amount) as average_amount
+		FROM ledger_entries le
+		JOIN customers c ON le.customer_id = c.id
+		WHERE le.user_id = ? AND ` + paymentEntryCond
 
 	args = []interface{}{userID}
 
 	if startDate != nil {
-		query += " AND date >= ?"
+		query += " AND le.date >= ?"
 		args = append(args, startDate.Format("2006-01-02"))
 	}
 
 	if endDate != nil {
-		query += " AND date <= ?"
+		query += " AND le.date <= ?"
 		args = append(args, endDate.Format("2006-01-02"))
 	}
 
-	query += " GROUP BY method ORDER BY total_amount DESC"
+	query += " GROUP BY le.method ORDER BY total_amount DESC"
+
+	registry, err := getPaymentMethodRegistry(userID)
+	if err != nil {
+		return nil, err
+	}
 
 	rows, err := database.DB.Query(query, args...)
 	if err != nil {
@@ -438,16 +785,26 @@ func getPaymentMethodReports(userID int, startDate, endDate *time.Time) ([]map[s
 	for rows.Next() {
 		var method string
 		var transactionCount int
-		var totalAmount, averageAmount float64
+		var moneyIn, moneyOut, totalAmount, averageAmount float64
 
-		err := rows.Scan(&method, &transactionCount, &totalAmount, &averageAmount)
+		err := rows.Scan(&method, &transactionCount, &moneyIn, &moneyOut, &totalAmount, &averageAmount)
 		if err != nil {
 			return nil, err
 		}
 
+		// Methods removed from the registry still report under their code
+		name, kind := method, "other"
+		if registered, ok := registry[method]; ok {
+			name, kind = registered.Name, registered.Kind
+		}
+
 		report := map[string]interface{}{
 			"method":            method,
+			"method_name":       name,
+			"kind":              kind,
 			"transaction_count": transactionCount,
+			"money_in":          moneyIn,
+			"money_out":         moneyOut,
 			"total_amount":      totalAmount,
 			"average_amount":    averageAmount,
 		}
@@ -456,3 +813,111 @@ func getPaymentMethodReports(userID int, startDate, endDate *time.Time) ([]map[s
 
 	return reports, nil
 }
+
+// getChequeReport returns cheque counts and amounts by clearing status
+func getChequeReport(userID int, startDate, endDate *time.Time) (map[string]interface{}, error) {
+	query := `
+		SELECT cd.status, COUNT(*) as cheque_count, COALESCE(SUM(le.amount), 0) as total_amount
+		FROM cheque_details cd
+		JOIN ledger_entries le ON cd.entry_id = le.id
+		WHERE le.user_id = ?`
+
+	args := []interface{}{userID}
+
+	if startDate != nil {
+		query += " AND le.date >= ?"
+		args = append(args, startDate.Format("2006-01-02"))
+	}
+
+	if endDate != nil {
+		query += " AND le.date <= ?"
+		args = append(args, endDate.Format("2006-01-02"))
+	}
+
+	query += " GROUP BY cd.status"
+
+	rows, err := database.DB.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	report := map[string]interface{}{}
+	for _, status := range []string{"pending", "cleared", "bounced"} {
+		report[status] = map[string]interface{}{"count": 0, "total_amount": 0.0}
+	}
+	for rows.Next() {
+		var status string
+		var chequeCount int
+		var totalAmount float64
+		if err := rows.Scan(&status, &chequeCount, &totalAmount); err != nil {
+			return nil, err
+		}
+		report[status] = map[string]interface{}{"count": chequeCount, "total_amount": totalAmount}
+	}
+
+	return report, rows.Err()
+}
+
+// getWriteOffReports totals write-off and discount entries per period.
+// groupBy is "month" or "year".
+func getWriteOffReports(userID int, groupBy string, startDate, endDate *time.Time) ([]map[string]interface{}, error) {
+	periodFormat := "%Y-%m"
+	if groupBy == "year" {
+		periodFormat = "%Y"
+	}
+
+	query := `
+		SELECT
+			DATE_FORMAT(date, '` + periodFormat + `') as period,
+			COALESCE(SUM(CASE WHEN type = 'write_off' THEN amount ELSE 0 END), 0) as total_write_offs,
+			COALESCE(SUM(CASE WHEN type = 'write_off' THEN 1 ELSE 0 END), 0) as write_off_count,
+			COALESCE(SUM(CASE WHEN type = 'discount' THEN amount ELSE 0 END), 0) as total_discounts,
+			COALESCE(SUM(CASE WHEN type = 'discount' THEN 1 ELSE 0 END), 0) as discount_count,
+			COUNT(DISTINCT customer_id) as customer_count
+		FROM ledger_entries
+		WHERE user_id = ? AND type IN ('write_off', 'discount')`
+
+	args := []interface{}{userID}
+
+	if startDate != nil {
+		query += " AND date >= ?"
+		args = append(args, startDate.Format("2006-01-02"))
+	}
+
+	if endDate != nil {
+		query += " AND date <= ?"
+		args = append(args, endDate.Format("2006-01-02"))
+	}
+
+	query += " GROUP BY period ORDER BY period DESC"
+
+	rows, err := database.DB.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	reports := []map[string]interface{}{}
+	for rows.Next() {
+		var period string
+		var totalWriteOffs, totalDiscounts float64
+		var writeOffCount, discountCount, customerCount int
+
+		err := rows.Scan(&period, &totalWriteOffs, &writeOffCount, &totalDiscounts, &discountCount, &customerCount)
+		if err != nil {
+			return nil, err
+		}
+
+		reports = append(reports, map[string]interface{}{
+			"period":           period,
+			"total_write_offs": totalWriteOffs,
+			"write_off_count":  writeOffCount,
+			"total_discounts":  totalDiscounts,
+			"discount_count":   discountCount,
+			"customer_count":   customerCount,
+		})
+	}
+
+	return reports, rows.Err()
+}
@@ -0,0 +1,725 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"khata-book-backend/database"
+	"khata-book-backend/models"
+	"khata-book-backend/pkg/logger"
+
+	"github.com/gorilla/mux"
+)
+
+const dayCloseSelect = `
+	SELECT id, date, status, expected_total, counted_total, variance_total, note, reopen_reason,
+		   closed_at, reopened_at, user_id, created_at, updated_at
+	FROM day_closes`
+
+// CloseDay records the cash counted per payment method at the end of a day,
+// compares it with the cash the ledger and cashbook say should be there and
+// locks the day's entries. A reopened day can be closed again.
+func CloseDay(w http.ResponseWriter, r *http.Request) {
+	// Get user ID from JWT token
+	userID, err := getUserIDFromToken(r)
+	if err != nil {
+		writeJSON(w, http.StatusUnauthorized, map[string]interface{}{"success": false, "error": "unauthorized", "message": "Invalid token"})
+		return
+	}
+
+	var closeReq models.DayCloseRequest
+	err = json.NewDecoder(r.Body).Decode(&closeReq)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"success": false, "error": "invalid_request", "message": "Invalid request body"})
+		return
+	}
+
+	today := calendarDate(time.Now())
+	date := today
+	if closeReq.Date != "" {
+		date, err = time.Parse("2006-01-02", closeReq.Date)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]interface{}{"success": false, "error": "invalid_date", "message": "Date must be in YYYY-MM-DD format"})
+			return
+		}
+	}
+	if date.After(today) {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"success": false, "error": "invalid_date", "message": "A day cannot be closed before it starts"})
+		return
+	}
+
+	counted := map[string]float64{}
+	for _, count := range closeReq.Counts {
+		method := strings.TrimSpace(count.Method)
+		if method == "" || count.Counted < 0 {
+			writeJSON(w, http.StatusBadRequest, map[string]interface{}{"success": false, "error": "invalid_counts", "message": "Each count needs a method and an amount of 0 or more"})
+			return
+		}
+		if _, ok := counted[method]; ok {
+			writeJSON(w, http.StatusBadRequest, map[string]interface{}{"success": false, "error": "invalid_counts", "message": "Each method may be counted only once"})
+			return
+		}
+		counted[method] = roundMoney(count.Counted)
+	}
+	if len(counted) == 0 {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"success": false, "error": "invalid_counts", "message": "At least one counted method is required"})
+		return
+	}
+
+	tx, err := database.DB.Begin()
+	if err != nil {
+		logger.L.WithField("error", err).Error("Could not start transaction")
+		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "server_error", "message": "Could not process request"})
+		return
+	}
+	defer tx.Rollback()
+
+	// Entries are written under a shared lock on the user's row (see
+	// dayLockedTx); holding it exclusively keeps them out while counting
+	var lockedUserID int
+	if err := tx.QueryRow("SELECT id FROM users WHERE id = ? FOR UPDATE", userID).Scan(&lockedUserID); err != nil {
+		logger.L.WithField("error", err).Error("Error locking user")
+		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "server_error", "message": "Could not close day"})
+		return
+	}
+
+	// Lock any earlier close of this day so two closes cannot race
+	dateStr := date.Format("2006-01-02")
+	var existingID int
+	var existingStatus string
+	err = tx.QueryRow("SELECT id, status FROM day_closes WHERE user_id = ? AND date = ? FOR UPDATE", userID, dateStr).Scan(&existingID, &existingStatus)
+	if err != nil && err != sql.ErrNoRows {
+		logger.L.WithField("error", err).Error("Error querying day close")
+		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "server_error", "message": "Could not close day"})
+		return
+	}
+	if existingStatus == "closed" {
+		writeJSON(w, http.StatusConflict, map[string]interface{}{"success": false, "error": "day_already_closed", "message": "This day is already closed; reopen it to count again"})
+		return
+	}
+
+	days, err := getCashBalances(tx, userID, date, date)
+	if err != nil {
+		logger.L.WithField("error", err).Error("Error computing expected cash")
+		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "server_error", "message": "Could not close day"})
+		return
+	}
+	expected := map[string]float64{}
+	for _, balance := range days[0].Methods {
+		expected[balance.Method] = balance.Closing
+	}
+
+	// A counted method with no movements yet must at least be a real one
+	for method := range counted {
+		if _, ok := expected[method]; ok {
+			continue
+		}
+		if _, err := lookupPaymentMethod(userID, method); err != nil {
+			if err == sql.ErrNoRows {
+				writeJSON(w, http.StatusBadRequest, map[string]interface{}{"success": false, "error": "invalid_method", "message": fmt.Sprintf("Unknown payment method '%s'", method)})
+				return
+			}
+			logger.L.WithField("error", err).Error("Error looking up payment method")
+			writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "server_error", "message": "Could not verify payment method"})
+			return
+		}
+		expected[method] = 0
+	}
+
+	// Totals only cover counted methods, so the variance is what was found
+	// missing or extra in what was actually checked
+	counts := buildDayCloseCounts(expected, counted)
+	var expectedTotal, countedTotal, varianceTotal float64
+	for _, count := range counts {
+		if count.Counted == nil {
+			continue
+		}
+		expectedTotal += count.Expected
+		countedTotal += *count.Counted
+		varianceTotal += *count.Variance
+	}
+
+	dayCloseID := existingID
+	if existingID == 0 {
+		result, err := tx.Exec(`
+			INSERT INTO day_closes (date, status, expected_total, counted_total, variance_total, note, closed_at, user_id)
+			VALUES (?, 'closed', ?, ?, ?, ?, CURRENT_TIMESTAMP, ?)`,
+			dateStr, roundMoney(expectedTotal), roundMoney(countedTotal), roundMoney(varianceTotal), closeReq.Note, userID)
+		if err != nil {
+			logger.L.WithField("error", err).Error("Error inserting day close")
+			writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "server_error", "message": "Could not close day"})
+			return
+		}
+		insertedID, err := result.LastInsertId()
+		if err != nil {
+			logger.L.WithField("error", err).Error("Error getting inserted day close ID")
+			writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "server_error", "message": "Could not close day"})
+			return
+		}
+		dayCloseID = int(insertedID)
+	} else {
+		_, err = tx.Exec(`
+			UPDATE day_closes
+			SET status = 'closed', expected_total = ?, counted_total = ?, variance_total = ?, note = ?,
+				closed_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP
+			WHERE id = ?`,
+			roundMoney(expectedTotal), roundMoney(countedTotal), roundMoney(varianceTotal), closeReq.Note, existingID)
+		if err == nil {
+			_, err = tx.Exec("DELETE FROM day_close_counts WHERE day_close_id = ?", existingID)
+		}
+		if err != nil {
+			logger.L.WithField("error", err).Error("Error updating day close")
+			writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "server_error", "message": "Could not close day"})
+			return
+		}
+	}
+
+	for _, count := range counts {
+		_, err = tx.Exec(`
+			INSERT INTO day_close_counts (day_close_id, method, expected, counted, variance)
+			VALUES (?, ?, ?, ?, ?)`,
+			dayCloseID, count.Method, count.Expected, count.Counted, count.Variance)
+		if err != nil {
+			logger.L.WithField("error", err).Error("Error inserting day close count")
+			writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "server_error", "message": "Could not close day"})
+			return
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		logger.L.WithField("error", err).Error("Error committing transaction")
+		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "server_error", "message": "Could not close day"})
+		return
+	}
+
+	dayClose, err := getDayClose(database.DB, userID, dateStr)
+	if err != nil {
+		logger.L.WithField("error", err).Error("Error loading day close")
+		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "server_error", "message": "Could not close day"})
+		return
+	}
+
+	logger.L.WithFields(map[string]interface{}{
+		"day_close_id": dayCloseID,
+		"user_id":      userID,
+		"date":         dateStr,
+		"variance":     dayClose.VarianceTotal,
+	}).Info("Day closed successfully")
+
+	writeJSON(w, http.StatusCreated, map[string]interface{}{
+		"success":   true,
+		"message":   "Day closed successfully",
+		"day_close": dayClose,
+	})
+}
+
+// GetDayCloses lists day closes, newest first
+func GetDayCloses(w http.ResponseWriter, r *http.Request) {
+	// Get user ID from JWT token
+	userID, err := getUserIDFromToken(r)
+	if err != nil {
+		writeJSON(w, http.StatusUnauthorized, map[string]interface{}{"success": false, "error": "unauthorized", "message": "Invalid token"})
+		return
+	}
+
+	// Parse query parameters
+	status := r.URL.Query().Get("status")
+	limitStr := r.URL.Query().Get("limit")
+	offsetStr := r.URL.Query().Get("offset")
+
+	where, args := dayCloseFilter(r, userID)
+	if status == "closed" || status == "reopened" {
+		where += " AND status = ?"
+		args = append(args, status)
+	}
+
+	limit := 50 // default limit
+	if limitStr != "" {
+		if parsedLimit, err := strconv.Atoi(limitStr); err == nil && parsedLimit > 0 && parsedLimit <= 200 {
+			limit = parsedLimit
+		}
+	}
+
+	offset := 0
+	if offsetStr != "" {
+		if parsedOffset, err := strconv.Atoi(offsetStr); err == nil && parsedOffset >= 0 {
+			offset = parsedOffset
+		}
+	}
+
+	dayCloses, err := listDayCloses(where+" ORDER BY date DESC LIMIT ? OFFSET ?", append(args, limit, offset))
+	if err != nil {
+		logger.L.WithField("error", err).Error("Error querying day closes")
+		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "server_error", "message": "Could not fetch day closes"})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"success":    true,
+		"day_closes": dayCloses,
+		"count":      len(dayCloses),
+	})
+}
+
+// GetDayClose returns the close of a single day
+func GetDayClose(w http.ResponseWriter, r *http.Request) {
+	// Get user ID from JWT token
+	userID, err := getUserIDFromToken(r)
+	if err != nil {
+		writeJSON(w, http.StatusUnauthorized, map[string]interface{}{"success": false, "error": "unauthorized", "message": "Invalid token"})
+		return
+	}
+
+	date, err := time.Parse("2006-01-02", mux.Vars(r)["date"])
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"success": false, "error": "invalid_date", "message": "Date must be in YYYY-MM-DD format"})
+		return
+	}
+
+	dayClose, err := getDayClose(database.DB, userID, date.Format("2006-01-02"))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			writeJSON(w, http.StatusNotFound, map[string]interface{}{"success": false, "error": "not_found", "message": "This day has not been closed"})
+			return
+		}
+		logger.L.WithField("error", err).Error("Error querying day close")
+		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "server_error", "message": "Could not fetch day close"})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{"success": true, "day_close": dayClose})
+}
+
+// ReopenDay unlocks a closed day so its entries can be changed. The counts
+// are kept until the day is closed again.
+func ReopenDay(w http.ResponseWriter, r *http.Request) {
+	// Get user ID from JWT token
+	userID, err := getUserIDFromToken(r)
+	if err != nil {
+		writeJSON(w, http.StatusUnauthorized, map[string]interface{}{"success": false, "error": "unauthorized", "message": "Invalid token"})
+		return
+	}
+
+	date, err := time.Parse("2006-01-02", mux.Vars(r)["date"])
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"success": false, "error": "invalid_date", "message": "Date must be in YYYY-MM-DD format"})
+		return
+	}
+	dateStr := date.Format("2006-01-02")
+
+	// The body with a reason is optional
+	var reopenReq models.DayReopenRequest
+	if err := json.NewDecoder(r.Body).Decode(&reopenReq); err != nil && err != io.EOF {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"success": false, "error": "invalid_request", "message": "Invalid request body"})
+		return
+	}
+
+	result, err := database.DB.Exec(`
+		UPDATE day_closes
+		SET status = 'reopened', reopen_reason = ?, reopened_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP
+		WHERE user_id = ? AND date = ? AND status = 'closed'`,
+		reopenReq.Reason, userID, dateStr)
+	if err != nil {
+		logger.L.WithField("error", err).Error("Error reopening day")
+		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "server_error", "message": "Could not reopen day"})
+		return
+	}
+
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		writeJSON(w, http.StatusNotFound, map[string]interface{}{"success": false, "error": "not_found", "message": "This day is not closed"})
+		return
+	}
+
+	dayClose, err := getDayClose(database.DB, userID, dateStr)
+	if err != nil {
+		logger.L.WithField("error", err).Error("Error loading day close")
+		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "server_error", "message": "Could not reopen day"})
+		return
+	}
+
+	logger.L.WithFields(map[string]interface{}{
+		"day_close_id": dayClose.ID,
+		"user_id":      userID,
+		"date":         dateStr,
+	}).Info("Day reopened successfully")
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"success":   true,
+		"message":   "Day reopened successfully",
+		"day_close": dayClose,
+	})
+}
+
+// GetDayCloseReport summarises cash variances over the closed days between
+// ?start_date and ?end_date, overall and per payment method
+func GetDayCloseReport(w http.ResponseWriter, r *http.Request) {
+	// Get user ID from JWT token
+	userID, err := getUserIDFromToken(r)
+	if err != nil {
+		writeJSON(w, http.StatusUnauthorized, map[string]interface{}{"success": false, "error": "unauthorized", "message": "Invalid token"})
+		return
+	}
+
+	where, args := dayCloseFilter(r, userID)
+	dayCloses, err := listDayCloses(where+" AND status = 'closed' ORDER BY date DESC", args)
+	if err != nil {
+		logger.L.WithField("error", err).Error("Error querying day closes")
+		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "server_error", "message": "Could not fetch day close report"})
+		return
+	}
+
+	var shortDays, overDays, balancedDays int
+	var shortTotal, overTotal float64
+	type methodSummary struct {
+		Method        string  `json:"method"`
+		ExpectedTotal float64 `json:"expected_total"`
+		CountedTotal  float64 `json:"counted_total"`
+		VarianceTotal float64 `json:"variance_total"`
+		DaysCounted   int     `json:"days_counted"`
+	}
+	byMethod := map[string]*methodSummary{}
+
+	for _, dayClose := range dayCloses {
+		switch {
+		case dayClose.VarianceTotal < 0:
+			shortDays++
+			shortTotal += -dayClose.VarianceTotal
+		case dayClose.VarianceTotal > 0:
+			overDays++
+			overTotal += dayClose.VarianceTotal
+		default:
+			balancedDays++
+		}
+		for _, count := range dayClose.Counts {
+			if count.Counted == nil {
+				continue
+			}
+			summary, ok := byMethod[count.Method]
+			if !ok {
+				summary = &methodSummary{Method: count.Method}
+				byMethod[count.Method] = summary
+			}
+			summary.ExpectedTotal = roundMoney(summary.ExpectedTotal + count.Expected)
+			summary.CountedTotal = roundMoney(summary.CountedTotal + *count.Counted)
+			summary.VarianceTotal = roundMoney(summary.VarianceTotal + *count.Variance)
+			summary.DaysCounted++
+		}
+	}
+
+	methods := []methodSummary{}
+	for _, summary := range byMethod {
+		methods = append(methods, *summary)
+	}
+	sort.Slice(methods, func(i, j int) bool { return methods[i].Method < methods[j].Method })
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"summary": map[string]interface{}{
+			"days_closed":   len(dayCloses),
+			"short_days":    shortDays,
+			"over_days":     overDays,
+			"balanced_days": balancedDays,
+			"total_short":   roundMoney(shortTotal),
+			"total_over":    roundMoney(overTotal),
+			"net_variance":  roundMoney(overTotal - shortTotal),
+			"by_method":     methods,
+		},
+		"day_closes": dayCloses,
+	})
+}
+
+// checkDayOpen writes a conflict response and returns false when entries
+// dated date are locked by a day close
+func checkDayOpen(w http.ResponseWriter, userID int, date time.Time) bool {
+	locked, err := dayLocked(database.DB, userID, date)
+	if err != nil {
+		logger.L.WithField("error", err).Error("Error checking day close")
+		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "server_error", "message": "Could not verify day close"})
+		return false
+	}
+	if locked {
+		writeJSON(w, http.StatusConflict, map[string]interface{}{"success": false, "error": "day_closed", "message": fmt.Sprintf("Entries dated %s are locked by a day close; reopen the day to change them", date.Format("2006-01-02"))})
+		return false
+	}
+	return true
+}
+
+// checkDayOpenTx is checkDayOpen inside the transaction that writes entries
+// dated date. Handlers check before starting their transaction to fail
+// early, then again here so a close committed in between is not missed.
+func checkDayOpenTx(w http.ResponseWriter, tx *sql.Tx, userID int, date time.Time) bool {
+	locked, err := dayLockedTx(tx, userID, date)
+	if err != nil {
+		logger.L.WithField("error", err).Error("Error checking day close")
+		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "server_error", "message": "Could not verify day close"})
+		return false
+	}
+	if locked {
+		writeJSON(w, http.StatusConflict, map[string]interface{}{"success": false, "error": "day_closed", "message": fmt.Sprintf("Entries dated %s are locked by a day close; reopen the day to change them", date.Format("2006-01-02"))})
+		return false
+	}
+	return true
+}
+
+// checkEntryDayOpen is checkDayOpen for the date of an existing ledger entry
+func checkEntryDayOpen(w http.ResponseWriter, entryID, userID int) bool {
+	var dateStr string
+	err := database.DB.QueryRow("SELECT date FROM ledger_entries WHERE id = ? AND user_id = ?", entryID, userID).Scan(&dateStr)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			writeJSON(w, http.StatusNotFound, map[string]interface{}{"success": false, "error": "not_found", "message": "Ledger entry not found"})
+			return false
+		}
+		logger.L.WithField("error", err).Error("Error querying ledger entry")
+		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "server_error", "message": "Could not fetch ledger entry"})
+		return false
+	}
+	date, _ := time.Parse("2006-01-02", dateStr)
+	return checkDayOpen(w, userID, date)
+}
+
+// checkEntryDayOpenTx is checkDayOpenTx for the date of an existing ledger
+// entry, which is locked until tx ends
+func checkEntryDayOpenTx(w http.ResponseWriter, tx *sql.Tx, entryID, userID int) bool {
+	var dateStr string
+	err := tx.QueryRow("SELECT date FROM ledger_entries WHERE id = ? AND user_id = ? FOR UPDATE", entryID, userID).Scan(&dateStr)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			writeJSON(w, http.StatusNotFound, map[string]interface{}{"success": false, "error": "not_found", "message": "Ledger entry not found"})
+			return false
+		}
+		logger.L.WithField("error", err).Error("Error querying ledger entry")
+		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "server_error", "message": "Could not fetch ledger entry"})
+		return false
+	}
+	date, _ := time.Parse("2006-01-02", dateStr)
+	return checkDayOpenTx(w, tx, userID, date)
+}
+
+// checkCashbookEntryDayOpenTx is checkDayOpenTx for the date of an existing
+// cashbook entry, which is locked until tx ends
+func checkCashbookEntryDayOpenTx(w http.ResponseWriter, tx *sql.Tx, entryID, userID int) bool {
+	var dateStr string
+	err := tx.QueryRow("SELECT date FROM cashbook_entries WHERE id = ? AND user_id = ? FOR UPDATE", entryID, userID).Scan(&dateStr)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			writeJSON(w, http.StatusNotFound, map[string]interface{}{"success": false, "error": "not_found", "message": "Cashbook entry not found"})
+			return false
+		}
+		logger.L.WithField("error", err).Error("Error querying cashbook entry")
+		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "server_error", "message": "Could not fetch cashbook entry"})
+		return false
+	}
+	date, _ := time.Parse("2006-01-02", dateStr)
+	return checkDayOpenTx(w, tx, userID, date)
+}
+
+// dayLocked reports whether entries dated date are locked: that day or a
+// later one is closed, and that day itself has not been reopened
+func dayLocked(q queryRower, userID int, date time.Time) (bool, error) {
+	dateStr := date.Format("2006-01-02")
+	var locked bool
+	err := q.QueryRow(`
+		SELECT EXISTS (SELECT 1 FROM day_closes WHERE user_id = ? AND status = 'closed' AND date >= ?)
+			AND NOT EXISTS (SELECT 1 FROM day_closes WHERE user_id = ? AND status = 'reopened' AND date = ?)`,
+		userID, dateStr, userID, dateStr).Scan(&locked)
+	return locked, err
+}
+
+// dayLockedTx is dayLocked for a transaction about to write entries dated
+// date. It takes a shared lock on the user's row, which CloseDay holds
+// exclusively while it counts, so no day can be closed until tx ends, and
+// reads day_closes with a locking read so a close committed after tx began
+// is seen.
+func dayLockedTx(tx *sql.Tx, userID int, date time.Time) (bool, error) {
+	var id int
+	if err := tx.QueryRow("SELECT id FROM users WHERE id = ? LOCK IN SHARE MODE", userID).Scan(&id); err != nil {
+		return false, err
+	}
+
+	dateStr := date.Format("2006-01-02")
+	var closed, reopened bool
+	err := tx.QueryRow(`
+		SELECT COALESCE(MAX(status = 'closed'), FALSE), COALESCE(MAX(status = 'reopened' AND date = ?), FALSE)
+		FROM day_closes
+		WHERE user_id = ? AND date >= ?
+		LOCK IN SHARE MODE`,
+		dateStr, userID, dateStr).Scan(&closed, &reopened)
+	return closed && !reopened, err
+}
+
+// buildDayCloseCounts pairs the expected closing cash of every method with
+// what was counted, sorted by method
+func buildDayCloseCounts(expected, counted map[string]float64) []models.DayCloseCount {
+	counts := []models.DayCloseCount{}
+	for method, amount := range expected {
+		count := models.DayCloseCount{Method: method, Expected: roundMoney(amount)}
+		if c, ok := counted[method]; ok {
+			variance := roundMoney(c - count.Expected)
+			count.Counted = &c
+			count.Variance = &variance
+		}
+		counts = append(counts, count)
+	}
+	sort.Slice(counts, func(i, j int) bool { return counts[i].Method < counts[j].Method })
+	return counts
+}
+
+// dayCloseFilter builds the WHERE clause shared by the history and the
+// report from ?start_date and ?end_date
+func dayCloseFilter(r *http.Request, userID int) (string, []interface{}) {
+	where := " WHERE user_id = ?"
+	args := []interface{}{userID}
+
+	if startDate, err := time.Parse("2006-01-02", r.URL.Query().Get("start_date")); err == nil {
+		where += " AND date >= ?"
+		args = append(args, startDate.Format("2006-01-02"))
+	}
+
+	if endDate, err := time.Parse("2006-01-02", r.URL.Query().Get("end_date")); err == nil {
+		where += " AND date <= ?"
+		args = append(args, endDate.Format("2006-01-02"))
+	}
+
+	return where, args
+}
+
+// listDayCloses loads day closes matching the clause with their counts
+func listDayCloses(clause string, args []interface{}) ([]models.DayClose, error) {
+	rows, err := database.DB.Query(dayCloseSelect+clause, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	dayCloses := []models.DayClose{}
+	byID := map[int]int{}
+	for rows.Next() {
+		dayClose, err := scanDayClose(rows)
+		if err != nil {
+			return nil, err
+		}
+		byID[dayClose.ID] = len(dayCloses)
+		dayCloses = append(dayCloses, *dayClose)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(dayCloses) == 0 {
+		return dayCloses, nil
+	}
+
+	ids := make([]interface{}, 0, len(dayCloses))
+	for _, dayClose := range dayCloses {
+		ids = append(ids, dayClose.ID)
+	}
+	countRows, err := database.DB.Query(`
+		SELECT day_close_id, method, expected, counted, variance
+		FROM day_close_counts
+		WHERE day_close_id IN (?`+strings.Repeat(", ?", len(ids)-1)+`)
+		ORDER BY method`, ids...)
+	if err != nil {
+		return nil, err
+	}
+	defer countRows.Close()
+
+	for countRows.Next() {
+		var dayCloseID int
+		count, err := scanDayCloseCount(countRows, &dayCloseID)
+		if err != nil {
+			return nil, err
+		}
+		i := byID[dayCloseID]
+		dayCloses[i].Counts = append(dayCloses[i].Counts, *count)
+	}
+
+	return dayCloses, countRows.Err()
+}
+
+// getDayClose loads the close of one day with its counts
+func getDayClose(q queryer, userID int, date string) (*models.DayClose, error) {
+	dayClose, err := scanDayClose(q.QueryRow(dayCloseSelect+" WHERE user_id = ? AND date = ?", userID, date))
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := q.Query(`
+		SELECT day_close_id, method, expected, counted, variance
+		FROM day_close_counts
+		WHERE day_close_id = ?
+		ORDER BY method`, dayClose.ID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var dayCloseID int
+		count, err := scanDayCloseCount(rows, &dayCloseID)
+		if err != nil {
+			return nil, err
+		}
+		dayClose.Counts = append(dayClose.Counts, *count)
+	}
+
+	return dayClose, rows.Err()
+}
+
+func scanDayClose(row rowScanner) (*models.DayClose, error) {
+	var dayClose models.DayClose
+	var note, reopenReason, closedAt, reopenedAt sql.NullString
+	var createdAtStr, updatedAtStr string
+
+	err := row.Scan(
+		&dayClose.ID, &dayClose.Date, &dayClose.Status, &dayClose.ExpectedTotal, &dayClose.CountedTotal,
+		&dayClose.VarianceTotal, &note, &reopenReason, &closedAt, &reopenedAt, &dayClose.UserID,
+		&createdAtStr, &updatedAtStr,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	dayClose.Note = nullStringPtr(note)
+	dayClose.ReopenReason = nullStringPtr(reopenReason)
+	dayClose.ClosedAt = parseNullTimestamp(closedAt)
+	dayClose.ReopenedAt = parseNullTimestamp(reopenedAt)
+	dayClose.Counts = []models.DayCloseCount{}
+	dayClose.CreatedAt, _ = time.Parse("2006-01-02 15:04:05", createdAtStr)
+	dayClose.UpdatedAt, _ = time.Parse("2006-01-02 15:04:05", updatedAtStr)
+
+	return &dayClose, nil
+}
+
+func scanDayCloseCount(row rowScanner, dayCloseID *int) (*models.DayCloseCount, error) {
+	var count models.DayCloseCount
+	var counted, variance sql.NullFloat64
+	if err := row.Scan(dayCloseID, &count.Method, &count.Expected, &counted, &variance); err != nil {
+		return nil, err
+	}
+	if counted.Valid {
+		count.Counted = &counted.Float64
+	}
+	if variance.Valid {
+		count.Variance = &variance.Float64
+	}
+	return &count, nil
+}
+
+// parseNullTimestamp parses an optional TIMESTAMP column
+func parseNullTimestamp(s sql.NullString) *time.Time {
+	if !s.Valid {
+		return nil
+	}
+	t, err := time.Parse("2006-01-02 15:04:05", s.String)
+	if err != nil {
+		return nil
+	}
+	return &t
+}
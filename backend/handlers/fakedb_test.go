@@ -0,0 +1,171 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+
+	"khata-book-backend/database"
+)
+
+// fakeDB stands in for MySQL in handler tests. Statements are routed by
+// their leading text, with whitespace collapsed, to functions that emulate
+// just the tables a test needs; a statement with no route fails the test.
+// Transactions are not isolated, and a rollback only counts as one.
+type fakeDB struct {
+	t *testing.T
+
+	mu        sync.Mutex
+	routes    []fakeRoute
+	commits   int
+	rollbacks int
+}
+
+type fakeRoute struct {
+	prefix string
+	handle func(args []driver.Value) (*fakeRows, int64, error)
+}
+
+// fakeRows is a result set; nil means a statement that returns none
+type fakeRows struct {
+	columns []string
+	values  [][]driver.Value
+	next    int
+}
+
+// useFakeDB points database.DB at a new fake for the length of the test
+func useFakeDB(t *testing.T) *fakeDB {
+	t.Helper()
+	fake := &fakeDB{t: t}
+	previous := database.DB
+	database.DB = sql.OpenDB(fake)
+	t.Cleanup(func() {
+		database.DB.Close()
+		database.DB = previous
+	})
+	return fake
+}
+
+// route handles statements starting with prefix. Longer prefixes are tried
+// first so a specific statement can be told apart from a general one.
+func (f *fakeDB) route(prefix string, handle func(args []driver.Value) (*fakeRows, int64, error)) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.routes = append(f.routes, fakeRoute{prefix: squash(prefix), handle: handle})
+}
+
+// run executes a statement; the store's lock is held so route functions
+// need no locking of their own
+func (f *fakeDB) run(query string, named []driver.NamedValue) (*fakeRows, int64, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	args := make([]driver.Value, len(named))
+	for i, arg := range named {
+		args[i] = arg.Value
+	}
+
+	query = squash(query)
+	var match *fakeRoute
+	for i := range f.routes {
+		if strings.HasPrefix(query, f.routes[i].prefix) && (match == nil || len(f.routes[i].prefix) > len(match.prefix)) {
+			match = &f.routes[i]
+		}
+	}
+	if match == nil {
+		f.t.Errorf("unexpected statement: %s", query)
+		return nil, 0, errors.New("fakedb: no route for statement")
+	}
+	return match.handle(args)
+}
+
+func squash(query string) string {
+	return strings.Join(strings.Fields(query), " ")
+}
+
+func (f *fakeDB) Connect(context.Context) (driver.Conn, error) { return &fakeConn{db: f}, nil }
+
+func (f *fakeDB) Driver() driver.Driver { return fakeDriver{} }
+
+type fakeDriver struct{}
+
+func (fakeDriver) Open(string) (driver.Conn, error) {
+	return nil, errors.New("fakedb: open through useFakeDB")
+}
+
+type fakeConn struct{ db *fakeDB }
+
+func (c *fakeConn) Prepare(string) (driver.Stmt, error) {
+	return nil, errors.New("fakedb: prepared statements are not supported")
+}
+
+func (c *fakeConn) Close() error { return nil }
+
+func (c *fakeConn) Begin() (driver.Tx, error) { return fakeTx{db: c.db}, nil }
+
+func (c *fakeConn) ExecContext(_ context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	_, affected, err := c.db.run(query, args)
+	if err != nil {
+		return nil, err
+	}
+	return driver.RowsAffected(affected), nil
+}
+
+func (c *fakeConn) QueryContext(_ context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	rows, _, err := c.db.run(query, args)
+	if err != nil {
+		return nil, err
+	}
+	if rows == nil {
+		rows = &fakeRows{}
+	}
+	return rows, nil
+}
+
+type fakeTx struct{ db *fakeDB }
+
+func (tx fakeTx) Commit() error {
+	tx.db.mu.Lock()
+	defer tx.db.mu.Unlock()
+	tx.db.commits++
+	return nil
+}
+
+func (tx fakeTx) Rollback() error {
+	tx.db.mu.Lock()
+	defer tx.db.mu.Unlock()
+	tx.db.rollbacks++
+	return nil
+}
+
+func (r *fakeRows) Columns() []string { return r.columns }
+
+func (r *fakeRows) Close() error { return nil }
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if r.next >= len(r.values) {
+		return io.EOF
+	}
+	copy(dest, r.values[r.next])
+	r.next++
+	return nil
+}
+
+// row is a one-row result set
+func row(values ...driver.Value) *fakeRows {
+	columns := make([]string, len(values))
+	for i := range columns {
+		columns[i] = "c" + string(rune('a'+i))
+	}
+	return &fakeRows{columns: columns, values: [][]driver.Value{values}}
+}
+
+// noRows is an empty result set of n columns
+func noRows(n int) *fakeRows {
+	return &fakeRows{columns: make([]string, n)}
+}
@@ -0,0 +1,555 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"khata-book-backend/database"
+	"khata-book-backend/models"
+	"khata-book-backend/pkg/logger"
+
+	"github.com/gorilla/mux"
+)
+
+// b2clThreshold is the invoice value above which an inter-state sale to an
+// unregistered customer is reported invoice by invoice (B2CL) rather than in
+// the B2CS totals
+const b2clThreshold = 100000
+
+// GetTaxRates lists the user's configured tax rates
+func GetTaxRates(w http.ResponseWriter, r *http.Request) {
+	// Get user ID from JWT token
+	userID, err := getUserIDFromToken(r)
+	if err != nil {
+		writeJSON(w, http.StatusUnauthorized, map[string]interface{}{"success": false, "error": "unauthorized", "message": "Invalid token"})
+		return
+	}
+
+	query := `
+		SELECT id, name, rate, hsn_code, is_active, user_id, created_at, updated_at
+		FROM tax_rates
+		WHERE user_id = ?`
+	if r.URL.Query().Get("active") == "true" {
+		query += " AND is_active = TRUE"
+	}
+	query += " ORDER BY rate ASC, name ASC"
+
+	rows, err := database.DB.Query(query, userID)
+	if err != nil {
+		logger.L.WithField("error", err).Error("Error querying tax rates")
+		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "server_error", "message": "Could not fetch tax rates"})
+		return
+	}
+	defer rows.Close()
+
+	rates := []models.TaxRate{}
+	for rows.Next() {
+		rate, err := scanTaxRate(rows)
+		if err != nil {
+			logger.L.WithField("error", err).Error("Error scanning tax rate")
+			continue
+		}
+		rates = append(rates, *rate)
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"success":   true,
+		"tax_rates": rates,
+	})
+}
+
+// CreateTaxRate adds a tax rate that invoice lines can refer to
+func CreateTaxRate(w http.ResponseWriter, r *http.Request) {
+	// Get user ID from JWT token
+	userID, err := getUserIDFromToken(r)
+	if err != nil {
+		writeJSON(w, http.StatusUnauthorized, map[string]interface{}{"success": false, "error": "unauthorized", "message": "Invalid token"})
+		return
+	}
+
+	var rateReq models.TaxRateRequest
+	err = json.NewDecoder(r.Body).Decode(&rateReq)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"success": false, "error": "invalid_request", "message": "Invalid request body"})
+		return
+	}
+
+	if rateReq.Rate == nil {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"success": false, "error": "invalid_rate", "message": "Rate is required"})
+		return
+	}
+
+	rate := models.TaxRate{
+		Name:     strings.TrimSpace(rateReq.Name),
+		Rate:     *rateReq.Rate,
+		HSNCode:  rateReq.HSNCode,
+		IsActive: true,
+		UserID:   userID,
+	}
+	if !validateTaxRate(w, &rate) {
+		return
+	}
+
+	result, err := database.DB.Exec(`
+		INSERT INTO tax_rates (name, rate, hsn_code, is_active, user_id)
+		VALUES (?, ?, ?, TRUE, ?)`,
+		rate.Name, rate.Rate, rate.HSNCode, userID)
+	if err != nil {
+		if strings.Contains(err.Error(), "Duplicate entry") {
+			writeJSON(w, http.StatusConflict, map[string]interface{}{"success": false, "error": "tax_rate_exists", "message": "A tax rate with this name already exists"})
+			return
+		}
+		logger.L.WithField("error", err).Error("Error inserting tax rate")
+		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "server_error", "message": "Could not create tax rate"})
+		return
+	}
+
+	rateID, err := result.LastInsertId()
+	if err != nil {
+		logger.L.WithField("error", err).Error("Error getting inserted tax rate ID")
+		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "server_error", "message": "Could not create tax rate"})
+		return
+	}
+
+	rate.ID = int(rateID)
+	rate.CreatedAt = time.Now()
+	rate.UpdatedAt = rate.CreatedAt
+
+	logger.L.WithFields(map[string]interface{}{
+		"tax_rate_id": rateID,
+		"user_id":     userID,
+		"rate":        rate.Rate,
+	}).Info("Tax rate created successfully")
+
+	writeJSON(w, http.StatusCreated, map[string]interface{}{
+		"success":  true,
+		"message":  "Tax rate created successfully",
+		"tax_rate": rate,
+	})
+}
+
+// UpdateTaxRate changes a tax rate. Issued invoices keep the rate they were
+// created with.
+func UpdateTaxRate(w http.ResponseWriter, r *http.Request) {
+	// Get user ID from JWT token
+	userID, err := getUserIDFromToken(r)
+	if err != nil {
+		writeJSON(w, http.StatusUnauthorized, map[string]interface{}{"success": false, "error": "unauthorized", "message": "Invalid token"})
+		return
+	}
+
+	rateID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"success": false, "error": "invalid_id", "message": "Invalid tax rate ID"})
+		return
+	}
+
+	var rateReq models.TaxRateRequest
+	err = json.NewDecoder(r.Body).Decode(&rateReq)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"success": false, "error": "invalid_request", "message": "Invalid request body"})
+		return
+	}
+
+	rate, err := scanTaxRate(database.DB.QueryRow(`
+		SELECT id, name, rate, hsn_code, is_active, user_id, created_at, updated_at
+		FROM tax_rates
+		WHERE id = ? AND user_id = ?`, rateID, userID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			writeJSON(w, http.StatusNotFound, map[string]interface{}{"success": false, "error": "not_found", "message": "Tax rate not found"})
+			return
+		}
+		logger.L.WithField("error", err).Error("Error querying tax rate")
+		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "server_error", "message": "Could not update tax rate"})
+		return
+	}
+
+	if rateReq.Name != "" {
+		rate.Name = strings.TrimSpace(rateReq.Name)
+	}
+	if rateReq.Rate != nil {
+		rate.Rate = *rateReq.Rate
+	}
+	if rateReq.HSNCode != nil {
+		rate.HSNCode = rateReq.HSNCode
+	}
+	if rateReq.IsActive != nil {
+		rate.IsActive = *rateReq.IsActive
+	}
+	if !validateTaxRate(w, rate) {
+		return
+	}
+
+	_, err = database.DB.Exec(`
+		UPDATE tax_rates
+		SET name = ?, rate = ?, hsn_code = ?, is_active = ?, updated_at = CURRENT_TIMESTAMP
+		WHERE id = ?`,
+		rate.Name, rate.Rate, rate.HSNCode, rate.IsActive, rateID)
+	if err != nil {
+		if strings.Contains(err.Error(), "Duplicate entry") {
+			writeJSON(w, http.StatusConflict, map[string]interface{}{"success": false, "error": "tax_rate_exists", "message": "A tax rate with this name already exists"})
+			return
+		}
+		logger.L.WithField("error", err).Error("Error updating tax rate")
+		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "server_error", "message": "Could not update tax rate"})
+		return
+	}
+
+	logger.L.WithFields(map[string]interface{}{
+		"tax_rate_id": rateID,
+		"user_id":     userID,
+	}).Info("Tax rate updated successfully")
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"success":  true,
+		"message":  "Tax rate updated successfully",
+		"tax_rate": rate,
+	})
+}
+
+// DeleteTaxRate removes a tax rate. Invoice lines store the rate itself, so
+// issued invoices are unaffected.
+func DeleteTaxRate(w http.ResponseWriter, r *http.Request) {
+	// Get user ID from JWT token
+	userID, err := getUserIDFromToken(r)
+	if err != nil {
+		writeJSON(w, http.StatusUnauthorized, map[string]interface{}{"success": false, "error": "unauthorized", "message": "Invalid token"})
+		return
+	}
+
+	rateID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"success": false, "error": "invalid_id", "message": "Invalid tax rate ID"})
+		return
+	}
+
+	result, err := database.DB.Exec("DELETE FROM tax_rates WHERE id = ? AND user_id = ?", rateID, userID)
+	if err != nil {
+		logger.L.WithField("error", err).Error("Error deleting tax rate")
+		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "server_error", "message": "Could not delete tax rate"})
+		return
+	}
+
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		writeJSON(w, http.StatusNotFound, map[string]interface{}{"success": false, "error": "not_found", "message": "Tax rate not found"})
+		return
+	}
+
+	logger.L.WithFields(map[string]interface{}{
+		"tax_rate_id": rateID,
+		"user_id":     userID,
+	}).Info("Tax rate deleted successfully")
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"message": "Tax rate deleted successfully",
+	})
+}
+
+// GetGSTR1Report summarises the GST invoices of one return period
+// (?period=MMYYYY, the previous month by default) in the GSTR-1 JSON layout.
+// Registered customers are reported under B2B, large inter-state sales to
+// unregistered customers under B2CL and the rest as B2CS totals.
+func GetGSTR1Report(w http.ResponseWriter, r *http.Request) {
+	// Get user ID from JWT token
+	userID, err := getUserIDFromToken(r)
+	if err != nil {
+		writeJSON(w, http.StatusUnauthorized, map[string]interface{}{"success": false, "error": "unauthorized", "message": "Invalid token"})
+		return
+	}
+
+	periodStart := calendarDate(time.Now())
+	periodStart = periodStart.AddDate(0, -1, 1-periodStart.Day())
+	if period := r.URL.Query().Get("period"); period != "" {
+		parsed, err := time.Parse("012006", period)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]interface{}{"success": false, "error": "invalid_period", "message": "Period must be MMYYYY"})
+			return
+		}
+		periodStart = parsed
+	}
+	periodEnd := periodStart.AddDate(0, 1, -1)
+
+	var gstin sql.NullString
+	err = database.DB.QueryRow("SELECT gstin FROM users WHERE id = ?", userID).Scan(&gstin)
+	if err != nil {
+		logger.L.WithField("error", err).Error("Error querying user GSTIN")
+		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "server_error", "message": "Could not build GSTR-1 report"})
+		return
+	}
+	if !gstin.Valid {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"success": false, "error": "gstin_required", "message": "Add your GSTIN to the profile to file GST returns"})
+		return
+	}
+
+	report, err := getGSTR1(userID, gstin.String, periodStart, periodEnd)
+	if err != nil {
+		logger.L.WithField("error", err).Error("Error building GSTR-1 report")
+		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "server_error", "message": "Could not build GSTR-1 report"})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"success":    true,
+		"start_date": periodStart.Format("2006-01-02"),
+		"end_date":   periodEnd.Format("2006-01-02"),
+		"gstr1":      report,
+	})
+}
+
+// gstr1Invoice collects one invoice's lines while the report is built
+type gstr1Invoice struct {
+	number        string
+	date          time.Time
+	value         float64
+	customerGSTIN string
+	placeOfSupply string
+	interState    bool
+	rates         map[float64]*models.GSTR1ItemDetail
+}
+
+// getGSTR1 builds the return filed under gstin from the invoices issued
+// under it; invoices raised under an earlier GSTIN belong to that one's return
+func getGSTR1(userID int, gstin string, start, end time.Time) (*models.GSTR1, error) {
+	rows, err := database.DB.Query(`
+		SELECT i.id, i.invoice_number, i.invoice_date, i.total, i.supplier_gstin, i.customer_gstin, i.place_of_supply,
+			   l.item, l.hsn_code, l.quantity, l.tax_rate, l.amount, l.cgst_amount, l.sgst_amount, l.igst_amount
+		FROM invoices i
+		JOIN invoice_lines l ON l.invoice_id = i.id
+		WHERE i.user_id = ? AND i.supplier_gstin = ? AND i.invoice_date BETWEEN ? AND ?
+		ORDER BY i.invoice_date, i.id, l.position`,
+		userID, gstin, start.Format("2006-01-02"), end.Format("2006-01-02"))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var invoices []*gstr1Invoice
+	byID := map[int]*gstr1Invoice{}
+	hsnRows := map[string]*models.GSTR1HSNRow{}
+	var hsnKeys []string
+
+	for rows.Next() {
+		var invoiceID int
+		var number, dateStr, supplierGSTIN, item string
+		var total, quantity float64
+		var customerGSTIN, placeOfSupply, hsnCode sql.NullString
+		var detail models.GSTR1ItemDetail
+
+		err := rows.Scan(&invoiceID, &number, &dateStr, &total, &supplierGSTIN, &customerGSTIN, &placeOfSupply,
+			&item, &hsnCode, &quantity, &detail.Rate, &detail.TaxableValue, &detail.CGST, &detail.SGST, &detail.IGST)
+		if err != nil {
+			return nil, err
+		}
+
+		invoice, ok := byID[invoiceID]
+		if !ok {
+			invoice = &gstr1Invoice{
+				number:        number,
+				value:         total,
+				customerGSTIN: customerGSTIN.String,
+				placeOfSupply: placeOfSupply.String,
+				interState:    placeOfSupply.String != supplierGSTIN[:2],
+				rates:         map[float64]*models.GSTR1ItemDetail{},
+			}
+			invoice.date, _ = time.Parse("2006-01-02", dateStr)
+			byID[invoiceID] = invoice
+			invoices = append(invoices, invoice)
+		}
+
+		sum, ok := invoice.rates[detail.Rate]
+		if !ok {
+			sum = &models.GSTR1ItemDetail{Rate: detail.Rate}
+			invoice.rates[detail.Rate] = sum
+		}
+		addItemDetail(sum, detail)
+
+		key := hsnCode.String + "|" + strconv.FormatFloat(detail.Rate, 'f', 2, 64)
+		hsnRow, ok := hsnRows[key]
+		if !ok {
+			hsnRow = &models.GSTR1HSNRow{HSN: hsnCode.String, Description: item, UQC: "NOS", Rate: detail.Rate}
+			hsnRows[key] = hsnRow
+			hsnKeys = append(hsnKeys, key)
+		}
+		hsnRow.Quantity += quantity
+		hsnRow.TaxableValue = roundMoney(hsnRow.TaxableValue + detail.TaxableValue)
+		hsnRow.IGST = roundMoney(hsnRow.IGST + detail.IGST)
+		hsnRow.CGST = roundMoney(hsnRow.CGST + detail.CGST)
+		hsnRow.SGST = roundMoney(hsnRow.SGST + detail.SGST)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	report := &models.GSTR1{
+		GSTIN:  gstin,
+		Period: start.Format("012006"),
+		B2B:    []models.GSTR1B2B{},
+		B2CL:   []models.GSTR1B2CL{},
+		B2CS:   []models.GSTR1B2CS{},
+		HSN:    models.GSTR1HSN{Data: []models.GSTR1HSNRow{}},
+	}
+	b2bIndex := map[string]int{}
+	b2clIndex := map[string]int{}
+	b2csIndex := map[string]int{}
+
+	for _, invoice := range invoices {
+		switch {
+		case invoice.customerGSTIN != "":
+			entry := invoice.toGSTR1()
+			entry.POS = invoice.placeOfSupply
+			entry.ReverseCharge = "N"
+			entry.Type = "R"
+			i, ok := b2bIndex[invoice.customerGSTIN]
+			if !ok {
+				i = len(report.B2B)
+				b2bIndex[invoice.customerGSTIN] = i
+				report.B2B = append(report.B2B, models.GSTR1B2B{CTIN: invoice.customerGSTIN})
+			}
+			report.B2B[i].Invoices = append(report.B2B[i].Invoices, entry)
+
+		case invoice.interState && invoice.value > b2clThreshold:
+			i, ok := b2clIndex[invoice.placeOfSupply]
+			if !ok {
+				i = len(report.B2CL)
+				b2clIndex[invoice.placeOfSupply] = i
+				report.B2CL = append(report.B2CL, models.GSTR1B2CL{POS: invoice.placeOfSupply})
+			}
+			report.B2CL[i].Invoices = append(report.B2CL[i].Invoices, invoice.toGSTR1())
+
+		default:
+			supplyType := "INTRA"
+			if invoice.interState {
+				supplyType = "INTER"
+			}
+			for _, rate := range invoice.sortedRates() {
+				detail := invoice.rates[rate]
+				key := supplyType + "|" + invoice.placeOfSupply + "|" + strconv.FormatFloat(rate, 'f', 2, 64)
+				i, ok := b2csIndex[key]
+				if !ok {
+					i = len(report.B2CS)
+					b2csIndex[key] = i
+					report.B2CS = append(report.B2CS, models.GSTR1B2CS{SupplyType: supplyType, POS: invoice.placeOfSupply, Type: "OE", Rate: rate})
+				}
+				row := &report.B2CS[i]
+				row.TaxableValue = roundMoney(row.TaxableValue + detail.TaxableValue)
+				row.IGST = roundMoney(row.IGST + detail.IGST)
+				row.CGST = roundMoney(row.CGST + detail.CGST)
+				row.SGST = roundMoney(row.SGST + detail.SGST)
+			}
+		}
+	}
+
+	for i, key := range hsnKeys {
+		row := hsnRows[key]
+		row.Num = i + 1
+		row.Quantity = roundMoney(row.Quantity)
+		report.HSN.Data = append(report.HSN.Data, *row)
+	}
+
+	return report, nil
+}
+
+// toGSTR1 lays an invoice out with one item per tax rate
+func (invoice *gstr1Invoice) toGSTR1() models.GSTR1Invoice {
+	entry := models.GSTR1Invoice{
+		Number: invoice.number,
+		Date:   invoice.date.Format("02-01-2006"),
+		Value:  invoice.value,
+	}
+	for i, rate := range invoice.sortedRates() {
+		entry.Items = append(entry.Items, models.GSTR1Item{Num: i + 1, Detail: *invoice.rates[rate]})
+	}
+	return entry
+}
+
+func (invoice *gstr1Invoice) sortedRates() []float64 {
+	rates := make([]float64, 0, len(invoice.rates))
+	for rate := range invoice.rates {
+		rates = append(rates, rate)
+	}
+	sort.Float64s(rates)
+	return rates
+}
+
+func addItemDetail(sum *models.GSTR1ItemDetail, detail models.GSTR1ItemDetail) {
+	sum.TaxableValue = roundMoney(sum.TaxableValue + detail.TaxableValue)
+	sum.IGST = roundMoney(sum.IGST + detail.IGST)
+	sum.CGST = roundMoney(sum.CGST + detail.CGST)
+	sum.SGST = roundMoney(sum.SGST + detail.SGST)
+}
+
+// applyTaxRates fills in the rate, and the HSN code when a line has none,
+// for invoice lines that refer to a configured tax rate. It returns
+// sql.ErrNoRows if a referenced rate is missing or inactive.
+func applyTaxRates(userID int, lines []models.InvoiceLineRequest) error {
+	for i := range lines {
+		line := &lines[i]
+		if line.TaxRateID == nil {
+			continue
+		}
+
+		var rate float64
+		var hsnCode sql.NullString
+		err := database.DB.QueryRow(`
+			SELECT rate, hsn_code FROM tax_rates
+			WHERE id = ? AND user_id = ? AND is_active = TRUE`, *line.TaxRateID, userID).Scan(&rate, &hsnCode)
+		if err != nil {
+			return err
+		}
+
+		line.TaxRate = rate
+		if (line.HSNCode == nil || strings.TrimSpace(*line.HSNCode) == "") && hsnCode.Valid {
+			line.HSNCode = &hsnCode.String
+		}
+	}
+	return nil
+}
+
+// validateTaxRate writes the error response and returns false when a tax
+// rate is unusable
+func validateTaxRate(w http.ResponseWriter, rate *models.TaxRate) bool {
+	if rate.Name == "" || len(rate.Name) > 50 {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"success": false, "error": "invalid_name", "message": "Name is required and must be at most 50 characters"})
+		return false
+	}
+	if rate.Rate < 0 || rate.Rate > 100 {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"success": false, "error": "invalid_rate", "message": "Rate must be between 0 and 100"})
+		return false
+	}
+	if rate.HSNCode != nil {
+		code := strings.TrimSpace(*rate.HSNCode)
+		if code == "" {
+			rate.HSNCode = nil
+		} else if !hsnCodeRegex.MatchString(code) {
+			writeJSON(w, http.StatusBadRequest, map[string]interface{}{"success": false, "error": "invalid_hsn_code", "message": "HSN code must be 4 to 8 digits"})
+			return false
+		} else {
+			rate.HSNCode = &code
+		}
+	}
+	return true
+}
+
+func scanTaxRate(row rowScanner) (*models.TaxRate, error) {
+	var rate models.TaxRate
+	var hsnCode sql.NullString
+	var createdAtStr, updatedAtStr string
+
+	err := row.Scan(&rate.ID, &rate.Name, &rate.Rate, &hsnCode, &rate.IsActive, &rate.UserID, &createdAtStr, &updatedAtStr)
+	if err != nil {
+		return nil, err
+	}
+
+	rate.HSNCode = nullStringPtr(hsnCode)
+	rate.CreatedAt, _ = time.Parse("2006-01-02 15:04:05", createdAtStr)
+	rate.UpdatedAt, _ = time.Parse("2006-01-02 15:04:05", updatedAtStr)
+
+	return &rate, nil
+}
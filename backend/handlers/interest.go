@@ -0,0 +1,626 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"khata-book-backend/database"
+	"khata-book-backend/models"
+	"khata-book-backend/pkg/logger"
+
+	"github.com/gorilla/mux"
+)
+
+// GetInterestSettings returns a customer's interest settings and recent
+// accruals. settings is null when interest is not configured.
+func GetInterestSettings(w http.ResponseWriter, r *http.Request) {
+	// Get user ID from JWT token
+	userID, err := getUserIDFromToken(r)
+	if err != nil {
+		writeJSON(w, http.StatusUnauthorized, map[string]interface{}{"success": false, "error": "unauthorized", "message": "Invalid token"})
+		return
+	}
+
+	customerID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"success": false, "error": "invalid_id", "message": "Invalid customer ID"})
+		return
+	}
+
+	if !checkCustomerOwnership(w, customerID, userID) {
+		return
+	}
+
+	settings, err := getInterestSettings(database.DB, customerID, false)
+	if err != nil && err != sql.ErrNoRows {
+		logger.L.WithField("error", err).Error("Error querying interest settings")
+		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "server_error", "message": "Could not fetch interest settings"})
+		return
+	}
+
+	rows, err := database.DB.Query(`
+		SELECT period_start, period_end, interest, late_fee, interest_entry_id, late_fee_entry_id
+		FROM interest_accruals
+		WHERE customer_id = ?
+		ORDER BY period_end DESC
+		LIMIT 12`, customerID)
+	if err != nil {
+		logger.L.WithField("error", err).Error("Error querying interest accruals")
+		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "server_error", "message": "Could not fetch interest settings"})
+		return
+	}
+	defer rows.Close()
+
+	accruals := []models.InterestAccrual{}
+	for rows.Next() {
+		var accrual models.InterestAccrual
+		var periodStartStr, periodEndStr string
+		var interestEntryID, lateFeeEntryID sql.NullInt64
+		err := rows.Scan(&periodStartStr, &periodEndStr, &accrual.Interest, &accrual.LateFee, &interestEntryID, &lateFeeEntryID)
+		if err != nil {
+			logger.L.WithField("error", err).Error("Error scanning interest accrual")
+			continue
+		}
+		accrual.PeriodStart, _ = time.Parse("2006-01-02", periodStartStr)
+		accrual.PeriodEnd, _ = time.Parse("2006-01-02", periodEndStr)
+		accrual.InterestEntryID = nullIntPtr(interestEntryID)
+		accrual.LateFeeEntryID = nullIntPtr(lateFeeEntryID)
+		accruals = append(accruals, accrual)
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"success":  true,
+		"settings": settings,
+		"accruals": accruals,
+	})
+}
+
+// UpdateInterestSettings creates or replaces a customer's interest settings.
+// Interest starts accruing from today; turning settings back on after a pause
+// does not charge for the paused days.
+func UpdateInterestSettings(w http.ResponseWriter, r *http.Request) {
+	// Get user ID from JWT token
+	userID, err := getUserIDFromToken(r)
+	if err != nil {
+		writeJSON(w, http.StatusUnauthorized, map[string]interface{}{"success": false, "error": "unauthorized", "message": "Invalid token"})
+		return
+	}
+
+	customerID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"success": false, "error": "invalid_id", "message": "Invalid customer ID"})
+		return
+	}
+
+	var settingsReq models.InterestSettingsRequest
+	err = json.NewDecoder(r.Body).Decode(&settingsReq)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"success": false, "error": "invalid_request", "message": "Invalid request body"})
+		return
+	}
+
+	// Validate fields
+	if settingsReq.Mode == "" {
+		settingsReq.Mode = "simple"
+	}
+	if settingsReq.Mode != "simple" && settingsReq.Mode != "compound" {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"success": false, "error": "invalid_mode", "message": "Mode must be 'simple' or 'compound'"})
+		return
+	}
+
+	if settingsReq.MonthlyRate <= 0 || settingsReq.MonthlyRate > 100 {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"success": false, "error": "invalid_rate", "message": "Monthly rate must be between 0 and 100 percent"})
+		return
+	}
+
+	if settingsReq.GraceDays < 0 || settingsReq.GraceDays > 365 {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"success": false, "error": "invalid_grace_days", "message": "Grace days must be between 0 and 365"})
+		return
+	}
+
+	if settingsReq.LateFee < 0 {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"success": false, "error": "invalid_late_fee", "message": "Late fee cannot be negative"})
+		return
+	}
+
+	partyType, ok := lookupPartyType(w, customerID, userID)
+	if !ok {
+		return
+	}
+	// A supplier's positive balance is an advance paid, not credit extended
+	if partyType == partySupplier {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"success": false, "error": "invalid_party", "message": "Interest can only be charged to customers"})
+		return
+	}
+
+	tx, err := database.DB.Begin()
+	if err != nil {
+		logger.L.WithField("error", err).Error("Could not start transaction")
+		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "server_error", "message": "Could not process request"})
+		return
+	}
+	defer tx.Rollback()
+
+	yesterday := calendarDate(time.Now()).AddDate(0, 0, -1)
+
+	settings, err := getInterestSettings(tx, customerID, true)
+	if err == sql.ErrNoRows {
+		settings = &models.InterestSettings{CustomerID: customerID, AccruedThrough: yesterday, UserID: userID}
+	} else if err != nil {
+		logger.L.WithField("error", err).Error("Error querying interest settings")
+		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "server_error", "message": "Could not update interest settings"})
+		return
+	} else if !settings.Active && (settingsReq.Active == nil || *settingsReq.Active) {
+		settings.AccruedThrough = yesterday
+	}
+
+	settings.Mode = settingsReq.Mode
+	settings.MonthlyRate = settingsReq.MonthlyRate
+	settings.GraceDays = settingsReq.GraceDays
+	settings.LateFee = settingsReq.LateFee
+	settings.Active = settingsReq.Active == nil || *settingsReq.Active
+
+	_, err = tx.Exec(`
+		INSERT INTO interest_settings (customer_id, mode, monthly_rate, grace_days, late_fee, is_active, accrued_through, user_id)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		ON DUPLICATE KEY UPDATE mode = VALUES(mode), monthly_rate = VALUES(monthly_rate), grace_days = VALUES(grace_days),
+			late_fee = VALUES(late_fee), is_active = VALUES(is_active), accrued_through = VALUES(accrued_through)`,
+		customerID, settings.Mode, settings.MonthlyRate, settings.GraceDays, settings.LateFee, settings.Active,
+		settings.AccruedThrough.Format("2006-01-02"), userID)
+	if err != nil {
+		logger.L.WithField("error", err).Error("Error saving interest settings")
+		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "server_error", "message": "Could not update interest settings"})
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		logger.L.WithField("error", err).Error("Error committing transaction")
+		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "server_error", "message": "Could not update interest settings"})
+		return
+	}
+
+	logger.L.WithFields(map[string]interface{}{
+		"customer_id":  customerID,
+		"user_id":      userID,
+		"mode":         settings.Mode,
+		"monthly_rate": settings.MonthlyRate,
+	}).Info("Interest settings updated successfully")
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"success":  true,
+		"message":  "Interest settings updated successfully",
+		"settings": settings,
+	})
+}
+
+// DeleteInterestSettings stops interest for a customer. Accruals already
+// posted stay in the ledger.
+func DeleteInterestSettings(w http.ResponseWriter, r *http.Request) {
+	// Get user ID from JWT token
+	userID, err := getUserIDFromToken(r)
+	if err != nil {
+		writeJSON(w, http.StatusUnauthorized, map[string]interface{}{"success": false, "error": "unauthorized", "message": "Invalid token"})
+		return
+	}
+
+	customerID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"success": false, "error": "invalid_id", "message": "Invalid customer ID"})
+		return
+	}
+
+	result, err := database.DB.Exec("DELETE FROM interest_settings WHERE customer_id = ? AND user_id = ?", customerID, userID)
+	if err != nil {
+		logger.L.WithField("error", err).Error("Error deleting interest settings")
+		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "server_error", "message": "Could not delete interest settings"})
+		return
+	}
+
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		writeJSON(w, http.StatusNotFound, map[string]interface{}{"success": false, "error": "not_found", "message": "Interest settings not found"})
+		return
+	}
+
+	logger.L.WithFields(map[string]interface{}{
+		"customer_id": customerID,
+		"user_id":     userID,
+	}).Info("Interest settings deleted successfully")
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"message": "Interest settings deleted successfully",
+	})
+}
+
+// PreviewInterest shows what would accrue for a customer between the last
+// accrual and ?date= (default today) without posting anything
+func PreviewInterest(w http.ResponseWriter, r *http.Request) {
+	// Get user ID from JWT token
+	userID, err := getUserIDFromToken(r)
+	if err != nil {
+		writeJSON(w, http.StatusUnauthorized, map[string]interface{}{"success": false, "error": "unauthorized", "message": "Invalid token"})
+		return
+	}
+
+	customerID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"success": false, "error": "invalid_id", "message": "Invalid customer ID"})
+		return
+	}
+
+	asOf := calendarDate(time.Now())
+	if dateStr := r.URL.Query().Get("date"); dateStr != "" {
+		asOf, err = time.Parse("2006-01-02", dateStr)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]interface{}{"success": false, "error": "invalid_date", "message": "Date must be YYYY-MM-DD"})
+			return
+		}
+	}
+
+	if !checkCustomerOwnership(w, customerID, userID) {
+		return
+	}
+
+	settings, err := getInterestSettings(database.DB, customerID, false)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			writeJSON(w, http.StatusNotFound, map[string]interface{}{"success": false, "error": "not_found", "message": "Interest is not configured for this customer"})
+			return
+		}
+		logger.L.WithField("error", err).Error("Error querying interest settings")
+		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "server_error", "message": "Could not preview interest"})
+		return
+	}
+
+	startingBalance, events, err := loadBalanceHistory(database.DB, customerID, asOf)
+	if err != nil {
+		logger.L.WithField("error", err).Error("Error loading balance history")
+		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "server_error", "message": "Could not preview interest"})
+		return
+	}
+
+	periods := computeAccruals(settings, startingBalance, events, asOf)
+
+	var totalInterest, totalLateFee float64
+	for _, period := range periods {
+		totalInterest += period.Interest
+		totalLateFee += period.LateFee
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"success":         true,
+		"date":            asOf.Format("2006-01-02"),
+		"accrued_through": settings.AccruedThrough.Format("2006-01-02"),
+		"periods":         periods,
+		"total_interest":  roundMoney(totalInterest),
+		"total_late_fee":  roundMoney(totalLateFee),
+	})
+}
+
+// runInterestAccruals posts interest and late fees for every complete month
+// that active settings have not been charged for yet
+func runInterestAccruals(now time.Time) error {
+	today := calendarDate(now)
+	through := today.AddDate(0, 0, -today.Day())
+
+	rows, err := database.DB.Query(`
+		SELECT customer_id FROM interest_settings
+		WHERE is_active = TRUE AND accrued_through < ?`, through.Format("2006-01-02"))
+	if err != nil {
+		return err
+	}
+
+	var customerIDs []int
+	for rows.Next() {
+		var customerID int
+		if err := rows.Scan(&customerID); err != nil {
+			rows.Close()
+			return err
+		}
+		customerIDs = append(customerIDs, customerID)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, customerID := range customerIDs {
+		if err := accrueInterest(customerID, through, today); err != nil {
+			logger.L.WithFields(map[string]interface{}{"customer_id": customerID, "error": err}).Error("Error accruing interest")
+		}
+	}
+
+	return nil
+}
+
+// accrueInterest posts the accruals for one customer up to through in a
+// single transaction. The settings row is locked while posting and each
+// period is recorded in interest_accruals, so a period is never charged twice.
+// A period ending on a closed day is charged today instead; when today is
+// closed too, accrual stops there and resumes on a later run.
+func accrueInterest(customerID int, through, today time.Time) error {
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	settings, err := getInterestSettings(tx, customerID, true)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil
+		}
+		return err
+	}
+	if !settings.Active || !settings.AccruedThrough.Before(through) {
+		return nil
+	}
+
+	startingBalance, events, err := loadBalanceHistory(tx, customerID, through)
+	if err != nil {
+		return err
+	}
+
+	accruedThrough := through
+	for _, period := range computeAccruals(settings, startingBalance, events, through) {
+		entryDate := period.PeriodEnd
+		locked, err := dayLockedTx(tx, settings.UserID, entryDate)
+		if err != nil {
+			return err
+		}
+		if locked {
+			entryDate = today
+			if locked, err = dayLockedTx(tx, settings.UserID, today); err != nil {
+				return err
+			} else if locked {
+				accruedThrough = period.PeriodStart.AddDate(0, 0, -1)
+				break
+			}
+		}
+
+		result, err := tx.Exec(`
+			INSERT IGNORE INTO interest_accruals (customer_id, period_start, period_end, interest, late_fee)
+			VALUES (?, ?, ?, ?, ?)`,
+			customerID, period.PeriodStart.Format("2006-01-02"), period.PeriodEnd.Format("2006-01-02"),
+			period.Interest, period.LateFee)
+		if err != nil {
+			return err
+		}
+		if affected, _ := result.RowsAffected(); affected == 0 {
+			continue
+		}
+
+		label := period.PeriodEnd.Format("Jan 2006")
+		var interestEntryID, lateFeeEntryID *int
+
+		if period.Interest > 0 {
+			note := fmt.Sprintf("Interest for %s at %g%% per month", label, settings.MonthlyRate)
+			entry := models.LedgerEntry{
+				CustomerID: customerID,
+				Type:       "interest",
+				Amount:     period.Interest,
+				Method:     adjustmentMethod,
+				Note:       &note,
+				Date:       entryDate,
+				UserID:     settings.UserID,
+			}
+			if err := postLedgerEntry(tx, &entry); err != nil {
+				return err
+			}
+			interestEntryID = &entry.ID
+		}
+
+		if period.LateFee > 0 {
+			note := "Late fee for " + label
+			entry := models.LedgerEntry{
+				CustomerID: customerID,
+				Type:       "late_fee",
+				Amount:     period.LateFee,
+				Method:     adjustmentMethod,
+				Note:       &note,
+				Date:       entryDate,
+				UserID:     settings.UserID,
+			}
+			if err := postLedgerEntry(tx, &entry); err != nil {
+				return err
+			}
+			lateFeeEntryID = &entry.ID
+		}
+
+		_, err = tx.Exec(`
+			UPDATE interest_accruals SET interest_entry_id = ?, late_fee_entry_id = ?
+			WHERE customer_id = ? AND period_end = ?`,
+			interestEntryID, lateFeeEntryID, customerID, period.PeriodEnd.Format("2006-01-02"))
+		if err != nil {
+			return err
+		}
+
+		logger.L.WithFields(map[string]interface{}{
+			"customer_id": customerID,
+			"period_end":  period.PeriodEnd.Format("2006-01-02"),
+			"interest":    period.Interest,
+			"late_fee":    period.LateFee,
+		}).Info("Interest accrued")
+	}
+
+	if !accruedThrough.After(settings.AccruedThrough) {
+		return nil
+	}
+	_, err = tx.Exec("UPDATE interest_settings SET accrued_through = ? WHERE customer_id = ?",
+		accruedThrough.Format("2006-01-02"), customerID)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// balanceEvent is one ledger entry as seen by the accrual calculation
+type balanceEvent struct {
+	Date   time.Time
+	Type   string
+	Amount float64
+}
+
+type queryer interface {
+	queryRower
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+}
+
+// loadBalanceHistory returns the part of a customer's balance the ledger does
+// not explain (an amount entered when the customer was created) and the
+// customer's entries up to through in date order
+func loadBalanceHistory(q queryer, customerID int, through time.Time) (float64, []balanceEvent, error) {
+	var startingBalance float64
+	err := q.QueryRow(`
+		SELECT c.balance - COALESCE(SUM(CASE WHEN le.type IN ('debit', 'write_off', 'discount') THEN -le.amount ELSE le.amount END), 0)
+		FROM customers c
+		LEFT JOIN ledger_entries le ON le.customer_id = c.id
+		WHERE c.id = ?
+		GROUP BY c.id, c.balance`, customerID).Scan(&startingBalance)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	rows, err := q.Query(`
+		SELECT date, type, amount FROM ledger_entries
+		WHERE customer_id = ? AND date <= ?
+		ORDER BY date, id`, customerID, through.Format("2006-01-02"))
+	if err != nil {
+		return 0, nil, err
+	}
+	defer rows.Close()
+
+	var events []balanceEvent
+	for rows.Next() {
+		var event balanceEvent
+		var dateStr string
+		if err := rows.Scan(&dateStr, &event.Type, &event.Amount); err != nil {
+			return 0, nil, err
+		}
+		event.Date, _ = time.Parse("2006-01-02", dateStr)
+		events = append(events, event)
+	}
+
+	return startingBalance, events, rows.Err()
+}
+
+// computeAccruals works out interest and late fees for each calendar month
+// from the day after settings.AccruedThrough up to through; the last period
+// may be a partial month.
+//
+// Interest accrues daily at the monthly rate × 12 / 365 on the overdue
+// balance, which leaves out credits given within the last GraceDays days.
+// Simple interest charges only on principal, treating payments as settling
+// earlier interest and fees first; compound interest charges on the whole
+// balance including unpaid interest. The late fee is charged once per period
+// that ends with an overdue balance.
+func computeAccruals(settings *models.InterestSettings, startingBalance float64, events []balanceEvent, through time.Time) []models.InterestAccrual {
+	dailyRate := settings.MonthlyRate / 100 * 12 / 365
+
+	var credits []balanceEvent
+	for _, event := range events {
+		if event.Type == "credit" {
+			credits = append(credits, event)
+		}
+	}
+
+	var creditTotal, paymentTotal, chargeTotal, graceCredits float64
+	next, nextGrace := 0, 0
+
+	periods := []models.InterestAccrual{}
+	day := settings.AccruedThrough.AddDate(0, 0, 1)
+	for !day.After(through) {
+		periodEnd := day.AddDate(0, 1, -day.Day())
+		if periodEnd.After(through) {
+			periodEnd = through
+		}
+
+		period := models.InterestAccrual{PeriodStart: day, PeriodEnd: periodEnd}
+		var interest float64
+
+		for ; !day.After(periodEnd); day = day.AddDate(0, 0, 1) {
+			for next < len(events) && !events[next].Date.After(day) {
+				switch events[next].Type {
+				case "credit":
+					creditTotal += events[next].Amount
+				case "debit", "write_off", "discount":
+					paymentTotal += events[next].Amount
+				default:
+					chargeTotal += events[next].Amount
+				}
+				next++
+			}
+
+			// Credits dated on or before this cutoff are past their grace
+			cutoff := day.AddDate(0, 0, -settings.GraceDays)
+			for nextGrace < len(credits) && !credits[nextGrace].Date.After(cutoff) {
+				graceCredits += credits[nextGrace].Amount
+				nextGrace++
+			}
+			inGrace := creditTotal - graceCredits
+
+			var overdue float64
+			if settings.Mode == "compound" {
+				overdue = startingBalance + creditTotal + chargeTotal - paymentTotal - inGrace
+			} else {
+				overdue = startingBalance + creditTotal - math.Max(0, paymentTotal-chargeTotal) - inGrace
+			}
+			overdue = math.Max(0, overdue)
+
+			interest += overdue * dailyRate
+			period.OverdueBalance = roundMoney(overdue)
+		}
+
+		period.Interest = roundMoney(interest)
+		if period.OverdueBalance > 0 {
+			period.LateFee = settings.LateFee
+		}
+
+		// Charges from earlier periods count towards later ones exactly as
+		// the posted entries would
+		chargeTotal += period.Interest + period.LateFee
+
+		periods = append(periods, period)
+	}
+
+	return periods
+}
+
+// getInterestSettings loads a customer's settings, locking the row when
+// forUpdate is set and q is a transaction
+func getInterestSettings(q queryRower, customerID int, forUpdate bool) (*models.InterestSettings, error) {
+	query := `
+		SELECT customer_id, mode, monthly_rate, grace_days, late_fee, is_active, accrued_through, user_id, created_at, updated_at
+		FROM interest_settings
+		WHERE customer_id = ?`
+	if forUpdate {
+		query += " FOR UPDATE"
+	}
+
+	var settings models.InterestSettings
+	var accruedThroughStr, createdAtStr, updatedAtStr string
+	err := q.QueryRow(query, customerID).Scan(
+		&settings.CustomerID, &settings.Mode, &settings.MonthlyRate, &settings.GraceDays, &settings.LateFee,
+		&settings.Active, &accruedThroughStr, &settings.UserID, &createdAtStr, &updatedAtStr,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	settings.AccruedThrough, _ = time.Parse("2006-01-02", accruedThroughStr)
+	settings.CreatedAt, _ = time.Parse("2006-01-02 15:04:05", createdAtStr)
+	settings.UpdatedAt, _ = time.Parse("2006-01-02 15:04:05", updatedAtStr)
+
+	return &settings, nil
+}
+
+// roundMoney rounds an amount to paise
+func roundMoney(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
@@ -0,0 +1,149 @@
+package handlers
+
+import (
+	"testing"
+	"time"
+
+	"khata-book-backend/models"
+)
+
+func testDate(s string) time.Time {
+	t, err := time.Parse("2006-01-02", s)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+// accrual is the part of a period the tests compare
+type accrual struct {
+	start, end string
+	overdue    float64
+	interest   float64
+	lateFee    float64
+}
+
+func TestComputeAccruals(t *testing.T) {
+	// 3% a month is 0.36/365 a day: ₹10,000 earns ₹9.863 a day
+	tests := []struct {
+		name     string
+		mode     string
+		rate     float64
+		grace    int
+		lateFee  float64
+		accrued  string
+		through  string
+		starting float64
+		events   []balanceEvent
+		want     []accrual
+	}{
+		{
+			name: "full month on the opening balance", mode: "simple", rate: 3,
+			accrued: "2026-01-31", through: "2026-02-28", starting: 10000,
+			want: []accrual{{"2026-02-01", "2026-02-28", 10000, 276.16, 0}},
+		},
+		{
+			name: "partial first period", mode: "simple", rate: 3,
+			accrued: "2026-01-15", through: "2026-02-28", starting: 10000,
+			want: []accrual{
+				{"2026-01-16", "2026-01-31", 10000, 157.81, 0},
+				{"2026-02-01", "2026-02-28", 10000, 276.16, 0},
+			},
+		},
+		{
+			name: "partial last period", mode: "simple", rate: 3,
+			accrued: "2026-01-31", through: "2026-02-10", starting: 10000,
+			want: []accrual{{"2026-02-01", "2026-02-10", 10000, 98.63, 0}},
+		},
+		{
+			name: "nothing to accrue", mode: "simple", rate: 3,
+			accrued: "2026-02-28", through: "2026-02-28", starting: 10000,
+			want: []accrual{},
+		},
+		{
+			name: "credit is interest free within its grace days", mode: "simple", rate: 3, grace: 10,
+			accrued: "2026-01-31", through: "2026-02-28",
+			events: []balanceEvent{{testDate("2026-02-01"), "credit", 10000}},
+			want:   []accrual{{"2026-02-01", "2026-02-28", 10000, 177.53, 0}},
+		},
+		{
+			name: "credit still in grace at the period end is not overdue", mode: "simple", rate: 3, grace: 15, lateFee: 50,
+			accrued: "2026-01-31", through: "2026-02-28",
+			events: []balanceEvent{{testDate("2026-02-20"), "credit", 10000}},
+			want:   []accrual{{"2026-02-01", "2026-02-28", 0, 0, 0}},
+		},
+		{
+			name: "payment counts from its own date", mode: "simple", rate: 3,
+			accrued: "2026-01-31", through: "2026-02-28", starting: 10000,
+			events: []balanceEvent{{testDate("2026-02-15"), "debit", 4000}},
+			want:   []accrual{{"2026-02-01", "2026-02-28", 6000, 220.93, 0}},
+		},
+		{
+			name: "simple interest is not charged on interest", mode: "simple", rate: 3,
+			accrued: "2025-12-31", through: "2026-02-28", starting: 10000,
+			want: []accrual{
+				{"2026-01-01", "2026-01-31", 10000, 305.75, 0},
+				{"2026-02-01", "2026-02-28", 10000, 276.16, 0},
+			},
+		},
+		{
+			name: "compound interest is charged on unpaid interest", mode: "compound", rate: 3,
+			accrued: "2025-12-31", through: "2026-02-28", starting: 10000,
+			want: []accrual{
+				{"2026-01-01", "2026-01-31", 10000, 305.75, 0},
+				{"2026-02-01", "2026-02-28", 10305.75, 284.61, 0},
+			},
+		},
+		{
+			name: "late fee for each period ending overdue", mode: "simple", lateFee: 50,
+			accrued: "2025-12-31", through: "2026-03-31", starting: 1000,
+			events: []balanceEvent{{testDate("2026-03-10"), "debit", 1100}},
+			want: []accrual{
+				{"2026-01-01", "2026-01-31", 1000, 0, 50},
+				{"2026-02-01", "2026-02-28", 1000, 0, 50},
+				{"2026-03-01", "2026-03-31", 0, 0, 0},
+			},
+		},
+		{
+			name: "simple payments settle fees before principal", mode: "simple", lateFee: 50,
+			accrued: "2025-12-31", through: "2026-02-28", starting: 1000,
+			events: []balanceEvent{{testDate("2026-02-10"), "debit", 1000}},
+			want: []accrual{
+				{"2026-01-01", "2026-01-31", 1000, 0, 50},
+				{"2026-02-01", "2026-02-28", 50, 0, 50},
+			},
+		},
+		{
+			name: "write-off reduces the overdue balance", mode: "compound", rate: 3,
+			accrued: "2026-01-31", through: "2026-02-28", starting: 10000,
+			events: []balanceEvent{{testDate("2026-02-01"), "write_off", 10000}},
+			want:   []accrual{{"2026-02-01", "2026-02-28", 0, 0, 0}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			settings := &models.InterestSettings{
+				Mode:           tt.mode,
+				MonthlyRate:    tt.rate,
+				GraceDays:      tt.grace,
+				LateFee:        tt.lateFee,
+				AccruedThrough: testDate(tt.accrued),
+			}
+			periods := computeAccruals(settings, tt.starting, tt.events, testDate(tt.through))
+
+			got := []accrual{}
+			for _, p := range periods {
+				got = append(got, accrual{p.PeriodStart.Format("2006-01-02"), p.PeriodEnd.Format("2006-01-02"), p.OverdueBalance, p.Interest, p.LateFee})
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("periods = %+v, want %+v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("period %d = %+v, want %+v", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,1179 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"khata-book-backend/database"
+	"khata-book-backend/models"
+	"khata-book-backend/pkg/gst"
+	"khata-book-backend/pkg/logger"
+
+	"github.com/gorilla/mux"
+)
+
+// defaultInvoiceTermDays is used when an invoice is created without a due date
+const defaultInvoiceTermDays = 30
+
+var (
+	invoicePrefixRegex   = regexp.MustCompile(`^[A-Za-z0-9/_-]{0,20}$`)
+	hsnCodeRegex         = regexp.MustCompile(`^[0-9]{4,8}$`)
+	errInvalidAllocation = errors.New("invalid allocation")
+)
+
+// GetInvoiceSeries lists the user's invoice numbering series
+func GetInvoiceSeries(w http.ResponseWriter, r *http.Request) {
+	// Get user ID from JWT token
+	userID, err := getUserIDFromToken(r)
+	if err != nil {
+		writeJSON(w, http.StatusUnauthorized, map[string]interface{}{"success": false, "error": "unauthorized", "message": "Invalid token"})
+		return
+	}
+
+	rows, err := database.DB.Query(`
+		SELECT id, name, prefix, next_number, padding, is_default, user_id, created_at, updated_at
+		FROM invoice_series
+		WHERE user_id = ?
+		ORDER BY is_default DESC, name ASC`, userID)
+	if err != nil {
+		logger.L.WithField("error", err).Error("Error querying invoice series")
+		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "server_error", "message": "Could not fetch invoice series"})
+		return
+	}
+	defer rows.Close()
+
+	series := []models.InvoiceSeries{}
+	for rows.Next() {
+		s, err := scanInvoiceSeries(rows)
+		if err != nil {
+			logger.L.WithField("error", err).Error("Error scanning invoice series")
+			continue
+		}
+		series = append(series, *s)
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"series":  series,
+	})
+}
+
+// CreateInvoiceSeries adds a numbering series. The first series a user
+// creates becomes the default.
+func CreateInvoiceSeries(w http.ResponseWriter, r *http.Request) {
+	// Get user ID from JWT token
+	userID, err := getUserIDFromToken(r)
+	if err != nil {
+		writeJSON(w, http.StatusUnauthorized, map[string]interface{}{"success": false, "error": "unauthorized", "message": "Invalid token"})
+		return
+	}
+
+	var seriesReq models.InvoiceSeriesRequest
+	err = json.NewDecoder(r.Body).Decode(&seriesReq)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"success": false, "error": "invalid_request", "message": "Invalid request body"})
+		return
+	}
+
+	series := models.InvoiceSeries{
+		Name:       strings.TrimSpace(seriesReq.Name),
+		Prefix:     strings.TrimSpace(seriesReq.Prefix),
+		NextNumber: 1,
+		Padding:    4,
+		UserID:     userID,
+	}
+	if seriesReq.NextNumber != nil {
+		series.NextNumber = *seriesReq.NextNumber
+	}
+	if seriesReq.Padding != nil {
+		series.Padding = *seriesReq.Padding
+	}
+
+	if !validateInvoiceSeries(w, &series) {
+		return
+	}
+
+	tx, err := database.DB.Begin()
+	if err != nil {
+		logger.L.WithField("error", err).Error("Could not start transaction")
+		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "server_error", "message": "Could not process request"})
+		return
+	}
+	defer tx.Rollback()
+
+	var existing int
+	err = tx.QueryRow("SELECT COUNT(*) FROM invoice_series WHERE user_id = ?", userID).Scan(&existing)
+	if err != nil {
+		logger.L.WithField("error", err).Error("Error counting invoice series")
+		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "server_error", "message": "Could not create invoice series"})
+		return
+	}
+	series.IsDefault = existing == 0 || (seriesReq.IsDefault != nil && *seriesReq.IsDefault)
+
+	if series.IsDefault {
+		if _, err := tx.Exec("UPDATE invoice_series SET is_default = FALSE WHERE user_id = ?", userID); err != nil {
+			logger.L.WithField("error", err).Error("Error clearing default invoice series")
+			writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "server_error", "message": "Could not create invoice series"})
+			return
+		}
+	}
+
+	result, err := tx.Exec(`
+		INSERT INTO invoice_series (name, prefix, next_number, padding, is_default, user_id)
+		VALUES (?, ?, ?, ?, ?, ?)`,
+		series.Name, series.Prefix, series.NextNumber, series.Padding, series.IsDefault, userID)
+	if err != nil {
+		if strings.Contains(err.Error(), "Duplicate entry") {
+			writeJSON(w, http.StatusConflict, map[string]interface{}{"success": false, "error": "series_exists", "message": "A series with this prefix already exists"})
+			return
+		}
+		logger.L.WithField("error", err).Error("Error inserting invoice series")
+		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "server_error", "message": "Could not create invoice series"})
+		return
+	}
+
+	seriesID, err := result.LastInsertId()
+	if err != nil {
+		logger.L.WithField("error", err).Error("Error getting inserted invoice series ID")
+		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "server_error", "message": "Could not create invoice series"})
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		logger.L.WithField("error", err).Error("Error committing transaction")
+		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "server_error", "message": "Could not create invoice series"})
+		return
+	}
+
+	series.ID = int(seriesID)
+	series.CreatedAt = time.Now()
+	series.UpdatedAt = series.CreatedAt
+
+	logger.L.WithFields(map[string]interface{}{
+		"series_id": seriesID,
+		"user_id":   userID,
+		"prefix":    series.Prefix,
+	}).Info("Invoice series created successfully")
+
+	writeJSON(w, http.StatusCreated, map[string]interface{}{
+		"success": true,
+		"message": "Invoice series created successfully",
+		"series":  series,
+	})
+}
+
+// UpdateInvoiceSeries renames a series, changes its format or next number, or
+// makes it the default
+func UpdateInvoiceSeries(w http.ResponseWriter, r *http.Request) {
+	// Get user ID from JWT token
+	userID, err := getUserIDFromToken(r)
+	if err != nil {
+		writeJSON(w, http.StatusUnauthorized, map[string]interface{}{"success": false, "error": "unauthorized", "message": "Invalid token"})
+		return
+	}
+
+	seriesID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"success": false, "error": "invalid_id", "message": "Invalid invoice series ID"})
+		return
+	}
+
+	var seriesReq models.InvoiceSeriesRequest
+	err = json.NewDecoder(r.Body).Decode(&seriesReq)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"success": false, "error": "invalid_request", "message": "Invalid request body"})
+		return
+	}
+
+	tx, err := database.DB.Begin()
+	if err != nil {
+		logger.L.WithField("error", err).Error("Could not start transaction")
+		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "server_error", "message": "Could not process request"})
+		return
+	}
+	defer tx.Rollback()
+
+	series, err := scanInvoiceSeries(tx.QueryRow(`
+		SELECT id, name, prefix, next_number, padding, is_default, user_id, created_at, updated_at
+		FROM invoice_series
+		WHERE id = ? AND user_id = ?
+		FOR UPDATE`, seriesID, userID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			writeJSON(w, http.StatusNotFound, map[string]interface{}{"success": false, "error": "not_found", "message": "Invoice series not found"})
+			return
+		}
+		logger.L.WithField("error", err).Error("Error querying invoice series")
+		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "server_error", "message": "Could not update invoice series"})
+		return
+	}
+
+	if seriesReq.Name != "" {
+		series.Name = strings.TrimSpace(seriesReq.Name)
+	}
+	if seriesReq.Prefix != "" {
+		series.Prefix = strings.TrimSpace(seriesReq.Prefix)
+	}
+	if seriesReq.NextNumber != nil {
+		series.NextNumber = *seriesReq.NextNumber
+	}
+	if seriesReq.Padding != nil {
+		series.Padding = *seriesReq.Padding
+	}
+
+	if !validateInvoiceSeries(w, series) {
+		return
+	}
+
+	if seriesReq.IsDefault != nil && *seriesReq.IsDefault && !series.IsDefault {
+		if _, err := tx.Exec("UPDATE invoice_series SET is_default = FALSE WHERE user_id = ?", userID); err != nil {
+			logger.L.WithField("error", err).Error("Error clearing default invoice series")
+			writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "server_error", "message": "Could not update invoice series"})
+			return
+		}
+		series.IsDefault = true
+	}
+
+	_, err = tx.Exec(`
+		UPDATE invoice_series
+		SET name = ?, prefix = ?, next_number = ?, padding = ?, is_default = ?, updated_at = CURRENT_TIMESTAMP
+		WHERE id = ?`,
+		series.Name, series.Prefix, series.NextNumber, series.Padding, series.IsDefault, seriesID)
+	if err != nil {
+		if strings.Contains(err.Error(), "Duplicate entry") {
+			writeJSON(w, http.StatusConflict, map[string]interface{}{"success": false, "error": "series_exists", "message": "A series with this prefix already exists"})
+			return
+		}
+		logger.L.WithField("error", err).Error("Error updating invoice series")
+		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "server_error", "message": "Could not update invoice series"})
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		logger.L.WithField("error", err).Error("Error committing transaction")
+		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "server_error", "message": "Could not update invoice series"})
+		return
+	}
+
+	logger.L.WithFields(map[string]interface{}{
+		"series_id": seriesID,
+		"user_id":   userID,
+	}).Info("Invoice series updated successfully")
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"message": "Invoice series updated successfully",
+		"series":  series,
+	})
+}
+
+// GetInvoices lists invoices, optionally filtered by customer and by status.
+// status=overdue selects unpaid invoices past their due date.
+func GetInvoices(w http.ResponseWriter, r *http.Request) {
+	// Get user ID from JWT token
+	userID, err := getUserIDFromToken(r)
+	if err != nil {
+		writeJSON(w, http.StatusUnauthorized, map[string]interface{}{"success": false, "error": "unauthorized", "message": "Invalid token"})
+		return
+	}
+
+	// Parse query parameters
+	customerIDStr := r.URL.Query().Get("customer_id")
+	status := r.URL.Query().Get("status")
+	limitStr := r.URL.Query().Get("limit")
+	offsetStr := r.URL.Query().Get("offset")
+
+	query := invoiceSelect + " WHERE i.user_id = ?"
+	args := []interface{}{userID}
+
+	if customerIDStr != "" {
+		if customerID, err := strconv.Atoi(customerIDStr); err == nil {
+			query += " AND i.customer_id = ?"
+			args = append(args, customerID)
+		}
+	}
+
+	today := calendarDate(time.Now()).Format("2006-01-02")
+	switch status {
+	case "open", "partial":
+		query += " AND i.status = ? AND i.due_date >= ?"
+		args = append(args, status, today)
+	case "paid":
+		query += " AND i.status = 'paid'"
+	case "overdue":
+		query += " AND i.status != 'paid' AND i.due_date < ?"
+		args = append(args, today)
+	case "unpaid":
+		query += " AND i.status != 'paid'"
+	}
+
+	limit := 50 // default limit
+	if limitStr != "" {
+		if parsedLimit, err := strconv.Atoi(limitStr); err == nil && parsedLimit > 0 && parsedLimit <= 200 {
+			limit = parsedLimit
+		}
+	}
+
+	offset := 0
+	if offsetStr != "" {
+		if parsedOffset, err := strconv.Atoi(offsetStr); err == nil && parsedOffset >= 0 {
+			offset = parsedOffset
+		}
+	}
+
+	query += " ORDER BY i.invoice_date DESC, i.id DESC LIMIT ? OFFSET ?"
+	args = append(args, limit, offset)
+
+	rows, err := database.DB.Query(query, args...)
+	if err != nil {
+		logger.L.WithField("error", err).Error("Error querying invoices")
+		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "server_error", "message": "Could not fetch invoices"})
+		return
+	}
+	defer rows.Close()
+
+	invoices := []models.Invoice{}
+	for rows.Next() {
+		invoice, err := scanInvoice(rows)
+		if err != nil {
+			logger.L.WithField("error", err).Error("Error scanning invoice")
+			continue
+		}
+		invoices = append(invoices, *invoice)
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"success":  true,
+		"invoices": invoices,
+		"count":    len(invoices),
+	})
+}
+
+// CreateInvoice issues an invoice. The invoice total is posted to the ledger
+// as a credit entry in the same transaction, so the customer balance always
+// matches the ledger.
+func CreateInvoice(w http.ResponseWriter, r *http.Request) {
+	// Get user ID from JWT token
+	userID, err := getUserIDFromToken(r)
+	if err != nil {
+		writeJSON(w, http.StatusUnauthorized, map[string]interface{}{"success": false, "error": "unauthorized", "message": "Invalid token"})
+		return
+	}
+
+	var invoiceReq models.InvoiceRequest
+	err = json.NewDecoder(r.Body).Decode(&invoiceReq)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"success": false, "error": "invalid_request", "message": "Invalid request body"})
+		return
+	}
+
+	// Validate required fields
+	if invoiceReq.CustomerID <= 0 {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"success": false, "error": "invalid_customer", "message": "Valid customer ID is required"})
+		return
+	}
+
+	if err := applyTaxRates(userID, invoiceReq.Lines); err != nil {
+		if err == sql.ErrNoRows {
+			writeJSON(w, http.StatusBadRequest, map[string]interface{}{"success": false, "error": "invalid_tax_rate", "message": "Tax rate must be one of your active tax rates"})
+			return
+		}
+		logger.L.WithField("error", err).Error("Error looking up tax rates")
+		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "server_error", "message": "Could not create invoice"})
+		return
+	}
+
+	lines, err := buildInvoiceLines(invoiceReq.Lines)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"success": false, "error": "invalid_lines", "message": err.Error()})
+		return
+	}
+
+	// Set default dates if not provided
+	invoiceDate := calendarDate(time.Now())
+	if !invoiceReq.InvoiceDate.IsZero() {
+		invoiceDate = calendarDate(invoiceReq.InvoiceDate)
+	}
+	dueDate := invoiceDate.AddDate(0, 0, defaultInvoiceTermDays)
+	if invoiceReq.DueDate != nil {
+		dueDate = calendarDate(*invoiceReq.DueDate)
+		if dueDate.Before(invoiceDate) {
+			writeJSON(w, http.StatusBadRequest, map[string]interface{}{"success": false, "error": "invalid_due_date", "message": "Due date must not be before the invoice date"})
+			return
+		}
+	}
+
+	if !checkDayOpen(w, userID, invoiceDate) {
+		return
+	}
+
+	// The GSTINs are copied onto the invoice so later profile edits do not
+	// change issued invoices
+	var partyType string
+	var supplierGSTIN, customerGSTIN, customerState sql.NullString
+	err = database.DB.QueryRow(`
+		SELECT c.party_type, u.gstin, c.gstin, c.state_code
+		FROM customers c
+		JOIN users u ON c.user_id = u.id
+		WHERE c.id = ? AND c.user_id = ?`, invoiceReq.CustomerID, userID).Scan(&partyType, &supplierGSTIN, &customerGSTIN, &customerState)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			writeJSON(w, http.StatusNotFound, map[string]interface{}{"success": false, "error": "customer_not_found", "message": "Customer not found"})
+			return
+		}
+		logger.L.WithField("error", err).Error("Error checking customer ownership")
+		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "server_error", "message": "Could not verify customer"})
+		return
+	}
+
+	if partyType == partySupplier {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"success": false, "error": "invalid_party", "message": "Invoices can only be issued to customers"})
+		return
+	}
+
+	invoice := models.Invoice{
+		CustomerID:  invoiceReq.CustomerID,
+		InvoiceDate: invoiceDate,
+		DueDate:     dueDate,
+		Note:        invoiceReq.Note,
+		Lines:       lines,
+		Status:      "open",
+		UserID:      userID,
+	}
+
+	// Only a registered business charges GST; its tax splits into CGST and
+	// SGST within its own state and is IGST for any other place of supply
+	if supplierGSTIN.Valid {
+		supplierState := gst.StateCode(supplierGSTIN.String)
+		placeOfSupply := supplierState
+		if customerState.Valid {
+			placeOfSupply = customerState.String
+		}
+		invoice.SupplierGSTIN = &supplierGSTIN.String
+		invoice.CustomerGSTIN = nullStringPtr(customerGSTIN)
+		invoice.PlaceOfSupply = &placeOfSupply
+
+		for i := range invoice.Lines {
+			line := &invoice.Lines[i]
+			split := gst.SplitTax(line.TaxAmount, supplierState, placeOfSupply)
+			line.CGSTAmount, line.SGSTAmount, line.IGSTAmount = split.CGST, split.SGST, split.IGST
+		}
+	}
+
+	for _, line := range invoice.Lines {
+		invoice.Subtotal += line.Amount
+		invoice.TaxTotal += line.TaxAmount
+		invoice.CGSTTotal += line.CGSTAmount
+		invoice.SGSTTotal += line.SGSTAmount
+		invoice.IGSTTotal += line.IGSTAmount
+	}
+	invoice.Subtotal = roundMoney(invoice.Subtotal)
+	invoice.TaxTotal = roundMoney(invoice.TaxTotal)
+	invoice.CGSTTotal = roundMoney(invoice.CGSTTotal)
+	invoice.SGSTTotal = roundMoney(invoice.SGSTTotal)
+	invoice.IGSTTotal = roundMoney(invoice.IGSTTotal)
+	invoice.Total = roundMoney(invoice.Subtotal + invoice.TaxTotal)
+	invoice.AmountDue = invoice.Total
+
+	if invoice.Total <= 0 {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"success": false, "error": "invalid_lines", "message": "Invoice total must be greater than 0"})
+		return
+	}
+
+	tx, err := database.DB.Begin()
+	if err != nil {
+		logger.L.WithField("error", err).Error("Could not start transaction")
+		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "server_error", "message": "Could not process request"})
+		return
+	}
+	defer tx.Rollback()
+
+	if !checkDayOpenTx(w, tx, userID, invoiceDate) {
+		return
+	}
+
+	seriesID, invoiceNumber, err := nextInvoiceNumber(tx, userID, invoiceReq.SeriesID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			writeJSON(w, http.StatusNotFound, map[string]interface{}{"success": false, "error": "series_not_found", "message": "Invoice series not found"})
+			return
+		}
+		logger.L.WithField("error", err).Error("Error allocating invoice number")
+		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "server_error", "message": "Could not create invoice"})
+		return
+	}
+	invoice.SeriesID = &seriesID
+	invoice.InvoiceNumber = invoiceNumber
+
+	note := "Invoice " + invoiceNumber
+	entry := models.LedgerEntry{
+		CustomerID: invoice.CustomerID,
+		Type:       "credit",
+		Amount:     invoice.Total,
+		Method:     adjustmentMethod,
+		Note:       &note,
+		Date:       invoiceDate,
+		UserID:     userID,
+	}
+	if err := postLedgerEntry(tx, &entry); err != nil {
+		logger.L.WithField("error", err).Error("Error posting invoice entry")
+		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "server_error", "message": "Could not create invoice"})
+		return
+	}
+	invoice.EntryID = &entry.ID
+
+	result, err := tx.Exec(`
+		INSERT INTO invoices (customer_id, series_id, invoice_number, invoice_date, due_date, subtotal, tax_total,
+			cgst_total, sgst_total, igst_total, total, status, note, supplier_gstin, customer_gstin, place_of_supply, entry_id, user_id)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, 'open', ?, ?, ?, ?, ?, ?)`,
+		invoice.CustomerID, seriesID, invoiceNumber, invoiceDate.Format("2006-01-02"), dueDate.Format("2006-01-02"),
+		invoice.Subtotal, invoice.TaxTotal, invoice.CGSTTotal, invoice.SGSTTotal, invoice.IGSTTotal, invoice.Total,
+		invoice.Note, invoice.SupplierGSTIN, invoice.CustomerGSTIN, invoice.PlaceOfSupply, entry.ID, userID)
+	if err != nil {
+		if strings.Contains(err.Error(), "Duplicate entry") {
+			writeJSON(w, http.StatusConflict, map[string]interface{}{"success": false, "error": "duplicate_number", "message": "Invoice number " + invoiceNumber + " is already used; adjust the series"})
+			return
+		}
+		logger.L.WithField("error", err).Error("Error inserting invoice")
+		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "server_error", "message": "Could not create invoice"})
+		return
+	}
+
+	invoiceID, err := result.LastInsertId()
+	if err != nil {
+		logger.L.WithField("error", err).Error("Error getting inserted invoice ID")
+		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "server_error", "message": "Could not create invoice"})
+		return
+	}
+	invoice.ID = int(invoiceID)
+
+	for i := range invoice.Lines {
+		line := &invoice.Lines[i]
+		result, err := tx.Exec(`
+			INSERT INTO invoice_lines (invoice_id, position, item, hsn_code, quantity, rate, tax_rate, amount, tax_amount,
+				cgst_amount, sgst_amount, igst_amount, total)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			invoiceID, i+1, line.Item, line.HSNCode, line.Quantity, line.Rate, line.TaxRate, line.Amount, line.TaxAmount,
+			line.CGSTAmount, line.SGSTAmount, line.IGSTAmount, line.Total)
+		if err != nil {
+			logger.L.WithField("error", err).Error("Error inserting invoice line")
+			writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "server_error", "message": "Could not create invoice"})
+			return
+		}
+		lineID, _ := result.LastInsertId()
+		line.ID = int(lineID)
+	}
+
+	if err := tx.Commit(); err != nil {
+		logger.L.WithField("error", err).Error("Error committing transaction")
+		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "server_error", "message": "Could not create invoice"})
+		return
+	}
+
+	invoice.CreatedAt = time.Now()
+	invoice.UpdatedAt = invoice.CreatedAt
+
+	logger.L.WithFields(map[string]interface{}{
+		"invoice_id":     invoiceID,
+		"invoice_number": invoiceNumber,
+		"user_id":        userID,
+		"total":          invoice.Total,
+	}).Info("Invoice created successfully")
+
+	writeJSON(w, http.StatusCreated, map[string]interface{}{
+		"success": true,
+		"message": "Invoice created successfully",
+		"invoice": invoice,
+	})
+}
+
+// GetInvoice returns an invoice with its lines and the payments allocated to it
+func GetInvoice(w http.ResponseWriter, r *http.Request) {
+	// Get user ID from JWT token
+	userID, err := getUserIDFromToken(r)
+	if err != nil {
+		writeJSON(w, http.StatusUnauthorized, map[string]interface{}{"success": false, "error": "unauthorized", "message": "Invalid token"})
+		return
+	}
+
+	invoiceID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"success": false, "error": "invalid_id", "message": "Invalid invoice ID"})
+		return
+	}
+
+	invoice, err := getInvoice(invoiceID, userID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			writeJSON(w, http.StatusNotFound, map[string]interface{}{"success": false, "error": "not_found", "message": "Invoice not found"})
+			return
+		}
+		logger.L.WithField("error", err).Error("Error querying invoice")
+		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "server_error", "message": "Could not fetch invoice"})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"invoice": invoice,
+	})
+}
+
+// AllocateInvoicePayment applies the unallocated part of a payment to an
+// invoice. Without an amount, as much as possible is applied.
+func AllocateInvoicePayment(w http.ResponseWriter, r *http.Request) {
+	// Get user ID from JWT token
+	userID, err := getUserIDFromToken(r)
+	if err != nil {
+		writeJSON(w, http.StatusUnauthorized, map[string]interface{}{"success": false, "error": "unauthorized", "message": "Invalid token"})
+		return
+	}
+
+	invoiceID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"success": false, "error": "invalid_id", "message": "Invalid invoice ID"})
+		return
+	}
+
+	var allocationReq models.AllocationRequest
+	err = json.NewDecoder(r.Body).Decode(&allocationReq)
+	if err != nil || allocationReq.EntryID <= 0 || allocationReq.Amount <= 0 {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"success": false, "error": "invalid_request", "message": "A payment entry_id and a positive amount are required"})
+		return
+	}
+	allocationReq.InvoiceID = invoiceID
+
+	tx, err := database.DB.Begin()
+	if err != nil {
+		logger.L.WithField("error", err).Error("Could not start transaction")
+		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "server_error", "message": "Could not process request"})
+		return
+	}
+	defer tx.Rollback()
+
+	var entry models.LedgerEntry
+	err = tx.QueryRow(`
+		SELECT id, customer_id, type, amount FROM ledger_entries
+		WHERE id = ? AND user_id = ?
+		FOR UPDATE`, allocationReq.EntryID, userID).Scan(&entry.ID, &entry.CustomerID, &entry.Type, &entry.Amount)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			writeJSON(w, http.StatusNotFound, map[string]interface{}{"success": false, "error": "not_found", "message": "Ledger entry not found"})
+			return
+		}
+		logger.L.WithField("error", err).Error("Error querying ledger entry")
+		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "server_error", "message": "Could not allocate payment"})
+		return
+	}
+	entry.UserID = userID
+
+	allocations, err := allocatePayment(tx, &entry, []models.AllocationRequest{allocationReq})
+	if err != nil {
+		if errors.Is(err, errInvalidAllocation) {
+			writeJSON(w, http.StatusBadRequest, map[string]interface{}{"success": false, "error": "invalid_allocation", "message": err.Error()})
+			return
+		}
+		logger.L.WithField("error", err).Error("Error allocating payment")
+		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "server_error", "message": "Could not allocate payment"})
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		logger.L.WithField("error", err).Error("Error committing transaction")
+		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "server_error", "message": "Could not allocate payment"})
+		return
+	}
+
+	logger.L.WithFields(map[string]interface{}{
+		"invoice_id": invoiceID,
+		"entry_id":   entry.ID,
+		"user_id":    userID,
+	}).Info("Payment allocated successfully")
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"success":     true,
+		"message":     "Payment allocated successfully",
+		"allocations": allocations,
+	})
+}
+
+// DeleteInvoiceAllocation detaches a payment from an invoice, leaving the
+// payment free to be allocated elsewhere
+func DeleteInvoiceAllocation(w http.ResponseWriter, r *http.Request) {
+	// Get user ID from JWT token
+	userID, err := getUserIDFromToken(r)
+	if err != nil {
+		writeJSON(w, http.StatusUnauthorized, map[string]interface{}{"success": false, "error": "unauthorized", "message": "Invalid token"})
+		return
+	}
+
+	invoiceID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"success": false, "error": "invalid_id", "message": "Invalid invoice ID"})
+		return
+	}
+
+	entryID, err := strconv.Atoi(mux.Vars(r)["entryId"])
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"success": false, "error": "invalid_id", "message": "Invalid ledger entry ID"})
+		return
+	}
+
+	tx, err := database.DB.Begin()
+	if err != nil {
+		logger.L.WithField("error", err).Error("Could not start transaction")
+		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "server_error", "message": "Could not process request"})
+		return
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`
+		DELETE ia FROM invoice_allocations ia
+		JOIN invoices i ON ia.invoice_id = i.id
+		WHERE ia.invoice_id = ? AND ia.entry_id = ? AND i.user_id = ?`,
+		invoiceID, entryID, userID)
+	if err != nil {
+		logger.L.WithField("error", err).Error("Error deleting invoice allocation")
+		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "server_error", "message": "Could not delete allocation"})
+		return
+	}
+
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		writeJSON(w, http.StatusNotFound, map[string]interface{}{"success": false, "error": "not_found", "message": "Allocation not found"})
+		return
+	}
+
+	if err := refreshInvoicePayments(tx, invoiceID); err != nil {
+		logger.L.WithField("error", err).Error("Error refreshing invoice payments")
+		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "server_error", "message": "Could not delete allocation"})
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		logger.L.WithField("error", err).Error("Error committing transaction")
+		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "server_error", "message": "Could not delete allocation"})
+		return
+	}
+
+	logger.L.WithFields(map[string]interface{}{
+		"invoice_id": invoiceID,
+		"entry_id":   entryID,
+		"user_id":    userID,
+	}).Info("Invoice allocation deleted successfully")
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"message": "Allocation deleted successfully",
+	})
+}
+
+// allocatePayment applies a balance-reducing entry to the customer's
+// invoices. Manual allocations must be positive; those naming the same
+// invoice are combined, then each is checked against the invoice's amount due
+// and the entry's unallocated amount. Without them the entry settles open
+// invoices oldest due date first. Validation failures wrap
+// errInvalidAllocation.
+func allocatePayment(tx *sql.Tx, entry *models.LedgerEntry, manual []models.AllocationRequest) ([]models.InvoiceAllocation, error) {
+	if !reducesBalance(entry.Type) {
+		if len(manual) > 0 {
+			return nil, fmt.Errorf("%w: only payments, write-offs and discounts can be allocated to invoices", errInvalidAllocation)
+		}
+		return nil, nil
+	}
+
+	var allocated float64
+	err := tx.QueryRow("SELECT COALESCE(SUM(amount), 0) FROM invoice_allocations WHERE entry_id = ?", entry.ID).Scan(&allocated)
+	if err != nil {
+		return nil, err
+	}
+	available := roundMoney(entry.Amount - allocated)
+
+	type target struct {
+		invoiceID int
+		number    string
+		due       float64
+		requested float64
+	}
+	var targets []target
+
+	if len(manual) > 0 {
+		requested := map[int]float64{}
+		var invoiceIDs []int
+		for _, req := range manual {
+			if req.Amount <= 0 {
+				return nil, fmt.Errorf("%w: allocation amounts must be greater than zero", errInvalidAllocation)
+			}
+			if _, ok := requested[req.InvoiceID]; !ok {
+				invoiceIDs = append(invoiceIDs, req.InvoiceID)
+			}
+			requested[req.InvoiceID] = roundMoney(requested[req.InvoiceID] + req.Amount)
+		}
+
+		for _, invoiceID := range invoiceIDs {
+			t := target{invoiceID: invoiceID, requested: requested[invoiceID]}
+			var total, paid float64
+			err := tx.QueryRow(`
+				SELECT invoice_number, total, amount_paid FROM invoices
+				WHERE id = ? AND customer_id = ? AND user_id = ?
+				FOR UPDATE`, invoiceID, entry.CustomerID, entry.UserID).Scan(&t.number, &total, &paid)
+			if err != nil {
+				if err == sql.ErrNoRows {
+					return nil, fmt.Errorf("%w: invoice %d not found for this customer", errInvalidAllocation, invoiceID)
+				}
+				return nil, err
+			}
+			t.due = roundMoney(total - paid)
+			if t.requested > t.due {
+				return nil, fmt.Errorf("%w: %.2f exceeds the %.2f due on invoice %s", errInvalidAllocation, t.requested, t.due, t.number)
+			}
+			targets = append(targets, t)
+		}
+	} else {
+		rows, err := tx.Query(`
+			SELECT id, invoice_number, total - amount_paid FROM invoices
+			WHERE customer_id = ? AND user_id = ? AND status != 'paid'
+			ORDER BY due_date, invoice_date, id
+			FOR UPDATE`, entry.CustomerID, entry.UserID)
+		if err != nil {
+			return nil, err
+		}
+		for rows.Next() {
+			var t target
+			if err := rows.Scan(&t.invoiceID, &t.number, &t.due); err != nil {
+				rows.Close()
+				return nil, err
+			}
+			targets = append(targets, t)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+	}
+
+	allocations := []models.InvoiceAllocation{}
+	for _, t := range targets {
+		amount := t.requested
+		if len(manual) == 0 {
+			amount = math.Min(t.due, available)
+		}
+		amount = roundMoney(amount)
+		if amount > available {
+			if len(manual) > 0 {
+				return nil, fmt.Errorf("%w: the entry has only %.2f left to allocate", errInvalidAllocation, available)
+			}
+			amount = available
+		}
+		if amount <= 0 {
+			continue
+		}
+
+		_, err := tx.Exec(`
+			INSERT INTO invoice_allocations (invoice_id, entry_id, amount)
+			VALUES (?, ?, ?)
+			ON DUPLICATE KEY UPDATE amount = amount + VALUES(amount)`,
+			t.invoiceID, entry.ID, amount)
+		if err != nil {
+			return nil, err
+		}
+		if err := refreshInvoicePayments(tx, t.invoiceID); err != nil {
+			return nil, err
+		}
+
+		available = roundMoney(available - amount)
+		allocations = append(allocations, models.InvoiceAllocation{
+			InvoiceID:     t.invoiceID,
+			InvoiceNumber: t.number,
+			EntryID:       entry.ID,
+			Amount:        amount,
+			CreatedAt:     time.Now(),
+		})
+
+		if available <= 0 {
+			break
+		}
+	}
+
+	return allocations, nil
+}
+
+// releaseAllocations removes every allocation of an entry, reopening the
+// invoices it paid. It is used when a payment turns out not to have happened,
+// such as a bounced cheque.
+func releaseAllocations(tx *sql.Tx, entryID int) error {
+	rows, err := tx.Query("SELECT invoice_id FROM invoice_allocations WHERE entry_id = ?", entryID)
+	if err != nil {
+		return err
+	}
+	var invoiceIDs []int
+	for rows.Next() {
+		var invoiceID int
+		if err := rows.Scan(&invoiceID); err != nil {
+			rows.Close()
+			return err
+		}
+		invoiceIDs = append(invoiceIDs, invoiceID)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec("DELETE FROM invoice_allocations WHERE entry_id = ?", entryID); err != nil {
+		return err
+	}
+	for _, invoiceID := range invoiceIDs {
+		if err := refreshInvoicePayments(tx, invoiceID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// refreshInvoicePayments recomputes an invoice's amount paid and status from
+// its allocations
+func refreshInvoicePayments(tx *sql.Tx, invoiceID int) error {
+	_, err := tx.Exec(`
+		UPDATE invoices i
+		SET i.amount_paid = (SELECT COALESCE(SUM(amount), 0) FROM invoice_allocations WHERE invoice_id = i.id),
+			i.status = CASE
+				WHEN i.amount_paid >= i.total THEN 'paid'
+				WHEN i.amount_paid > 0 THEN 'partial'
+				ELSE 'open'
+			END,
+			i.updated_at = CURRENT_TIMESTAMP
+		WHERE i.id = ?`, invoiceID)
+	return err
+}
+
+// nextInvoiceNumber takes the next number from the requested series, or from
+// the default series, creating an "INV-" series for users without one. The
+// series row stays locked until tx ends so numbers are never handed out
+// twice. It returns sql.ErrNoRows if seriesID does not belong to the user.
+func nextInvoiceNumber(tx *sql.Tx, userID int, seriesID *int) (int, string, error) {
+	query := "SELECT id, prefix, next_number, padding FROM invoice_series WHERE user_id = ?"
+	args := []interface{}{userID}
+	if seriesID != nil {
+		query += " AND id = ?"
+		args = append(args, *seriesID)
+	} else {
+		query += " ORDER BY is_default DESC, id ASC LIMIT 1"
+	}
+	query += " FOR UPDATE"
+
+	var id, number, padding int
+	var prefix string
+	err := tx.QueryRow(query, args...).Scan(&id, &prefix, &number, &padding)
+	if err == sql.ErrNoRows && seriesID == nil {
+		result, err := tx.Exec(`
+			INSERT INTO invoice_series (name, prefix, next_number, padding, is_default, user_id)
+			VALUES ('Default', 'INV-', 1, 4, TRUE, ?)`, userID)
+		if err != nil {
+			return 0, "", err
+		}
+		newID, err := result.LastInsertId()
+		if err != nil {
+			return 0, "", err
+		}
+		id, prefix, number, padding = int(newID), "INV-", 1, 4
+	} else if err != nil {
+		return 0, "", err
+	}
+
+	if _, err := tx.Exec("UPDATE invoice_series SET next_number = next_number + 1 WHERE id = ?", id); err != nil {
+		return 0, "", err
+	}
+
+	return id, fmt.Sprintf("%s%0*d", prefix, padding, number), nil
+}
+
+// buildInvoiceLines validates line requests and computes their amounts
+func buildInvoiceLines(reqs []models.InvoiceLineRequest) ([]models.InvoiceLine, error) {
+	if len(reqs) == 0 || len(reqs) > 200 {
+		return nil, errors.New("An invoice needs between 1 and 200 lines")
+	}
+
+	lines := make([]models.InvoiceLine, 0, len(reqs))
+	for i, req := range reqs {
+		item := strings.TrimSpace(req.Item)
+		if item == "" || len(item) > 255 {
+			return nil, fmt.Errorf("Line %d needs an item name of up to 255 characters", i+1)
+		}
+		if req.Quantity <= 0 || req.Rate < 0 {
+			return nil, fmt.Errorf("Line %d needs a positive quantity and a non-negative rate", i+1)
+		}
+		if req.TaxRate < 0 || req.TaxRate > 100 {
+			return nil, fmt.Errorf("Line %d has an invalid tax rate", i+1)
+		}
+
+		var hsnCode *string
+		if req.HSNCode != nil && strings.TrimSpace(*req.HSNCode) != "" {
+			code := strings.TrimSpace(*req.HSNCode)
+			if !hsnCodeRegex.MatchString(code) {
+				return nil, fmt.Errorf("Line %d has an invalid HSN code; use 4 to 8 digits", i+1)
+			}
+			hsnCode = &code
+		}
+
+		line := models.InvoiceLine{
+			Item:     item,
+			HSNCode:  hsnCode,
+			Quantity: req.Quantity,
+			Rate:     req.Rate,
+			TaxRate:  req.TaxRate,
+			Amount:   roundMoney(req.Quantity * req.Rate),
+		}
+		line.TaxAmount = roundMoney(line.Amount * line.TaxRate / 100)
+		line.Total = roundMoney(line.Amount + line.TaxAmount)
+		lines = append(lines, line)
+	}
+
+	return lines, nil
+}
+
+// validateInvoiceSeries writes the error response and returns false when a
+// series has an unusable format
+func validateInvoiceSeries(w http.ResponseWriter, series *models.InvoiceSeries) bool {
+	if series.Name == "" || len(series.Name) > 100 {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"success": false, "error": "invalid_name", "message": "Name is required and must be at most 100 characters"})
+		return false
+	}
+	if !invoicePrefixRegex.MatchString(series.Prefix) {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"success": false, "error": "invalid_prefix", "message": "Prefix may use up to 20 letters, digits, '/', '-' or '_'"})
+		return false
+	}
+	if series.NextNumber < 1 {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"success": false, "error": "invalid_next_number", "message": "Next number must be at least 1"})
+		return false
+	}
+	if series.Padding < 0 || series.Padding > 10 {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"success": false, "error": "invalid_padding", "message": "Padding must be between 0 and 10"})
+		return false
+	}
+	return true
+}
+
+const invoiceSelect = `
+	SELECT i.id, i.customer_id, c.name, i.series_id, i.invoice_number, i.invoice_date, i.due_date,
+		   i.subtotal, i.tax_total, i.cgst_total, i.sgst_total, i.igst_total, i.total, i.amount_paid, i.status, i.note,
+		   i.supplier_gstin, i.customer_gstin, i.place_of_supply, i.entry_id, i.user_id,
+		   i.created_at, i.updated_at
+	FROM invoices i
+	JOIN customers c ON i.customer_id = c.id`
+
+// getInvoice loads an invoice owned by userID with its lines and allocations
+func getInvoice(invoiceID, userID int) (*models.Invoice, error) {
+	invoice, err := scanInvoice(database.DB.QueryRow(invoiceSelect+" WHERE i.id = ? AND i.user_id = ?", invoiceID, userID))
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := database.DB.Query(`
+		SELECT id, item, hsn_code, quantity, rate, tax_rate, amount, tax_amount, cgst_amount, sgst_amount, igst_amount, total
+		FROM invoice_lines
+		WHERE invoice_id = ?
+		ORDER BY position`, invoiceID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	invoice.Lines = []models.InvoiceLine{}
+	for rows.Next() {
+		var line models.InvoiceLine
+		var hsnCode sql.NullString
+		err := rows.Scan(&line.ID, &line.Item, &hsnCode, &line.Quantity, &line.Rate, &line.TaxRate, &line.Amount,
+			&line.TaxAmount, &line.CGSTAmount, &line.SGSTAmount, &line.IGSTAmount, &line.Total)
+		if err != nil {
+			return nil, err
+		}
+		line.HSNCode = nullStringPtr(hsnCode)
+		invoice.Lines = append(invoice.Lines, line)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	allocationRows, err := database.DB.Query(`
+		SELECT entry_id, amount, created_at
+		FROM invoice_allocations
+		WHERE invoice_id = ?
+		ORDER BY created_at, entry_id`, invoiceID)
+	if err != nil {
+		return nil, err
+	}
+	defer allocationRows.Close()
+
+	invoice.Allocations = []models.InvoiceAllocation{}
+	for allocationRows.Next() {
+		allocation := models.InvoiceAllocation{InvoiceID: invoice.ID, InvoiceNumber: invoice.InvoiceNumber}
+		var createdAtStr string
+		if err := allocationRows.Scan(&allocation.EntryID, &allocation.Amount, &createdAtStr); err != nil {
+			return nil, err
+		}
+		allocation.CreatedAt, _ = time.Parse("2006-01-02 15:04:05", createdAtStr)
+		invoice.Allocations = append(invoice.Allocations, allocation)
+	}
+
+	return invoice, allocationRows.Err()
+}
+
+func scanInvoice(row rowScanner) (*models.Invoice, error) {
+	var invoice models.Invoice
+	var seriesID, entryID sql.NullInt64
+	var note, supplierGSTIN, customerGSTIN, placeOfSupply sql.NullString
+	var invoiceDateStr, dueDateStr, createdAtStr, updatedAtStr string
+
+	err := row.Scan(
+		&invoice.ID, &invoice.CustomerID, &invoice.CustomerName, &seriesID, &invoice.InvoiceNumber,
+		&invoiceDateStr, &dueDateStr, &invoice.Subtotal, &invoice.TaxTotal, &invoice.CGSTTotal, &invoice.SGSTTotal,
+		&invoice.IGSTTotal, &invoice.Total, &invoice.AmountPaid, &invoice.Status, &note,
+		&supplierGSTIN, &customerGSTIN, &placeOfSupply, &entryID, &invoice.UserID, &createdAtStr, &updatedAtStr,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	invoice.SeriesID = nullIntPtr(seriesID)
+	invoice.EntryID = nullIntPtr(entryID)
+	invoice.Note = nullStringPtr(note)
+	invoice.SupplierGSTIN = nullStringPtr(supplierGSTIN)
+	invoice.CustomerGSTIN = nullStringPtr(customerGSTIN)
+	invoice.PlaceOfSupply = nullStringPtr(placeOfSupply)
+	invoice.InvoiceDate, _ = time.Parse("2006-01-02", invoiceDateStr)
+	invoice.DueDate, _ = time.Parse("2006-01-02", dueDateStr)
+	invoice.CreatedAt, _ = time.Parse("2006-01-02 15:04:05", createdAtStr)
+	invoice.UpdatedAt, _ = time.Parse("2006-01-02 15:04:05", updatedAtStr)
+	invoice.AmountDue = roundMoney(invoice.Total - invoice.AmountPaid)
+
+	// Overdue is not stored; it is any unpaid invoice past its due date
+	if invoice.Status != "paid" && invoice.DueDate.Before(calendarDate(time.Now())) {
+		invoice.Status = "overdue"
+	}
+
+	return &invoice, nil
+}
+
+func scanInvoiceSeries(row rowScanner) (*models.InvoiceSeries, error) {
+	var series models.InvoiceSeries
+	var createdAtStr, updatedAtStr string
+
+	err := row.Scan(&series.ID, &series.Name, &series.Prefix, &series.NextNumber, &series.Padding,
+		&series.IsDefault, &series.UserID, &createdAtStr, &updatedAtStr)
+	if err != nil {
+		return nil, err
+	}
+
+	series.CreatedAt, _ = time.Parse("2006-01-02 15:04:05", createdAtStr)
+	series.UpdatedAt, _ = time.Parse("2006-01-02 15:04:05", updatedAtStr)
+
+	return &series, nil
+}
@@ -0,0 +1,50 @@
+package handlers
+
+import (
+	"os"
+	"time"
+
+	"khata-book-backend/pkg/logger"
+)
+
+// StartBackgroundJobs launches the periodic jobs that post scheduled work.
+// Each job runs once at startup and then on its own interval, which can be
+// overridden with a Go duration in the named environment variable.
+func StartBackgroundJobs() {
+	go runEvery("recurring_entries", jobInterval("RECURRING_JOB_INTERVAL", 15*time.Minute), runDueRecurringEntries)
+	go runEvery("interest_accruals", jobInterval("INTEREST_JOB_INTERVAL", time.Hour), runInterestAccruals)
+	go runEvery("reminder_dispatch", jobInterval("REMINDER_JOB_INTERVAL", time.Minute), runReminderDispatch)
+	go runEvery("reminder_rules", jobInterval("REMINDER_RULES_JOB_INTERVAL", time.Hour), runReminderRules)
+}
+
+// runEvery calls job on a fixed interval until the process exits. Errors are
+// logged and the job is retried on the next tick.
+func runEvery(name string, interval time.Duration, job func(now time.Time) error) {
+	logger.L.WithFields(map[string]interface{}{"job": name, "interval": interval.String()}).Info("Background job started")
+
+	for {
+		started := time.Now()
+		if err := job(started); err != nil {
+			logger.L.WithFields(map[string]interface{}{"job": name, "error": err}).Error("Background job failed")
+		} else {
+			logger.L.WithFields(map[string]interface{}{"job": name, "duration": time.Since(started).String()}).Debug("Background job finished")
+		}
+		time.Sleep(interval)
+	}
+}
+
+func jobInterval(envName string, fallback time.Duration) time.Duration {
+	if value := os.Getenv(envName); value != "" {
+		if interval, err := time.ParseDuration(value); err == nil && interval > 0 {
+			return interval
+		}
+		logger.L.WithField("variable", envName).Warn("Invalid job interval, using default")
+	}
+	return fallback
+}
+
+// calendarDate returns the date of t as midnight UTC, the form used for DATE
+// columns and recurrence calculations
+func calendarDate(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
@@ -2,9 +2,12 @@ package handlers
 
 import (
 	"database/sql"
+	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	"khata-book-backend/database"
@@ -34,8 +37,8 @@ func CreateLedgerEntry(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if entryReq.Type != "credit" && entryReq.Type != "debit" {
-		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"success": false, "error": "invalid_type", "message": "Type must be 'credit' or 'debit'"})
+	if entryReq.Type != "credit" && entryReq.Type != "debit" && !isAdjustmentType(entryReq.Type) {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"success": false, "error": "invalid_type", "message": "Type must be 'credit', 'debit', 'write_off' or 'discount'"})
 		return
 	}
 
@@ -44,14 +47,43 @@ func CreateLedgerEntry(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if entryReq.Method != "cash" && entryReq.Method != "upi" && entryReq.Method != "bank" {
-		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"success": false, "error": "invalid_method", "message": "Method must be 'cash', 'upi', or 'bank'"})
+	if len(entryReq.Allocations) > 0 && !reducesBalance(entryReq.Type) {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"success": false, "error": "invalid_allocation", "message": "Only payments, write-offs and discounts can be allocated to invoices"})
+		return
+	}
+
+	// Write-offs and discounts move no money, so they carry no payment method
+	method := &models.PaymentMethod{Code: adjustmentMethod, Kind: "other"}
+	if !isAdjustmentType(entryReq.Type) {
+		method, err = lookupPaymentMethod(userID, entryReq.Method)
+	}
+	if err != nil {
+		if err == sql.ErrNoRows {
+			writeJSON(w, http.StatusBadRequest, map[string]interface{}{"success": false, "error": "invalid_method", "message": "Method must be one of your active payment methods"})
+			return
+		}
+		logger.L.WithField("error", err).Error("Error looking up payment method")
+		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "server_error", "message": "Could not verify payment method"})
+		return
+	}
+
+	if method.Kind == "cheque" {
+		if entryReq.Cheque == nil || strings.TrimSpace(entryReq.Cheque.ChequeNumber) == "" || len(strings.TrimSpace(entryReq.Cheque.ChequeNumber)) > 30 {
+			writeJSON(w, http.StatusBadRequest, map[string]interface{}{"success": false, "error": "invalid_cheque", "message": "A cheque number of up to 30 characters is required for cheque entries"})
+			return
+		}
+	}
+
+	tags, err := normalizeTags(entryReq.Tags)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"success": false, "error": "invalid_tags", "message": "At most 20 tags of up to 50 characters are allowed"})
 		return
 	}
 
 	// Verify customer exists and belongs to user
 	var customerUserID int
-	err = database.DB.QueryRow("SELECT user_id FROM customers WHERE id = ?", entryReq.CustomerID).Scan(&customerUserID)
+	var partyType string
+	err = database.DB.QueryRow("SELECT user_id, party_type FROM customers WHERE id = ?", entryReq.CustomerID).Scan(&customerUserID, &partyType)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			writeJSON(w, http.StatusNotFound, map[string]interface{}{"success": false, "error": "customer_not_found", "message": "Customer not found"})
@@ -67,12 +99,40 @@ func CreateLedgerEntry(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// A write-off or discount lowers the balance, which is only right when
+	// the party owes it; a supplier is usually owed money, so supplier
+	// balances are forgiven through settlement, which checks the direction
+	if partyType == partySupplier && isAdjustmentType(entryReq.Type) {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"success": false, "error": "invalid_type", "message": "Write-offs and discounts cannot be posted to a supplier; settle the supplier instead"})
+		return
+	}
+
+	if entryReq.CategoryID != nil && !checkCategoryOwnership(w, *entryReq.CategoryID, userID) {
+		return
+	}
+
 	// Set default date if not provided
 	entryDate := time.Now()
 	if !entryReq.Date.IsZero() {
 		entryDate = entryReq.Date
 	}
 
+	if !checkDayOpen(w, userID, entryDate) {
+		return
+	}
+
+	entry := models.LedgerEntry{
+		CustomerID: entryReq.CustomerID,
+		Type:       entryReq.Type,
+		Amount:     entryReq.Amount,
+		Method:     method.Code,
+		CategoryID: entryReq.CategoryID,
+		Tags:       tags,
+		Note:       entryReq.Note,
+		Date:       entryDate,
+		UserID:     userID,
+	}
+
 	// Use transaction for atomic operation
 	tx, err := database.DB.Begin()
 	if err != nil {
@@ -81,47 +141,53 @@ func CreateLedgerEntry(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Insert ledger entry
-	result, err := tx.Exec(`
-		INSERT INTO ledger_entries (customer_id, type, amount, method, note, date, user_id)
-		VALUES (?, ?, ?, ?, ?, ?, ?)`,
-		entryReq.CustomerID, entryReq.Type, entryReq.Amount, entryReq.Method,
-		entryReq.Note, entryDate.Format("2006-01-02"), userID)
-	if err != nil {
+	if !checkDayOpenTx(w, tx, userID, entryDate) {
 		tx.Rollback()
-		logger.L.WithField("error", err).Error("Error inserting ledger entry")
-		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "server_error", "message": "Could not create ledger entry"})
 		return
 	}
 
-	entryID, err := result.LastInsertId()
-	if err != nil {
+	if err := postLedgerEntry(tx, &entry); err != nil {
 		tx.Rollback()
-		logger.L.WithField("error", err).Error("Error getting inserted entry ID")
+		logger.L.WithField("error", err).Error("Error posting ledger entry")
 		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "server_error", "message": "Could not create ledger entry"})
 		return
 	}
 
-	// Update customer balance
-	var balanceUpdate float64
-	if entryReq.Type == "credit" {
-		balanceUpdate = entryReq.Amount
-	} else {
-		balanceUpdate = -entryReq.Amount
+	if method.Kind == "cheque" {
+		cheque, err := insertChequeDetails(tx, entry.ID, userID, entryReq.Cheque)
+		if err != nil {
+			tx.Rollback()
+			logger.L.WithField("error", err).Error("Error inserting cheque details")
+			writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "server_error", "message": "Could not create ledger entry"})
+			return
+		}
+		entry.Cheque = cheque
 	}
 
-	_, err = tx.Exec(`
-		UPDATE customers
-		SET balance = balance + ?, updated_at = CURRENT_TIMESTAMP
-		WHERE id = ?`,
-		balanceUpdate, entryReq.CustomerID)
+	allocations, err := allocatePayment(tx, &entry, entryReq.Allocations)
 	if err != nil {
 		tx.Rollback()
-		logger.L.WithField("error", err).Error("Error updating customer balance")
-		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "server_error", "message": "Could not update customer balance"})
+		if errors.Is(err, errInvalidAllocation) {
+			writeJSON(w, http.StatusBadRequest, map[string]interface{}{"success": false, "error": "invalid_allocation", "message": err.Error()})
+			return
+		}
+		logger.L.WithField("error", err).Error("Error allocating payment to invoices")
+		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "server_error", "message": "Could not create ledger entry"})
 		return
 	}
 
+	// Reminders only chase customers; a supplier debit is a purchase
+	var remindersClosed, remindersReduced int
+	if partyType == partyCustomer && reducesBalance(entry.Type) {
+		remindersClosed, remindersReduced, err = reconcileReminders(tx, entry.CustomerID, userID, "ledger entry "+strconv.Itoa(entry.ID), false)
+		if err != nil {
+			tx.Rollback()
+			logger.L.WithField("error", err).Error("Error reconciling reminders")
+			writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "server_error", "message": "Could not create ledger entry"})
+			return
+		}
+	}
+
 	if err := tx.Commit(); err != nil {
 		logger.L.WithField("error", err).Error("Error committing transaction")
 		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "server_error", "message": "Could not create ledger entry"})
@@ -129,33 +195,67 @@ func CreateLedgerEntry(w http.ResponseWriter, r *http.Request) {
 	}
 
 	logger.L.WithFields(map[string]interface{}{
-		"entry_id": entryID,
+		"entry_id": entry.ID,
 		"user_id":  userID,
-		"type":     entryReq.Type,
-		"amount":   entryReq.Amount,
+		"type":     entry.Type,
+		"amount":   entry.Amount,
 	}).Info("Ledger entry created successfully")
 
-	// Create response entry
-	entry := models.LedgerEntry{
-		ID:         int(entryID),
-		CustomerID: entryReq.CustomerID,
-		Type:       entryReq.Type,
-		Amount:     entryReq.Amount,
-		Method:     entryReq.Method,
-		Note:       entryReq.Note,
-		Date:       entryDate,
-		UserID:     userID,
-		CreatedAt:  time.Now(),
-		UpdatedAt:  time.Now(),
-	}
-
 	writeJSON(w, http.StatusCreated, map[string]interface{}{
-		"success": true,
-		"message": "Ledger entry created successfully",
-		"entry":   entry,
+		"success":           true,
+		"message":           "Ledger entry created successfully",
+		"entry":             entry,
+		"type_label":        entryTypeLabel(partyType, entry.Type),
+		"allocations":       allocations,
+		"reminders_closed":  remindersClosed,
+		"reminders_reduced": remindersReduced,
 	})
 }
 
+// postLedgerEntry inserts an entry with its tags and applies it to the
+// customer's balance inside tx. Every ledger write goes through here so the
+// balance and the ledger cannot drift apart. entry.ID and the timestamps are
+// filled in on success.
+func postLedgerEntry(tx *sql.Tx, entry *models.LedgerEntry) error {
+	result, err := tx.Exec(`
+		INSERT INTO ledger_entries (customer_id, type, amount, method, category_id, note, date, user_id)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		entry.CustomerID, entry.Type, entry.Amount, entry.Method, entry.CategoryID,
+		entry.Note, entry.Date.Format("2006-01-02"), entry.UserID)
+	if err != nil {
+		return err
+	}
+
+	entryID, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+	entry.ID = int(entryID)
+
+	if err := replaceEntryTags(tx, entry.ID, entry.UserID, entry.Tags); err != nil {
+		return err
+	}
+
+	// Update customer balance
+	balanceUpdate := entry.Amount
+	if reducesBalance(entry.Type) {
+		balanceUpdate = -entry.Amount
+	}
+
+	_, err = tx.Exec(`
+		UPDATE customers
+		SET balance = balance + ?, updated_at = CURRENT_TIMESTAMP
+		WHERE id = ?`,
+		balanceUpdate, entry.CustomerID)
+	if err != nil {
+		return err
+	}
+
+	entry.CreatedAt = time.Now()
+	entry.UpdatedAt = entry.CreatedAt
+	return nil
+}
+
 // GetLedgerEntries retrieves ledger entries for the authenticated user
 func GetLedgerEntries(w http.ResponseWriter, r *http.Request) {
 	// Get user ID from JWT token
@@ -166,33 +266,62 @@ func GetLedgerEntries(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Parse query parameters
-	customerIDStr := r.URL.Query().Get("customer_id")
-	entryType := r.URL.Query().Get("type")
 	limitStr := r.URL.Query().Get("limit")
 	offsetStr := r.URL.Query().Get("offset")
+	cursorStr := r.URL.Query().Get("cursor")
+
+	// Entries are ordered newest first by created_at (default) or by date,
+	// with id as the tie-breaker so keyset pages are stable
+	sortField := "created_at"
+	if r.URL.Query().Get("sort") == "date" {
+		sortField = "date"
+	}
+
+	var cursor *ledgerCursor
+	if cursorStr != "" {
+		cursor, err = decodeLedgerCursor(cursorStr)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]interface{}{"success": false, "error": "invalid_cursor", "message": "Invalid pagination cursor"})
+			return
+		}
+		sortField = cursor.Sort
+	}
+
+	where, whereArgs := buildLedgerFilter(r, userID)
+
+	// Totals for the whole filtered set, independent of pagination
+	totals, err := getLedgerTotals(where, whereArgs)
+	if err != nil {
+		logger.L.WithField("error", err).Error("Error calculating ledger totals")
+		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "server_error", "message": "Could not fetch ledger entries"})
+		return
+	}
 
 	// Build query
 	query := `
-		SELECT le.id, le.customer_id, le.type, le.amount, le.method, le.note, le.date, le.created_at, le.updated_at,
-			   c.name as customer_name
+		SELECT le.id, le.customer_id, le.type, le.amount, le.method, le.category_id, le.note, le.date, le.created_at, le.updated_at,
+			   c.name as customer_name, c.party_type, cat.name as category_name
 		FROM ledger_entries le
 		JOIN customers c ON le.customer_id = c.id
-		WHERE le.user_id = ?`
-	args := []interface{}{userID}
-
-	if customerIDStr != "" {
-		if customerID, err := strconv.Atoi(customerIDStr); err == nil {
-			query += " AND le.customer_id = ?"
-			args = append(args, customerID)
+		LEFT JOIN categories cat ON le.category_id = cat.id
+		WHERE ` + where
+	args := append([]interface{}{}, whereArgs...)
+
+	sortColumn := "le." + sortField
+	order := "DESC"
+	if cursor != nil {
+		// Walking backwards reads the preceding rows in ascending order and
+		// flips them afterwards
+		comparison := "<"
+		if cursor.Dir == "prev" {
+			comparison = ">"
+			order = "ASC"
 		}
+		query += " AND (" + sortColumn + " " + comparison + " ? OR (" + sortColumn + " = ? AND le.id " + comparison + " ?))"
+		args = append(args, cursor.Key, cursor.Key, cursor.ID)
 	}
 
-	if entryType != "" && (entryType == "credit" || entryType == "debit") {
-		query += " AND le.type = ?"
-		args = append(args, entryType)
-	}
-
-	query += " ORDER BY le.created_at DESC"
+	query += " ORDER BY " + sortColumn + " " + order + ", le.id " + order
 
 	// Add pagination
 	limit := 50 // default limit
@@ -201,11 +330,14 @@ func GetLedgerEntries(w http.ResponseWriter, r *http.Request) {
 			limit = parsedLimit
 		}
 	}
+	// Fetch one extra row to learn whether another page exists
 	query += " LIMIT ?"
-	args = append(args, limit)
+	args = append(args, limit+1)
 
-	if offsetStr != "" {
-		if offset, err := strconv.Atoi(offsetStr); err == nil && offset >= 0 {
+	offset := 0
+	if offsetStr != "" && cursor == nil {
+		if parsedOffset, err := strconv.Atoi(offsetStr); err == nil && parsedOffset >= 0 {
+			offset = parsedOffset
 			query += " OFFSET ?"
 			args = append(args, offset)
 		}
@@ -220,15 +352,17 @@ func GetLedgerEntries(w http.ResponseWriter, r *http.Request) {
 	defer rows.Close()
 
 	var entries []map[string]interface{}
+	var keys []ledgerCursor
 	for rows.Next() {
 		var entry models.LedgerEntry
-		var customerName string
-		var note sql.NullString
+		var customerName, partyType string
+		var note, categoryName sql.NullString
+		var categoryID sql.NullInt64
 		var createdAtStr, updatedAtStr, dateStr string
 
 		err := rows.Scan(
-			&entry.ID, &entry.CustomerID, &entry.Type, &entry.Amount, &entry.Method,
-			&note, &dateStr, &createdAtStr, &updatedAtStr, &customerName,
+			&entry.ID, &entry.CustomerID, &entry.Type, &entry.Amount, &entry.Method, &categoryID,
+			&note, &dateStr, &createdAtStr, &updatedAtStr, &customerName, &partyType, &categoryName,
 		)
 		if err != nil {
 			logger.L.WithField("error", err).Error("Error scanning ledger entry")
@@ -247,24 +381,281 @@ func GetLedgerEntries(w http.ResponseWriter, r *http.Request) {
 			"id":            entry.ID,
 			"customer_id":   entry.CustomerID,
 			"customer_name": customerName,
+			"party_type":    partyType,
 			"type":          entry.Type,
+			"type_label":    entryTypeLabel(partyType, entry.Type),
 			"amount":        entry.Amount,
 			"method":        entry.Method,
+			"category_id":   nullIntPtr(categoryID),
+			"category_name": nullStringPtr(categoryName),
+			"tags":          []string{},
 			"note":          entry.Note,
 			"date":          dateStr,
 			"created_at":    createdAtStr,
 			"updated_at":    updatedAtStr,
 		}
 		entries = append(entries, entryMap)
+
+		key := createdAtStr
+		if sortField == "date" {
+			key = dateStr
+		}
+		keys = append(keys, ledgerCursor{Sort: sortField, Key: key, ID: entry.ID})
+	}
+
+	hasMore := len(entries) > limit
+	if hasMore {
+		entries = entries[:limit]
+		keys = keys[:limit]
+	}
+
+	entryIDs := make([]int, len(keys))
+	for i, key := range keys {
+		entryIDs[i] = key.ID
+	}
+	entryTags, err := getEntryTags(userID, entryIDs)
+	if err != nil {
+		logger.L.WithField("error", err).Error("Error querying ledger entry tags")
+		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "server_error", "message": "Could not fetch ledger entries"})
+		return
+	}
+	for i, key := range keys {
+		if tags, ok := entryTags[key.ID]; ok {
+			entries[i]["tags"] = tags
+		}
+	}
+
+	backwards := cursor != nil && cursor.Dir == "prev"
+	if backwards {
+		for i, j := 0, len(entries)-1; i < j; i, j = i+1, j-1 {
+			entries[i], entries[j] = entries[j], entries[i]
+			keys[i], keys[j] = keys[j], keys[i]
+		}
+	}
+
+	var nextCursor, prevCursor interface{}
+	if len(entries) > 0 {
+		if backwards || hasMore {
+			last := keys[len(keys)-1]
+			last.Dir = "next"
+			nextCursor = last.encode()
+		}
+		if (backwards && hasMore) || (cursor != nil && !backwards) || (cursor == nil && offset > 0) {
+			first := keys[0]
+			first.Dir = "prev"
+			prevCursor = first.encode()
+		}
 	}
 
 	writeJSON(w, http.StatusOK, map[string]interface{}{
-		"success": true,
-		"entries": entries,
-		"count":   len(entries),
+		"success":     true,
+		"entries":     entries,
+		"count":       len(entries),
+		"totals":      totals,
+		"next_cursor": nextCursor,
+		"prev_cursor": prevCursor,
 	})
 }
 
+// ledgerCursor is the position of a ledger entry in a sorted listing. It is
+// handed to clients as an opaque base64 token.
+type ledgerCursor struct {
+	Sort string `json:"s"`
+	Key  string `json:"k"`
+	ID   int    `json:"i"`
+	Dir  string `json:"d"`
+}
+
+func (c ledgerCursor) encode() string {
+	raw, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+// decodeLedgerCursor parses and validates a cursor produced by encode
+func decodeLedgerCursor(s string) (*ledgerCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+
+	var c ledgerCursor
+	if err := json.Unmarshal(raw, &c); err != nil {
+		return nil, err
+	}
+
+	layout := "2006-01-02 15:04:05"
+	switch c.Sort {
+	case "created_at":
+	case "date":
+		layout = "2006-01-02"
+	default:
+		return nil, errors.New("invalid cursor sort")
+	}
+	if _, err := time.Parse(layout, c.Key); err != nil {
+		return nil, err
+	}
+	if c.Dir != "next" && c.Dir != "prev" {
+		return nil, errors.New("invalid cursor direction")
+	}
+
+	return &c, nil
+}
+
+// buildLedgerFilter builds the WHERE clause shared by the ledger listing and
+// its totals from the request's query parameters. Invalid values are ignored.
+func buildLedgerFilter(r *http.Request, userID int) (string, []interface{}) {
+	q := r.URL.Query()
+
+	where := "le.user_id = ?"
+	args := []interface{}{userID}
+
+	if customerIDStr := q.Get("customer_id"); customerIDStr != "" {
+		if customerID, err := strconv.Atoi(customerIDStr); err == nil {
+			where += " AND le.customer_id = ?"
+			args = append(args, customerID)
+		}
+	}
+
+	if partyType := q.Get("party_type"); partyType == partyCustomer || partyType == partySupplier {
+		where += " AND c.party_type = ?"
+		args = append(args, partyType)
+	}
+
+	if entryType := q.Get("type"); isLedgerEntryType(entryType) {
+		where += " AND le.type = ?"
+		args = append(args, entryType)
+	}
+
+	if fromStr := q.Get("from"); fromStr != "" {
+		if from, err := time.Parse("2006-01-02", fromStr); err == nil {
+			where += " AND le.date >= ?"
+			args = append(args, from.Format("2006-01-02"))
+		}
+	}
+
+	if toStr := q.Get("to"); toStr != "" {
+		if to, err := time.Parse("2006-01-02", toStr); err == nil {
+			where += " AND le.date <= ?"
+			args = append(args, to.Format("2006-01-02"))
+		}
+	}
+
+	// method may be repeated (?method=cash&method=upi) or comma separated
+	var methods []string
+	for _, value := range q["method"] {
+		for _, method := range strings.Split(value, ",") {
+			method = strings.TrimSpace(method)
+			if paymentMethodCodeRegex.MatchString(method) {
+				methods = append(methods, method)
+			}
+		}
+	}
+	if len(methods) > 0 {
+		where += " AND le.method IN (?" + strings.Repeat(", ?", len(methods)-1) + ")"
+		for _, method := range methods {
+			args = append(args, method)
+		}
+	}
+
+	if minStr := q.Get("min_amount"); minStr != "" {
+		if minAmount, err := strconv.ParseFloat(minStr, 64); err == nil && minAmount >= 0 {
+			where += " AND le.amount >= ?"
+			args = append(args, minAmount)
+		}
+	}
+
+	if maxStr := q.Get("max_amount"); maxStr != "" {
+		if maxAmount, err := strconv.ParseFloat(maxStr, 64); err == nil && maxAmount >= 0 {
+			where += " AND le.amount <= ?"
+			args = append(args, maxAmount)
+		}
+	}
+
+	if categoryIDStr := q.Get("category_id"); categoryIDStr != "" {
+		if categoryID, err := strconv.Atoi(categoryIDStr); err == nil {
+			where += " AND le.category_id = ?"
+			args = append(args, categoryID)
+		}
+	}
+
+	if tag := strings.ToLower(strings.TrimSpace(q.Get("tag"))); tag != "" {
+		where += " AND EXISTS (SELECT 1 FROM ledger_entry_tags t WHERE t.entry_id = le.id AND t.tag = ?)"
+		args = append(args, tag)
+	}
+
+	if search := strings.TrimSpace(q.Get("q")); search != "" {
+		pattern := "%" + escapeLike(search) + "%"
+		where += " AND (le.note LIKE ? OR c.name LIKE ?)"
+		args = append(args, pattern, pattern)
+	}
+
+	return where, args
+}
+
+// getLedgerTotals aggregates credits, debits and balance over a filtered set
+// of ledger entries built by buildLedgerFilter
+func getLedgerTotals(where string, args []interface{}) (map[string]interface{}, error) {
+	query := `
+		SELECT
+			COUNT(*) as entry_count,
+			COALESCE(SUM(CASE WHEN le.type = 'credit' THEN le.amount ELSE 0 END), 0) as total_credit,
+			COALESCE(SUM(CASE WHEN le.type = 'debit' THEN le.amount ELSE 0 END), 0) as total_debit,
+			COALESCE(SUM(CASE WHEN le.type = 'interest' THEN le.amount ELSE 0 END), 0) as total_interest,
+			COALESCE(SUM(CASE WHEN le.type = 'late_fee' THEN le.amount ELSE 0 END), 0) as total_late_fees,
+			COALESCE(SUM(CASE WHEN le.type = 'write_off' THEN le.amount ELSE 0 END), 0) as total_write_offs,
+			COALESCE(SUM(CASE WHEN le.type = 'discount' THEN le.amount ELSE 0 END), 0) as total_discounts,
+			COALESCE(SUM(CASE WHEN le.type IN ('debit', 'write_off', 'discount') THEN -le.amount ELSE le.amount END), 0) as balance
+		FROM ledger_entries le
+		JOIN customers c ON le.customer_id = c.id
+		WHERE ` + where
+
+	var entryCount int
+	var totalCredit, totalDebit, totalInterest, totalLateFees, totalWriteOffs, totalDiscounts, balance float64
+	err := database.DB.QueryRow(query, args...).Scan(&entryCount, &totalCredit, &totalDebit, &totalInterest, &totalLateFees,
+		&totalWriteOffs, &totalDiscounts, &balance)
+	if err != nil {
+		return nil, err
+	}
+
+	return map[string]interface{}{
+		"count":            entryCount,
+		"total_credit":     totalCredit,
+		"total_debit":      totalDebit,
+		"total_interest":   totalInterest,
+		"total_late_fees":  totalLateFees,
+		"total_write_offs": totalWriteOffs,
+		"total_discounts":  totalDiscounts,
+		"balance":          balance,
+	}, nil
+}
+
+// isLedgerEntryType reports whether t is a type stored in ledger_entries.
+// Interest and late fees are only posted by the accrual job.
+func isLedgerEntryType(t string) bool {
+	switch t {
+	case "credit", "debit", "interest", "late_fee", "write_off", "discount":
+		return true
+	}
+	return false
+}
+
+// isAdjustmentType reports whether t closes part of a balance without any
+// money changing hands
+func isAdjustmentType(t string) bool {
+	return t == "write_off" || t == "discount"
+}
+
+// reducesBalance reports whether an entry of type t lowers what the customer
+// owes. Payments received, write-offs and discounts do; everything else adds.
+func reducesBalance(t string) bool {
+	return t == "debit" || isAdjustmentType(t)
+}
+
+// escapeLike escapes the LIKE wildcards in user supplied search text
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
 // GetLedgerEntry retrieves a specific ledger entry
 func GetLedgerEntry(w http.ResponseWriter, r *http.Request) {
 	// Get user ID from JWT token
@@ -285,18 +676,20 @@ func GetLedgerEntry(w http.ResponseWriter, r *http.Request) {
 
 	// Query the entry
 	var entry models.LedgerEntry
-	var customerName string
-	var note sql.NullString
+	var customerName, partyType string
+	var note, categoryName sql.NullString
+	var categoryID sql.NullInt64
 	var createdAtStr, updatedAtStr, dateStr string
 
 	err = database.DB.QueryRow(`
-		SELECT le.id, le.customer_id, le.type, le.amount, le.method, le.note, le.date, le.created_at, le.updated_at,
-			   c.name as customer_name
+		SELECT le.id, le.customer_id, le.type, le.amount, le.method, le.category_id, le.note, le.date, le.created_at, le.updated_at,
+			   c.name as customer_name, c.party_type, cat.name as category_name
 		FROM ledger_entries le
 		JOIN customers c ON le.customer_id = c.id
+		LEFT JOIN categories cat ON le.category_id = cat.id
 		WHERE le.id = ? AND le.user_id = ?`, entryID, userID).Scan(
-		&entry.ID, &entry.CustomerID, &entry.Type, &entry.Amount, &entry.Method,
-		&note, &dateStr, &createdAtStr, &updatedAtStr, &customerName,
+		&entry.ID, &entry.CustomerID, &entry.Type, &entry.Amount, &entry.Method, &categoryID,
+		&note, &dateStr, &createdAtStr, &updatedAtStr, &customerName, &partyType, &categoryName,
 	)
 
 	if err != nil {
@@ -317,14 +710,38 @@ func GetLedgerEntry(w http.ResponseWriter, r *http.Request) {
 	entry.UpdatedAt, _ = time.Parse("2006-01-02 15:04:05", updatedAtStr)
 	entry.Date, _ = time.Parse("2006-01-02", dateStr)
 
+	entryTags, err := getEntryTags(userID, []int{entry.ID})
+	if err != nil {
+		logger.L.WithField("error", err).Error("Error querying ledger entry tags")
+		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "server_error", "message": "Could not fetch ledger entry"})
+		return
+	}
+	tags := entryTags[entry.ID]
+	if tags == nil {
+		tags = []string{}
+	}
+
+	cheque, err := getChequeDetails(entry.ID)
+	if err != nil {
+		logger.L.WithField("error", err).Error("Error querying cheque details")
+		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "server_error", "message": "Could not fetch ledger entry"})
+		return
+	}
+
 	response := map[string]interface{}{
 		"id":            entry.ID,
 		"customer_id":   entry.CustomerID,
 		"customer_name": customerName,
+		"party_type":    partyType,
 		"type":          entry.Type,
+		"type_label":    entryTypeLabel(partyType, entry.Type),
 		"amount":        entry.Amount,
 		"method":        entry.Method,
+		"category_id":   nullIntPtr(categoryID),
+		"category_name": nullStringPtr(categoryName),
+		"tags":          tags,
 		"note":          entry.Note,
+		"cheque":        cheque,
 		"date":          dateStr,
 		"created_at":    createdAtStr,
 		"updated_at":    updatedAtStr,
@@ -335,3 +752,20 @@ func GetLedgerEntry(w http.ResponseWriter, r *http.Request) {
 		"entry":   response,
 	})
 }
+
+// nullIntPtr converts a nullable column to a pointer for JSON output
+func nullIntPtr(n sql.NullInt64) *int {
+	if !n.Valid {
+		return nil
+	}
+	v := int(n.Int64)
+	return &v
+}
+
+// nullStringPtr converts a nullable column to a pointer for JSON output
+func nullStringPtr(s sql.NullString) *string {
+	if !s.Valid {
+		return nil
+	}
+	return &s.String
+}
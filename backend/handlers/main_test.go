@@ -0,0 +1,12 @@
+package handlers
+
+import "os"
+
+// The package refuses to load without a token signing secret, so tests
+// supply one before init runs; package variables are set up first
+var _ = func() bool {
+	if os.Getenv("JWT_SECRET") == "" {
+		os.Setenv("JWT_SECRET", "test-secret")
+	}
+	return true
+}()
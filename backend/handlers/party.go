@@ -0,0 +1,79 @@
+package handlers
+
+// Parties are the people a business keeps a khata with. Both kinds live in
+// the customers table and share one sign convention: a positive balance is
+// owed to the business and a negative one is owed by it. What changes is the
+// everyday meaning of each entry type, which the labels below spell out.
+const (
+	partyCustomer = "customer"
+	partySupplier = "supplier"
+)
+
+// partyNoun is the capitalised word used in messages about a party
+func partyNoun(partyType string) string {
+	if partyType == partySupplier {
+		return "Supplier"
+	}
+	return "Customer"
+}
+
+// balanceDirection classifies a party balance from the business's side
+func balanceDirection(balance float64) string {
+	switch {
+	case balance > 0:
+		return "receivable"
+	case balance < 0:
+		return "payable"
+	default:
+		return "settled"
+	}
+}
+
+// balanceLabel describes a party balance in words
+func balanceLabel(partyType string, balance float64) string {
+	switch {
+	case balance > 0 && partyType == partySupplier:
+		return "Supplier owes you (advance paid)"
+	case balance > 0:
+		return "Customer owes you"
+	case balance < 0 && partyType == partySupplier:
+		return "You owe the supplier"
+	case balance < 0:
+		return "You owe the customer (advance received)"
+	default:
+		return "Settled"
+	}
+}
+
+// entryTypeLabel describes what an entry type means for the party it was
+// posted to. With a supplier, goods bought on credit are a debit and paying
+// for them is a credit.
+func entryTypeLabel(partyType, entryType string) string {
+	if partyType == partySupplier {
+		switch entryType {
+		case "credit":
+			return "Payment made"
+		case "debit":
+			return "Purchase on credit"
+		case "discount":
+			return "Discount given"
+		case "write_off":
+			return "Written off"
+		}
+	}
+	switch entryType {
+	case "credit":
+		return "Credit given"
+	case "debit":
+		return "Payment received"
+	case "interest":
+		return "Interest charged"
+	case "late_fee":
+		return "Late fee charged"
+	case "discount":
+		return "Discount given"
+	case "write_off":
+		return "Written off"
+	}
+	return entryType
+}
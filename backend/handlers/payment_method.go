@@ -0,0 +1,627 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"khata-book-backend/database"
+	"khata-book-backend/models"
+	"khata-book-backend/pkg/logger"
+
+	"github.com/gorilla/mux"
+)
+
+// builtInPaymentMethods are available to every business without setup
+var builtInPaymentMethods = []models.PaymentMethod{
+	{Code: "cash", Name: "Cash", Kind: "cash", BuiltIn: true, Active: true},
+	{Code: "upi", Name: "UPI", Kind: "upi", BuiltIn: true, Active: true},
+	{Code: "bank", Name: "Bank Transfer", Kind: "bank", BuiltIn: true, Active: true},
+	{Code: "cheque", Name: "Cheque", Kind: "cheque", BuiltIn: true, Active: true},
+	{Code: "card", Name: "Card", Kind: "card", BuiltIn: true, Active: true},
+	{Code: "wallet", Name: "Wallet", Kind: "wallet", BuiltIn: true, Active: true},
+}
+
+// adjustmentMethod is stored as the method of entries that move no money,
+// such as invoices, settlements, write-offs and interest. It is reserved so
+// no real payment method can be mistaken for one.
+const adjustmentMethod = "adjustment"
+
+var paymentMethodKinds = map[string]bool{
+	"cash": true, "upi": true, "bank": true, "cheque": true, "card": true, "wallet": true, "other": true,
+}
+
+var paymentMethodCodeRegex = regexp.MustCompile(`^[a-z0-9_]{1,30}$`)
+
+// GetPaymentMethods lists built-in and custom payment methods for the user
+func GetPaymentMethods(w http.ResponseWriter, r *http.Request) {
+	// Get user ID from JWT token
+	userID, err := getUserIDFromToken(r)
+	if err != nil {
+		writeJSON(w, http.StatusUnauthorized, map[string]interface{}{"success": false, "error": "unauthorized", "message": "Invalid token"})
+		return
+	}
+
+	methods := append([]models.PaymentMethod{}, builtInPaymentMethods...)
+
+	rows, err := database.DB.Query(`
+		SELECT id, code, name, kind, is_active
+		FROM payment_methods
+		WHERE user_id = ?
+		ORDER BY name ASC`, userID)
+	if err != nil {
+		logger.L.WithField("error", err).Error("Error querying payment methods")
+		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "server_error", "message": "Could not fetch payment methods"})
+		return
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var method models.PaymentMethod
+		var id int
+		if err := rows.Scan(&id, &method.Code, &method.Name, &method.Kind, &method.Active); err != nil {
+			logger.L.WithField("error", err).Error("Error scanning payment method")
+			continue
+		}
+		method.ID = &id
+		methods = append(methods, method)
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"success":         true,
+		"payment_methods": methods,
+	})
+}
+
+// CreatePaymentMethod registers a custom payment method for the user
+func CreatePaymentMethod(w http.ResponseWriter, r *http.Request) {
+	// Get user ID from JWT token
+	userID, err := getUserIDFromToken(r)
+	if err != nil {
+		writeJSON(w, http.StatusUnauthorized, map[string]interface{}{"success": false, "error": "unauthorized", "message": "Invalid token"})
+		return
+	}
+
+	var methodReq models.PaymentMethodRequest
+	err = json.NewDecoder(r.Body).Decode(&methodReq)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"success": false, "error": "invalid_request", "message": "Invalid request body"})
+		return
+	}
+
+	methodReq.Name = strings.TrimSpace(methodReq.Name)
+	code := paymentMethodCode(methodReq.Name)
+	if methodReq.Name == "" || len(methodReq.Name) > 100 || code == "" {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"success": false, "error": "invalid_name", "message": "Payment method name is required and must contain letters or digits"})
+		return
+	}
+
+	if methodReq.Kind == "" {
+		methodReq.Kind = "other"
+	}
+	if !paymentMethodKinds[methodReq.Kind] {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"success": false, "error": "invalid_kind", "message": "Kind must be 'cash', 'upi', 'bank', 'cheque', 'card', 'wallet', or 'other'"})
+		return
+	}
+
+	if code == adjustmentMethod {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"success": false, "error": "invalid_name", "message": "'Adjustment' is reserved for entries that move no money"})
+		return
+	}
+
+	for _, builtIn := range builtInPaymentMethods {
+		if builtIn.Code == code {
+			writeJSON(w, http.StatusConflict, map[string]interface{}{"success": false, "error": "method_exists", "message": "A payment method with this name already exists"})
+			return
+		}
+	}
+
+	active := true
+	if methodReq.Active != nil {
+		active = *methodReq.Active
+	}
+
+	result, err := database.DB.Exec(`
+		INSERT INTO payment_methods (code, name, kind, is_active, user_id)
+		VALUES (?, ?, ?, ?, ?)`,
+		code, methodReq.Name, methodReq.Kind, active, userID)
+	if err != nil {
+		if strings.Contains(err.Error(), "Duplicate entry") {
+			writeJSON(w, http.StatusConflict, map[string]interface{}{"success": false, "error": "method_exists", "message": "A payment method with this name already exists"})
+			return
+		}
+		logger.L.WithField("error", err).Error("Error inserting payment method")
+		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "server_error", "message": "Could not create payment method"})
+		return
+	}
+
+	methodID, err := result.LastInsertId()
+	if err != nil {
+		logger.L.WithField("error", err).Error("Error getting inserted payment method ID")
+		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "server_error", "message": "Could not create payment method"})
+		return
+	}
+
+	logger.L.WithFields(map[string]interface{}{
+		"method_id": methodID,
+		"user_id":   userID,
+		"code":      code,
+	}).Info("Payment method created successfully")
+
+	id := int(methodID)
+	method := models.PaymentMethod{
+		ID:     &id,
+		Code:   code,
+		Name:   methodReq.Name,
+		Kind:   methodReq.Kind,
+		Active: active,
+	}
+
+	writeJSON(w, http.StatusCreated, map[string]interface{}{
+		"success":        true,
+		"message":        "Payment method created successfully",
+		"payment_method": method,
+	})
+}
+
+// UpdatePaymentMethod renames or (de)activates a custom payment method. The
+// code stays fixed so existing entries keep pointing at it.
+func UpdatePaymentMethod(w http.ResponseWriter, r *http.Request) {
+	// Get user ID from JWT token
+	userID, err := getUserIDFromToken(r)
+	if err != nil {
+		writeJSON(w, http.StatusUnauthorized, map[string]interface{}{"success": false, "error": "unauthorized", "message": "Invalid token"})
+		return
+	}
+
+	methodID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"success": false, "error": "invalid_id", "message": "Invalid payment method ID"})
+		return
+	}
+
+	var methodReq models.PaymentMethodRequest
+	err = json.NewDecoder(r.Body).Decode(&methodReq)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"success": false, "error": "invalid_request", "message": "Invalid request body"})
+		return
+	}
+
+	// Build update query dynamically
+	setParts := []string{}
+	args := []interface{}{}
+
+	if name := strings.TrimSpace(methodReq.Name); name != "" && len(name) <= 100 {
+		setParts = append(setParts, "name = ?")
+		args = append(args, name)
+	}
+
+	if paymentMethodKinds[methodReq.Kind] {
+		setParts = append(setParts, "kind = ?")
+		args = append(args, methodReq.Kind)
+	}
+
+	if methodReq.Active != nil {
+		setParts = append(setParts, "is_active = ?")
+		args = append(args, *methodReq.Active)
+	}
+
+	if len(setParts) == 0 {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"success": false, "error": "no_updates", "message": "No valid fields to update"})
+		return
+	}
+
+	setParts = append(setParts, "updated_at = CURRENT_TIMESTAMP")
+	query := "UPDATE payment_methods SET " + strings.Join(setParts, ", ") + " WHERE id = ? AND user_id = ?"
+	args = append(args, methodID, userID)
+
+	result, err := database.DB.Exec(query, args...)
+	if err != nil {
+		logger.L.WithField("error", err).Error("Error updating payment method")
+		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "server_error", "message": "Could not update payment method"})
+		return
+	}
+
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		var id int
+		err := database.DB.QueryRow("SELECT id FROM payment_methods WHERE id = ? AND user_id = ?", methodID, userID).Scan(&id)
+		if err == sql.ErrNoRows {
+			writeJSON(w, http.StatusNotFound, map[string]interface{}{"success": false, "error": "not_found", "message": "Payment method not found"})
+			return
+		}
+	}
+
+	logger.L.WithFields(map[string]interface{}{
+		"method_id": methodID,
+		"user_id":   userID,
+	}).Info("Payment method updated successfully")
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"message": "Payment method updated successfully",
+	})
+}
+
+// DeletePaymentMethod removes an unused custom payment method. Methods that
+// already appear on entries can only be deactivated.
+func DeletePaymentMethod(w http.ResponseWriter, r *http.Request) {
+	// Get user ID from JWT token
+	userID, err := getUserIDFromToken(r)
+	if err != nil {
+		writeJSON(w, http.StatusUnauthorized, map[string]interface{}{"success": false, "error": "unauthorized", "message": "Invalid token"})
+		return
+	}
+
+	methodID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"success": false, "error": "invalid_id", "message": "Invalid payment method ID"})
+		return
+	}
+
+	var code string
+	err = database.DB.QueryRow("SELECT code FROM payment_methods WHERE id = ? AND user_id = ?", methodID, userID).Scan(&code)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			writeJSON(w, http.StatusNotFound, map[string]interface{}{"success": false, "error": "not_found", "message": "Payment method not found"})
+			return
+		}
+		logger.L.WithField("error", err).Error("Error querying payment method")
+		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "server_error", "message": "Could not delete payment method"})
+		return
+	}
+
+	var usage int
+	err = database.DB.QueryRow("SELECT COUNT(*) FROM ledger_entries WHERE user_id = ? AND method = ?", userID, code).Scan(&usage)
+	if err != nil {
+		logger.L.WithField("error", err).Error("Error counting payment method usage")
+		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "server_error", "message": "Could not delete payment method"})
+		return
+	}
+	if usage > 0 {
+		writeJSON(w, http.StatusConflict, map[string]interface{}{"success": false, "error": "method_in_use", "message": "Payment method is used by existing entries; deactivate it instead"})
+		return
+	}
+
+	_, err = database.DB.Exec("DELETE FROM payment_methods WHERE id = ?", methodID)
+	if err != nil {
+		logger.L.WithField("error", err).Error("Error deleting payment method")
+		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "server_error", "message": "Could not delete payment method"})
+		return
+	}
+
+	logger.L.WithFields(map[string]interface{}{
+		"method_id": methodID,
+		"user_id":   userID,
+	}).Info("Payment method deleted successfully")
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"message": "Payment method deleted successfully",
+	})
+}
+
+// UpdateLedgerCheque records that a cheque cleared or bounced. A bounce posts
+// a reversing entry so the customer's balance goes back to what it was, and
+// releases any invoices the cheque was allocated to.
+func UpdateLedgerCheque(w http.ResponseWriter, r *http.Request) {
+	// Get user ID from JWT token
+	userID, err := getUserIDFromToken(r)
+	if err != nil {
+		writeJSON(w, http.StatusUnauthorized, map[string]interface{}{"success": false, "error": "unauthorized", "message": "Invalid token"})
+		return
+	}
+
+	entryID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"success": false, "error": "invalid_id", "message": "Invalid entry ID"})
+		return
+	}
+
+	var chequeReq models.ChequeRequest
+	err = json.NewDecoder(r.Body).Decode(&chequeReq)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"success": false, "error": "invalid_request", "message": "Invalid request body"})
+		return
+	}
+
+	if chequeReq.Status != "" && chequeReq.Status != "pending" && chequeReq.Status != "cleared" && chequeReq.Status != "bounced" {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"success": false, "error": "invalid_status", "message": "Status must be 'pending', 'cleared', or 'bounced'"})
+		return
+	}
+
+	tx, err := database.DB.Begin()
+	if err != nil {
+		logger.L.WithField("error", err).Error("Could not start transaction")
+		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "server_error", "message": "Could not process request"})
+		return
+	}
+	defer tx.Rollback()
+
+	// Lock the cheque row so two bounce requests cannot both post a reversal
+	var entry models.LedgerEntry
+	var cheque models.Cheque
+	var entryDate string
+	var bankName, clearingDate sql.NullString
+	var bounceEntryID sql.NullInt64
+	err = tx.QueryRow(`
+		SELECT le.id, le.customer_id, le.type, le.amount, le.method, le.date,
+			   cd.cheque_number, cd.bank_name, cd.clearing_date, cd.status, cd.bounce_entry_id
+		FROM cheque_details cd
+		JOIN ledger_entries le ON cd.entry_id = le.id
+		WHERE cd.entry_id = ? AND le.user_id = ?
+		FOR UPDATE`, entryID, userID).Scan(
+		&entry.ID, &entry.CustomerID, &entry.Type, &entry.Amount, &entry.Method, &entryDate,
+		&cheque.ChequeNumber, &bankName, &clearingDate, &cheque.Status, &bounceEntryID,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			writeJSON(w, http.StatusNotFound, map[string]interface{}{"success": false, "error": "not_found", "message": "Cheque entry not found"})
+			return
+		}
+		logger.L.WithField("error", err).Error("Error querying cheque details")
+		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "server_error", "message": "Could not update cheque"})
+		return
+	}
+
+	if cheque.Status == "bounced" && chequeReq.Status != "" && chequeReq.Status != "bounced" {
+		writeJSON(w, http.StatusConflict, map[string]interface{}{"success": false, "error": "cheque_bounced", "message": "A bounced cheque cannot be reopened; record a new entry instead"})
+		return
+	}
+
+	cheque.EntryID = entryID
+	cheque.BankName = nullStringPtr(bankName)
+	cheque.BounceEntryID = nullIntPtr(bounceEntryID)
+	if clearingDate.Valid {
+		if parsed, err := time.Parse("2006-01-02", clearingDate.String); err == nil {
+			cheque.ClearingDate = &parsed
+		}
+	}
+	entry.Date, _ = time.Parse("2006-01-02", entryDate)
+	oldCashDate := chequeCashDate(cheque.Status, cheque.ClearingDate, entry.Date)
+
+	newStatus := cheque.Status
+	if chequeReq.Status != "" {
+		newStatus = chequeReq.Status
+	}
+	if chequeReq.ClearingDate != nil {
+		cheque.ClearingDate = chequeReq.ClearingDate
+	} else if newStatus == "cleared" && cheque.Status != "cleared" {
+		// A cheque marked cleared without a date cleared today
+		today := calendarDate(time.Now())
+		cheque.ClearingDate = &today
+	}
+	if chequeReq.BankName != nil {
+		cheque.BankName = chequeReq.BankName
+	}
+
+	// The days a cleared cheque's cash leaves or lands on must still be open
+	if newCashDate := chequeCashDate(newStatus, cheque.ClearingDate, entry.Date); !newCashDate.Equal(oldCashDate) {
+		if !oldCashDate.IsZero() && !checkDayOpenTx(w, tx, userID, oldCashDate) {
+			return
+		}
+		if !newCashDate.IsZero() && !checkDayOpenTx(w, tx, userID, newCashDate) {
+			return
+		}
+	}
+
+	if chequeReq.Status == "bounced" && cheque.Status != "bounced" {
+		// The reversal is dated today, so today must still be open
+		if !checkDayOpenTx(w, tx, userID, time.Now()) {
+			return
+		}
+		reversalType := "debit"
+		if entry.Type == "debit" {
+			reversalType = "credit"
+		}
+		note := fmt.Sprintf("Cheque %s bounced", cheque.ChequeNumber)
+		reversal := models.LedgerEntry{
+			CustomerID: entry.CustomerID,
+			Type:       reversalType,
+			Amount:     entry.Amount,
+			Method:     entry.Method,
+			Note:       &note,
+			Date:       time.Now(),
+			UserID:     userID,
+		}
+		if err := postLedgerEntry(tx, &reversal); err != nil {
+			logger.L.WithField("error", err).Error("Error posting cheque bounce reversal")
+			writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "server_error", "message": "Could not update cheque"})
+			return
+		}
+		cheque.BounceEntryID = &reversal.ID
+
+		// The money never arrived, so the invoices it paid are open again
+		if err := releaseAllocations(tx, entry.ID); err != nil {
+			logger.L.WithField("error", err).Error("Error releasing invoice allocations")
+			writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "server_error", "message": "Could not update cheque"})
+			return
+		}
+	}
+	if chequeReq.Status != "" {
+		cheque.Status = chequeReq.Status
+	}
+
+	var clearingDateArg interface{}
+	if cheque.ClearingDate != nil {
+		clearingDateArg = cheque.ClearingDate.Format("2006-01-02")
+	}
+	_, err = tx.Exec(`
+		UPDATE cheque_details
+		SET bank_name = ?, clearing_date = ?, status = ?, bounce_entry_id = ?, updated_at = CURRENT_TIMESTAMP
+		WHERE entry_id = ?`,
+		cheque.BankName, clearingDateArg, cheque.Status, cheque.BounceEntryID, entryID)
+	if err != nil {
+		logger.L.WithField("error", err).Error("Error updating cheque details")
+		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "server_error", "message": "Could not update cheque"})
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		logger.L.WithField("error", err).Error("Error committing transaction")
+		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "server_error", "message": "Could not update cheque"})
+		return
+	}
+
+	logger.L.WithFields(map[string]interface{}{
+		"entry_id": entryID,
+		"user_id":  userID,
+		"status":   cheque.Status,
+	}).Info("Cheque updated successfully")
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"message": "Cheque updated successfully",
+		"cheque":  cheque,
+	})
+}
+
+// chequeCashDate returns the day a cheque counts as cash in cashMovements:
+// its clearing date, or the entry's date if none was recorded. It is zero
+// unless the cheque has cleared.
+func chequeCashDate(status string, clearingDate *time.Time, entryDate time.Time) time.Time {
+	if status != "cleared" {
+		return time.Time{}
+	}
+	if clearingDate != nil {
+		return calendarDate(*clearingDate)
+	}
+	return entryDate
+}
+
+// lookupPaymentMethod resolves a method code to a built-in or an active custom
+// method of the user. It returns sql.ErrNoRows for unknown codes.
+func lookupPaymentMethod(userID int, code string) (*models.PaymentMethod, error) {
+	for _, builtIn := range builtInPaymentMethods {
+		if builtIn.Code == code {
+			method := builtIn
+			return &method, nil
+		}
+	}
+
+	// A custom method saved under the reserved code before it was reserved
+	// is not used for new entries
+	if !paymentMethodCodeRegex.MatchString(code) || code == adjustmentMethod {
+		return nil, sql.ErrNoRows
+	}
+
+	var method models.PaymentMethod
+	var id int
+	err := database.DB.QueryRow(`
+		SELECT id, code, name, kind, is_active
+		FROM payment_methods
+		WHERE user_id = ? AND code = ? AND is_active = TRUE`, userID, code).Scan(
+		&id, &method.Code, &method.Name, &method.Kind, &method.Active,
+	)
+	if err != nil {
+		return nil, err
+	}
+	method.ID = &id
+	return &method, nil
+}
+
+// getPaymentMethodRegistry returns every method the user has ever had,
+// including inactive custom ones, keyed by code
+func getPaymentMethodRegistry(userID int) (map[string]models.PaymentMethod, error) {
+	registry := map[string]models.PaymentMethod{}
+	for _, builtIn := range builtInPaymentMethods {
+		registry[builtIn.Code] = builtIn
+	}
+
+	rows, err := database.DB.Query(`
+		SELECT id, code, name, kind, is_active FROM payment_methods WHERE user_id = ?`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var method models.PaymentMethod
+		var id int
+		if err := rows.Scan(&id, &method.Code, &method.Name, &method.Kind, &method.Active); err != nil {
+			return nil, err
+		}
+		method.ID = &id
+		registry[method.Code] = method
+	}
+	return registry, rows.Err()
+}
+
+// getChequeDetails loads the cheque fields of an entry, or nil when the entry
+// was not paid by cheque
+func getChequeDetails(entryID int) (*models.Cheque, error) {
+	var cheque models.Cheque
+	var bankName, clearingDate sql.NullString
+	var bounceEntryID sql.NullInt64
+	err := database.DB.QueryRow(`
+		SELECT entry_id, cheque_number, bank_name, clearing_date, status, bounce_entry_id
+		FROM cheque_details WHERE entry_id = ?`, entryID).Scan(
+		&cheque.EntryID, &cheque.ChequeNumber, &bankName, &clearingDate, &cheque.Status, &bounceEntryID,
+	)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	cheque.BankName = nullStringPtr(bankName)
+	cheque.BounceEntryID = nullIntPtr(bounceEntryID)
+	if clearingDate.Valid {
+		if parsed, err := time.Parse("2006-01-02", clearingDate.String); err == nil {
+			cheque.ClearingDate = &parsed
+		}
+	}
+	return &cheque, nil
+}
+
+// paymentMethodCode derives a stable lowercase code from a display name,
+// e.g. "Paytm Wallet" becomes "paytm_wallet"
+func paymentMethodCode(name string) string {
+	var b strings.Builder
+	underscore := false
+	for _, c := range strings.ToLower(name) {
+		switch {
+		case (c >= 'a' && c <= 'z') || (c >= '0' && c <= '9'):
+			b.WriteRune(c)
+			underscore = false
+		case b.Len() > 0 && !underscore:
+			b.WriteByte('_')
+			underscore = true
+		}
+	}
+	code := strings.TrimSuffix(b.String(), "_")
+	if len(code) > 30 {
+		code = strings.TrimSuffix(code[:30], "_")
+	}
+	return code
+}
+
+// insertChequeDetails stores the cheque fields of a newly posted entry
+func insertChequeDetails(tx *sql.Tx, entryID, userID int, req *models.ChequeRequest) (*models.Cheque, error) {
+	cheque := models.Cheque{
+		EntryID:      entryID,
+		ChequeNumber: strings.TrimSpace(req.ChequeNumber),
+		BankName:     req.BankName,
+		ClearingDate: req.ClearingDate,
+		Status:       "pending",
+	}
+
+	var clearingDateArg interface{}
+	if cheque.ClearingDate != nil {
+		clearingDateArg = cheque.ClearingDate.Format("2006-01-02")
+	}
+
+	_, err := tx.Exec(`
+		INSERT INTO cheque_details (entry_id, cheque_number, bank_name, clearing_date, status, user_id)
+		VALUES (?, ?, ?, ?, 'pending', ?)`,
+		entryID, cheque.ChequeNumber, cheque.BankName, clearingDateArg, userID)
+	if err != nil {
+		return nil, err
+	}
+	return &cheque, nil
+}
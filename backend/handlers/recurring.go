@@ -0,0 +1,866 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"khata-book-backend/database"
+	"khata-book-backend/models"
+	"khata-book-backend/pkg/logger"
+	"khata-book-backend/pkg/rrule"
+
+	"github.com/gorilla/mux"
+)
+
+// maxCatchUpOccurrences bounds how many missed occurrences of one template a
+// single scheduler pass will post
+const maxCatchUpOccurrences = 400
+
+// GetRecurringEntries lists the user's recurring entry templates
+func GetRecurringEntries(w http.ResponseWriter, r *http.Request) {
+	// Get user ID from JWT token
+	userID, err := getUserIDFromToken(r)
+	if err != nil {
+		writeJSON(w, http.StatusUnauthorized, map[string]interface{}{"success": false, "error": "unauthorized", "message": "Invalid token"})
+		return
+	}
+
+	// Parse query parameters
+	status := r.URL.Query().Get("status")
+	customerIDStr := r.URL.Query().Get("customer_id")
+
+	query := recurringEntrySelect + " WHERE re.user_id = ?"
+	args := []interface{}{userID}
+
+	if status == "active" || status == "paused" || status == "ended" {
+		query += " AND re.status = ?"
+		args = append(args, status)
+	}
+
+	if customerIDStr != "" {
+		if customerID, err := strconv.Atoi(customerIDStr); err == nil {
+			query += " AND re.customer_id = ?"
+			args = append(args, customerID)
+		}
+	}
+
+	query += " ORDER BY re.next_run_date IS NULL, re.next_run_date ASC, re.id ASC"
+
+	rows, err := database.DB.Query(query, args...)
+	if err != nil {
+		logger.L.WithField("error", err).Error("Error querying recurring entries")
+		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "server_error", "message": "Could not fetch recurring entries"})
+		return
+	}
+	defer rows.Close()
+
+	recurring := []models.RecurringEntry{}
+	for rows.Next() {
+		entry, err := scanRecurringEntry(rows)
+		if err != nil {
+			logger.L.WithField("error", err).Error("Error scanning recurring entry")
+			continue
+		}
+		recurring = append(recurring, *entry)
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"success":           true,
+		"recurring_entries": recurring,
+		"count":             len(recurring),
+	})
+}
+
+// CreateRecurringEntry creates a recurring entry template
+func CreateRecurringEntry(w http.ResponseWriter, r *http.Request) {
+	// Get user ID from JWT token
+	userID, err := getUserIDFromToken(r)
+	if err != nil {
+		writeJSON(w, http.StatusUnauthorized, map[string]interface{}{"success": false, "error": "unauthorized", "message": "Invalid token"})
+		return
+	}
+
+	var recurringReq models.RecurringEntryRequest
+	err = json.NewDecoder(r.Body).Decode(&recurringReq)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"success": false, "error": "invalid_request", "message": "Invalid request body"})
+		return
+	}
+
+	// Validate required fields
+	if recurringReq.CustomerID <= 0 {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"success": false, "error": "invalid_customer", "message": "Valid customer ID is required"})
+		return
+	}
+
+	if recurringReq.Type != "credit" && recurringReq.Type != "debit" {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"success": false, "error": "invalid_type", "message": "Type must be 'credit' or 'debit'"})
+		return
+	}
+
+	if recurringReq.Amount <= 0 {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"success": false, "error": "invalid_amount", "message": "Amount must be greater than 0"})
+		return
+	}
+
+	rule, err := rrule.Parse(recurringReq.RRule)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"success": false, "error": "invalid_rrule", "message": "Invalid schedule: " + err.Error()})
+		return
+	}
+
+	if !checkRecurringMethod(w, userID, recurringReq.Method) {
+		return
+	}
+
+	// Verify customer exists and belongs to user
+	var customerUserID int
+	err = database.DB.QueryRow("SELECT user_id FROM customers WHERE id = ?", recurringReq.CustomerID).Scan(&customerUserID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			writeJSON(w, http.StatusNotFound, map[string]interface{}{"success": false, "error": "customer_not_found", "message": "Customer not found"})
+			return
+		}
+		logger.L.WithField("error", err).Error("Error checking customer ownership")
+		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "server_error", "message": "Could not verify customer"})
+		return
+	}
+
+	if customerUserID != userID {
+		writeJSON(w, http.StatusForbidden, map[string]interface{}{"success": false, "error": "forbidden", "message": "Customer does not belong to this user"})
+		return
+	}
+
+	if recurringReq.CategoryID != nil && !checkCategoryOwnership(w, *recurringReq.CategoryID, userID) {
+		return
+	}
+
+	// Set default start date if not provided
+	startDate := calendarDate(time.Now())
+	if !recurringReq.StartDate.IsZero() {
+		startDate = calendarDate(recurringReq.StartDate)
+	}
+
+	var endDate *time.Time
+	if recurringReq.EndDate != nil {
+		end := calendarDate(*recurringReq.EndDate)
+		if end.Before(startDate) {
+			writeJSON(w, http.StatusBadRequest, map[string]interface{}{"success": false, "error": "invalid_end_date", "message": "End date must not be before the start date"})
+			return
+		}
+		endDate = &end
+	}
+
+	recurring := models.RecurringEntry{
+		CustomerID: recurringReq.CustomerID,
+		Type:       recurringReq.Type,
+		Amount:     recurringReq.Amount,
+		Method:     recurringReq.Method,
+		CategoryID: recurringReq.CategoryID,
+		Note:       recurringReq.Note,
+		RRule:      recurringReq.RRule,
+		StartDate:  startDate,
+		EndDate:    endDate,
+		Status:     "active",
+		UserID:     userID,
+	}
+
+	// The first occurrence may be the start date itself
+	if next, ok := rule.OnOrAfter(startDate, startDate); ok && (endDate == nil || !next.After(*endDate)) {
+		recurring.NextRunDate = &next
+	} else {
+		recurring.Status = "ended"
+	}
+
+	result, err := database.DB.Exec(`
+		INSERT INTO recurring_entries (customer_id, type, amount, method, category_id, note, rrule, start_date, end_date, next_run_date, status, user_id)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		recurring.CustomerID, recurring.Type, recurring.Amount, recurring.Method, recurring.CategoryID,
+		recurring.Note, recurring.RRule, recurring.StartDate.Format("2006-01-02"), dateArg(recurring.EndDate),
+		dateArg(recurring.NextRunDate), recurring.Status, userID)
+	if err != nil {
+		logger.L.WithField("error", err).Error("Error inserting recurring entry")
+		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "server_error", "message": "Could not create recurring entry"})
+		return
+	}
+
+	recurringID, err := result.LastInsertId()
+	if err != nil {
+		logger.L.WithField("error", err).Error("Error getting inserted recurring entry ID")
+		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "server_error", "message": "Could not create recurring entry"})
+		return
+	}
+
+	recurring.ID = int(recurringID)
+	recurring.CreatedAt = time.Now()
+	recurring.UpdatedAt = recurring.CreatedAt
+
+	logger.L.WithFields(map[string]interface{}{
+		"recurring_id": recurringID,
+		"user_id":      userID,
+		"rrule":        recurring.RRule,
+	}).Info("Recurring entry created successfully")
+
+	writeJSON(w, http.StatusCreated, map[string]interface{}{
+		"success":         true,
+		"message":         "Recurring entry created successfully",
+		"recurring_entry": recurring,
+	})
+}
+
+// GetRecurringEntry returns a template with its recent runs and the next few
+// scheduled dates
+func GetRecurringEntry(w http.ResponseWriter, r *http.Request) {
+	// Get user ID from JWT token
+	userID, err := getUserIDFromToken(r)
+	if err != nil {
+		writeJSON(w, http.StatusUnauthorized, map[string]interface{}{"success": false, "error": "unauthorized", "message": "Invalid token"})
+		return
+	}
+
+	recurringID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"success": false, "error": "invalid_id", "message": "Invalid recurring entry ID"})
+		return
+	}
+
+	recurring, err := getRecurringEntry(database.DB, recurringID, userID, false)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			writeJSON(w, http.StatusNotFound, map[string]interface{}{"success": false, "error": "not_found", "message": "Recurring entry not found"})
+			return
+		}
+		logger.L.WithField("error", err).Error("Error querying recurring entry")
+		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "server_error", "message": "Could not fetch recurring entry"})
+		return
+	}
+
+	rows, err := database.DB.Query(`
+		SELECT occurrence_date, entry_id, status, created_at
+		FROM recurring_entry_runs
+		WHERE recurring_id = ?
+		ORDER BY occurrence_date DESC
+		LIMIT 24`, recurringID)
+	if err != nil {
+		logger.L.WithField("error", err).Error("Error querying recurring entry runs")
+		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "server_error", "message": "Could not fetch recurring entry"})
+		return
+	}
+	defer rows.Close()
+
+	runs := []models.RecurringRun{}
+	for rows.Next() {
+		var run models.RecurringRun
+		var entryID sql.NullInt64
+		if err := rows.Scan(&run.OccurrenceDate, &entryID, &run.Status, &run.CreatedAt); err != nil {
+			logger.L.WithField("error", err).Error("Error scanning recurring entry run")
+			continue
+		}
+		run.EntryID = nullIntPtr(entryID)
+		runs = append(runs, run)
+	}
+
+	upcoming := []string{}
+	if rule, err := rrule.Parse(recurring.RRule); err == nil && recurring.NextRunDate != nil && recurring.Status != "ended" {
+		next := *recurring.NextRunDate
+		for i := 0; i < 5; i++ {
+			if recurring.EndDate != nil && next.After(*recurring.EndDate) {
+				break
+			}
+			upcoming = append(upcoming, next.Format("2006-01-02"))
+			var ok bool
+			if next, ok = rule.After(recurring.StartDate, next); !ok {
+				break
+			}
+		}
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"success":         true,
+		"recurring_entry": recurring,
+		"runs":            runs,
+		"upcoming":        upcoming,
+	})
+}
+
+// UpdateRecurringEntry changes the amount, note, method, schedule or end date
+// of a template. Occurrences already posted are not touched.
+func UpdateRecurringEntry(w http.ResponseWriter, r *http.Request) {
+	// Get user ID from JWT token
+	userID, err := getUserIDFromToken(r)
+	if err != nil {
+		writeJSON(w, http.StatusUnauthorized, map[string]interface{}{"success": false, "error": "unauthorized", "message": "Invalid token"})
+		return
+	}
+
+	recurringID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"success": false, "error": "invalid_id", "message": "Invalid recurring entry ID"})
+		return
+	}
+
+	var updateReq map[string]interface{}
+	err = json.NewDecoder(r.Body).Decode(&updateReq)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"success": false, "error": "invalid_request", "message": "Invalid request body"})
+		return
+	}
+
+	tx, err := database.DB.Begin()
+	if err != nil {
+		logger.L.WithField("error", err).Error("Could not start transaction")
+		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "server_error", "message": "Could not process request"})
+		return
+	}
+	defer tx.Rollback()
+
+	recurring, err := getRecurringEntry(tx, recurringID, userID, true)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			writeJSON(w, http.StatusNotFound, map[string]interface{}{"success": false, "error": "not_found", "message": "Recurring entry not found"})
+			return
+		}
+		logger.L.WithField("error", err).Error("Error querying recurring entry")
+		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "server_error", "message": "Could not update recurring entry"})
+		return
+	}
+
+	updated := false
+	reschedule := false
+
+	if amount, ok := updateReq["amount"].(float64); ok && amount > 0 {
+		recurring.Amount = amount
+		updated = true
+	}
+
+	if note, ok := updateReq["note"].(string); ok {
+		recurring.Note = &note
+		updated = true
+	}
+
+	if method, ok := updateReq["method"].(string); ok {
+		if !checkRecurringMethod(w, userID, method) {
+			return
+		}
+		recurring.Method = method
+		updated = true
+	}
+
+	if ruleStr, ok := updateReq["rrule"].(string); ok {
+		if _, err := rrule.Parse(ruleStr); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]interface{}{"success": false, "error": "invalid_rrule", "message": "Invalid schedule: " + err.Error()})
+			return
+		}
+		recurring.RRule = ruleStr
+		updated = true
+		reschedule = true
+	}
+
+	if endDateValue, ok := updateReq["end_date"]; ok {
+		if endDateValue == nil {
+			recurring.EndDate = nil
+		} else if endDateStr, ok := endDateValue.(string); ok {
+			end, err := time.Parse("2006-01-02", endDateStr)
+			if err != nil || end.Before(recurring.StartDate) {
+				writeJSON(w, http.StatusBadRequest, map[string]interface{}{"success": false, "error": "invalid_end_date", "message": "End date must be YYYY-MM-DD and not before the start date"})
+				return
+			}
+			recurring.EndDate = &end
+		}
+		updated = true
+		reschedule = true
+	}
+
+	if !updated {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"success": false, "error": "no_updates", "message": "No valid fields to update"})
+		return
+	}
+
+	if reschedule && recurring.Status != "paused" {
+		recurring.Status = "active"
+		if err := rescheduleRecurring(recurring, calendarDate(time.Now())); err != nil {
+			logger.L.WithField("error", err).Error("Error rescheduling recurring entry")
+			writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "server_error", "message": "Could not update recurring entry"})
+			return
+		}
+	}
+
+	if err := saveRecurringEntry(tx, recurring); err != nil {
+		logger.L.WithField("error", err).Error("Error updating recurring entry")
+		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "server_error", "message": "Could not update recurring entry"})
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		logger.L.WithField("error", err).Error("Error committing transaction")
+		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "server_error", "message": "Could not update recurring entry"})
+		return
+	}
+
+	logger.L.WithFields(map[string]interface{}{
+		"recurring_id": recurringID,
+		"user_id":      userID,
+	}).Info("Recurring entry updated successfully")
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"success":         true,
+		"message":         "Recurring entry updated successfully",
+		"recurring_entry": recurring,
+	})
+}
+
+// DeleteRecurringEntry deletes a template. Entries it already posted stay in
+// the ledger.
+func DeleteRecurringEntry(w http.ResponseWriter, r *http.Request) {
+	// Get user ID from JWT token
+	userID, err := getUserIDFromToken(r)
+	if err != nil {
+		writeJSON(w, http.StatusUnauthorized, map[string]interface{}{"success": false, "error": "unauthorized", "message": "Invalid token"})
+		return
+	}
+
+	recurringID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"success": false, "error": "invalid_id", "message": "Invalid recurring entry ID"})
+		return
+	}
+
+	result, err := database.DB.Exec("DELETE FROM recurring_entries WHERE id = ? AND user_id = ?", recurringID, userID)
+	if err != nil {
+		logger.L.WithField("error", err).Error("Error deleting recurring entry")
+		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "server_error", "message": "Could not delete recurring entry"})
+		return
+	}
+
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		writeJSON(w, http.StatusNotFound, map[string]interface{}{"success": false, "error": "not_found", "message": "Recurring entry not found"})
+		return
+	}
+
+	logger.L.WithFields(map[string]interface{}{
+		"recurring_id": recurringID,
+		"user_id":      userID,
+	}).Info("Recurring entry deleted successfully")
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"message": "Recurring entry deleted successfully",
+	})
+}
+
+// PauseRecurringEntry stops a template from posting until it is resumed
+func PauseRecurringEntry(w http.ResponseWriter, r *http.Request) {
+	changeRecurringEntry(w, r, "pause", func(tx *sql.Tx, recurring *models.RecurringEntry) (int, string) {
+		if recurring.Status != "active" {
+			return http.StatusConflict, "Only active recurring entries can be paused"
+		}
+		recurring.Status = "paused"
+		return 0, ""
+	})
+}
+
+// ResumeRecurringEntry restarts a paused template from today. Occurrences
+// that fell inside the pause are not back-filled.
+func ResumeRecurringEntry(w http.ResponseWriter, r *http.Request) {
+	changeRecurringEntry(w, r, "resume", func(tx *sql.Tx, recurring *models.RecurringEntry) (int, string) {
+		if recurring.Status != "paused" {
+			return http.StatusConflict, "Only paused recurring entries can be resumed"
+		}
+		recurring.Status = "active"
+		if err := rescheduleRecurring(recurring, calendarDate(time.Now())); err != nil {
+			logger.L.WithField("error", err).Error("Error rescheduling recurring entry")
+			return http.StatusInternalServerError, "Could not resume recurring entry"
+		}
+		return 0, ""
+	})
+}
+
+// SkipRecurringEntry skips the next scheduled occurrence
+func SkipRecurringEntry(w http.ResponseWriter, r *http.Request) {
+	changeRecurringEntry(w, r, "skip", func(tx *sql.Tx, recurring *models.RecurringEntry) (int, string) {
+		if recurring.Status == "ended" || recurring.NextRunDate == nil {
+			return http.StatusConflict, "Recurring entry has no upcoming occurrence"
+		}
+		_, err := tx.Exec(`
+			INSERT IGNORE INTO recurring_entry_runs (recurring_id, occurrence_date, status)
+			VALUES (?, ?, 'skipped')`,
+			recurring.ID, recurring.NextRunDate.Format("2006-01-02"))
+		if err != nil {
+			logger.L.WithField("error", err).Error("Error recording skipped occurrence")
+			return http.StatusInternalServerError, "Could not skip occurrence"
+		}
+		if err := advanceRecurring(tx, recurring, *recurring.NextRunDate); err != nil {
+			logger.L.WithField("error", err).Error("Error advancing recurring entry")
+			return http.StatusInternalServerError, "Could not skip occurrence"
+		}
+		return 0, ""
+	})
+}
+
+// changeRecurringEntry loads and locks a template, applies change and saves
+// it. change returns a non-zero status and message to reject the request.
+func changeRecurringEntry(w http.ResponseWriter, r *http.Request, action string, change func(tx *sql.Tx, recurring *models.RecurringEntry) (int, string)) {
+	// Get user ID from JWT token
+	userID, err := getUserIDFromToken(r)
+	if err != nil {
+		writeJSON(w, http.StatusUnauthorized, map[string]interface{}{"success": false, "error": "unauthorized", "message": "Invalid token"})
+		return
+	}
+
+	recurringID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"success": false, "error": "invalid_id", "message": "Invalid recurring entry ID"})
+		return
+	}
+
+	tx, err := database.DB.Begin()
+	if err != nil {
+		logger.L.WithField("error", err).Error("Could not start transaction")
+		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "server_error", "message": "Could not process request"})
+		return
+	}
+	defer tx.Rollback()
+
+	recurring, err := getRecurringEntry(tx, recurringID, userID, true)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			writeJSON(w, http.StatusNotFound, map[string]interface{}{"success": false, "error": "not_found", "message": "Recurring entry not found"})
+			return
+		}
+		logger.L.WithField("error", err).Error("Error querying recurring entry")
+		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "server_error", "message": "Could not update recurring entry"})
+		return
+	}
+
+	if status, message := change(tx, recurring); status != 0 {
+		errorCode := "invalid_state"
+		if status == http.StatusInternalServerError {
+			errorCode = "server_error"
+		}
+		writeJSON(w, status, map[string]interface{}{"success": false, "error": errorCode, "message": message})
+		return
+	}
+
+	if err := saveRecurringEntry(tx, recurring); err != nil {
+		logger.L.WithField("error", err).Error("Error updating recurring entry")
+		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "server_error", "message": "Could not update recurring entry"})
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		logger.L.WithField("error", err).Error("Error committing transaction")
+		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "server_error", "message": "Could not update recurring entry"})
+		return
+	}
+
+	logger.L.WithFields(map[string]interface{}{
+		"recurring_id": recurringID,
+		"user_id":      userID,
+		"action":       action,
+	}).Info("Recurring entry updated successfully")
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"success":         true,
+		"message":         "Recurring entry updated successfully",
+		"recurring_entry": recurring,
+	})
+}
+
+// runDueRecurringEntries posts every occurrence that is due on or before
+// today, catching up on occurrences missed while the server was down
+func runDueRecurringEntries(now time.Time) error {
+	today := calendarDate(now)
+
+	rows, err := database.DB.Query(`
+		SELECT id FROM recurring_entries
+		WHERE status = 'active' AND next_run_date <= ?`, today.Format("2006-01-02"))
+	if err != nil {
+		return err
+	}
+
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return err
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, id := range ids {
+		for i := 0; i < maxCatchUpOccurrences; i++ {
+			posted, err := postRecurringOccurrence(id, today)
+			if err != nil {
+				logger.L.WithFields(map[string]interface{}{"recurring_id": id, "error": err}).Error("Error posting recurring entry")
+				break
+			}
+			if !posted {
+				break
+			}
+		}
+	}
+
+	return nil
+}
+
+// postRecurringOccurrence posts the template's next occurrence if it is due,
+// in one transaction with the run record and the schedule advance. The run
+// table's primary key makes a second attempt at the same occurrence a no-op,
+// so overlapping scheduler passes cannot double post.
+func postRecurringOccurrence(recurringID int, today time.Time) (bool, error) {
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	var userID int
+	if err := tx.QueryRow("SELECT user_id FROM recurring_entries WHERE id = ?", recurringID).Scan(&userID); err != nil {
+		if err == sql.ErrNoRows {
+			return false, nil
+		}
+		return false, err
+	}
+
+	recurring, err := getRecurringEntry(tx, recurringID, userID, true)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return false, nil
+		}
+		return false, err
+	}
+	if recurring.Status != "active" || recurring.NextRunDate == nil || recurring.NextRunDate.After(today) {
+		return false, nil
+	}
+	occurrence := *recurring.NextRunDate
+
+	// An occurrence on a day that has since been closed is posted today
+	// instead, so the closed day's cash stays as counted. When today is
+	// closed too it waits for a later run.
+	entryDate := occurrence
+	locked, err := dayLockedTx(tx, recurring.UserID, occurrence)
+	if err != nil {
+		return false, err
+	}
+	if locked {
+		entryDate = today
+		if locked, err = dayLockedTx(tx, recurring.UserID, today); err != nil || locked {
+			return false, err
+		}
+	}
+
+	result, err := tx.Exec(`
+		INSERT IGNORE INTO recurring_entry_runs (recurring_id, occurrence_date, status)
+		VALUES (?, ?, 'posted')`,
+		recurringID, occurrence.Format("2006-01-02"))
+	if err != nil {
+		return false, err
+	}
+
+	// A zero row count means this occurrence was already handled; only the
+	// schedule needs to move on
+	if affected, _ := result.RowsAffected(); affected > 0 {
+		entry := models.LedgerEntry{
+			CustomerID: recurring.CustomerID,
+			Type:       recurring.Type,
+			Amount:     recurring.Amount,
+			Method:     recurring.Method,
+			CategoryID: recurring.CategoryID,
+			Note:       recurring.Note,
+			Date:       entryDate,
+			UserID:     recurring.UserID,
+		}
+		if err := postLedgerEntry(tx, &entry); err != nil {
+			return false, err
+		}
+		if reducesBalance(entry.Type) {
+			if _, _, err := reconcileReminders(tx, entry.CustomerID, entry.UserID, "recurring entry "+strconv.Itoa(entry.ID), false); err != nil {
+				return false, err
+			}
+		}
+
+		_, err = tx.Exec(`
+			UPDATE recurring_entry_runs SET entry_id = ?
+			WHERE recurring_id = ? AND occurrence_date = ?`,
+			entry.ID, recurringID, occurrence.Format("2006-01-02"))
+		if err != nil {
+			return false, err
+		}
+
+		logger.L.WithFields(map[string]interface{}{
+			"recurring_id": recurringID,
+			"entry_id":     entry.ID,
+			"occurrence":   occurrence.Format("2006-01-02"),
+			"date":         entryDate.Format("2006-01-02"),
+		}).Info("Recurring entry posted")
+	}
+
+	if err := advanceRecurring(tx, recurring, occurrence); err != nil {
+		return false, err
+	}
+	if err := saveRecurringEntry(tx, recurring); err != nil {
+		return false, err
+	}
+
+	return true, tx.Commit()
+}
+
+// advanceRecurring moves next_run_date past occurrence, ending the template
+// when its end date or COUNT has been reached
+func advanceRecurring(tx *sql.Tx, recurring *models.RecurringEntry, occurrence time.Time) error {
+	rule, err := rrule.Parse(recurring.RRule)
+	if err != nil {
+		return err
+	}
+
+	if rule.Count > 0 {
+		var used int
+		err := tx.QueryRow("SELECT COUNT(*) FROM recurring_entry_runs WHERE recurring_id = ?", recurring.ID).Scan(&used)
+		if err != nil {
+			return err
+		}
+		if used >= rule.Count {
+			recurring.Status = "ended"
+			recurring.NextRunDate = nil
+			return nil
+		}
+	}
+
+	next, ok := rule.After(recurring.StartDate, occurrence)
+	if !ok || (recurring.EndDate != nil && next.After(*recurring.EndDate)) {
+		recurring.Status = "ended"
+		recurring.NextRunDate = nil
+		return nil
+	}
+	recurring.NextRunDate = &next
+	return nil
+}
+
+// rescheduleRecurring points next_run_date at the first occurrence on or after
+// from, or ends the template when none is left
+func rescheduleRecurring(recurring *models.RecurringEntry, from time.Time) error {
+	rule, err := rrule.Parse(recurring.RRule)
+	if err != nil {
+		return err
+	}
+
+	if from.Before(recurring.StartDate) {
+		from = recurring.StartDate
+	}
+	next, ok := rule.OnOrAfter(recurring.StartDate, from)
+	if !ok || (recurring.EndDate != nil && next.After(*recurring.EndDate)) {
+		recurring.Status = "ended"
+		recurring.NextRunDate = nil
+		return nil
+	}
+	recurring.NextRunDate = &next
+	return nil
+}
+
+const recurringEntrySelect = `
+	SELECT re.id, re.customer_id, re.type, re.amount, re.method, re.category_id, re.note, re.rrule,
+		   re.start_date, re.end_date, re.next_run_date, re.status, re.user_id, re.created_at, re.updated_at
+	FROM recurring_entries re`
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+type queryRower interface {
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// getRecurringEntry loads a template owned by userID, locking the row when
+// forUpdate is set and q is a transaction
+func getRecurringEntry(q queryRower, recurringID, userID int, forUpdate bool) (*models.RecurringEntry, error) {
+	query := recurringEntrySelect + " WHERE re.id = ? AND re.user_id = ?"
+	if forUpdate {
+		query += " FOR UPDATE"
+	}
+	return scanRecurringEntry(q.QueryRow(query, recurringID, userID))
+}
+
+func scanRecurringEntry(row rowScanner) (*models.RecurringEntry, error) {
+	var recurring models.RecurringEntry
+	var categoryID sql.NullInt64
+	var note, endDateStr, nextRunDateStr sql.NullString
+	var startDateStr, createdAtStr, updatedAtStr string
+
+	err := row.Scan(
+		&recurring.ID, &recurring.CustomerID, &recurring.Type, &recurring.Amount, &recurring.Method,
+		&categoryID, &note, &recurring.RRule, &startDateStr, &endDateStr, &nextRunDateStr,
+		&recurring.Status, &recurring.UserID, &createdAtStr, &updatedAtStr,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	recurring.CategoryID = nullIntPtr(categoryID)
+	recurring.Note = nullStringPtr(note)
+	recurring.StartDate, _ = time.Parse("2006-01-02", startDateStr)
+	recurring.EndDate = parseNullDate(endDateStr)
+	recurring.NextRunDate = parseNullDate(nextRunDateStr)
+	recurring.CreatedAt, _ = time.Parse("2006-01-02 15:04:05", createdAtStr)
+	recurring.UpdatedAt, _ = time.Parse("2006-01-02 15:04:05", updatedAtStr)
+
+	return &recurring, nil
+}
+
+// saveRecurringEntry writes back the mutable fields of a template
+func saveRecurringEntry(tx *sql.Tx, recurring *models.RecurringEntry) error {
+	_, err := tx.Exec(`
+		UPDATE recurring_entries
+		SET amount = ?, method = ?, note = ?, rrule = ?, end_date = ?, next_run_date = ?, status = ?, updated_at = CURRENT_TIMESTAMP
+		WHERE id = ?`,
+		recurring.Amount, recurring.Method, recurring.Note, recurring.RRule,
+		dateArg(recurring.EndDate), dateArg(recurring.NextRunDate), recurring.Status, recurring.ID)
+	return err
+}
+
+// checkRecurringMethod validates a payment method for recurring posting.
+// Cheques need a fresh cheque number each time, so they cannot recur.
+func checkRecurringMethod(w http.ResponseWriter, userID int, code string) bool {
+	method, err := lookupPaymentMethod(userID, code)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			writeJSON(w, http.StatusBadRequest, map[string]interface{}{"success": false, "error": "invalid_method", "message": "Method must be one of your active payment methods"})
+			return false
+		}
+		logger.L.WithField("error", err).Error("Error looking up payment method")
+		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "server_error", "message": "Could not verify payment method"})
+		return false
+	}
+	if method.Kind == "cheque" {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"success": false, "error": "invalid_method", "message": "Cheque payments cannot recur"})
+		return false
+	}
+	return true
+}
+
+// dateArg formats an optional date for a DATE column
+func dateArg(t *time.Time) interface{} {
+	if t == nil {
+		return nil
+	}
+	return t.Format("2006-01-02")
+}
+
+// parseNullDate parses an optional DATE column
+func parseNullDate(s sql.NullString) *time.Time {
+	if !s.Valid {
+		return nil
+	}
+	t, err := time.Parse("2006-01-02", s.String)
+	if err != nil {
+		return nil
+	}
+	return &t
+}
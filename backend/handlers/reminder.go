@@ -23,29 +23,26 @@ func GetReminders(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Parse query parameters
-	status := r.URL.Query().Get("status")
-	customerIDStr := r.URL.Query().Get("customer_id")
+	var filter models.ReminderFilter
+	filter.Status = r.URL.Query().Get("status")
+	if customerIDStr := r.URL.Query().Get("customer_id"); customerIDStr != "" {
+		filter.CustomerID, _ = strconv.Atoi(customerIDStr)
+	}
 
 	// Build query
 	query := `
-		SELECT r.id, r.customer_id, r.due_amount, r.due_date, r.channel, r.status, r.created_at, r.updated_at,
+		SELECT r.id, r.customer_id, r.due_amount, r.due_date, r.channel, r.template_id, r.rule_id, r.status, r.snoozed_until,
+			   r.attempts, r.next_attempt_at, r.last_error, r.sent_at, r.delivery_status, r.delivery_status_at,
+			   (SELECT MAX(e.created_at) FROM reminder_events e
+				WHERE e.reminder_id = r.id AND e.event IN ('sent', 'attempt_failed', 'failed')) AS last_attempt_at,
+			   r.created_at, r.updated_at,
 			   c.name as customer_name
 		FROM reminders r
 		JOIN customers c ON r.customer_id = c.id
 		WHERE r.user_id = ?`
-	args := []interface{}{userID}
-
-	if status != "" && (status == "pending" || status == "sent" || status == "snoozed" || status == "paid") {
-		query += " AND r.status = ?"
-		args = append(args, status)
-	}
-
-	if customerIDStr != "" {
-		if customerID, err := strconv.Atoi(customerIDStr); err == nil {
-			query += " AND r.customer_id = ?"
-			args = append(args, customerID)
-		}
-	}
+	where, args := reminderFilterClause(filter)
+	query += where
+	args = append([]interface{}{userID}, args...)
 
 	query += " ORDER BY r.due_date ASC, r.created_at DESC"
 
@@ -62,10 +59,14 @@ func GetReminders(w http.ResponseWriter, r *http.Request) {
 		var reminder models.Reminder
 		var customerName string
 		var createdAtStr, updatedAtStr, dueDateStr string
+		var templateID, ruleID sql.NullInt64
+		var snoozedUntil, nextAttemptAt, lastError, sentAt, deliveryStatus, deliveryStatusAt, lastAttemptAt sql.NullString
 
 		err := rows.Scan(
 			&reminder.ID, &reminder.CustomerID, &reminder.DueAmount, &dueDateStr,
-			&reminder.Channel, &reminder.Status, &createdAtStr, &updatedAtStr, &customerName,
+			&reminder.Channel, &templateID, &ruleID, &reminder.Status, &snoozedUntil, &reminder.Attempts, &nextAttemptAt, &lastError, &sentAt,
+			&deliveryStatus, &deliveryStatusAt, &lastAttemptAt,
+			&createdAtStr, &updatedAtStr, &customerName,
 		)
 		if err != nil {
 			logger.L.WithField("error", err).Error("Error scanning reminder")
@@ -77,15 +78,25 @@ func GetReminders(w http.ResponseWriter, r *http.Request) {
 		reminder.DueDate, _ = time.Parse("2006-01-02", dueDateStr)
 
 		reminderMap := map[string]interface{}{
-			"id":            reminder.ID,
-			"customer_id":   reminder.CustomerID,
-			"customer_name": customerName,
-			"due_amount":    reminder.DueAmount,
-			"due_date":      dueDateStr,
-			"channel":       reminder.Channel,
-			"status":        reminder.Status,
-			"created_at":    createdAtStr,
-			"updated_at":    updatedAtStr,
+			"id":                 reminder.ID,
+			"customer_id":        reminder.CustomerID,
+			"customer_name":      customerName,
+			"due_amount":         reminder.DueAmount,
+			"due_date":           dueDateStr,
+			"channel":            reminder.Channel,
+			"template_id":        nullIntPtr(templateID),
+			"rule_id":            nullIntPtr(ruleID),
+			"status":             reminder.Status,
+			"snoozed_until":      nullStringPtr(snoozedUntil),
+			"attempts":           reminder.Attempts,
+			"next_attempt_at":    nullStringPtr(nextAttemptAt),
+			"last_error":         nullStringPtr(lastError),
+			"sent_at":            nullStringPtr(sentAt),
+			"delivery_status":    nullStringPtr(deliveryStatus),
+			"delivery_status_at": nullStringPtr(deliveryStatusAt),
+			"last_attempt_at":    nullStringPtr(lastAttemptAt),
+			"created_at":         createdAtStr,
+			"updated_at":         updatedAtStr,
 		}
 		reminders = append(reminders, reminderMap)
 	}
@@ -131,7 +142,8 @@ func CreateReminder(w http.ResponseWriter, r *http.Request) {
 
 	// Verify customer exists and belongs to user
 	var customerUserID int
-	err = database.DB.QueryRow("SELECT user_id FROM customers WHERE id = ?", reminderReq.CustomerID).Scan(&customerUserID)
+	var partyType string
+	err = database.DB.QueryRow("SELECT user_id, party_type FROM customers WHERE id = ?", reminderReq.CustomerID).Scan(&customerUserID, &partyType)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			writeJSON(w, http.StatusNotFound, map[string]interface{}{"success": false, "error": "customer_not_found", "message": "Customer not found"})
@@ -147,12 +159,34 @@ func CreateReminder(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Reminders are worded as a customer's dues and closed by customer payments
+	if partyType != partyCustomer {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"success": false, "error": "invalid_party", "message": "Reminders can only be sent to customers"})
+		return
+	}
+
+	if reminderReq.TemplateID != nil && !checkReminderTemplate(w, *reminderReq.TemplateID, userID, reminderReq.Channel) {
+		return
+	}
+
+	if !checkCustomerConsent(w, reminderReq.CustomerID, reminderReq.Channel) {
+		return
+	}
+
+	tx, err := database.DB.Begin()
+	if err != nil {
+		logger.L.WithField("error", err).Error("Error starting transaction")
+		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "server_error", "message": "Could not create reminder"})
+		return
+	}
+	defer tx.Rollback()
+
 	// Insert reminder
-	result, err := database.DB.Exec(`
-		INSERT INTO reminders (customer_id, due_amount, due_date, channel, status, user_id)
-		VALUES (?, ?, ?, ?, 'pending', ?)`,
+	result, err := tx.Exec(`
+		INSERT INTO reminders (customer_id, due_amount, due_date, channel, template_id, status, user_id)
+		VALUES (?, ?, ?, ?, ?, 'pending', ?)`,
 		reminderReq.CustomerID, reminderReq.DueAmount, reminderReq.DueDate.Format("2006-01-02"),
-		reminderReq.Channel, userID)
+		reminderReq.Channel, reminderReq.TemplateID, userID)
 	if err != nil {
 		logger.L.WithField("error", err).Error("Error inserting reminder")
 		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "server_error", "message": "Could not create reminder"})
@@ -166,6 +200,22 @@ func CreateReminder(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	err = recordReminderEvent(tx, reminderEvent{
+		ReminderID: int(reminderID),
+		UserID:     userID,
+		Event:      reminderEventCreated,
+		ToStatus:   "pending",
+		Channel:    reminderReq.Channel,
+	})
+	if err == nil {
+		err = tx.Commit()
+	}
+	if err != nil {
+		logger.L.WithField("error", err).Error("Error recording reminder creation")
+		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "server_error", "message": "Could not create reminder"})
+		return
+	}
+
 	logger.L.WithFields(map[string]interface{}{
 		"reminder_id": reminderID,
 		"user_id":     userID,
@@ -180,6 +230,7 @@ func CreateReminder(w http.ResponseWriter, r *http.Request) {
 		DueAmount:  reminderReq.DueAmount,
 		DueDate:    reminderReq.DueDate,
 		Channel:    reminderReq.Channel,
+		TemplateID: reminderReq.TemplateID,
 		Status:     "pending",
 		UserID:     userID,
 		CreatedAt:  time.Now(),
@@ -218,8 +269,11 @@ func UpdateReminder(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Verify reminder exists and belongs to user
-	var reminderUserID int
-	err = database.DB.QueryRow("SELECT user_id FROM reminders WHERE id = ?", reminderID).Scan(&reminderUserID)
+	var reminderUserID, reminderCustomerID int
+	var reminderChannel, oldStatus string
+	var reminderTemplateID sql.NullInt64
+	err = database.DB.QueryRow("SELECT user_id, customer_id, channel, template_id, status FROM reminders WHERE id = ?", reminderID).Scan(
+		&reminderUserID, &reminderCustomerID, &reminderChannel, &reminderTemplateID, &oldStatus)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			writeJSON(w, http.StatusNotFound, map[string]interface{}{"success": false, "error": "not_found", "message": "Reminder not found"})
@@ -246,22 +300,58 @@ func UpdateReminder(w http.ResponseWriter, r *http.Request) {
 
 	if dueDateStr, ok := updateReq["due_date"].(string); ok {
 		if dueDate, err := time.Parse("2006-01-02", dueDateStr); err == nil {
-			setParts = append(setParts, "due_date = ?")
-			args = append(args, dueDate.Format("2006-01-02"))
+			// A send deferred until the old due date is rescheduled
+			setParts = append(setParts, "due_date = ?", requeueReminderSchedule)
+			args = append(append(args, dueDate.Format("2006-01-02")), reminderLeaseWindow()...)
 		}
 	}
 
 	if channel, ok := updateReq["channel"].(string); ok {
 		if channel == "sms" || channel == "whatsapp" || channel == "email" {
+			if channel != reminderChannel && !checkCustomerConsent(w, reminderCustomerID, channel) {
+				return
+			}
 			setParts = append(setParts, "channel = ?")
 			args = append(args, channel)
+			reminderChannel = channel
 		}
 	}
 
+	// template_id null goes back to the default template. A kept template
+	// must still suit the reminder's channel if that changes.
+	if rawTemplateID, ok := updateReq["template_id"]; ok {
+		reminderTemplateID = sql.NullInt64{}
+		if templateID, ok := rawTemplateID.(float64); ok {
+			reminderTemplateID = sql.NullInt64{Int64: int64(templateID), Valid: true}
+		}
+		setParts = append(setParts, "template_id = ?")
+		args = append(args, nullIntPtr(reminderTemplateID))
+	}
+	if reminderTemplateID.Valid && !checkReminderTemplate(w, int(reminderTemplateID.Int64), userID, reminderChannel) {
+		return
+	}
+
+	// Only the dispatcher marks a reminder sent or failed, and snoozing
+	// needs a wake-up time. Moving one back to pending queues it for delivery
+	// again from scratch, after any send already in progress.
+	newStatus := ""
 	if status, ok := updateReq["status"].(string); ok {
-		if status == "pending" || status == "sent" || status == "snoozed" || status == "paid" {
-			setParts = append(setParts, "status = ?")
+		if status == "sent" || status == "failed" {
+			writeJSON(w, http.StatusBadRequest, map[string]interface{}{"success": false, "error": "invalid_status", "message": "Reminders are marked sent or failed by delivery; set 'pending' to send again"})
+			return
+		}
+		if status == "snoozed" {
+			writeJSON(w, http.StatusBadRequest, map[string]interface{}{"success": false, "error": "invalid_status", "message": "Use POST /api/reminders/{id}/snooze to snooze a reminder until a given time"})
+			return
+		}
+		if status == "pending" || status == "paid" {
+			setParts = append(setParts, "status = ?", "snoozed_until = NULL", "send_now = FALSE")
 			args = append(args, status)
+			newStatus = status
+		}
+		if status == "pending" {
+			setParts = append(setParts, "attempts = 0", requeueReminderSchedule, "last_error = NULL")
+			args = append(args, reminderLeaseWindow()...)
 		}
 	}
 
@@ -274,7 +364,28 @@ func UpdateReminder(w http.ResponseWriter, r *http.Request) {
 	query := "UPDATE reminders SET " + strings.Join(setParts, ", ") + " WHERE id = ?"
 	args = append(args, reminderID)
 
-	_, err = database.DB.Exec(query, args...)
+	tx, err := database.DB.Begin()
+	if err != nil {
+		logger.L.WithField("error", err).Error("Error starting transaction")
+		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "server_error", "message": "Could not update reminder"})
+		return
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(query, args...)
+	if err == nil && newStatus != "" && newStatus != oldStatus {
+		err = recordReminderEvent(tx, reminderEvent{
+			ReminderID: reminderID,
+			UserID:     userID,
+			Event:      reminderEventStatusChanged,
+			FromStatus: oldStatus,
+			ToStatus:   newStatus,
+			Channel:    reminderChannel,
+		})
+	}
+	if err == nil {
+		err = tx.Commit()
+	}
 	if err != nil {
 		logger.L.WithField("error", err).Error("Error updating reminder")
 		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "server_error", "message": "Could not update reminder"})
@@ -292,6 +403,26 @@ func UpdateReminder(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// reminderFilterClause turns the reminder list filters into conditions on
+// the reminders table aliased r. Unknown statuses are ignored.
+func reminderFilterClause(filter models.ReminderFilter) (string, []interface{}) {
+	where := ""
+	args := []interface{}{}
+
+	switch filter.Status {
+	case "pending", "sent", "snoozed", "paid", "failed":
+		where += " AND r.status = ?"
+		args = append(args, filter.Status)
+	}
+
+	if filter.CustomerID > 0 {
+		where += " AND r.customer_id = ?"
+		args = append(args, filter.CustomerID)
+	}
+
+	return where, args
+}
+
 // DeleteReminder deletes a reminder
 func DeleteReminder(w http.ResponseWriter, r *http.Request) {
 	// Get user ID from JWT token
@@ -0,0 +1,201 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"khata-book-backend/database"
+	"khata-book-backend/models"
+	"khata-book-backend/pkg/logger"
+)
+
+// maxBulkReminders caps the reminders one bulk action may touch
+const maxBulkReminders = 500
+
+// BulkReminderAction sends, snoozes, marks paid or deletes many reminders in
+// one transaction. Reminders the action does not apply to are skipped and
+// reported; the rest are changed together or not at all.
+func BulkReminderAction(w http.ResponseWriter, r *http.Request) {
+	// Get user ID from JWT token
+	userID, err := getUserIDFromToken(r)
+	if err != nil {
+		writeJSON(w, http.StatusUnauthorized, map[string]interface{}{"success": false, "error": "unauthorized", "message": "Invalid token"})
+		return
+	}
+
+	var bulkReq models.ReminderBulkRequest
+	err = json.NewDecoder(r.Body).Decode(&bulkReq)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"success": false, "error": "invalid_request", "message": "Invalid request body"})
+		return
+	}
+
+	switch bulkReq.Action {
+	case "send", "snooze", "mark_paid", "delete":
+	default:
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"success": false, "error": "invalid_action", "message": "Action must be 'send', 'snooze', 'mark_paid' or 'delete'"})
+		return
+	}
+
+	if (len(bulkReq.IDs) > 0) == (bulkReq.Filter != nil) {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"success": false, "error": "invalid_selection", "message": "Select reminders either by ids or by filter"})
+		return
+	}
+	if len(bulkReq.IDs) > maxBulkReminders {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"success": false, "error": "too_many_reminders", "message": "At most 500 reminders can be changed at once"})
+		return
+	}
+
+	now := time.Now()
+	var snoozeUntil string
+	if bulkReq.Action == "snooze" {
+		until, err := parseReminderSnooze(models.ReminderSnoozeRequest{Duration: bulkReq.Duration, Until: bulkReq.Until}, now)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]interface{}{"success": false, "error": "invalid_snooze", "message": err.Error()})
+			return
+		}
+		snoozeUntil = until.Format("2006-01-02 15:04:05")
+	}
+
+	tx, err := database.DB.Begin()
+	if err != nil {
+		logger.L.WithField("error", err).Error("Error starting transaction")
+		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "server_error", "message": "Could not update reminders"})
+		return
+	}
+	defer tx.Rollback()
+
+	// Lock the selected reminders; one more than the cap is read to tell
+	// whether a filter matched too many
+	query := "SELECT r.id, r.status, r.channel FROM reminders r WHERE r.user_id = ?"
+	args := []interface{}{userID}
+	if bulkReq.Filter != nil {
+		where, filterArgs := reminderFilterClause(*bulkReq.Filter)
+		query += where
+		args = append(args, filterArgs...)
+	} else {
+		query += " AND r.id IN (?" + strings.Repeat(", ?", len(bulkReq.IDs)-1) + ")"
+		for _, id := range bulkReq.IDs {
+			args = append(args, id)
+		}
+	}
+	query += " ORDER BY r.id ASC LIMIT ? FOR UPDATE"
+	args = append(args, maxBulkReminders+1)
+
+	rows, err := tx.Query(query, args...)
+	if err != nil {
+		logger.L.WithField("error", err).Error("Error selecting reminders for bulk action")
+		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "server_error", "message": "Could not update reminders"})
+		return
+	}
+
+	type selectedReminder struct {
+		id      int
+		status  string
+		channel string
+	}
+	var selected []selectedReminder
+	found := map[int]bool{}
+	for rows.Next() {
+		var rem selectedReminder
+		if err := rows.Scan(&rem.id, &rem.status, &rem.channel); err != nil {
+			rows.Close()
+			logger.L.WithField("error", err).Error("Error scanning reminder for bulk action")
+			writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "server_error", "message": "Could not update reminders"})
+			return
+		}
+		selected = append(selected, rem)
+		found[rem.id] = true
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		logger.L.WithField("error", err).Error("Error reading reminders for bulk action")
+		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "server_error", "message": "Could not update reminders"})
+		return
+	}
+
+	if len(selected) > maxBulkReminders {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"success": false, "error": "too_many_reminders", "message": "The filter matches more than 500 reminders; narrow it down"})
+		return
+	}
+
+	results := []models.ReminderBulkResult{}
+	for _, id := range bulkReq.IDs {
+		if !found[id] {
+			results = append(results, models.ReminderBulkResult{ID: id, Error: "not_found", Message: "Reminder not found"})
+			found[id] = true
+		}
+	}
+
+	applied := 0
+	for _, rem := range selected {
+		result := models.ReminderBulkResult{ID: rem.id}
+		event := reminderEvent{ReminderID: rem.id, UserID: userID, FromStatus: rem.status, Channel: rem.channel}
+
+		switch {
+		case bulkReq.Action == "delete":
+			_, err = tx.Exec("DELETE FROM reminders WHERE id = ?", rem.id)
+		case rem.status == "paid":
+			result.Status, result.Error, result.Message = rem.status, "reminder_paid", "Reminder is already paid"
+		case bulkReq.Action == "send":
+			_, err = tx.Exec(`
+				UPDATE reminders
+				SET status = 'pending', send_now = TRUE, snoozed_until = NULL, attempts = 0, `+requeueReminderSchedule+`,
+					last_error = NULL, updated_at = CURRENT_TIMESTAMP
+				WHERE id = ?`, append(reminderLeaseWindow(), rem.id)...)
+			result.Status = "pending"
+			event.Event, event.ToStatus, event.Detail = reminderEventStatusChanged, "pending", "Queued to send now by bulk action"
+		case bulkReq.Action == "snooze":
+			_, err = tx.Exec(`
+				UPDATE reminders
+				SET status = 'snoozed', snoozed_until = ?, send_now = FALSE, next_attempt_at = NULL, updated_at = CURRENT_TIMESTAMP
+				WHERE id = ?`, snoozeUntil, rem.id)
+			result.Status = "snoozed"
+			event.Event, event.ToStatus, event.Detail = reminderEventSnoozed, "snoozed", "Snoozed until "+snoozeUntil+" by bulk action"
+		case bulkReq.Action == "mark_paid":
+			_, err = tx.Exec(`
+				UPDATE reminders
+				SET status = 'paid', snoozed_until = NULL, send_now = FALSE, next_attempt_at = NULL, updated_at = CURRENT_TIMESTAMP
+				WHERE id = ?`, rem.id)
+			result.Status = "paid"
+			event.Event, event.ToStatus, event.Detail = reminderEventStatusChanged, "paid", "Marked paid by bulk action"
+		}
+		if err == nil && event.Event != "" {
+			err = recordReminderEvent(tx, event)
+		}
+		if err != nil {
+			logger.L.WithFields(map[string]interface{}{"reminder_id": rem.id, "error": err}).Error("Error applying bulk reminder action")
+			writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "server_error", "message": "Could not update reminders"})
+			return
+		}
+
+		if result.Error == "" {
+			result.Success = true
+			applied++
+		}
+		results = append(results, result)
+	}
+
+	if err := tx.Commit(); err != nil {
+		logger.L.WithField("error", err).Error("Error committing bulk reminder action")
+		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "server_error", "message": "Could not update reminders"})
+		return
+	}
+
+	logger.L.WithFields(map[string]interface{}{
+		"user_id": userID,
+		"action":  bulkReq.Action,
+		"applied": applied,
+		"skipped": len(results) - applied,
+	}).Info("Bulk reminder action applied")
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"action":  bulkReq.Action,
+		"applied": applied,
+		"skipped": len(results) - applied,
+		"results": results,
+	})
+}
@@ -0,0 +1,379 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"khata-book-backend/database"
+	"khata-book-backend/models"
+	"khata-book-backend/pkg/logger"
+	"khata-book-backend/pkg/notify"
+)
+
+const (
+	// maxReminderAttempts is how often delivery is tried before a reminder
+	// is marked failed
+	maxReminderAttempts = 6
+	// reminderBatchSize caps the reminders handled in one dispatcher run
+	reminderBatchSize = 100
+	// reminderLease keeps other dispatcher instances off a reminder while it
+	// is being sent
+	reminderLease = 5 * time.Minute
+	// reminderSendTimeout bounds a single provider call
+	reminderSendTimeout = 30 * time.Second
+)
+
+// reminderBackoff returns the wait before the next delivery attempt: one
+// minute after the first failure, doubling up to an hour
+func reminderBackoff(attempts int) time.Duration {
+	backoff := time.Minute
+	for i := 1; i < attempts && backoff < time.Hour; i++ {
+		backoff *= 2
+	}
+	if backoff > time.Hour {
+		backoff = time.Hour
+	}
+	return backoff
+}
+
+// requeueReminderSchedule is the SET clause that clears a reminder's retry
+// time so the next dispatcher run picks it up. A time that may still be a
+// dispatcher's lease is kept: releasing it while the send is in progress
+// would let a second dispatcher send the reminder again. Its arguments come
+// from reminderLeaseWindow.
+const requeueReminderSchedule = "next_attempt_at = IF(next_attempt_at > ? AND next_attempt_at <= ?, next_attempt_at, NULL)"
+
+// reminderLeaseWindow returns the bounds within which a reminder's
+// next_attempt_at may be a lease taken by dispatchReminder
+func reminderLeaseWindow() []interface{} {
+	now := time.Now()
+	return []interface{}{now.Format("2006-01-02 15:04:05"), now.Add(reminderLease).Format("2006-01-02 15:04:05")}
+}
+
+// reminderDelivery is a due reminder with what is needed to word and
+// address it
+type reminderDelivery struct {
+	ID               int
+	UserID           int
+	CustomerID       int
+	Channel          string
+	TemplateID       sql.NullInt64
+	DueAmount        float64
+	DueDate          string
+	SendNow          bool
+	Attempts         int
+	CustomerName     string
+	CustomerPhone    sql.NullString
+	CustomerEmail    sql.NullString
+	CustomerLanguage sql.NullString
+	CustomerTimezone sql.NullString
+	BusinessName     sql.NullString
+	BusinessUPIID    sql.NullString
+	BusinessTimezone sql.NullString
+	QuietHoursStart  sql.NullString
+	QuietHoursEnd    sql.NullString
+	OptedOut         bool
+}
+
+// runReminderDispatch wakes snoozed reminders whose time has come, then
+// sends pending reminders whose due date has arrived, or that were asked to
+// go out now, and whose retry time, if any, has passed
+func runReminderDispatch(now time.Time) error {
+	if err := wakeSnoozedReminders(now); err != nil {
+		return err
+	}
+
+	// Due dates are in each business's time zone, so candidates are taken up
+	// to the date in the earliest zone, UTC+14, and those not yet due are
+	// deferred by dispatchReminder
+	rows, err := database.DB.Query(`
+		SELECT id FROM reminders
+		WHERE status = 'pending' AND (due_date <= ? OR send_now = TRUE) AND (next_attempt_at IS NULL OR next_attempt_at <= ?)
+		ORDER BY due_date ASC, id ASC
+		LIMIT ?`,
+		calendarDate(now.UTC().Add(14*time.Hour)).Format("2006-01-02"), now.Format("2006-01-02 15:04:05"), reminderBatchSize)
+	if err != nil {
+		return err
+	}
+
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return err
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, id := range ids {
+		if err := dispatchReminder(id, now); err != nil {
+			logger.L.WithFields(map[string]interface{}{"reminder_id": id, "error": err}).Error("Error dispatching reminder")
+		}
+	}
+	return nil
+}
+
+// dispatchReminder claims one reminder, sends it and records the outcome.
+// A reminder another dispatcher already claimed is skipped.
+func dispatchReminder(reminderID int, now time.Time) error {
+	// The lease runs from the claim rather than from the start of the run, so
+	// reminders late in a slow batch are not left with a lease already spent
+	result, err := database.DB.Exec(`
+		UPDATE reminders SET next_attempt_at = ?
+		WHERE id = ? AND status = 'pending' AND (next_attempt_at IS NULL OR next_attempt_at <= ?)`,
+		time.Now().Add(reminderLease).Format("2006-01-02 15:04:05"), reminderID, now.Format("2006-01-02 15:04:05"))
+	if err != nil {
+		return err
+	}
+	if claimed, err := result.RowsAffected(); err != nil || claimed == 0 {
+		return err
+	}
+
+	delivery, err := loadReminderDelivery(reminderID)
+	if err != nil {
+		return err
+	}
+
+	// A customer who opted out of the channel is not contacted on it again
+	if delivery.OptedOut {
+		return markReminderAttemptFailed(delivery, notify.Permanent(fmt.Errorf("customer has opted out of %s reminders", delivery.Channel)), now)
+	}
+
+	if sendAt, quiet := reminderSendTime(delivery, now); sendAt.After(now) {
+		return deferReminder(delivery, sendAt, quiet)
+	}
+
+	msg, err := buildReminderMessage(delivery)
+	if err == nil {
+		ctx, cancel := context.WithTimeout(context.Background(), reminderSendTimeout)
+		var receipt *notify.Receipt
+		receipt, err = notify.Send(ctx, msg)
+		cancel()
+		if err == nil {
+			return markReminderSent(delivery, receipt)
+		}
+	}
+
+	// Backoff is measured from the failure, not from the start of the run
+	return markReminderAttemptFailed(delivery, err, time.Now())
+}
+
+func loadReminderDelivery(reminderID int) (*reminderDelivery, error) {
+	var d reminderDelivery
+	err := database.DB.QueryRow(`
+		SELECT r.id, r.user_id, r.customer_id, r.channel, r.template_id, r.due_amount, r.due_date, r.send_now, r.attempts,
+			   c.name, c.phone, c.email, c.language, c.timezone,
+			   u.name, u.upi_id, u.timezone, u.quiet_hours_start, u.quiet_hours_end,
+			   COALESCE(cc.status = 'opted_out', FALSE)
+		FROM reminders r
+		JOIN customers c ON r.customer_id = c.id
+		JOIN users u ON r.user_id = u.id
+		LEFT JOIN customer_consents cc ON cc.customer_id = r.customer_id AND cc.channel = r.channel
+		WHERE r.id = ?`, reminderID).Scan(
+		&d.ID, &d.UserID, &d.CustomerID, &d.Channel, &d.TemplateID, &d.DueAmount, &d.DueDate, &d.SendNow, &d.Attempts,
+		&d.CustomerName, &d.CustomerPhone, &d.CustomerEmail, &d.CustomerLanguage, &d.CustomerTimezone,
+		&d.BusinessName, &d.BusinessUPIID, &d.BusinessTimezone, &d.QuietHoursStart, &d.QuietHoursEnd, &d.OptedOut,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
+
+// buildReminderMessage words the reminder with its template and addresses
+// it to the customer on its channel. A template that cannot be rendered or a
+// customer who cannot be reached is a permanent failure.
+func buildReminderMessage(d *reminderDelivery) (notify.Message, error) {
+	tmpl, err := resolveReminderTemplate(d)
+	if err != nil {
+		return notify.Message{}, err
+	}
+	rendered, err := renderReminder(d, tmpl)
+	if err != nil {
+		return notify.Message{}, notify.Permanent(fmt.Errorf("render template: %w", err))
+	}
+	return addressReminder(d, rendered)
+}
+
+// addressReminder turns a rendered reminder into a message for the
+// customer's phone number or email address
+func addressReminder(d *reminderDelivery, rendered *models.RenderedReminder) (notify.Message, error) {
+	msg := notify.Message{
+		Channel:   d.Channel,
+		Subject:   rendered.Subject,
+		Body:      rendered.Body,
+		Reference: "reminder-" + strconv.Itoa(d.ID),
+	}
+
+	switch d.Channel {
+	case notify.ChannelEmail:
+		if !d.CustomerEmail.Valid || d.CustomerEmail.String == "" {
+			return msg, notify.Permanent(errors.New("customer has no email address"))
+		}
+		msg.To = d.CustomerEmail.String
+	default:
+		msg.To = notify.NormalizePhone(d.CustomerPhone.String, defaultCountryCode())
+		if msg.To == "" {
+			return msg, notify.Permanent(errors.New("customer has no usable phone number"))
+		}
+	}
+	return msg, nil
+}
+
+// markReminderSent records a successful send and its history event together
+func markReminderSent(d *reminderDelivery, receipt *notify.Receipt) error {
+	var providerMessageID string
+	if receipt != nil {
+		providerMessageID = receipt.ProviderMessageID
+	}
+
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	event := reminderEvent{
+		ReminderID:        d.ID,
+		UserID:            d.UserID,
+		Event:             reminderEventSent,
+		FromStatus:        "pending",
+		ToStatus:          "sent",
+		Attempt:           d.Attempts + 1,
+		Channel:           d.Channel,
+		ProviderMessageID: providerMessageID,
+	}
+
+	result, err := tx.Exec(`
+		UPDATE reminders
+		SET status = 'sent', send_now = FALSE, attempts = attempts + 1, next_attempt_at = NULL, last_error = NULL,
+			sent_at = CURRENT_TIMESTAMP, provider_message_id = NULLIF(?, ''), delivery_status = 'sent',
+			delivery_status_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP
+		WHERE id = ? AND status = 'pending'`, providerMessageID, d.ID)
+	if err != nil {
+		return err
+	}
+
+	// A reminder closed or snoozed while it was being sent keeps its new
+	// status; only the delivery is recorded
+	if updated, err := result.RowsAffected(); err != nil {
+		return err
+	} else if updated == 0 {
+		_, err = tx.Exec(`
+			UPDATE reminders
+			SET attempts = attempts + 1, sent_at = CURRENT_TIMESTAMP, provider_message_id = NULLIF(?, ''),
+				delivery_status = 'sent', delivery_status_at = CURRENT_TIMESTAMP
+			WHERE id = ?`, providerMessageID, d.ID)
+		if err != nil {
+			return err
+		}
+		event.FromStatus, event.ToStatus = "", ""
+	}
+
+	if err := recordReminderEvent(tx, event); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	logger.L.WithFields(map[string]interface{}{
+		"reminder_id": d.ID,
+		"user_id":     d.UserID,
+		"channel":     d.Channel,
+		"message_id":  providerMessageID,
+	}).Info("Reminder sent")
+	return nil
+}
+
+// markReminderAttemptFailed schedules a retry with backoff, or marks the
+// reminder failed when the error is permanent or attempts are used up
+func markReminderAttemptFailed(d *reminderDelivery, sendErr error, now time.Time) error {
+	attempts := d.Attempts + 1
+	fields := map[string]interface{}{
+		"reminder_id": d.ID,
+		"user_id":     d.UserID,
+		"channel":     d.Channel,
+		"attempt":     attempts,
+		"error":       sendErr,
+	}
+	event := reminderEvent{
+		ReminderID: d.ID,
+		UserID:     d.UserID,
+		Attempt:    attempts,
+		Channel:    d.Channel,
+		Detail:     sendErr.Error(),
+	}
+
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// A reminder closed or snoozed while it was being sent keeps its new
+	// status and is not retried
+	var status string
+	if err := tx.QueryRow("SELECT status FROM reminders WHERE id = ? FOR UPDATE", d.ID).Scan(&status); err != nil {
+		return err
+	}
+
+	final := notify.IsPermanent(sendErr) || attempts >= maxReminderAttempts
+	var nextAttempt time.Time
+	if status != "pending" {
+		_, err = tx.Exec("UPDATE reminders SET attempts = ?, last_error = ? WHERE id = ?", attempts, sendErr.Error(), d.ID)
+		event.Event = reminderEventAttemptFailed
+		final = false
+	} else if final {
+		_, err = tx.Exec(`
+			UPDATE reminders
+			SET status = 'failed', send_now = FALSE, attempts = ?, next_attempt_at = NULL, last_error = ?, updated_at = CURRENT_TIMESTAMP
+			WHERE id = ?`, attempts, sendErr.Error(), d.ID)
+		event.Event, event.FromStatus, event.ToStatus = reminderEventFailed, "pending", "failed"
+	} else {
+		nextAttempt = now.Add(reminderBackoff(attempts))
+		_, err = tx.Exec(`
+			UPDATE reminders
+			SET attempts = ?, next_attempt_at = ?, last_error = ?, updated_at = CURRENT_TIMESTAMP
+			WHERE id = ?`, attempts, nextAttempt.Format("2006-01-02 15:04:05"), sendErr.Error(), d.ID)
+		event.Event = reminderEventAttemptFailed
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := recordReminderEvent(tx, event); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	if final {
+		logger.L.WithFields(fields).Warn("Reminder delivery failed")
+	} else if !nextAttempt.IsZero() {
+		fields["next_attempt_at"] = nextAttempt
+		logger.L.WithFields(fields).Info("Reminder delivery will be retried")
+	}
+	return nil
+}
+
+// defaultCountryCode is prefixed to local phone numbers, India unless
+// NOTIFY_DEFAULT_COUNTRY_CODE says otherwise
+func defaultCountryCode() string {
+	if code := os.Getenv("NOTIFY_DEFAULT_COUNTRY_CODE"); code != "" {
+		return code
+	}
+	return "91"
+}
@@ -0,0 +1,416 @@
+package handlers
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"khata-book-backend/pkg/notify"
+)
+
+// testReminder is a row of the emulated reminders table; empty strings
+// stand for NULL
+type testReminder struct {
+	id, userID, customerID int
+	channel                string
+	status                 string
+	dueDate                string
+	sendNow                bool
+	attempts               int
+	nextAttemptAt          string
+	lastError              string
+	providerMessageID      string
+	deliveryStatus         string
+	phone                  string
+	optedOut               bool
+}
+
+type testReminderEvent struct {
+	reminderID        int
+	event             string
+	fromStatus        string
+	toStatus          string
+	attempt           int
+	channel           string
+	providerMessageID string
+	detail            string
+}
+
+// reminderStore emulates the reminder tables for the statements the
+// dispatcher and delivery receipts run
+type reminderStore struct {
+	db        *fakeDB
+	reminders map[int]*testReminder
+	events    []testReminderEvent
+}
+
+func newReminderStore(t *testing.T, reminders ...*testReminder) *reminderStore {
+	s := &reminderStore{db: useFakeDB(t), reminders: map[int]*testReminder{}}
+	for _, rem := range reminders {
+		s.reminders[rem.id] = rem
+	}
+	db := s.db
+
+	db.route("SELECT id, user_id, channel FROM reminders WHERE status = 'snoozed'", func([]driver.Value) (*fakeRows, int64, error) {
+		return noRows(3), 0, nil
+	})
+
+	db.route("SELECT id FROM reminders WHERE status = 'pending'", func(args []driver.Value) (*fakeRows, int64, error) {
+		ids := &fakeRows{columns: []string{"id"}}
+		for _, rem := range s.sorted() {
+			if rem.status == "pending" && (rem.dueDate <= argString(args[0]) || rem.sendNow) &&
+				(rem.nextAttemptAt == "" || rem.nextAttemptAt <= argString(args[1])) {
+				ids.values = append(ids.values, []driver.Value{int64(rem.id)})
+			}
+		}
+		return ids, 0, nil
+	})
+
+	// Claim
+	db.route("UPDATE reminders SET next_attempt_at = ? WHERE id = ? AND status = 'pending' AND", func(args []driver.Value) (*fakeRows, int64, error) {
+		rem := s.reminders[argInt(args[1])]
+		if rem == nil || rem.status != "pending" || (rem.nextAttemptAt != "" && rem.nextAttemptAt > argString(args[2])) {
+			return nil, 0, nil
+		}
+		rem.nextAttemptAt = argString(args[0])
+		return nil, 1, nil
+	})
+
+	// Deferral
+	db.route("UPDATE reminders SET next_attempt_at = ? WHERE id = ? AND status = 'pending'", func(args []driver.Value) (*fakeRows, int64, error) {
+		rem := s.reminders[argInt(args[1])]
+		if rem == nil || rem.status != "pending" {
+			return nil, 0, nil
+		}
+		rem.nextAttemptAt = argString(args[0])
+		return nil, 1, nil
+	})
+
+	db.route("SELECT r.id, r.user_id, r.customer_id", func(args []driver.Value) (*fakeRows, int64, error) {
+		rem := s.reminders[argInt(args[0])]
+		if rem == nil {
+			return noRows(20), 0, nil
+		}
+		return row(int64(rem.id), int64(rem.userID), int64(rem.customerID), rem.channel, nil, 500.0, rem.dueDate, rem.sendNow, int64(rem.attempts),
+			"Asha", nullable(rem.phone), nil, nil, nil,
+			"Ravi Stores", nil, nil, nil, nil,
+			rem.optedOut), 0, nil
+	})
+
+	// No templates, so the built-in one is used
+	db.route("SELECT id, name, channel, language, subject, body, is_default, user_id, created_at, updated_at FROM reminder_templates", func([]driver.Value) (*fakeRows, int64, error) {
+		return noRows(10), 0, nil
+	})
+
+	db.route("SELECT status FROM reminders WHERE id = ? FOR UPDATE", func(args []driver.Value) (*fakeRows, int64, error) {
+		rem := s.reminders[argInt(args[0])]
+		if rem == nil {
+			return noRows(1), 0, nil
+		}
+		return row(rem.status), 0, nil
+	})
+
+	db.route("UPDATE reminders SET status = 'sent'", func(args []driver.Value) (*fakeRows, int64, error) {
+		rem := s.reminders[argInt(args[1])]
+		if rem == nil || rem.status != "pending" {
+			return nil, 0, nil
+		}
+		rem.status, rem.sendNow, rem.nextAttemptAt, rem.lastError = "sent", false, "", ""
+		rem.attempts++
+		rem.providerMessageID, rem.deliveryStatus = argString(args[0]), "sent"
+		return nil, 1, nil
+	})
+
+	db.route("UPDATE reminders SET attempts = attempts + 1, sent_at", func(args []driver.Value) (*fakeRows, int64, error) {
+		rem := s.reminders[argInt(args[1])]
+		rem.attempts++
+		rem.providerMessageID, rem.deliveryStatus = argString(args[0]), "sent"
+		return nil, 1, nil
+	})
+
+	db.route("UPDATE reminders SET attempts = ?, last_error = ? WHERE id = ?", func(args []driver.Value) (*fakeRows, int64, error) {
+		rem := s.reminders[argInt(args[2])]
+		rem.attempts, rem.lastError = argInt(args[0]), argString(args[1])
+		return nil, 1, nil
+	})
+
+	db.route("UPDATE reminders SET status = 'failed', send_now = FALSE, attempts = ?", func(args []driver.Value) (*fakeRows, int64, error) {
+		rem := s.reminders[argInt(args[2])]
+		rem.status, rem.sendNow, rem.nextAttemptAt = "failed", false, ""
+		rem.attempts, rem.lastError = argInt(args[0]), argString(args[1])
+		return nil, 1, nil
+	})
+
+	db.route("UPDATE reminders SET attempts = ?, next_attempt_at = ?", func(args []driver.Value) (*fakeRows, int64, error) {
+		rem := s.reminders[argInt(args[3])]
+		rem.attempts, rem.nextAttemptAt, rem.lastError = argInt(args[0]), argString(args[1]), argString(args[2])
+		return nil, 1, nil
+	})
+
+	db.route("INSERT INTO reminder_events", func(args []driver.Value) (*fakeRows, int64, error) {
+		s.events = append(s.events, testReminderEvent{
+			reminderID:        argInt(args[0]),
+			event:             argString(args[1]),
+			fromStatus:        argString(args[2]),
+			toStatus:          argString(args[3]),
+			attempt:           argInt(args[4]),
+			channel:           argString(args[5]),
+			providerMessageID: argString(args[6]),
+			detail:            argString(args[7]),
+		})
+		return nil, 1, nil
+	})
+
+	return s
+}
+
+func (s *reminderStore) sorted() []*testReminder {
+	var list []*testReminder
+	for _, rem := range s.reminders {
+		list = append(list, rem)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].dueDate != list[j].dueDate {
+			return list[i].dueDate < list[j].dueDate
+		}
+		return list[i].id < list[j].id
+	})
+	return list
+}
+
+// reminder returns a copy of a row, taken under the store's lock
+func (s *reminderStore) reminder(id int) testReminder {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+	return *s.reminders[id]
+}
+
+func (s *reminderStore) eventList() []testReminderEvent {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+	return append([]testReminderEvent(nil), s.events...)
+}
+
+func argInt(v driver.Value) int {
+	n, _ := v.(int64)
+	return int(n)
+}
+
+func argString(v driver.Value) string {
+	s, _ := v.(string)
+	return s
+}
+
+func nullable(s string) driver.Value {
+	if s == "" {
+		return nil
+	}
+	return s
+}
+
+// useNotifier installs n for channel for the length of the test
+func useNotifier(t *testing.T, channel string, n notify.Notifier) {
+	previous, had := notify.Notifiers[channel]
+	notify.Notifiers[channel] = n
+	t.Cleanup(func() {
+		if had {
+			notify.Notifiers[channel] = previous
+		} else {
+			delete(notify.Notifiers, channel)
+		}
+	})
+}
+
+func parseLocalTimestamp(t *testing.T, s string) time.Time {
+	t.Helper()
+	ts, err := time.ParseInLocation("2006-01-02 15:04:05", s, time.Local)
+	if err != nil {
+		t.Fatalf("parse timestamp %q: %v", s, err)
+	}
+	return ts
+}
+
+func dueReminder(id int) *testReminder {
+	return &testReminder{
+		id:         id,
+		userID:     7,
+		customerID: 11,
+		channel:    notify.ChannelSMS,
+		status:     "pending",
+		dueDate:    time.Now().AddDate(0, 0, -1).Format("2006-01-02"),
+		phone:      "9876543210",
+	}
+}
+
+func TestDispatchSendsDueReminder(t *testing.T) {
+	store := newReminderStore(t, dueReminder(1))
+	sms := notify.NewFake(notify.ChannelSMS)
+	useNotifier(t, notify.ChannelSMS, sms)
+
+	if err := runReminderDispatch(time.Now()); err != nil {
+		t.Fatalf("runReminderDispatch: %v", err)
+	}
+
+	sent := sms.Sent()
+	if len(sent) != 1 || sent[0].Reference != "reminder-1" || !strings.HasSuffix(sent[0].To, "9876543210") {
+		t.Fatalf("sent = %+v, want one message to the customer for reminder 1", sent)
+	}
+
+	rem := store.reminder(1)
+	if rem.status != "sent" || rem.attempts != 1 || rem.providerMessageID != "fake-sms-1" || rem.nextAttemptAt != "" {
+		t.Errorf("reminder = %+v, want sent on the first attempt with message fake-sms-1", rem)
+	}
+
+	events := store.eventList()
+	if len(events) != 1 || events[0].event != reminderEventSent || events[0].fromStatus != "pending" ||
+		events[0].toStatus != "sent" || events[0].attempt != 1 || events[0].providerMessageID != "fake-sms-1" {
+		t.Errorf("events = %+v, want one sent event", events)
+	}
+}
+
+func TestDispatchRetriesWithBackoff(t *testing.T) {
+	store := newReminderStore(t, dueReminder(1))
+	sms := notify.NewFake(notify.ChannelSMS)
+	sms.FailNext(errors.New("gateway timeout"))
+	useNotifier(t, notify.ChannelSMS, sms)
+
+	before := time.Now().Truncate(time.Second)
+	if err := runReminderDispatch(time.Now()); err != nil {
+		t.Fatalf("runReminderDispatch: %v", err)
+	}
+	after := time.Now()
+
+	rem := store.reminder(1)
+	if rem.status != "pending" || rem.attempts != 1 || rem.lastError != "gateway timeout" {
+		t.Fatalf("reminder = %+v, want pending after one failed attempt", rem)
+	}
+	next := parseLocalTimestamp(t, rem.nextAttemptAt)
+	if next.Before(before.Add(time.Minute)) || next.After(after.Add(time.Minute)) {
+		t.Errorf("next attempt at %v, want a minute after the failure", next)
+	}
+
+	events := store.eventList()
+	if len(events) != 1 || events[0].event != reminderEventAttemptFailed || events[0].attempt != 1 || events[0].detail != "gateway timeout" {
+		t.Errorf("events = %+v, want one attempt_failed event", events)
+	}
+
+	// The reminder is not tried again before its backoff runs out
+	if err := runReminderDispatch(time.Now()); err != nil {
+		t.Fatalf("runReminderDispatch: %v", err)
+	}
+	if got := store.reminder(1).attempts; got != 1 || len(sms.Sent()) != 0 {
+		t.Errorf("attempts = %d, sent = %d after an early second run, want 1 and 0", got, len(sms.Sent()))
+	}
+}
+
+func TestDispatchFailsAfterLastAttempt(t *testing.T) {
+	rem := dueReminder(1)
+	rem.attempts = maxReminderAttempts - 1
+	store := newReminderStore(t, rem)
+	sms := notify.NewFake(notify.ChannelSMS)
+	sms.FailNext(errors.New("gateway timeout"))
+	useNotifier(t, notify.ChannelSMS, sms)
+
+	if err := runReminderDispatch(time.Now()); err != nil {
+		t.Fatalf("runReminderDispatch: %v", err)
+	}
+
+	if got := store.reminder(1); got.status != "failed" || got.attempts != maxReminderAttempts || got.nextAttemptAt != "" {
+		t.Errorf("reminder = %+v, want failed after %d attempts", got, maxReminderAttempts)
+	}
+}
+
+func TestDispatchPermanentFailure(t *testing.T) {
+	store := newReminderStore(t, dueReminder(1))
+	sms := notify.NewFake(notify.ChannelSMS)
+	sms.FailNext(notify.Permanent(errors.New("invalid number")))
+	useNotifier(t, notify.ChannelSMS, sms)
+
+	if err := runReminderDispatch(time.Now()); err != nil {
+		t.Fatalf("runReminderDispatch: %v", err)
+	}
+
+	rem := store.reminder(1)
+	if rem.status != "failed" || rem.attempts != 1 || rem.nextAttemptAt != "" {
+		t.Errorf("reminder = %+v, want failed on the first attempt", rem)
+	}
+	events := store.eventList()
+	if len(events) != 1 || events[0].event != reminderEventFailed || events[0].fromStatus != "pending" || events[0].toStatus != "failed" {
+		t.Errorf("events = %+v, want one failed event", events)
+	}
+}
+
+func TestDispatchSkipsOptedOutCustomer(t *testing.T) {
+	rem := dueReminder(1)
+	rem.optedOut = true
+	store := newReminderStore(t, rem)
+	sms := notify.NewFake(notify.ChannelSMS)
+	useNotifier(t, notify.ChannelSMS, sms)
+
+	if err := runReminderDispatch(time.Now()); err != nil {
+		t.Fatalf("runReminderDispatch: %v", err)
+	}
+
+	if sent := sms.Sent(); len(sent) != 0 {
+		t.Errorf("sent %d messages to an opted-out customer", len(sent))
+	}
+	if got := store.reminder(1); got.status != "failed" || !strings.Contains(got.lastError, "opted out") {
+		t.Errorf("reminder = %+v, want failed as opted out", got)
+	}
+	if events := store.eventList(); len(events) != 1 || events[0].event != reminderEventFailed {
+		t.Errorf("events = %+v, want one failed event", events)
+	}
+}
+
+// leaseProbe records the reminder's lease at the moment it is sent
+type leaseProbe struct {
+	store *reminderStore
+	lease string
+}
+
+func (p *leaseProbe) Send(context.Context, notify.Message) (*notify.Receipt, error) {
+	p.lease = p.store.reminder(1).nextAttemptAt
+	return &notify.Receipt{ProviderMessageID: "probe-1"}, nil
+}
+
+func TestDispatchLeaseRunsFromClaim(t *testing.T) {
+	store := newReminderStore(t, dueReminder(1))
+	probe := &leaseProbe{store: store}
+	useNotifier(t, notify.ChannelSMS, probe)
+
+	// A run that started well before this reminder's turn came
+	runStart := time.Now().Add(-10 * time.Minute)
+	if err := dispatchReminder(1, runStart); err != nil {
+		t.Fatalf("dispatchReminder: %v", err)
+	}
+
+	if probe.lease == "" {
+		t.Fatal("reminder was not sent")
+	}
+	if lease := parseLocalTimestamp(t, probe.lease); lease.Before(time.Now().Add(reminderLease - time.Minute)) {
+		t.Errorf("lease ends %v, want %v from the claim", lease, reminderLease)
+	}
+}
+
+func TestReminderBackoff(t *testing.T) {
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{1, time.Minute},
+		{2, 2 * time.Minute},
+		{3, 4 * time.Minute},
+		{6, 32 * time.Minute},
+		{7, time.Hour},
+		{20, time.Hour},
+	}
+	for _, tt := range tests {
+		if got := reminderBackoff(tt.attempts); got != tt.want {
+			t.Errorf("reminderBackoff(%d) = %v, want %v", tt.attempts, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,138 @@
+package handlers
+
+import (
+	"database/sql"
+	"net/http"
+	"strconv"
+	"time"
+
+	"khata-book-backend/database"
+	"khata-book-backend/models"
+	"khata-book-backend/pkg/logger"
+
+	"github.com/gorilla/mux"
+)
+
+// Events recorded in a reminder's history
+const (
+	reminderEventCreated       = "created"
+	reminderEventSent          = "sent"
+	reminderEventAttemptFailed = "attempt_failed"
+	reminderEventFailed        = "failed"
+	reminderEventStatusChanged = "status_changed"
+	reminderEventAmountReduced = "amount_reduced"
+	reminderEventSnoozed       = "snoozed"
+	reminderEventWoke          = "woke"
+	reminderEventDeferred      = "deferred"
+)
+
+// Events recorded when a provider reports on a message after sending it
+const (
+	reminderEventDelivered      = "delivered"
+	reminderEventRead           = "read"
+	reminderEventDeliveryFailed = "delivery_failed"
+)
+
+type execer interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+}
+
+// reminderEvent is a history entry about to be recorded. Empty strings and
+// a zero attempt are stored as NULL.
+type reminderEvent struct {
+	ReminderID        int
+	UserID            int
+	Event             string
+	FromStatus        string
+	ToStatus          string
+	Attempt           int
+	Channel           string
+	ProviderMessageID string
+	Detail            string
+}
+
+func recordReminderEvent(q execer, e reminderEvent) error {
+	_, err := q.Exec(`
+		INSERT INTO reminder_events (reminder_id, event, from_status, to_status, attempt, channel, provider_message_id, detail, user_id)
+		VALUES (?, ?, NULLIF(?, ''), NULLIF(?, ''), NULLIF(?, 0), NULLIF(?, ''), NULLIF(?, ''), NULLIF(?, ''), ?)`,
+		e.ReminderID, e.Event, e.FromStatus, e.ToStatus, e.Attempt, e.Channel, e.ProviderMessageID, e.Detail, e.UserID)
+	return err
+}
+
+// GetReminderHistory returns every recorded event of a reminder, oldest first
+func GetReminderHistory(w http.ResponseWriter, r *http.Request) {
+	// Get user ID from JWT token
+	userID, err := getUserIDFromToken(r)
+	if err != nil {
+		writeJSON(w, http.StatusUnauthorized, map[string]interface{}{"success": false, "error": "unauthorized", "message": "Invalid token"})
+		return
+	}
+
+	reminderID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"success": false, "error": "invalid_id", "message": "Invalid reminder ID"})
+		return
+	}
+
+	var status string
+	var attempts int
+	var sentAt sql.NullString
+	err = database.DB.QueryRow("SELECT status, attempts, sent_at FROM reminders WHERE id = ? AND user_id = ?", reminderID, userID).Scan(&status, &attempts, &sentAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			writeJSON(w, http.StatusNotFound, map[string]interface{}{"success": false, "error": "not_found", "message": "Reminder not found"})
+			return
+		}
+		logger.L.WithField("error", err).Error("Error querying reminder")
+		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "server_error", "message": "Could not fetch reminder history"})
+		return
+	}
+
+	rows, err := database.DB.Query(`
+		SELECT id, reminder_id, event, from_status, to_status, attempt, channel, provider_message_id, detail, created_at
+		FROM reminder_events
+		WHERE reminder_id = ?
+		ORDER BY id ASC`, reminderID)
+	if err != nil {
+		logger.L.WithField("error", err).Error("Error querying reminder events")
+		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "server_error", "message": "Could not fetch reminder history"})
+		return
+	}
+	defer rows.Close()
+
+	events := []models.ReminderEvent{}
+	for rows.Next() {
+		var event models.ReminderEvent
+		var fromStatus, toStatus, channel, providerMessageID, detail sql.NullString
+		var attempt sql.NullInt64
+		var createdAtStr string
+
+		err := rows.Scan(
+			&event.ID, &event.ReminderID, &event.Event, &fromStatus, &toStatus, &attempt, &channel,
+			&providerMessageID, &detail, &createdAtStr,
+		)
+		if err != nil {
+			logger.L.WithField("error", err).Error("Error scanning reminder event")
+			continue
+		}
+
+		event.FromStatus = nullStringPtr(fromStatus)
+		event.ToStatus = nullStringPtr(toStatus)
+		event.Attempt = nullIntPtr(attempt)
+		event.Channel = nullStringPtr(channel)
+		event.ProviderMessageID = nullStringPtr(providerMessageID)
+		event.Detail = nullStringPtr(detail)
+		event.CreatedAt, _ = time.Parse("2006-01-02 15:04:05", createdAtStr)
+		events = append(events, event)
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"success":     true,
+		"reminder_id": reminderID,
+		"status":      status,
+		"attempts":    attempts,
+		"sent_at":     nullStringPtr(sentAt),
+		"events":      events,
+		"count":       len(events),
+	})
+}
@@ -0,0 +1,124 @@
+package handlers
+
+import (
+	"database/sql"
+	"time"
+
+	"khata-book-backend/database"
+	"khata-book-backend/pkg/logger"
+	"khata-book-backend/pkg/notify"
+)
+
+// deliveryStatusRank orders the delivery statuses of a message so a late
+// "delivered" callback cannot undo an earlier "read"
+var deliveryStatusRank = map[string]int{
+	notify.StatusSent:      1,
+	notify.StatusFailed:    2,
+	notify.StatusDelivered: 3,
+	notify.StatusRead:      4,
+}
+
+// applyDeliveryStatus records a provider's report on a reminder message. The
+// message is traced back through the history to the attempt that sent it on
+// the reporting channel, since message IDs are only unique per provider, and
+// every report is recorded once however often the provider repeats it.
+// A failure of the reminder's latest message marks a sent reminder failed.
+// It returns false when the message is not one of ours.
+func applyDeliveryStatus(st notify.DeliveryStatus) (bool, error) {
+	var event string
+	switch st.Status {
+	case notify.StatusDelivered:
+		event = reminderEventDelivered
+	case notify.StatusRead:
+		event = reminderEventRead
+	case notify.StatusFailed:
+		event = reminderEventDeliveryFailed
+	default:
+		// "sent" was recorded when the provider accepted the message
+		return st.Status == notify.StatusSent, nil
+	}
+	if st.ProviderMessageID == "" {
+		return false, nil
+	}
+
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	sent := reminderEvent{Event: event, ProviderMessageID: st.ProviderMessageID}
+	err = tx.QueryRow(`
+		SELECT reminder_id, user_id, COALESCE(attempt, 0), COALESCE(channel, '')
+		FROM reminder_events
+		WHERE provider_message_id = ? AND channel = ? AND event = 'sent'
+		ORDER BY id DESC
+		LIMIT 1`, st.ProviderMessageID, st.Channel).Scan(&sent.ReminderID, &sent.UserID, &sent.Attempt, &sent.Channel)
+	if err == sql.ErrNoRows {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+
+	// Locking the reminder serialises concurrent callbacks for it, so the
+	// duplicate check below holds
+	var status string
+	var currentMessageID, deliveryStatus sql.NullString
+	err = tx.QueryRow("SELECT status, provider_message_id, delivery_status FROM reminders WHERE id = ? FOR UPDATE", sent.ReminderID).Scan(
+		&status, &currentMessageID, &deliveryStatus)
+	if err != nil {
+		return false, err
+	}
+
+	var seen int
+	err = tx.QueryRow("SELECT COUNT(*) FROM reminder_events WHERE provider_message_id = ? AND event = ? AND reminder_id = ?",
+		st.ProviderMessageID, event, sent.ReminderID).Scan(&seen)
+	if err != nil || seen > 0 {
+		return err == nil, err
+	}
+
+	reportedAt := st.Timestamp
+	if reportedAt.IsZero() {
+		reportedAt = time.Now()
+	}
+	sent.Detail = st.Error
+	if sent.Detail == "" {
+		sent.Detail = "Reported by provider at " + reportedAt.Format(time.RFC3339)
+	}
+
+	latest := currentMessageID.Valid && currentMessageID.String == st.ProviderMessageID
+	if latest && deliveryStatusRank[st.Status] > deliveryStatusRank[deliveryStatus.String] {
+		_, err = tx.Exec("UPDATE reminders SET delivery_status = ?, delivery_status_at = ? WHERE id = ?",
+			st.Status, reportedAt.In(time.Local).Format("2006-01-02 15:04:05"), sent.ReminderID)
+		if err != nil {
+			return false, err
+		}
+	}
+
+	if latest && st.Status == notify.StatusFailed && status == "sent" {
+		_, err = tx.Exec(`
+			UPDATE reminders
+			SET status = 'failed', last_error = ?, updated_at = CURRENT_TIMESTAMP
+			WHERE id = ?`, sent.Detail, sent.ReminderID)
+		if err != nil {
+			return false, err
+		}
+		sent.FromStatus, sent.ToStatus = "sent", "failed"
+	}
+
+	if err := recordReminderEvent(tx, sent); err != nil {
+		return false, err
+	}
+	if err := tx.Commit(); err != nil {
+		return false, err
+	}
+
+	logger.L.WithFields(map[string]interface{}{
+		"reminder_id": sent.ReminderID,
+		"user_id":     sent.UserID,
+		"channel":     sent.Channel,
+		"message_id":  st.ProviderMessageID,
+		"status":      st.Status,
+	}).Info("Reminder delivery status recorded")
+	return true, nil
+}
@@ -0,0 +1,164 @@
+package handlers
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"khata-book-backend/pkg/notify"
+)
+
+// routeDeliveryReceipts adds the statements applyDeliveryStatus runs
+func (s *reminderStore) routeDeliveryReceipts() {
+	db := s.db
+
+	db.route("SELECT reminder_id, user_id, COALESCE(attempt, 0), COALESCE(channel, '') FROM reminder_events WHERE provider_message_id = ? AND channel = ? AND event = 'sent'", func(args []driver.Value) (*fakeRows, int64, error) {
+		for i := len(s.events) - 1; i >= 0; i-- {
+			e := s.events[i]
+			if e.event == reminderEventSent && e.providerMessageID == argString(args[0]) && e.channel == argString(args[1]) {
+				return row(int64(e.reminderID), int64(s.reminders[e.reminderID].userID), int64(e.attempt), e.channel), 0, nil
+			}
+		}
+		return noRows(4), 0, nil
+	})
+
+	db.route("SELECT status, provider_message_id, delivery_status FROM reminders WHERE id = ? FOR UPDATE", func(args []driver.Value) (*fakeRows, int64, error) {
+		rem := s.reminders[argInt(args[0])]
+		return row(rem.status, nullable(rem.providerMessageID), nullable(rem.deliveryStatus)), 0, nil
+	})
+
+	db.route("SELECT COUNT(*) FROM reminder_events WHERE provider_message_id = ? AND event = ? AND reminder_id = ?", func(args []driver.Value) (*fakeRows, int64, error) {
+		seen := 0
+		for _, e := range s.events {
+			if e.providerMessageID == argString(args[0]) && e.event == argString(args[1]) && e.reminderID == argInt(args[2]) {
+				seen++
+			}
+		}
+		return row(int64(seen)), 0, nil
+	})
+
+	db.route("UPDATE reminders SET delivery_status = ?, delivery_status_at = ? WHERE id = ?", func(args []driver.Value) (*fakeRows, int64, error) {
+		s.reminders[argInt(args[2])].deliveryStatus = argString(args[0])
+		return nil, 1, nil
+	})
+
+	db.route("UPDATE reminders SET status = 'failed', last_error = ?", func(args []driver.Value) (*fakeRows, int64, error) {
+		rem := s.reminders[argInt(args[1])]
+		rem.status, rem.lastError = "failed", argString(args[0])
+		return nil, 1, nil
+	})
+}
+
+// sentReminderStore returns a store holding reminder 1 already sent by SMS
+// as message fake-sms-1
+func sentReminderStore(t *testing.T) *reminderStore {
+	t.Helper()
+	store := newReminderStore(t, dueReminder(1))
+	store.routeDeliveryReceipts()
+	useNotifier(t, notify.ChannelSMS, notify.NewFake(notify.ChannelSMS))
+	if err := runReminderDispatch(time.Now()); err != nil {
+		t.Fatalf("runReminderDispatch: %v", err)
+	}
+	if rem := store.reminder(1); rem.status != "sent" || rem.providerMessageID != "fake-sms-1" {
+		t.Fatalf("reminder = %+v, want sent as fake-sms-1", rem)
+	}
+	return store
+}
+
+// postSMSReceipt delivers a signed SMS gateway callback and returns how many
+// of its statuses matched a reminder
+func postSMSReceipt(t *testing.T, payload map[string]string) int {
+	t.Helper()
+	body, _ := json.Marshal(payload)
+	req := httptest.NewRequest(http.MethodPost, "/api/webhooks/sms", bytes.NewReader(body))
+	req.Header.Set("X-Signature", hex.EncodeToString(notify.SignWebhook("s3cret", body)))
+	rec := httptest.NewRecorder()
+	SMSWebhook(rec, req)
+
+	var resp struct {
+		Success         bool `json:"success"`
+		StatusesMatched int  `json:"statuses_matched"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil || rec.Code != http.StatusOK || !resp.Success {
+		t.Fatalf("SMSWebhook = %d %s, want success", rec.Code, rec.Body.String())
+	}
+	return resp.StatusesMatched
+}
+
+func (s *reminderStore) countEvents(event string) int {
+	n := 0
+	for _, e := range s.eventList() {
+		if e.event == event {
+			n++
+		}
+	}
+	return n
+}
+
+func TestRepeatedDeliveryReceiptRecordedOnce(t *testing.T) {
+	t.Setenv("SMS_WEBHOOK_SECRET", "s3cret")
+	store := sentReminderStore(t)
+
+	receipt := map[string]string{"message_id": "fake-sms-1", "status": "DELIVRD"}
+	for i := 0; i < 3; i++ {
+		if matched := postSMSReceipt(t, receipt); matched != 1 {
+			t.Fatalf("callback %d matched %d statuses, want 1", i+1, matched)
+		}
+	}
+
+	if n := store.countEvents(reminderEventDelivered); n != 1 {
+		t.Errorf("recorded %d delivered events for a repeated callback, want 1", n)
+	}
+	if rem := store.reminder(1); rem.status != "sent" || rem.deliveryStatus != notify.StatusDelivered {
+		t.Errorf("reminder = %+v, want sent and delivered", rem)
+	}
+}
+
+func TestRepeatedFailureReceiptRecordedOnce(t *testing.T) {
+	t.Setenv("SMS_WEBHOOK_SECRET", "s3cret")
+	store := sentReminderStore(t)
+
+	receipt := map[string]string{"message_id": "fake-sms-1", "status": "undelivered", "error": "Handset unreachable"}
+	postSMSReceipt(t, receipt)
+	postSMSReceipt(t, receipt)
+
+	if n := store.countEvents(reminderEventDeliveryFailed); n != 1 {
+		t.Errorf("recorded %d delivery_failed events for a repeated callback, want 1", n)
+	}
+	rem := store.reminder(1)
+	if rem.status != "failed" || rem.deliveryStatus != notify.StatusFailed || rem.lastError != "Handset unreachable" {
+		t.Errorf("reminder = %+v, want failed with the provider's error", rem)
+	}
+}
+
+func TestInterimReceiptIgnored(t *testing.T) {
+	t.Setenv("SMS_WEBHOOK_SECRET", "s3cret")
+	store := sentReminderStore(t)
+
+	if matched := postSMSReceipt(t, map[string]string{"message_id": "fake-sms-1", "status": "enroute"}); matched != 0 {
+		t.Errorf("interim receipt matched %d statuses, want 0", matched)
+	}
+	if rem := store.reminder(1); rem.status != "sent" || rem.deliveryStatus != notify.StatusSent {
+		t.Errorf("reminder = %+v, want still sent", rem)
+	}
+	if n := len(store.eventList()); n != 1 {
+		t.Errorf("recorded %d events, want only the send", n)
+	}
+}
+
+func TestDeliveryReceiptFromOtherChannelIgnored(t *testing.T) {
+	store := sentReminderStore(t)
+
+	matched, err := applyDeliveryStatus(notify.DeliveryStatus{Channel: notify.ChannelWhatsApp, ProviderMessageID: "fake-sms-1", Status: notify.StatusFailed})
+	if err != nil || matched {
+		t.Fatalf("applyDeliveryStatus = %v, %v, want no match for another channel's message", matched, err)
+	}
+	if rem := store.reminder(1); rem.status != "sent" {
+		t.Errorf("reminder = %+v, want still sent", rem)
+	}
+}
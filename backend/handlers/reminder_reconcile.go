@@ -0,0 +1,89 @@
+package handlers
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// reconcileReminders brings a customer's open reminders in line with their
+// balance after a payment: every reminder is closed as paid once nothing is
+// owed, and a reminder asking for more than the balance is cut down to it.
+// Suppliers are never reminded, so their entries leave reminders alone.
+// Unless force is set this only happens when the business has automatic
+// closing turned on. cause describes the payment in the reminder history.
+func reconcileReminders(tx *sql.Tx, customerID, userID int, cause string, force bool) (closed, reduced int, err error) {
+	if !force {
+		var autoClose bool
+		err := tx.QueryRow("SELECT auto_close_reminders FROM users WHERE id = ?", userID).Scan(&autoClose)
+		if err != nil || !autoClose {
+			return 0, 0, err
+		}
+	}
+
+	var balance float64
+	var partyType string
+	err = tx.QueryRow("SELECT balance, party_type FROM customers WHERE id = ? AND user_id = ? FOR UPDATE", customerID, userID).Scan(&balance, &partyType)
+	if err != nil || partyType != partyCustomer {
+		return 0, 0, err
+	}
+	balance = roundMoney(balance)
+
+	rows, err := tx.Query(`
+		SELECT id, status, channel, due_amount FROM reminders
+		WHERE customer_id = ? AND user_id = ? AND status != 'paid'
+		FOR UPDATE`, customerID, userID)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	type openReminder struct {
+		id        int
+		status    string
+		channel   string
+		dueAmount float64
+	}
+	var open []openReminder
+	for rows.Next() {
+		var rem openReminder
+		if err := rows.Scan(&rem.id, &rem.status, &rem.channel, &rem.dueAmount); err != nil {
+			rows.Close()
+			return 0, 0, err
+		}
+		open = append(open, rem)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, 0, err
+	}
+
+	for _, rem := range open {
+		event := reminderEvent{ReminderID: rem.id, UserID: userID, Channel: rem.channel}
+
+		if balance <= 0 {
+			_, err = tx.Exec(`
+				UPDATE reminders
+				SET status = 'paid', snoozed_until = NULL, send_now = FALSE, next_attempt_at = NULL, updated_at = CURRENT_TIMESTAMP
+				WHERE id = ?`, rem.id)
+			event.Event = reminderEventStatusChanged
+			event.FromStatus, event.ToStatus = rem.status, "paid"
+			event.Detail = "Closed automatically: balance cleared by " + cause
+			closed++
+		} else if roundMoney(rem.dueAmount) > balance {
+			_, err = tx.Exec("UPDATE reminders SET due_amount = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ?", balance, rem.id)
+			event.Event = reminderEventAmountReduced
+			event.Detail = fmt.Sprintf("Due amount reduced from %.2f to %.2f after %s", rem.dueAmount, balance, cause)
+			reduced++
+		} else {
+			continue
+		}
+		if err != nil {
+			return 0, 0, err
+		}
+
+		if err := recordReminderEvent(tx, event); err != nil {
+			return 0, 0, err
+		}
+	}
+
+	return closed, reduced, nil
+}
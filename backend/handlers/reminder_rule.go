@@ -0,0 +1,651 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"khata-book-backend/database"
+	"khata-book-backend/models"
+	"khata-book-backend/pkg/logger"
+	"khata-book-backend/pkg/notify"
+
+	"github.com/gorilla/mux"
+)
+
+const (
+	// maxRuleDaysSincePayment and maxRuleRepeatDays bound the rule settings
+	maxRuleDaysSincePayment = 3650
+	maxRuleRepeatDays       = 365
+	// maxRulePreview caps the matches rendered by a preview
+	maxRulePreview = 20
+)
+
+const reminderRuleSelect = `
+	SELECT id, name, min_balance, days_since_payment, channel, template_id, repeat_every_days,
+		   is_active, last_run_at, user_id, created_at, updated_at
+	FROM reminder_rules`
+
+// GetReminderRules lists the business's automatic reminder rules
+func GetReminderRules(w http.ResponseWriter, r *http.Request) {
+	// Get user ID from JWT token
+	userID, err := getUserIDFromToken(r)
+	if err != nil {
+		writeJSON(w, http.StatusUnauthorized, map[string]interface{}{"success": false, "error": "unauthorized", "message": "Invalid token"})
+		return
+	}
+
+	rows, err := database.DB.Query(reminderRuleSelect+" WHERE user_id = ? ORDER BY name ASC", userID)
+	if err != nil {
+		logger.L.WithField("error", err).Error("Error querying reminder rules")
+		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "server_error", "message": "Could not fetch reminder rules"})
+		return
+	}
+	defer rows.Close()
+
+	rules := []*models.ReminderRule{}
+	for rows.Next() {
+		rule, err := scanReminderRule(rows)
+		if err != nil {
+			logger.L.WithField("error", err).Error("Error scanning reminder rule")
+			continue
+		}
+		rules = append(rules, rule)
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"rules":   rules,
+		"count":   len(rules),
+	})
+}
+
+// CreateReminderRule adds an automatic reminder rule
+func CreateReminderRule(w http.ResponseWriter, r *http.Request) {
+	// Get user ID from JWT token
+	userID, err := getUserIDFromToken(r)
+	if err != nil {
+		writeJSON(w, http.StatusUnauthorized, map[string]interface{}{"success": false, "error": "unauthorized", "message": "Invalid token"})
+		return
+	}
+
+	var ruleReq models.ReminderRuleRequest
+	err = json.NewDecoder(r.Body).Decode(&ruleReq)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"success": false, "error": "invalid_request", "message": "Invalid request body"})
+		return
+	}
+
+	rule := models.ReminderRule{RepeatEveryDays: 7, IsActive: true, UserID: userID}
+	applyReminderRuleRequest(&rule, &ruleReq)
+	if !validateReminderRule(w, &rule) {
+		return
+	}
+
+	result, err := database.DB.Exec(`
+		INSERT INTO reminder_rules (name, min_balance, days_since_payment, channel, template_id, repeat_every_days, is_active, user_id)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		rule.Name, rule.MinBalance, rule.DaysSincePayment, rule.Channel, rule.TemplateID, rule.RepeatEveryDays, rule.IsActive, userID)
+	if err != nil {
+		if strings.Contains(err.Error(), "Duplicate entry") {
+			writeJSON(w, http.StatusConflict, map[string]interface{}{"success": false, "error": "rule_exists", "message": "A reminder rule with this name already exists"})
+			return
+		}
+		logger.L.WithField("error", err).Error("Error inserting reminder rule")
+		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "server_error", "message": "Could not create reminder rule"})
+		return
+	}
+
+	ruleID, err := result.LastInsertId()
+	if err != nil {
+		logger.L.WithField("error", err).Error("Error getting inserted reminder rule ID")
+		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "server_error", "message": "Could not create reminder rule"})
+		return
+	}
+
+	created, err := getReminderRule(database.DB, int(ruleID), userID)
+	if err != nil {
+		logger.L.WithField("error", err).Error("Error loading created reminder rule")
+		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "server_error", "message": "Could not create reminder rule"})
+		return
+	}
+
+	logger.L.WithFields(map[string]interface{}{
+		"rule_id": ruleID,
+		"user_id": userID,
+		"channel": rule.Channel,
+	}).Info("Reminder rule created successfully")
+
+	writeJSON(w, http.StatusCreated, map[string]interface{}{
+		"success": true,
+		"message": "Reminder rule created successfully",
+		"rule":    created,
+	})
+}
+
+// GetReminderRule returns a single reminder rule
+func GetReminderRule(w http.ResponseWriter, r *http.Request) {
+	// Get user ID from JWT token
+	userID, err := getUserIDFromToken(r)
+	if err != nil {
+		writeJSON(w, http.StatusUnauthorized, map[string]interface{}{"success": false, "error": "unauthorized", "message": "Invalid token"})
+		return
+	}
+
+	rule, ok := loadReminderRule(w, r, userID, "Could not fetch reminder rule")
+	if !ok {
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{"success": true, "rule": rule})
+}
+
+// UpdateReminderRule changes the fields given in the request body
+func UpdateReminderRule(w http.ResponseWriter, r *http.Request) {
+	// Get user ID from JWT token
+	userID, err := getUserIDFromToken(r)
+	if err != nil {
+		writeJSON(w, http.StatusUnauthorized, map[string]interface{}{"success": false, "error": "unauthorized", "message": "Invalid token"})
+		return
+	}
+
+	var ruleReq models.ReminderRuleRequest
+	err = json.NewDecoder(r.Body).Decode(&ruleReq)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"success": false, "error": "invalid_request", "message": "Invalid request body"})
+		return
+	}
+
+	rule, ok := loadReminderRule(w, r, userID, "Could not update reminder rule")
+	if !ok {
+		return
+	}
+
+	applyReminderRuleRequest(rule, &ruleReq)
+	if !validateReminderRule(w, rule) {
+		return
+	}
+
+	_, err = database.DB.Exec(`
+		UPDATE reminder_rules
+		SET name = ?, min_balance = ?, days_since_payment = ?, channel = ?, template_id = ?, repeat_every_days = ?,
+			is_active = ?, updated_at = CURRENT_TIMESTAMP
+		WHERE id = ? AND user_id = ?`,
+		rule.Name, rule.MinBalance, rule.DaysSincePayment, rule.Channel, rule.TemplateID, rule.RepeatEveryDays,
+		rule.IsActive, rule.ID, userID)
+	if err != nil {
+		if strings.Contains(err.Error(), "Duplicate entry") {
+			writeJSON(w, http.StatusConflict, map[string]interface{}{"success": false, "error": "rule_exists", "message": "A reminder rule with this name already exists"})
+			return
+		}
+		logger.L.WithField("error", err).Error("Error updating reminder rule")
+		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "server_error", "message": "Could not update reminder rule"})
+		return
+	}
+
+	updated, err := getReminderRule(database.DB, rule.ID, userID)
+	if err != nil {
+		logger.L.WithField("error", err).Error("Error loading updated reminder rule")
+		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "server_error", "message": "Could not update reminder rule"})
+		return
+	}
+
+	logger.L.WithFields(map[string]interface{}{
+		"rule_id": rule.ID,
+		"user_id": userID,
+	}).Info("Reminder rule updated successfully")
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"message": "Reminder rule updated successfully",
+		"rule":    updated,
+	})
+}
+
+// DeleteReminderRule removes a rule. Reminders it already created stay.
+func DeleteReminderRule(w http.ResponseWriter, r *http.Request) {
+	// Get user ID from JWT token
+	userID, err := getUserIDFromToken(r)
+	if err != nil {
+		writeJSON(w, http.StatusUnauthorized, map[string]interface{}{"success": false, "error": "unauthorized", "message": "Invalid token"})
+		return
+	}
+
+	ruleID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"success": false, "error": "invalid_id", "message": "Invalid reminder rule ID"})
+		return
+	}
+
+	result, err := database.DB.Exec("DELETE FROM reminder_rules WHERE id = ? AND user_id = ?", ruleID, userID)
+	if err != nil {
+		logger.L.WithField("error", err).Error("Error deleting reminder rule")
+		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "server_error", "message": "Could not delete reminder rule"})
+		return
+	}
+
+	if deleted, err := result.RowsAffected(); err == nil && deleted == 0 {
+		writeJSON(w, http.StatusNotFound, map[string]interface{}{"success": false, "error": "not_found", "message": "Reminder rule not found"})
+		return
+	}
+
+	logger.L.WithFields(map[string]interface{}{
+		"rule_id": ruleID,
+		"user_id": userID,
+	}).Info("Reminder rule deleted successfully")
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{"success": true, "message": "Reminder rule deleted successfully"})
+}
+
+// RunReminderRule applies a rule now, active or not. With dry_run the
+// matching customers are returned without creating any reminders.
+func RunReminderRule(w http.ResponseWriter, r *http.Request) {
+	// Get user ID from JWT token
+	userID, err := getUserIDFromToken(r)
+	if err != nil {
+		writeJSON(w, http.StatusUnauthorized, map[string]interface{}{"success": false, "error": "unauthorized", "message": "Invalid token"})
+		return
+	}
+
+	// The body is optional; an empty one is a real run
+	var runReq models.ReminderRuleRunRequest
+	if err := json.NewDecoder(r.Body).Decode(&runReq); err != nil && err != io.EOF {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"success": false, "error": "invalid_request", "message": "Invalid request body"})
+		return
+	}
+	if r.URL.Query().Get("dry_run") == "true" {
+		runReq.DryRun = true
+	}
+
+	rule, ok := loadReminderRule(w, r, userID, "Could not run reminder rule")
+	if !ok {
+		return
+	}
+
+	matches, err := applyReminderRule(rule.ID, time.Now(), runReq.DryRun)
+	if err != nil {
+		logger.L.WithField("error", err).Error("Error running reminder rule")
+		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "server_error", "message": "Could not run reminder rule"})
+		return
+	}
+
+	if !runReq.DryRun {
+		logger.L.WithFields(map[string]interface{}{
+			"rule_id":   rule.ID,
+			"user_id":   userID,
+			"reminders": len(matches),
+		}).Info("Reminder rule run successfully")
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"dry_run": runReq.DryRun,
+		"rule_id": rule.ID,
+		"matches": matches,
+		"count":   len(matches),
+	})
+}
+
+// PreviewReminderRule lists the customers the rule would remind right now
+// with the message each of them would receive
+func PreviewReminderRule(w http.ResponseWriter, r *http.Request) {
+	// Get user ID from JWT token
+	userID, err := getUserIDFromToken(r)
+	if err != nil {
+		writeJSON(w, http.StatusUnauthorized, map[string]interface{}{"success": false, "error": "unauthorized", "message": "Invalid token"})
+		return
+	}
+
+	rule, ok := loadReminderRule(w, r, userID, "Could not preview reminder rule")
+	if !ok {
+		return
+	}
+
+	now := time.Now()
+	matches, err := reminderRuleMatches(database.DB, rule, now, maxRulePreview)
+	if err != nil {
+		logger.L.WithField("error", err).Error("Error matching reminder rule")
+		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "server_error", "message": "Could not preview reminder rule"})
+		return
+	}
+
+	today, err := businessToday(database.DB, userID, now)
+	if err != nil {
+		logger.L.WithField("error", err).Error("Error getting business date")
+		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "server_error", "message": "Could not preview reminder rule"})
+		return
+	}
+
+	for i := range matches {
+		rendered, err := previewRuleReminder(rule, &matches[i], today.Format("2006-01-02"))
+		if err != nil {
+			msg := err.Error()
+			matches[i].PreviewError = &msg
+		}
+		matches[i].Preview = rendered
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"rule_id": rule.ID,
+		"matches": matches,
+		"count":   len(matches),
+	})
+}
+
+// runReminderRules applies every active rule. The reminders it creates are
+// sent by the reminder dispatcher.
+func runReminderRules(now time.Time) error {
+	rows, err := database.DB.Query("SELECT id FROM reminder_rules WHERE is_active = TRUE ORDER BY id ASC")
+	if err != nil {
+		return err
+	}
+
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return err
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, id := range ids {
+		matches, err := applyReminderRule(id, now, false)
+		if err != nil {
+			logger.L.WithFields(map[string]interface{}{"rule_id": id, "error": err}).Error("Error applying reminder rule")
+			continue
+		}
+		if len(matches) > 0 {
+			logger.L.WithFields(map[string]interface{}{"rule_id": id, "reminders": len(matches)}).Info("Reminder rule created reminders")
+		}
+	}
+	return nil
+}
+
+// applyReminderRule creates a reminder for each customer the rule matches,
+// or only reports them on a dry run. Runs for one business are serialised
+// on its user row so two rules cannot remind the same customer at once.
+func applyReminderRule(ruleID int, now time.Time, dryRun bool) ([]models.ReminderRuleMatch, error) {
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var userID int
+	if err := tx.QueryRow("SELECT user_id FROM reminder_rules WHERE id = ?", ruleID).Scan(&userID); err != nil {
+		return nil, err
+	}
+	if !dryRun {
+		if err := tx.QueryRow("SELECT id FROM users WHERE id = ? FOR UPDATE", userID).Scan(&userID); err != nil {
+			return nil, err
+		}
+	}
+
+	rule, err := getReminderRule(tx, ruleID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	matches, err := reminderRuleMatches(tx, rule, now, reminderBatchSize)
+	if err != nil || dryRun {
+		return matches, err
+	}
+
+	today, err := businessToday(tx, userID, now)
+	if err != nil {
+		return nil, err
+	}
+	for i := range matches {
+		result, err := tx.Exec(`
+			INSERT INTO reminders (customer_id, due_amount, due_date, channel, template_id, rule_id, status, user_id)
+			VALUES (?, ?, ?, ?, ?, ?, 'pending', ?)`,
+			matches[i].CustomerID, matches[i].Balance, today.Format("2006-01-02"), rule.Channel, rule.TemplateID, rule.ID, userID)
+		if err != nil {
+			return nil, err
+		}
+		reminderID, err := result.LastInsertId()
+		if err != nil {
+			return nil, err
+		}
+
+		err = recordReminderEvent(tx, reminderEvent{
+			ReminderID: int(reminderID),
+			UserID:     userID,
+			Event:      reminderEventCreated,
+			ToStatus:   "pending",
+			Channel:    rule.Channel,
+			Detail:     "Created by rule " + rule.Name,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		id := int(reminderID)
+		matches[i].ReminderID = &id
+	}
+
+	_, err = tx.Exec("UPDATE reminder_rules SET last_run_at = ? WHERE id = ?", now.Format("2006-01-02 15:04:05"), rule.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return matches, tx.Commit()
+}
+
+// reminderRuleMatches finds the customers a rule applies to: owing more than
+// its minimum, with no payment for its number of days (counted from their
+// first entry if they never paid), no reminder waiting to go out, not opted
+// out of the rule's channel, and no reminder from this rule within its
+// repeat interval. The largest balances come first.
+func reminderRuleMatches(q queryer, rule *models.ReminderRule, now time.Time, limit int) ([]models.ReminderRuleMatch, error) {
+	today, err := businessToday(q, rule.UserID, now)
+	if err != nil {
+		return nil, err
+	}
+	paidBefore := today.AddDate(0, 0, -rule.DaysSincePayment).Format("2006-01-02")
+	repeatAfter := now.AddDate(0, 0, -rule.RepeatEveryDays).Format("2006-01-02 15:04:05")
+
+	rows, err := q.Query(`
+		SELECT c.id, c.name, c.balance, le.last_payment
+		FROM customers c
+		LEFT JOIN (
+			SELECT customer_id, MAX(CASE WHEN type = 'debit' THEN date END) AS last_payment, MIN(date) AS first_entry
+			FROM ledger_entries
+			WHERE user_id = ?
+			GROUP BY customer_id
+		) le ON le.customer_id = c.id
+		WHERE c.user_id = ? AND c.party_type = 'customer' AND c.balance > 0 AND c.balance > ?
+		  AND COALESCE(le.last_payment, le.first_entry, DATE(c.created_at)) <= ?
+		  AND NOT EXISTS (
+			SELECT 1 FROM reminders r
+			WHERE r.customer_id = c.id AND r.status IN ('pending', 'snoozed'))
+		  AND NOT EXISTS (
+			SELECT 1 FROM customer_consents cc
+			WHERE cc.customer_id = c.id AND cc.channel = ? AND cc.status = 'opted_out')
+		  AND NOT EXISTS (
+			SELECT 1 FROM reminders r
+			WHERE r.customer_id = c.id AND r.rule_id = ? AND r.created_at > ?)
+		ORDER BY c.balance DESC, c.id ASC
+		LIMIT ?`,
+		rule.UserID, rule.UserID, rule.MinBalance, paidBefore, rule.Channel, rule.ID, repeatAfter, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	matches := []models.ReminderRuleMatch{}
+	for rows.Next() {
+		var match models.ReminderRuleMatch
+		var lastPayment sql.NullString
+		if err := rows.Scan(&match.CustomerID, &match.CustomerName, &match.Balance, &lastPayment); err != nil {
+			return nil, err
+		}
+		match.Balance = roundMoney(match.Balance)
+		match.LastPaymentDate = nullStringPtr(lastPayment)
+		matches = append(matches, match)
+	}
+	return matches, rows.Err()
+}
+
+// previewRuleReminder renders the reminder a rule would create for a match
+// and addresses it, so a customer who cannot be reached shows up as an error
+func previewRuleReminder(rule *models.ReminderRule, match *models.ReminderRuleMatch, dueDate string) (*models.RenderedReminder, error) {
+	d := reminderDelivery{
+		UserID:     rule.UserID,
+		CustomerID: match.CustomerID,
+		Channel:    rule.Channel,
+		DueAmount:  match.Balance,
+		DueDate:    dueDate,
+	}
+	if rule.TemplateID != nil {
+		d.TemplateID = sql.NullInt64{Int64: int64(*rule.TemplateID), Valid: true}
+	}
+
+	err := database.DB.QueryRow(`
+		SELECT c.name, c.phone, c.email, c.language, u.name, u.upi_id
+		FROM customers c
+		JOIN users u ON c.user_id = u.id
+		WHERE c.id = ?`, match.CustomerID).Scan(
+		&d.CustomerName, &d.CustomerPhone, &d.CustomerEmail, &d.CustomerLanguage, &d.BusinessName, &d.BusinessUPIID,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	tmpl, err := resolveReminderTemplate(&d)
+	if err != nil {
+		return nil, err
+	}
+	rendered, err := renderReminder(&d, tmpl)
+	if err != nil {
+		return nil, err
+	}
+	msg, err := addressReminder(&d, rendered)
+	rendered.To = msg.To
+	return rendered, err
+}
+
+// applyReminderRuleRequest copies the fields present in req onto rule
+func applyReminderRuleRequest(rule *models.ReminderRule, req *models.ReminderRuleRequest) {
+	if req.Name != "" {
+		rule.Name = req.Name
+	}
+	if req.MinBalance != nil {
+		rule.MinBalance = *req.MinBalance
+	}
+	if req.DaysSincePayment != nil {
+		rule.DaysSincePayment = *req.DaysSincePayment
+	}
+	if req.Channel != "" {
+		rule.Channel = req.Channel
+	}
+	if req.TemplateID != nil {
+		rule.TemplateID = req.TemplateID
+		// A template ID of 0 goes back to the default template
+		if *req.TemplateID == 0 {
+			rule.TemplateID = nil
+		}
+	}
+	if req.RepeatEveryDays != nil {
+		rule.RepeatEveryDays = *req.RepeatEveryDays
+	}
+	if req.IsActive != nil {
+		rule.IsActive = *req.IsActive
+	}
+}
+
+// validateReminderRule checks a rule before it is written
+func validateReminderRule(w http.ResponseWriter, rule *models.ReminderRule) bool {
+	rule.Name = strings.TrimSpace(rule.Name)
+	if rule.Name == "" || utf8.RuneCountInString(rule.Name) > 100 {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"success": false, "error": "invalid_name", "message": "Rule name is required and must be at most 100 characters"})
+		return false
+	}
+
+	if rule.MinBalance < 0 {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"success": false, "error": "invalid_min_balance", "message": "Minimum balance cannot be negative"})
+		return false
+	}
+	rule.MinBalance = roundMoney(rule.MinBalance)
+
+	if rule.DaysSincePayment < 0 || rule.DaysSincePayment > maxRuleDaysSincePayment {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"success": false, "error": "invalid_days_since_payment", "message": "Days since payment must be between 0 and 3650"})
+		return false
+	}
+
+	if rule.RepeatEveryDays < 1 || rule.RepeatEveryDays > maxRuleRepeatDays {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"success": false, "error": "invalid_repeat_every_days", "message": "Repeat interval must be between 1 and 365 days"})
+		return false
+	}
+
+	if rule.Channel != notify.ChannelSMS && rule.Channel != notify.ChannelWhatsApp && rule.Channel != notify.ChannelEmail {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"success": false, "error": "invalid_channel", "message": "Channel must be 'sms', 'whatsapp', or 'email'"})
+		return false
+	}
+
+	if rule.TemplateID != nil && !checkReminderTemplate(w, *rule.TemplateID, rule.UserID, rule.Channel) {
+		return false
+	}
+
+	return true
+}
+
+// loadReminderRule reads the rule named by the {id} path variable, writing
+// the error response and returning false when it cannot
+func loadReminderRule(w http.ResponseWriter, r *http.Request, userID int, failMessage string) (*models.ReminderRule, bool) {
+	ruleID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"success": false, "error": "invalid_id", "message": "Invalid reminder rule ID"})
+		return nil, false
+	}
+
+	rule, err := getReminderRule(database.DB, ruleID, userID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			writeJSON(w, http.StatusNotFound, map[string]interface{}{"success": false, "error": "not_found", "message": "Reminder rule not found"})
+			return nil, false
+		}
+		logger.L.WithField("error", err).Error("Error querying reminder rule")
+		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "server_error", "message": failMessage})
+		return nil, false
+	}
+	return rule, true
+}
+
+func getReminderRule(q queryRower, ruleID, userID int) (*models.ReminderRule, error) {
+	return scanReminderRule(q.QueryRow(reminderRuleSelect+" WHERE id = ? AND user_id = ?", ruleID, userID))
+}
+
+func scanReminderRule(row rowScanner) (*models.ReminderRule, error) {
+	var rule models.ReminderRule
+	var templateID sql.NullInt64
+	var lastRunAt sql.NullString
+	var createdAtStr, updatedAtStr string
+
+	err := row.Scan(
+		&rule.ID, &rule.Name, &rule.MinBalance, &rule.DaysSincePayment, &rule.Channel, &templateID,
+		&rule.RepeatEveryDays, &rule.IsActive, &lastRunAt, &rule.UserID, &createdAtStr, &updatedAtStr,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	rule.TemplateID = nullIntPtr(templateID)
+	rule.LastRunAt = parseNullTimestamp(lastRunAt)
+	rule.CreatedAt, _ = time.Parse("2006-01-02 15:04:05", createdAtStr)
+	rule.UpdatedAt, _ = time.Parse("2006-01-02 15:04:05", updatedAtStr)
+
+	return &rule, nil
+}
@@ -0,0 +1,174 @@
+package handlers
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"time"
+	_ "time/tzdata" // time zones resolve even where the host has no zoneinfo
+
+	"khata-book-backend/database"
+	"khata-book-backend/pkg/logger"
+)
+
+// quietHourRegex matches a wall-clock time such as "21:00"
+var quietHourRegex = regexp.MustCompile(`^([01][0-9]|2[0-3]):[0-5][0-9]$`)
+
+// defaultTimezone is the time zone of businesses and customers that have not
+// set one: DEFAULT_TIMEZONE if set, otherwise India
+func defaultTimezone() string {
+	if tz := os.Getenv("DEFAULT_TIMEZONE"); tz != "" {
+		return tz
+	}
+	return "Asia/Kolkata"
+}
+
+// validTimezone reports whether name is an IANA time zone such as
+// "Asia/Kolkata" or "America/New_York"
+func validTimezone(name string) bool {
+	if name == "" || name == "Local" {
+		return false
+	}
+	_, err := time.LoadLocation(name)
+	return err == nil
+}
+
+// reminderLocation resolves the first usable time zone among names, falling
+// back to the default time zone and then the server's
+func reminderLocation(names ...sql.NullString) *time.Location {
+	names = append(names, sql.NullString{String: defaultTimezone(), Valid: true})
+	for _, name := range names {
+		if !name.Valid || name.String == "" {
+			continue
+		}
+		if loc, err := time.LoadLocation(name.String); err == nil {
+			return loc
+		}
+	}
+	return time.Local
+}
+
+// businessToday returns the business's current date, in the calendarDate
+// form, as seen in its own time zone
+func businessToday(q queryRower, userID int, now time.Time) (time.Time, error) {
+	var timezone sql.NullString
+	if err := q.QueryRow("SELECT timezone FROM users WHERE id = ?", userID).Scan(&timezone); err != nil {
+		return time.Time{}, err
+	}
+	return calendarDate(now.In(reminderLocation(timezone))), nil
+}
+
+// quietHourMinutes turns a TIME column value or "HH:MM" string into minutes
+// after midnight
+func quietHourMinutes(value string) (int, bool) {
+	if len(value) < 5 || !quietHourRegex.MatchString(value[:5]) {
+		return 0, false
+	}
+	hours, _ := strconv.Atoi(value[:2])
+	minutes, _ := strconv.Atoi(value[3:5])
+	return hours*60 + minutes, true
+}
+
+// quietHourString formats a TIME column value as "HH:MM", empty when unset
+func quietHourString(value sql.NullString) string {
+	if !value.Valid || len(value.String) < 5 {
+		return ""
+	}
+	return value.String[:5]
+}
+
+// quietHoursEnd reports whether now falls in the quiet hours from start to
+// end in loc, and if so when they end. A window whose end is before its
+// start runs past midnight; quiet hours that are unset or empty never apply.
+func quietHoursEnd(now time.Time, loc *time.Location, start, end sql.NullString) (time.Time, bool) {
+	startMin, ok := quietHourMinutes(start.String)
+	if !start.Valid || !ok {
+		return time.Time{}, false
+	}
+	endMin, ok := quietHourMinutes(end.String)
+	if !end.Valid || !ok || startMin == endMin {
+		return time.Time{}, false
+	}
+
+	local := now.In(loc)
+	current := local.Hour()*60 + local.Minute()
+	var quiet bool
+	if startMin < endMin {
+		quiet = current >= startMin && current < endMin
+	} else {
+		quiet = current >= startMin || current < endMin
+	}
+	if !quiet {
+		return time.Time{}, false
+	}
+
+	wake := time.Date(local.Year(), local.Month(), local.Day(), endMin/60, endMin%60, 0, 0, loc)
+	if !wake.After(local) {
+		wake = wake.AddDate(0, 0, 1)
+	}
+	return wake, true
+}
+
+// reminderSendTime returns when a claimed reminder may go out, now if it
+// can be sent straight away. A reminder is due from the start of its due
+// date in the business's time zone unless it was asked to go out now, and is
+// held back during the business's quiet hours as the customer sees them.
+func reminderSendTime(d *reminderDelivery, now time.Time) (sendAt time.Time, quiet bool) {
+	if !d.SendNow {
+		dueAt, err := time.ParseInLocation("2006-01-02", d.DueDate, reminderLocation(d.BusinessTimezone))
+		if err == nil && dueAt.After(now) {
+			return dueAt, false
+		}
+	}
+
+	customerLoc := reminderLocation(d.CustomerTimezone, d.BusinessTimezone)
+	if wake, ok := quietHoursEnd(now, customerLoc, d.QuietHoursStart, d.QuietHoursEnd); ok {
+		return wake, true
+	}
+	return now, false
+}
+
+// deferReminder releases a claimed reminder until sendAt without counting a
+// delivery attempt. Quiet-hours deferrals are recorded in the history.
+func deferReminder(d *reminderDelivery, sendAt time.Time, quiet bool) error {
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec("UPDATE reminders SET next_attempt_at = ? WHERE id = ? AND status = 'pending'",
+		sendAt.In(time.Local).Format("2006-01-02 15:04:05"), d.ID)
+	if err != nil {
+		return err
+	}
+	if deferred, err := result.RowsAffected(); err != nil || deferred == 0 {
+		return err
+	}
+
+	if quiet {
+		err = recordReminderEvent(tx, reminderEvent{
+			ReminderID: d.ID,
+			UserID:     d.UserID,
+			Event:      reminderEventDeferred,
+			Channel:    d.Channel,
+			Detail:     fmt.Sprintf("Quiet hours; deferred until %s", sendAt.Format("2006-01-02 15:04 MST")),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	logger.L.WithFields(map[string]interface{}{
+		"reminder_id": d.ID,
+		"user_id":     d.UserID,
+		"send_at":     sendAt,
+		"quiet_hours": quiet,
+	}).Debug("Reminder deferred")
+	return nil
+}
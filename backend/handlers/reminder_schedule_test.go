@@ -0,0 +1,131 @@
+package handlers
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+)
+
+func nullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
+
+func mustLoadLocation(t *testing.T, name string) *time.Location {
+	t.Helper()
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		t.Fatalf("load %s: %v", name, err)
+	}
+	return loc
+}
+
+func TestQuietHoursEnd(t *testing.T) {
+	ist := mustLoadLocation(t, "Asia/Kolkata")
+	at := func(s string) time.Time {
+		ts, err := time.ParseInLocation("2006-01-02 15:04", s, ist)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return ts
+	}
+
+	tests := []struct {
+		name       string
+		now        string
+		start, end string
+		want       string // empty when now is not in quiet hours
+	}{
+		{"overnight window before midnight", "2026-10-19 23:30", "21:00", "08:00", "2026-10-20 08:00"},
+		{"overnight window after midnight", "2026-10-20 02:00", "21:00", "08:00", "2026-10-20 08:00"},
+		{"overnight window starts on the minute", "2026-10-19 21:00", "21:00", "08:00", "2026-10-20 08:00"},
+		{"overnight window has ended", "2026-10-20 08:00", "21:00", "08:00", ""},
+		{"outside overnight window", "2026-10-19 12:00", "21:00", "08:00", ""},
+		{"daytime window", "2026-10-19 14:00", "13:00", "15:00", "2026-10-19 15:00"},
+		{"before daytime window", "2026-10-19 12:59", "13:00", "15:00", ""},
+		{"TIME column values", "2026-10-19 23:30", "21:00:00", "08:00:00", "2026-10-20 08:00"},
+		{"start equals end", "2026-10-19 22:00", "22:00", "22:00", ""},
+		{"unset start", "2026-10-19 23:30", "", "08:00", ""},
+		{"unset end", "2026-10-19 23:30", "21:00", "", ""},
+		{"invalid time", "2026-10-19 23:30", "25:00", "08:00", ""},
+	}
+	for _, tt := range tests {
+		wake, quiet := quietHoursEnd(at(tt.now), ist, nullString(tt.start), nullString(tt.end))
+		if tt.want == "" {
+			if quiet {
+				t.Errorf("%s: quiet until %v, want not quiet", tt.name, wake)
+			}
+			continue
+		}
+		if !quiet || !wake.Equal(at(tt.want)) {
+			t.Errorf("%s: quietHoursEnd = %v, %v, want %s", tt.name, wake, quiet, tt.want)
+		}
+	}
+}
+
+func TestReminderSendTime(t *testing.T) {
+	utc := func(s string) time.Time {
+		ts, err := time.Parse("2006-01-02 15:04", s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return ts
+	}
+
+	tests := []struct {
+		name      string
+		delivery  reminderDelivery
+		now       string
+		want      string
+		wantQuiet bool
+	}{
+		{
+			name:     "not yet due in the business's time zone",
+			delivery: reminderDelivery{DueDate: "2026-10-20", BusinessTimezone: nullString("Asia/Kolkata")},
+			now:      "2026-10-19 17:00", // 22:30 on the 19th in India
+			want:     "2026-10-19 18:30", // midnight in India
+		},
+		{
+			name:     "due once the business's day starts",
+			delivery: reminderDelivery{DueDate: "2026-10-20", BusinessTimezone: nullString("Asia/Kolkata")},
+			now:      "2026-10-19 19:00",
+			want:     "2026-10-19 19:00",
+		},
+		{
+			name:     "send now skips the due date",
+			delivery: reminderDelivery{DueDate: "2026-12-01", SendNow: true, BusinessTimezone: nullString("Asia/Kolkata")},
+			now:      "2026-10-19 12:00",
+			want:     "2026-10-19 12:00",
+		},
+		{
+			name: "quiet hours as the business sees them",
+			delivery: reminderDelivery{DueDate: "2026-10-19", BusinessTimezone: nullString("Asia/Kolkata"),
+				QuietHoursStart: nullString("21:00"), QuietHoursEnd: nullString("08:00")},
+			now:       "2026-10-19 18:00", // 23:30 in India
+			want:      "2026-10-20 02:30", // 08:00 in India
+			wantQuiet: true,
+		},
+		{
+			name: "quiet hours as a customer abroad sees them",
+			delivery: reminderDelivery{DueDate: "2026-10-19", BusinessTimezone: nullString("Asia/Kolkata"),
+				CustomerTimezone: nullString("America/New_York"),
+				QuietHoursStart:  nullString("21:00"), QuietHoursEnd: nullString("08:00")},
+			now:       "2026-10-20 03:00", // 08:30 in India, 23:00 in New York
+			want:      "2026-10-20 12:00", // 08:00 in New York
+			wantQuiet: true,
+		},
+		{
+			name: "customer abroad is awake during the business's night",
+			delivery: reminderDelivery{DueDate: "2026-10-19", BusinessTimezone: nullString("Asia/Kolkata"),
+				CustomerTimezone: nullString("America/New_York"),
+				QuietHoursStart:  nullString("21:00"), QuietHoursEnd: nullString("08:00")},
+			now:  "2026-10-19 18:00", // 23:30 in India, 14:00 in New York
+			want: "2026-10-19 18:00",
+		},
+	}
+	for _, tt := range tests {
+		sendAt, quiet := reminderSendTime(&tt.delivery, utc(tt.now))
+		if !sendAt.Equal(utc(tt.want)) || quiet != tt.wantQuiet {
+			t.Errorf("%s: reminderSendTime = %v, %v, want %s UTC, %v", tt.name, sendAt.UTC(), quiet, tt.want, tt.wantQuiet)
+		}
+	}
+}
@@ -0,0 +1,230 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"khata-book-backend/database"
+	"khata-book-backend/models"
+	"khata-book-backend/pkg/logger"
+
+	"github.com/gorilla/mux"
+)
+
+const (
+	// minReminderSnooze and maxReminderSnooze bound how long a reminder can
+	// be snoozed for
+	minReminderSnooze = time.Minute
+	maxReminderSnooze = 365 * 24 * time.Hour
+)
+
+// SnoozeReminder puts a reminder to sleep until a given time, after which
+// the dispatcher picks it up again as pending
+func SnoozeReminder(w http.ResponseWriter, r *http.Request) {
+	// Get user ID from JWT token
+	userID, err := getUserIDFromToken(r)
+	if err != nil {
+		writeJSON(w, http.StatusUnauthorized, map[string]interface{}{"success": false, "error": "unauthorized", "message": "Invalid token"})
+		return
+	}
+
+	reminderID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"success": false, "error": "invalid_id", "message": "Invalid reminder ID"})
+		return
+	}
+
+	var snoozeReq models.ReminderSnoozeRequest
+	err = json.NewDecoder(r.Body).Decode(&snoozeReq)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"success": false, "error": "invalid_request", "message": "Invalid request body"})
+		return
+	}
+
+	now := time.Now()
+	until, err := parseReminderSnooze(snoozeReq, now)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"success": false, "error": "invalid_snooze", "message": err.Error()})
+		return
+	}
+
+	tx, err := database.DB.Begin()
+	if err != nil {
+		logger.L.WithField("error", err).Error("Error starting transaction")
+		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "server_error", "message": "Could not snooze reminder"})
+		return
+	}
+	defer tx.Rollback()
+
+	var status, channel string
+	err = tx.QueryRow("SELECT status, channel FROM reminders WHERE id = ? AND user_id = ? FOR UPDATE", reminderID, userID).Scan(&status, &channel)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			writeJSON(w, http.StatusNotFound, map[string]interface{}{"success": false, "error": "not_found", "message": "Reminder not found"})
+			return
+		}
+		logger.L.WithField("error", err).Error("Error querying reminder")
+		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "server_error", "message": "Could not snooze reminder"})
+		return
+	}
+
+	if status == "paid" {
+		writeJSON(w, http.StatusConflict, map[string]interface{}{"success": false, "error": "reminder_paid", "message": "A paid reminder cannot be snoozed"})
+		return
+	}
+
+	untilStr := until.Format("2006-01-02 15:04:05")
+	_, err = tx.Exec(`
+		UPDATE reminders
+		SET status = 'snoozed', snoozed_until = ?, send_now = FALSE, next_attempt_at = NULL, updated_at = CURRENT_TIMESTAMP
+		WHERE id = ?`, untilStr, reminderID)
+	if err == nil {
+		err = recordReminderEvent(tx, reminderEvent{
+			ReminderID: reminderID,
+			UserID:     userID,
+			Event:      reminderEventSnoozed,
+			FromStatus: status,
+			ToStatus:   "snoozed",
+			Channel:    channel,
+			Detail:     "Snoozed until " + untilStr,
+		})
+	}
+	if err == nil {
+		err = tx.Commit()
+	}
+	if err != nil {
+		logger.L.WithField("error", err).Error("Error snoozing reminder")
+		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "server_error", "message": "Could not snooze reminder"})
+		return
+	}
+
+	logger.L.WithFields(map[string]interface{}{
+		"reminder_id":   reminderID,
+		"user_id":       userID,
+		"snoozed_until": untilStr,
+	}).Info("Reminder snoozed successfully")
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"success":       true,
+		"message":       "Reminder snoozed successfully",
+		"reminder_id":   reminderID,
+		"status":        "snoozed",
+		"snoozed_until": untilStr,
+	})
+}
+
+// parseReminderSnooze works out the wake-up time of a snooze request. A
+// date without a time wakes at the start of that day.
+func parseReminderSnooze(req models.ReminderSnoozeRequest, now time.Time) (time.Time, error) {
+	duration := strings.TrimSpace(req.Duration)
+	untilStr := strings.TrimSpace(req.Until)
+	if (duration == "") == (untilStr == "") {
+		return time.Time{}, errors.New("exactly one of duration or until is required")
+	}
+
+	var until time.Time
+	if duration != "" {
+		var d time.Duration
+		if days, err := strconv.Atoi(strings.TrimSuffix(duration, "d")); err == nil && strings.HasSuffix(duration, "d") {
+			d = time.Duration(days) * 24 * time.Hour
+		} else if d, err = time.ParseDuration(duration); err != nil {
+			return time.Time{}, errors.New("duration must be like '30m', '2h' or '3d'")
+		}
+		if d < minReminderSnooze {
+			return time.Time{}, errors.New("duration must be at least a minute")
+		}
+		until = now.Add(d)
+	} else {
+		var err error
+		until, err = time.ParseInLocation("2006-01-02", untilStr, time.Local)
+		if err != nil {
+			until, err = time.Parse(time.RFC3339, untilStr)
+			if err != nil {
+				return time.Time{}, errors.New("until must be a date in YYYY-MM-DD format or an RFC 3339 time")
+			}
+			until = until.Local()
+		}
+		if !until.After(now) {
+			return time.Time{}, errors.New("until must be in the future")
+		}
+	}
+
+	if until.Sub(now) > maxReminderSnooze {
+		return time.Time{}, errors.New("a reminder can be snoozed for at most a year")
+	}
+	return until.Truncate(time.Second), nil
+}
+
+// wakeSnoozedReminders moves reminders whose snooze has run out back to
+// pending with a fresh set of delivery attempts
+func wakeSnoozedReminders(now time.Time) error {
+	nowStr := now.Format("2006-01-02 15:04:05")
+	rows, err := database.DB.Query(`
+		SELECT id, user_id, channel FROM reminders
+		WHERE status = 'snoozed' AND snoozed_until <= ?
+		ORDER BY snoozed_until ASC
+		LIMIT ?`, nowStr, reminderBatchSize)
+	if err != nil {
+		return err
+	}
+
+	var woken []reminderEvent
+	for rows.Next() {
+		e := reminderEvent{Event: reminderEventWoke, FromStatus: "snoozed", ToStatus: "pending"}
+		if err := rows.Scan(&e.ReminderID, &e.UserID, &e.Channel); err != nil {
+			rows.Close()
+			return err
+		}
+		woken = append(woken, e)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, e := range woken {
+		if err := wakeSnoozedReminder(e, nowStr); err != nil {
+			logger.L.WithFields(map[string]interface{}{"reminder_id": e.ReminderID, "error": err}).Error("Error waking snoozed reminder")
+		}
+	}
+	return nil
+}
+
+func wakeSnoozedReminder(e reminderEvent, nowStr string) error {
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// The status check keeps a reminder that was changed meanwhile asleep
+	result, err := tx.Exec(`
+		UPDATE reminders
+		SET status = 'pending', snoozed_until = NULL, attempts = 0, next_attempt_at = NULL, last_error = NULL,
+			updated_at = CURRENT_TIMESTAMP
+		WHERE id = ? AND status = 'snoozed' AND snoozed_until <= ?`, e.ReminderID, nowStr)
+	if err != nil {
+		return err
+	}
+	if woke, err := result.RowsAffected(); err != nil || woke == 0 {
+		return err
+	}
+
+	if err := recordReminderEvent(tx, e); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	logger.L.WithFields(map[string]interface{}{
+		"reminder_id": e.ReminderID,
+		"user_id":     e.UserID,
+	}).Info("Snoozed reminder woke up")
+	return nil
+}
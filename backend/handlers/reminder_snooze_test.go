@@ -0,0 +1,51 @@
+package handlers
+
+import (
+	"testing"
+	"time"
+
+	"khata-book-backend/models"
+)
+
+func TestParseReminderSnooze(t *testing.T) {
+	now := time.Date(2026, 10, 19, 10, 30, 15, 500_000_000, time.Local)
+
+	tests := []struct {
+		name     string
+		duration string
+		until    string
+		want     time.Time // zero when the request is rejected
+	}{
+		{"minutes", "30m", "", time.Date(2026, 10, 19, 11, 0, 15, 0, time.Local)},
+		{"hours", "2h", "", time.Date(2026, 10, 19, 12, 30, 15, 0, time.Local)},
+		{"days", "3d", "", time.Date(2026, 10, 22, 10, 30, 15, 0, time.Local)},
+		{"surrounding spaces", " 1h ", "", time.Date(2026, 10, 19, 11, 30, 15, 0, time.Local)},
+		{"exactly a minute", "1m", "", time.Date(2026, 10, 19, 10, 31, 15, 0, time.Local)},
+		{"exactly a year", "365d", "", time.Date(2027, 10, 19, 10, 30, 15, 0, time.Local)},
+		{"under a minute", "30s", "", time.Time{}},
+		{"no days", "0d", "", time.Time{}},
+		{"negative days", "-1d", "", time.Time{}},
+		{"more than a year", "366d", "", time.Time{}},
+		{"not a duration", "soon", "", time.Time{}},
+		{"date wakes at the start of the day", "", "2026-10-25", time.Date(2026, 10, 25, 0, 0, 0, 0, time.Local)},
+		{"RFC 3339 time", "", "2026-10-20T09:00:00Z", time.Date(2026, 10, 20, 9, 0, 0, 0, time.UTC)},
+		{"today has already started", "", "2026-10-19", time.Time{}},
+		{"past time", "", "2026-10-18T09:00:00Z", time.Time{}},
+		{"date more than a year away", "", "2027-11-01", time.Time{}},
+		{"not a date", "", "next week", time.Time{}},
+		{"neither given", "", "", time.Time{}},
+		{"both given", "2h", "2026-10-25", time.Time{}},
+	}
+	for _, tt := range tests {
+		got, err := parseReminderSnooze(models.ReminderSnoozeRequest{Duration: tt.duration, Until: tt.until}, now)
+		if tt.want.IsZero() {
+			if err == nil {
+				t.Errorf("%s: parseReminderSnooze = %v, want an error", tt.name, got)
+			}
+			continue
+		}
+		if err != nil || !got.Equal(tt.want) {
+			t.Errorf("%s: parseReminderSnooze = %v, %v, want %v", tt.name, got, err, tt.want)
+		}
+	}
+}
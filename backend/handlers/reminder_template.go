@@ -0,0 +1,715 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"khata-book-backend/database"
+	"khata-book-backend/models"
+	"khata-book-backend/pkg/logger"
+	"khata-book-backend/pkg/notify"
+
+	"github.com/gorilla/mux"
+)
+
+const (
+	// maxReminderTemplateBody caps the length of a template body in characters
+	maxReminderTemplateBody = 2000
+	// maxReminderTemplateSubject caps the length of an email subject template
+	maxReminderTemplateSubject = 200
+	// defaultReminderLanguage is used when a customer has no language set or
+	// no template exists in their language
+	defaultReminderLanguage = "en"
+)
+
+// languageTagRegex accepts a language code with an optional region, e.g.
+// "hi" or "en-IN"
+var languageTagRegex = regexp.MustCompile(`^[a-z]{2,3}(-[A-Z]{2})?$`)
+
+// builtinReminderTemplate is the message used when a business has no
+// template of its own for a channel and language. The payment link line is
+// only added when the business has a UPI ID.
+type builtinReminderTemplate struct {
+	Subject  string
+	Body     string
+	LinkLine string
+}
+
+var builtinReminderTemplates = map[string]builtinReminderTemplate{
+	"en": {
+		Subject:  "Payment reminder from {businessName}",
+		Body:     "Dear {customerName}, a payment of {amount} to {businessName} is due on {dueDate}. Please pay at the earliest.",
+		LinkLine: "\nPay now: {paymentLink}",
+	},
+	"hi": {
+		Subject:  "{businessName} से भुगतान अनुस्मारक",
+		Body:     "प्रिय {customerName}, {businessName} को {amount} का भुगतान {dueDate} तक देय है। कृपया जल्द से जल्द भुगतान करें।",
+		LinkLine: "\nअभी भुगतान करें: {paymentLink}",
+	},
+}
+
+const reminderTemplateSelect = `
+	SELECT id, name, channel, language, subject, body, is_default, user_id, created_at, updated_at
+	FROM reminder_templates`
+
+// GetReminderTemplates lists the business's reminder templates, optionally
+// filtered by ?channel and ?language
+func GetReminderTemplates(w http.ResponseWriter, r *http.Request) {
+	// Get user ID from JWT token
+	userID, err := getUserIDFromToken(r)
+	if err != nil {
+		writeJSON(w, http.StatusUnauthorized, map[string]interface{}{"success": false, "error": "unauthorized", "message": "Invalid token"})
+		return
+	}
+
+	query := reminderTemplateSelect + " WHERE user_id = ?"
+	args := []interface{}{userID}
+
+	if channel := r.URL.Query().Get("channel"); channel != "" {
+		query += " AND channel = ?"
+		args = append(args, channel)
+	}
+
+	if language := r.URL.Query().Get("language"); language != "" {
+		query += " AND language = ?"
+		args = append(args, language)
+	}
+
+	query += " ORDER BY channel ASC, language ASC, is_default DESC, name ASC"
+
+	rows, err := database.DB.Query(query, args...)
+	if err != nil {
+		logger.L.WithField("error", err).Error("Error querying reminder templates")
+		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "server_error", "message": "Could not fetch reminder templates"})
+		return
+	}
+	defer rows.Close()
+
+	templates := []*models.ReminderTemplate{}
+	for rows.Next() {
+		tmpl, err := scanReminderTemplate(rows)
+		if err != nil {
+			logger.L.WithField("error", err).Error("Error scanning reminder template")
+			continue
+		}
+		templates = append(templates, tmpl)
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"success":   true,
+		"templates": templates,
+		"variables": notify.TemplateVariables,
+		"count":     len(templates),
+	})
+}
+
+// CreateReminderTemplate adds a reminder template. Making it the default
+// replaces the previous default for its channel and language.
+func CreateReminderTemplate(w http.ResponseWriter, r *http.Request) {
+	// Get user ID from JWT token
+	userID, err := getUserIDFromToken(r)
+	if err != nil {
+		writeJSON(w, http.StatusUnauthorized, map[string]interface{}{"success": false, "error": "unauthorized", "message": "Invalid token"})
+		return
+	}
+
+	var tmplReq models.ReminderTemplateRequest
+	err = json.NewDecoder(r.Body).Decode(&tmplReq)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"success": false, "error": "invalid_request", "message": "Invalid request body"})
+		return
+	}
+
+	tmpl := models.ReminderTemplate{
+		Name:     tmplReq.Name,
+		Channel:  tmplReq.Channel,
+		Language: tmplReq.Language,
+		Subject:  tmplReq.Subject,
+		Body:     tmplReq.Body,
+		UserID:   userID,
+	}
+	if tmpl.Language == "" {
+		tmpl.Language = defaultReminderLanguage
+	}
+	if tmplReq.IsDefault != nil {
+		tmpl.IsDefault = *tmplReq.IsDefault
+	}
+	if !validateReminderTemplate(w, &tmpl) {
+		return
+	}
+
+	tx, err := database.DB.Begin()
+	if err != nil {
+		logger.L.WithField("error", err).Error("Error starting transaction")
+		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "server_error", "message": "Could not create reminder template"})
+		return
+	}
+	defer tx.Rollback()
+
+	if tmpl.IsDefault {
+		if err := clearDefaultReminderTemplate(tx, userID, tmpl.Channel, tmpl.Language, 0); err != nil {
+			logger.L.WithField("error", err).Error("Error clearing default reminder template")
+			writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "server_error", "message": "Could not create reminder template"})
+			return
+		}
+	}
+
+	result, err := tx.Exec(`
+		INSERT INTO reminder_templates (name, channel, language, subject, body, is_default, user_id)
+		VALUES (?, ?, ?, NULLIF(?, ''), ?, ?, ?)`,
+		tmpl.Name, tmpl.Channel, tmpl.Language, tmpl.Subject, tmpl.Body, tmpl.IsDefault, userID)
+	if err != nil {
+		if strings.Contains(err.Error(), "Duplicate entry") {
+			writeJSON(w, http.StatusConflict, map[string]interface{}{"success": false, "error": "template_exists", "message": "A reminder template with this name already exists"})
+			return
+		}
+		logger.L.WithField("error", err).Error("Error inserting reminder template")
+		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "server_error", "message": "Could not create reminder template"})
+		return
+	}
+
+	templateID, err := result.LastInsertId()
+	if err != nil {
+		logger.L.WithField("error", err).Error("Error getting inserted reminder template ID")
+		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "server_error", "message": "Could not create reminder template"})
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		logger.L.WithField("error", err).Error("Error committing reminder template")
+		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "server_error", "message": "Could not create reminder template"})
+		return
+	}
+
+	created, err := getReminderTemplate(database.DB, int(templateID), userID)
+	if err != nil {
+		logger.L.WithField("error", err).Error("Error loading created reminder template")
+		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "server_error", "message": "Could not create reminder template"})
+		return
+	}
+
+	logger.L.WithFields(map[string]interface{}{
+		"template_id": templateID,
+		"user_id":     userID,
+		"channel":     tmpl.Channel,
+		"language":    tmpl.Language,
+	}).Info("Reminder template created successfully")
+
+	writeJSON(w, http.StatusCreated, map[string]interface{}{
+		"success":  true,
+		"message":  "Reminder template created successfully",
+		"template": created,
+	})
+}
+
+// GetReminderTemplate returns a single reminder template
+func GetReminderTemplate(w http.ResponseWriter, r *http.Request) {
+	// Get user ID from JWT token
+	userID, err := getUserIDFromToken(r)
+	if err != nil {
+		writeJSON(w, http.StatusUnauthorized, map[string]interface{}{"success": false, "error": "unauthorized", "message": "Invalid token"})
+		return
+	}
+
+	templateID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"success": false, "error": "invalid_id", "message": "Invalid reminder template ID"})
+		return
+	}
+
+	tmpl, err := getReminderTemplate(database.DB, templateID, userID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			writeJSON(w, http.StatusNotFound, map[string]interface{}{"success": false, "error": "not_found", "message": "Reminder template not found"})
+			return
+		}
+		logger.L.WithField("error", err).Error("Error querying reminder template")
+		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "server_error", "message": "Could not fetch reminder template"})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{"success": true, "template": tmpl})
+}
+
+// UpdateReminderTemplate changes the fields given in the request body
+func UpdateReminderTemplate(w http.ResponseWriter, r *http.Request) {
+	// Get user ID from JWT token
+	userID, err := getUserIDFromToken(r)
+	if err != nil {
+		writeJSON(w, http.StatusUnauthorized, map[string]interface{}{"success": false, "error": "unauthorized", "message": "Invalid token"})
+		return
+	}
+
+	templateID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"success": false, "error": "invalid_id", "message": "Invalid reminder template ID"})
+		return
+	}
+
+	var tmplReq models.ReminderTemplateRequest
+	err = json.NewDecoder(r.Body).Decode(&tmplReq)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"success": false, "error": "invalid_request", "message": "Invalid request body"})
+		return
+	}
+
+	tx, err := database.DB.Begin()
+	if err != nil {
+		logger.L.WithField("error", err).Error("Error starting transaction")
+		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "server_error", "message": "Could not update reminder template"})
+		return
+	}
+	defer tx.Rollback()
+
+	tmpl, err := getReminderTemplate(tx, templateID, userID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			writeJSON(w, http.StatusNotFound, map[string]interface{}{"success": false, "error": "not_found", "message": "Reminder template not found"})
+			return
+		}
+		logger.L.WithField("error", err).Error("Error querying reminder template")
+		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "server_error", "message": "Could not update reminder template"})
+		return
+	}
+
+	if tmplReq.Name != "" {
+		tmpl.Name = tmplReq.Name
+	}
+	if tmplReq.Channel != "" {
+		tmpl.Channel = tmplReq.Channel
+	}
+	if tmplReq.Language != "" {
+		tmpl.Language = tmplReq.Language
+	}
+	if tmplReq.Subject != nil {
+		tmpl.Subject = tmplReq.Subject
+	}
+	if tmplReq.Body != "" {
+		tmpl.Body = tmplReq.Body
+	}
+	if tmplReq.IsDefault != nil {
+		tmpl.IsDefault = *tmplReq.IsDefault
+	}
+	if !validateReminderTemplate(w, tmpl) {
+		return
+	}
+
+	if tmpl.IsDefault {
+		if err := clearDefaultReminderTemplate(tx, userID, tmpl.Channel, tmpl.Language, templateID); err != nil {
+			logger.L.WithField("error", err).Error("Error clearing default reminder template")
+			writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "server_error", "message": "Could not update reminder template"})
+			return
+		}
+	}
+
+	_, err = tx.Exec(`
+		UPDATE reminder_templates
+		SET name = ?, channel = ?, language = ?, subject = NULLIF(?, ''), body = ?, is_default = ?, updated_at = CURRENT_TIMESTAMP
+		WHERE id = ? AND user_id = ?`,
+		tmpl.Name, tmpl.Channel, tmpl.Language, tmpl.Subject, tmpl.Body, tmpl.IsDefault, templateID, userID)
+	if err != nil {
+		if strings.Contains(err.Error(), "Duplicate entry") {
+			writeJSON(w, http.StatusConflict, map[string]interface{}{"success": false, "error": "template_exists", "message": "A reminder template with this name already exists"})
+			return
+		}
+		logger.L.WithField("error", err).Error("Error updating reminder template")
+		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "server_error", "message": "Could not update reminder template"})
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		logger.L.WithField("error", err).Error("Error committing reminder template")
+		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "server_error", "message": "Could not update reminder template"})
+		return
+	}
+
+	updated, err := getReminderTemplate(database.DB, templateID, userID)
+	if err != nil {
+		logger.L.WithField("error", err).Error("Error loading updated reminder template")
+		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "server_error", "message": "Could not update reminder template"})
+		return
+	}
+
+	logger.L.WithFields(map[string]interface{}{
+		"template_id": templateID,
+		"user_id":     userID,
+	}).Info("Reminder template updated successfully")
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"success":  true,
+		"message":  "Reminder template updated successfully",
+		"template": updated,
+	})
+}
+
+// DeleteReminderTemplate removes a reminder template. Reminders that used it
+// fall back to the default template for their channel and language.
+func DeleteReminderTemplate(w http.ResponseWriter, r *http.Request) {
+	// Get user ID from JWT token
+	userID, err := getUserIDFromToken(r)
+	if err != nil {
+		writeJSON(w, http.StatusUnauthorized, map[string]interface{}{"success": false, "error": "unauthorized", "message": "Invalid token"})
+		return
+	}
+
+	templateID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"success": false, "error": "invalid_id", "message": "Invalid reminder template ID"})
+		return
+	}
+
+	result, err := database.DB.Exec("DELETE FROM reminder_templates WHERE id = ? AND user_id = ?", templateID, userID)
+	if err != nil {
+		logger.L.WithField("error", err).Error("Error deleting reminder template")
+		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "server_error", "message": "Could not delete reminder template"})
+		return
+	}
+
+	if deleted, err := result.RowsAffected(); err == nil && deleted == 0 {
+		writeJSON(w, http.StatusNotFound, map[string]interface{}{"success": false, "error": "not_found", "message": "Reminder template not found"})
+		return
+	}
+
+	logger.L.WithFields(map[string]interface{}{
+		"template_id": templateID,
+		"user_id":     userID,
+	}).Info("Reminder template deleted successfully")
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{"success": true, "message": "Reminder template deleted successfully"})
+}
+
+// PreviewReminderTemplate renders a template against one of the business's
+// reminders exactly as the dispatcher would send it, without sending it
+func PreviewReminderTemplate(w http.ResponseWriter, r *http.Request) {
+	// Get user ID from JWT token
+	userID, err := getUserIDFromToken(r)
+	if err != nil {
+		writeJSON(w, http.StatusUnauthorized, map[string]interface{}{"success": false, "error": "unauthorized", "message": "Invalid token"})
+		return
+	}
+
+	templateID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"success": false, "error": "invalid_id", "message": "Invalid reminder template ID"})
+		return
+	}
+
+	var previewReq models.ReminderPreviewRequest
+	err = json.NewDecoder(r.Body).Decode(&previewReq)
+	if err != nil || previewReq.ReminderID <= 0 {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"success": false, "error": "invalid_request", "message": "reminder_id is required"})
+		return
+	}
+
+	tmpl, err := getReminderTemplate(database.DB, templateID, userID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			writeJSON(w, http.StatusNotFound, map[string]interface{}{"success": false, "error": "not_found", "message": "Reminder template not found"})
+			return
+		}
+		logger.L.WithField("error", err).Error("Error querying reminder template")
+		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "server_error", "message": "Could not preview reminder template"})
+		return
+	}
+
+	delivery, err := loadReminderDelivery(previewReq.ReminderID)
+	if err == sql.ErrNoRows || (err == nil && delivery.UserID != userID) {
+		writeJSON(w, http.StatusNotFound, map[string]interface{}{"success": false, "error": "reminder_not_found", "message": "Reminder not found"})
+		return
+	}
+	if err != nil {
+		logger.L.WithField("error", err).Error("Error loading reminder for preview")
+		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "server_error", "message": "Could not preview reminder template"})
+		return
+	}
+
+	// Preview the template on its own channel even if the reminder uses another
+	delivery.Channel = tmpl.Channel
+	rendered, err := renderReminder(delivery, tmpl)
+	if err != nil {
+		writeJSON(w, http.StatusUnprocessableEntity, map[string]interface{}{"success": false, "error": "render_failed", "message": err.Error()})
+		return
+	}
+	if msg, err := addressReminder(delivery, rendered); err == nil {
+		rendered.To = msg.To
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{"success": true, "preview": rendered})
+}
+
+// validateReminderTemplate trims and checks a template before it is written.
+// Placeholders must be known variables so a template cannot fail at send
+// time.
+func validateReminderTemplate(w http.ResponseWriter, tmpl *models.ReminderTemplate) bool {
+	tmpl.Name = strings.TrimSpace(tmpl.Name)
+	if tmpl.Name == "" || utf8.RuneCountInString(tmpl.Name) > 100 {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"success": false, "error": "invalid_name", "message": "Template name is required and must be at most 100 characters"})
+		return false
+	}
+
+	if tmpl.Channel != notify.ChannelSMS && tmpl.Channel != notify.ChannelWhatsApp && tmpl.Channel != notify.ChannelEmail {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"success": false, "error": "invalid_channel", "message": "Channel must be 'sms', 'whatsapp', or 'email'"})
+		return false
+	}
+
+	tmpl.Language = strings.TrimSpace(tmpl.Language)
+	if !languageTagRegex.MatchString(tmpl.Language) {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"success": false, "error": "invalid_language", "message": "Language must be a code such as 'en', 'hi' or 'en-IN'"})
+		return false
+	}
+
+	tmpl.Body = strings.TrimSpace(tmpl.Body)
+	if tmpl.Body == "" || utf8.RuneCountInString(tmpl.Body) > maxReminderTemplateBody {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"success": false, "error": "invalid_body", "message": fmt.Sprintf("Template body is required and must be at most %d characters", maxReminderTemplateBody)})
+		return false
+	}
+	if err := notify.ValidateTemplate(tmpl.Body); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"success": false, "error": "invalid_body", "message": "Template body: " + err.Error()})
+		return false
+	}
+
+	if tmpl.Subject != nil {
+		subject := strings.TrimSpace(*tmpl.Subject)
+		tmpl.Subject = &subject
+		if utf8.RuneCountInString(subject) > maxReminderTemplateSubject || strings.ContainsAny(subject, "\r\n") {
+			writeJSON(w, http.StatusBadRequest, map[string]interface{}{"success": false, "error": "invalid_subject", "message": fmt.Sprintf("Subject must be a single line of at most %d characters", maxReminderTemplateSubject)})
+			return false
+		}
+		if err := notify.ValidateTemplate(subject); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]interface{}{"success": false, "error": "invalid_subject", "message": "Template subject: " + err.Error()})
+			return false
+		}
+	}
+
+	return true
+}
+
+// clearDefaultReminderTemplate unsets the default flag on the business's
+// other templates for a channel and language
+func clearDefaultReminderTemplate(tx *sql.Tx, userID int, channel, language string, exceptID int) error {
+	_, err := tx.Exec(`
+		UPDATE reminder_templates SET is_default = FALSE
+		WHERE user_id = ? AND channel = ? AND language = ? AND is_default = TRUE AND id != ?`,
+		userID, channel, language, exceptID)
+	return err
+}
+
+// checkReminderTemplate writes the error response and returns false when the
+// template does not belong to the user or is for another channel
+func checkReminderTemplate(w http.ResponseWriter, templateID, userID int, channel string) bool {
+	tmpl, err := getReminderTemplate(database.DB, templateID, userID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			writeJSON(w, http.StatusNotFound, map[string]interface{}{"success": false, "error": "template_not_found", "message": "Reminder template not found"})
+			return false
+		}
+		logger.L.WithField("error", err).Error("Error checking reminder template")
+		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "server_error", "message": "Could not verify reminder template"})
+		return false
+	}
+	if tmpl.Channel != channel {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"success": false, "error": "template_channel_mismatch", "message": "Reminder template is for the " + tmpl.Channel + " channel"})
+		return false
+	}
+	return true
+}
+
+// resolveReminderTemplate picks the template a reminder is sent with: the
+// one set on the reminder, else the business's default for the channel in
+// the customer's language, its base language, then English. A nil template
+// means the built-in one.
+func resolveReminderTemplate(d *reminderDelivery) (*models.ReminderTemplate, error) {
+	if d.TemplateID.Valid {
+		tmpl, err := getReminderTemplate(database.DB, int(d.TemplateID.Int64), d.UserID)
+		if err == nil && tmpl.Channel == d.Channel {
+			return tmpl, nil
+		}
+		if err != nil && err != sql.ErrNoRows {
+			return nil, err
+		}
+	}
+
+	for _, language := range reminderLanguages(d) {
+		tmpl, err := scanReminderTemplate(database.DB.QueryRow(reminderTemplateSelect+`
+			WHERE user_id = ? AND channel = ? AND language = ? AND is_default = TRUE
+			LIMIT 1`, d.UserID, d.Channel, language))
+		if err == nil {
+			return tmpl, nil
+		}
+		if err != sql.ErrNoRows {
+			return nil, err
+		}
+	}
+	return nil, nil
+}
+
+// reminderLanguages lists the languages to look for a template in, most
+// specific first
+func reminderLanguages(d *reminderDelivery) []string {
+	var languages []string
+	if d.CustomerLanguage.Valid && d.CustomerLanguage.String != "" {
+		languages = append(languages, d.CustomerLanguage.String)
+		if base, _, ok := strings.Cut(d.CustomerLanguage.String, "-"); ok {
+			languages = append(languages, base)
+		}
+	}
+	if len(languages) == 0 || languages[len(languages)-1] != defaultReminderLanguage {
+		languages = append(languages, defaultReminderLanguage)
+	}
+	return languages
+}
+
+// renderReminder fills a template with the reminder's details. A nil
+// template renders the built-in message in the customer's language.
+func renderReminder(d *reminderDelivery, tmpl *models.ReminderTemplate) (*models.RenderedReminder, error) {
+	var subject, body, language string
+	var templateID *int
+
+	if tmpl != nil {
+		templateID = &tmpl.ID
+		language = tmpl.Language
+		body = tmpl.Body
+		if tmpl.Subject != nil {
+			subject = *tmpl.Subject
+		}
+	} else {
+		language = defaultReminderLanguage
+		for _, l := range reminderLanguages(d) {
+			if _, ok := builtinReminderTemplates[l]; ok {
+				language = l
+				break
+			}
+		}
+	}
+
+	builtin := builtinReminderTemplates[defaultReminderLanguage]
+	if b, ok := builtinReminderTemplates[strings.SplitN(language, "-", 2)[0]]; ok {
+		builtin = b
+	}
+	vars := reminderTemplateVars(d, language)
+	if tmpl == nil {
+		body = builtin.Body
+		if vars["paymentLink"] != "" {
+			body += builtin.LinkLine
+		}
+	}
+	if subject == "" {
+		subject = builtin.Subject
+	}
+
+	rendered := &models.RenderedReminder{
+		TemplateID: templateID,
+		Channel:    d.Channel,
+		Language:   language,
+		Variables:  vars,
+	}
+
+	var err error
+	rendered.Body, err = notify.RenderTemplate(body, vars)
+	if err != nil {
+		return nil, err
+	}
+	if d.Channel == notify.ChannelEmail {
+		rendered.Subject, err = notify.RenderTemplate(subject, vars)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return rendered, nil
+}
+
+// reminderTemplateVars returns the values of the template variables for a
+// reminder, with amounts and dates formatted for the language
+func reminderTemplateVars(d *reminderDelivery, language string) map[string]string {
+	businessName := "your shop"
+	if d.BusinessName.Valid && d.BusinessName.String != "" {
+		businessName = d.BusinessName.String
+	}
+
+	dueDate := d.DueDate
+	if t, err := time.Parse("2006-01-02", d.DueDate); err == nil {
+		if strings.HasPrefix(language, "en") {
+			dueDate = t.Format("02 Jan 2006")
+		} else {
+			dueDate = t.Format("02-01-2006")
+		}
+	}
+
+	return map[string]string{
+		"customerName": d.CustomerName,
+		"amount":       "Rs. " + formatINR(d.DueAmount),
+		"businessName": businessName,
+		"dueDate":      dueDate,
+		"paymentLink":  upiPaymentLink(d, businessName),
+	}
+}
+
+// upiPaymentLink builds a UPI deep link for the due amount, or returns ""
+// when the business has no UPI ID
+func upiPaymentLink(d *reminderDelivery, businessName string) string {
+	if !d.BusinessUPIID.Valid || d.BusinessUPIID.String == "" {
+		return ""
+	}
+	escape := func(s string) string {
+		return strings.ReplaceAll(url.QueryEscape(s), "+", "%20")
+	}
+	// The payee address keeps its @ as UPI apps expect it unescaped
+	return "upi://pay?pa=" + strings.ReplaceAll(escape(d.BusinessUPIID.String), "%40", "@") +
+		"&pn=" + escape(businessName) +
+		"&am=" + strconv.FormatFloat(roundMoney(d.DueAmount), 'f', 2, 64) +
+		"&cu=INR&tn=" + escape("Reminder "+strconv.Itoa(d.ID))
+}
+
+// formatINR formats an amount with Indian digit grouping, e.g. 12,34,567.50
+func formatINR(amount float64) string {
+	s := strconv.FormatFloat(roundMoney(amount), 'f', 2, 64)
+	sign := ""
+	if strings.HasPrefix(s, "-") {
+		sign, s = "-", s[1:]
+	}
+	whole, frac := s[:len(s)-3], s[len(s)-3:]
+
+	if len(whole) > 3 {
+		head, tail := whole[:len(whole)-3], whole[len(whole)-3:]
+		var groups []string
+		for len(head) > 2 {
+			groups = append([]string{head[len(head)-2:]}, groups...)
+			head = head[:len(head)-2]
+		}
+		groups = append([]string{head}, groups...)
+		whole = strings.Join(groups, ",") + "," + tail
+	}
+	return sign + whole + frac
+}
+
+func getReminderTemplate(q queryRower, templateID, userID int) (*models.ReminderTemplate, error) {
+	return scanReminderTemplate(q.QueryRow(reminderTemplateSelect+" WHERE id = ? AND user_id = ?", templateID, userID))
+}
+
+func scanReminderTemplate(row rowScanner) (*models.ReminderTemplate, error) {
+	var tmpl models.ReminderTemplate
+	var subject sql.NullString
+	var createdAtStr, updatedAtStr string
+
+	err := row.Scan(
+		&tmpl.ID, &tmpl.Name, &tmpl.Channel, &tmpl.Language, &subject, &tmpl.Body, &tmpl.IsDefault,
+		&tmpl.UserID, &createdAtStr, &updatedAtStr,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	tmpl.Subject = nullStringPtr(subject)
+	tmpl.CreatedAt, _ = time.Parse("2006-01-02 15:04:05", createdAtStr)
+	tmpl.UpdatedAt, _ = time.Parse("2006-01-02 15:04:05", updatedAtStr)
+
+	return &tmpl, nil
+}
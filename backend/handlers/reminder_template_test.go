@@ -0,0 +1,26 @@
+package handlers
+
+import "testing"
+
+func TestFormatINR(t *testing.T) {
+	tests := []struct {
+		amount float64
+		want   string
+	}{
+		{0, "0.00"},
+		{5, "5.00"},
+		{999.5, "999.50"},
+		{1000, "1,000.00"},
+		{12345.678, "12,345.68"},
+		{123456, "1,23,456.00"},
+		{1234567.5, "12,34,567.50"},
+		{123456789, "12,34,56,789.00"},
+		{-1234.5, "-1,234.50"},
+		{-99, "-99.00"},
+	}
+	for _, tt := range tests {
+		if got := formatINR(tt.amount); got != tt.want {
+			t.Errorf("formatINR(%v) = %q, want %q", tt.amount, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,246 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"khata-book-backend/database"
+	"khata-book-backend/models"
+	"khata-book-backend/pkg/logger"
+
+	"github.com/gorilla/mux"
+)
+
+// SettleCustomer brings a customer's balance to zero. In one transaction it
+// posts entries for the outstanding balance, split into the amount paid and
+// optional discount and write-off portions, allocates them to open invoices
+// and marks the customer's open reminders as paid. The response carries the
+// statement for the settlement date.
+func SettleCustomer(w http.ResponseWriter, r *http.Request) {
+	// Get user ID from JWT token
+	userID, err := getUserIDFromToken(r)
+	if err != nil {
+		writeJSON(w, http.StatusUnauthorized, map[string]interface{}{"success": false, "error": "unauthorized", "message": "Invalid token"})
+		return
+	}
+
+	customerID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"success": false, "error": "invalid_id", "message": "Invalid customer ID"})
+		return
+	}
+
+	var settleReq models.SettleRequest
+	err = json.NewDecoder(r.Body).Decode(&settleReq)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"success": false, "error": "invalid_request", "message": "Invalid request body"})
+		return
+	}
+
+	if settleReq.Discount < 0 || settleReq.WriteOff < 0 {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"success": false, "error": "invalid_discount", "message": "Discount and write-off cannot be negative"})
+		return
+	}
+
+	method, err := lookupPaymentMethod(userID, settleReq.Method)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			writeJSON(w, http.StatusBadRequest, map[string]interface{}{"success": false, "error": "invalid_method", "message": "Method must be one of your active payment methods"})
+			return
+		}
+		logger.L.WithField("error", err).Error("Error looking up payment method")
+		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "server_error", "message": "Could not verify payment method"})
+		return
+	}
+
+	if method.Kind == "cheque" {
+		if settleReq.Cheque == nil || strings.TrimSpace(settleReq.Cheque.ChequeNumber) == "" || len(strings.TrimSpace(settleReq.Cheque.ChequeNumber)) > 30 {
+			writeJSON(w, http.StatusBadRequest, map[string]interface{}{"success": false, "error": "invalid_cheque", "message": "A cheque number of up to 30 characters is required for cheque entries"})
+			return
+		}
+	}
+
+	// Set default date if not provided
+	settleDate := time.Now()
+	if !settleReq.Date.IsZero() {
+		settleDate = settleReq.Date
+	}
+
+	if !checkDayOpen(w, userID, settleDate) {
+		return
+	}
+
+	tx, err := database.DB.Begin()
+	if err != nil {
+		logger.L.WithField("error", err).Error("Could not start transaction")
+		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "server_error", "message": "Could not process request"})
+		return
+	}
+	defer tx.Rollback()
+
+	if !checkDayOpenTx(w, tx, userID, settleDate) {
+		return
+	}
+
+	// Lock the customer so the balance cannot move between reading and
+	// settling it
+	var balance float64
+	var partyType string
+	err = tx.QueryRow("SELECT balance, party_type FROM customers WHERE id = ? AND user_id = ? FOR UPDATE", customerID, userID).Scan(&balance, &partyType)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			writeJSON(w, http.StatusNotFound, map[string]interface{}{"success": false, "error": "customer_not_found", "message": "Customer not found"})
+			return
+		}
+		logger.L.WithField("error", err).Error("Error querying customer balance")
+		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "server_error", "message": "Could not settle customer"})
+		return
+	}
+
+	outstanding := roundMoney(math.Abs(balance))
+	forgiven := roundMoney(settleReq.Discount + settleReq.WriteOff)
+	if forgiven > outstanding {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"success": false, "error": "invalid_discount", "message": "Discount and write-off cannot exceed the outstanding balance"})
+		return
+	}
+
+	// Only an amount the party owes can be forgiven
+	if forgiven > 0 && balance < 0 {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"success": false, "error": "invalid_discount", "message": "Discount and write-off only apply when the " + strings.ToLower(partyNoun(partyType)) + " owes you a balance"})
+		return
+	}
+
+	// A positive balance is owed by the party and is cleared by a debit;
+	// a negative one is owed to the party and cleared by a credit, which for
+	// a supplier is paying its bill
+	entryType := "debit"
+	if balance < 0 {
+		entryType = "credit"
+	}
+
+	entries := []models.LedgerEntry{}
+	allocations := []models.InvoiceAllocation{}
+	paid := roundMoney(outstanding - forgiven)
+
+	if paid > 0 {
+		entry := models.LedgerEntry{
+			CustomerID: customerID,
+			Type:       entryType,
+			Amount:     paid,
+			Method:     method.Code,
+			Note:       settleReq.Note,
+			Date:       settleDate,
+			UserID:     userID,
+		}
+		if err := postLedgerEntry(tx, &entry); err != nil {
+			logger.L.WithField("error", err).Error("Error posting settlement entry")
+			writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "server_error", "message": "Could not settle customer"})
+			return
+		}
+		if method.Kind == "cheque" {
+			cheque, err := insertChequeDetails(tx, entry.ID, userID, settleReq.Cheque)
+			if err != nil {
+				logger.L.WithField("error", err).Error("Error inserting cheque details")
+				writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "server_error", "message": "Could not settle customer"})
+				return
+			}
+			entry.Cheque = cheque
+		}
+		if !settleInvoices(w, tx, &entry, &allocations) {
+			return
+		}
+		entries = append(entries, entry)
+	}
+
+	adjustments := []struct {
+		entryType string
+		amount    float64
+		note      string
+	}{
+		{"discount", settleReq.Discount, "Discount on settlement"},
+		{"write_off", settleReq.WriteOff, "Written off on settlement"},
+	}
+	for _, adjustment := range adjustments {
+		if adjustment.amount <= 0 {
+			continue
+		}
+		note := adjustment.note
+		entry := models.LedgerEntry{
+			CustomerID: customerID,
+			Type:       adjustment.entryType,
+			Amount:     roundMoney(adjustment.amount),
+			Method:     adjustmentMethod,
+			Note:       &note,
+			Date:       settleDate,
+			UserID:     userID,
+		}
+		if err := postLedgerEntry(tx, &entry); err != nil {
+			logger.L.WithField("error", err).Error("Error posting settlement adjustment")
+			writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "server_error", "message": "Could not settle customer"})
+			return
+		}
+		if !settleInvoices(w, tx, &entry, &allocations) {
+			return
+		}
+		entries = append(entries, entry)
+	}
+
+	// A settlement clears the balance, so this closes every open reminder
+	// whether or not the business closes them on ordinary payments
+	remindersClosed, _, err := reconcileReminders(tx, customerID, userID, "settlement", true)
+	if err != nil {
+		logger.L.WithField("error", err).Error("Error closing reminders")
+		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "server_error", "message": "Could not settle customer"})
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		logger.L.WithField("error", err).Error("Error committing transaction")
+		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "server_error", "message": "Could not settle customer"})
+		return
+	}
+
+	logger.L.WithFields(map[string]interface{}{
+		"customer_id":      customerID,
+		"user_id":          userID,
+		"settled_amount":   outstanding,
+		"discount":         settleReq.Discount,
+		"write_off":        settleReq.WriteOff,
+		"reminders_closed": remindersClosed,
+	}).Info("Customer settled successfully")
+
+	statementDate := calendarDate(settleDate)
+	statement, err := getCustomerStatement(customerID, userID, &statementDate, nil, 100, 0)
+	if err != nil {
+		// The settlement is committed; only the statement could not be built
+		logger.L.WithField("error", err).Error("Error building customer statement")
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"success":          true,
+		"message":          partyNoun(partyType) + " settled successfully",
+		"settled_amount":   outstanding,
+		"entries":          entries,
+		"allocations":      allocations,
+		"reminders_closed": remindersClosed,
+		"statement":        statement,
+	})
+}
+
+// settleInvoices allocates a settlement entry to the customer's open invoices,
+// writing the error response and returning false on failure
+func settleInvoices(w http.ResponseWriter, tx *sql.Tx, entry *models.LedgerEntry, allocations *[]models.InvoiceAllocation) bool {
+	allocated, err := allocatePayment(tx, entry, nil)
+	if err != nil {
+		logger.L.WithField("error", err).Error("Error allocating settlement to invoices")
+		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "server_error", "message": "Could not settle customer"})
+		return false
+	}
+	*allocations = append(*allocations, allocated...)
+	return true
+}
@@ -0,0 +1,196 @@
+package handlers
+
+import (
+	"database/sql"
+	"net/http"
+	"strconv"
+	"time"
+
+	"khata-book-backend/database"
+	"khata-book-backend/pkg/logger"
+
+	"github.com/gorilla/mux"
+)
+
+// GetCustomerStatement returns a customer's ledger between two dates with the
+// balance after every entry
+func GetCustomerStatement(w http.ResponseWriter, r *http.Request) {
+	// Get user ID from JWT token
+	userID, err := getUserIDFromToken(r)
+	if err != nil {
+		writeJSON(w, http.StatusUnauthorized, map[string]interface{}{"success": false, "error": "unauthorized", "message": "Invalid token"})
+		return
+	}
+
+	customerID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"success": false, "error": "invalid_id", "message": "Invalid customer ID"})
+		return
+	}
+
+	// Parse query parameters
+	fromStr := r.URL.Query().Get("from")
+	toStr := r.URL.Query().Get("to")
+	limitStr := r.URL.Query().Get("limit")
+	offsetStr := r.URL.Query().Get("offset")
+
+	var from, to *time.Time
+
+	if fromStr != "" {
+		if parsedDate, err := time.Parse("2006-01-02", fromStr); err == nil {
+			from = &parsedDate
+		}
+	}
+
+	if toStr != "" {
+		if parsedDate, err := time.Parse("2006-01-02", toStr); err == nil {
+			to = &parsedDate
+		}
+	}
+
+	limit := 100 // default limit
+	if limitStr != "" {
+		if parsedLimit, err := strconv.Atoi(limitStr); err == nil && parsedLimit > 0 && parsedLimit <= 500 {
+			limit = parsedLimit
+		}
+	}
+
+	offset := 0
+	if offsetStr != "" {
+		if parsedOffset, err := strconv.Atoi(offsetStr); err == nil && parsedOffset >= 0 {
+			offset = parsedOffset
+		}
+	}
+
+	statement, err := getCustomerStatement(customerID, userID, from, to, limit, offset)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			writeJSON(w, http.StatusNotFound, map[string]interface{}{"success": false, "error": "not_found", "message": "Customer not found"})
+			return
+		}
+		logger.L.WithField("error", err).Error("Error building customer statement")
+		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "server_error", "message": "Could not fetch statement"})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"success":   true,
+		"statement": statement,
+	})
+}
+
+// getCustomerStatement builds the statement for a customer owned by userID.
+// Running balances are computed over the customer's full history, so every
+// page of a long statement carries the correct balance. It returns
+// sql.ErrNoRows when the customer does not exist for this user.
+func getCustomerStatement(customerID, userID int, from, to *time.Time, limit, offset int) (map[string]interface{}, error) {
+	var customerName, partyType string
+	var currentBalance float64
+	err := database.DB.QueryRow(`
+		SELECT name, party_type, balance FROM customers WHERE id = ? AND user_id = ?`,
+		customerID, userID).Scan(&customerName, &partyType, &currentBalance)
+	if err != nil {
+		return nil, err
+	}
+
+	// customers.balance may include an amount entered when the customer was
+	// created; whatever the ledger does not explain is the starting balance
+	fromArg := "0001-01-01"
+	if from != nil {
+		fromArg = from.Format("2006-01-02")
+	}
+	toArg := "9999-12-31"
+	if to != nil {
+		toArg = to.Format("2006-01-02")
+	}
+
+	var ledgerTotal, beforeFrom, throughTo float64
+	err = database.DB.QueryRow(`
+		SELECT
+			COALESCE(SUM(CASE WHEN type IN ('debit', 'write_off', 'discount') THEN -amount ELSE amount END), 0),
+			COALESCE(SUM(CASE WHEN date < ? THEN (CASE WHEN type IN ('debit', 'write_off', 'discount') THEN -amount ELSE amount END) ELSE 0 END), 0),
+			COALESCE(SUM(CASE WHEN date <= ? THEN (CASE WHEN type IN ('debit', 'write_off', 'discount') THEN -amount ELSE amount END) ELSE 0 END), 0)
+		FROM ledger_entries
+		WHERE customer_id = ? AND user_id = ?`,
+		fromArg, toArg, customerID, userID).Scan(&ledgerTotal, &beforeFrom, &throughTo)
+	if err != nil {
+		return nil, err
+	}
+
+	startingBalance := currentBalance - ledgerTotal
+	openingBalance := startingBalance + beforeFrom
+	closingBalance := startingBalance + throughTo
+
+	rows, err := database.DB.Query(`
+		WITH running AS (
+			SELECT id, type, amount, method, note, date, created_at,
+				SUM(CASE WHEN type IN ('debit', 'write_off', 'discount') THEN -amount ELSE amount END)
+					OVER (ORDER BY date, id ROWS BETWEEN UNBOUNDED PRECEDING AND CURRENT ROW) as running_total
+			FROM ledger_entries
+			WHERE customer_id = ? AND user_id = ?
+		)
+		SELECT id, type, amount, method, note, date, created_at, ? + running_total as running_balance
+		FROM running
+		WHERE date >= ? AND date <= ?
+		ORDER BY date, id
+		LIMIT ? OFFSET ?`,
+		customerID, userID, startingBalance, fromArg, toArg, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := []map[string]interface{}{}
+	for rows.Next() {
+		var id int
+		var entryType, method, dateStr, createdAtStr string
+		var amount, runningBalance float64
+		var note sql.NullString
+
+		err := rows.Scan(&id, &entryType, &amount, &method, &note, &dateStr, &createdAtStr, &runningBalance)
+		if err != nil {
+			return nil, err
+		}
+
+		var notePtr *string
+		if note.Valid {
+			notePtr = &note.String
+		}
+
+		entries = append(entries, map[string]interface{}{
+			"id":              id,
+			"type":            entryType,
+			"type_label":      entryTypeLabel(partyType, entryType),
+			"amount":          amount,
+			"method":          method,
+			"note":            notePtr,
+			"date":            dateStr,
+			"created_at":      createdAtStr,
+			"running_balance": runningBalance,
+		})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	statement := map[string]interface{}{
+		"customer_id":     customerID,
+		"customer_name":   customerName,
+		"party_type":      partyType,
+		"from":            nil,
+		"to":              nil,
+		"opening_balance": openingBalance,
+		"closing_balance": closingBalance,
+		"balance_label":   balanceLabel(partyType, closingBalance),
+		"entries":         entries,
+		"count":           len(entries),
+	}
+	if from != nil {
+		statement["from"] = fromArg
+	}
+	if to != nil {
+		statement["to"] = toArg
+	}
+
+	return statement, nil
+}
@@ -0,0 +1,185 @@
+package handlers
+
+import (
+	"crypto/subtle"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+
+	"khata-book-backend/database"
+	"khata-book-backend/pkg/logger"
+	"khata-book-backend/pkg/notify"
+)
+
+// maxWebhookBody caps the size of a provider callback
+const maxWebhookBody = 1 << 20
+
+// WhatsAppWebhook receives callbacks from the WhatsApp Business Cloud API
+func WhatsAppWebhook(w http.ResponseWriter, r *http.Request) {
+	handleProviderWebhook(w, r, notify.ChannelWhatsApp, notify.ParseWhatsAppWebhook)
+}
+
+// VerifyWhatsAppWebhook answers Meta's subscription check by echoing the
+// challenge when the verify token matches WHATSAPP_VERIFY_TOKEN
+func VerifyWhatsAppWebhook(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	token := os.Getenv("WHATSAPP_VERIFY_TOKEN")
+	if token == "" || query.Get("hub.mode") != "subscribe" ||
+		subtle.ConstantTimeCompare([]byte(query.Get("hub.verify_token")), []byte(token)) != 1 {
+		writeJSON(w, http.StatusForbidden, map[string]interface{}{"success": false, "error": "forbidden", "message": "Invalid verify token"})
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain")
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(query.Get("hub.challenge")))
+}
+
+// SMSWebhook receives callbacks from the SMS gateway
+func SMSWebhook(w http.ResponseWriter, r *http.Request) {
+	handleProviderWebhook(w, r, notify.ChannelSMS, notify.ParseSMSWebhook)
+}
+
+// handleProviderWebhook verifies a provider callback's signature, parses it
+// and acts on what it carries: STOP and START replies and delivery statuses.
+// Once the signature checks out the provider is answered with success
+// unless processing failed, so it does not retry callbacks that matched no
+// customer or reminder. Repeated callbacks are harmless.
+func handleProviderWebhook(w http.ResponseWriter, r *http.Request, channel string, parse func([]byte) (*notify.Webhook, error)) {
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxWebhookBody))
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"success": false, "error": "invalid_request", "message": "Could not read request body"})
+		return
+	}
+
+	if err := notify.VerifyWebhook(channel, r.Header, body); err != nil {
+		logger.L.WithFields(map[string]interface{}{"channel": channel, "error": err}).Warn("Rejected provider webhook")
+		if errors.Is(err, notify.ErrWebhookNotConfigured) {
+			writeJSON(w, http.StatusServiceUnavailable, map[string]interface{}{"success": false, "error": "webhook_not_configured", "message": "Webhook is not configured"})
+			return
+		}
+		writeJSON(w, http.StatusUnauthorized, map[string]interface{}{"success": false, "error": "invalid_signature", "message": "Invalid webhook signature"})
+		return
+	}
+
+	webhook, err := parse(body)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"success": false, "error": "invalid_request", "message": "Invalid webhook payload"})
+		return
+	}
+
+	consentChanges := 0
+	for _, msg := range webhook.Messages {
+		changed, err := processInboundMessage(msg)
+		if err != nil {
+			logger.L.WithFields(map[string]interface{}{"channel": channel, "message_id": msg.ProviderMessageID, "error": err}).Error("Error processing inbound message")
+			writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "server_error", "message": "Could not process webhook"})
+			return
+		}
+		consentChanges += changed
+	}
+
+	statusesMatched := 0
+	for _, st := range webhook.Statuses {
+		matched, err := applyDeliveryStatus(st)
+		if err != nil {
+			logger.L.WithFields(map[string]interface{}{"channel": channel, "message_id": st.ProviderMessageID, "error": err}).Error("Error applying delivery status")
+			writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"success": false, "error": "server_error", "message": "Could not process webhook"})
+			return
+		}
+		if matched {
+			statusesMatched++
+		} else {
+			logger.L.WithFields(map[string]interface{}{"channel": channel, "message_id": st.ProviderMessageID}).Debug("Delivery status for unknown message")
+		}
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"success":          true,
+		"messages":         len(webhook.Messages),
+		"consent_changes":  consentChanges,
+		"statuses":         len(webhook.Statuses),
+		"statuses_matched": statusesMatched,
+	})
+}
+
+// processInboundMessage opts every customer with the sender's number out of
+// or back in to reminders on the message's channel when the text is a STOP
+// or START keyword. Other texts are ignored. It returns how many customers
+// changed.
+func processInboundMessage(msg notify.InboundMessage) (int, error) {
+	optOut, optIn := notify.ConsentKeyword(msg.Text)
+	if !optOut && !optIn {
+		return 0, nil
+	}
+	from := notify.NormalizePhone(msg.From, defaultCountryCode())
+	if from == "" {
+		return 0, nil
+	}
+	status := "opted_in"
+	if optOut {
+		status = "opted_out"
+	}
+
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	// Numbers are stored as typed, so candidates are narrowed on their last
+	// digits and then compared in normalised form. The reply came from a
+	// shared sender, so it applies to every business the number is with.
+	suffix := from
+	if len(suffix) > 10 {
+		suffix = suffix[len(suffix)-10:]
+	}
+	rows, err := tx.Query(`
+		SELECT id, user_id, phone FROM customers
+		WHERE phone IS NOT NULL
+		  AND REPLACE(REPLACE(REPLACE(REPLACE(phone, ' ', ''), '-', ''), '(', ''), ')', '') LIKE ?`,
+		"%"+suffix)
+	if err != nil {
+		return 0, err
+	}
+
+	type matchedCustomer struct{ id, userID int }
+	var matched []matchedCustomer
+	for rows.Next() {
+		var c matchedCustomer
+		var phone string
+		if err := rows.Scan(&c.id, &c.userID, &phone); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		if notify.NormalizePhone(phone, defaultCountryCode()) == from {
+			matched = append(matched, c)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	detail := "Replied \"" + msg.Text + "\""
+	if len(detail) > 255 {
+		detail = detail[:255]
+	}
+	for _, c := range matched {
+		if err := setCustomerConsent(tx, c.id, c.userID, msg.Channel, status, "keyword", detail); err != nil {
+			return 0, err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+
+	logger.L.WithFields(map[string]interface{}{
+		"channel":    msg.Channel,
+		"status":     status,
+		"customers":  len(matched),
+		"message_id": msg.ProviderMessageID,
+	}).Info("Customer consent changed by keyword")
+	return len(matched), nil
+}
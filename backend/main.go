@@ -8,6 +8,8 @@ import (
 	"khata-book-backend/database"
 	"khata-book-backend/handlers"
 	"khata-book-backend/pkg/logger"
+	"khata-book-backend/pkg/notify"
+	"khata-book-backend/pkg/storage"
 
 	"github.com/gorilla/mux"
 	"github.com/joho/godotenv"
@@ -23,6 +25,12 @@ func main() {
 	// Initialize database
 	database.InitDB()
 
+	// Initialize attachment storage
+	storage.InitStore()
+
+	// Initialize reminder delivery providers
+	notify.InitNotifiers()
+
 	// Create router
 	r := mux.NewRouter()
 
@@ -41,20 +49,122 @@ func main() {
 	r.HandleFunc("/api/reports/monthly", handlers.GetMonthlyReports).Methods("GET")
 	r.HandleFunc("/api/reports/categories", handlers.GetCategoryReports).Methods("GET")
 	r.HandleFunc("/api/reports/payment-methods", handlers.GetPaymentMethodReports).Methods("GET")
+	r.HandleFunc("/api/reports/write-offs", handlers.GetWriteOffReports).Methods("GET")
+	r.HandleFunc("/api/reports/gstr1", handlers.GetGSTR1Report).Methods("GET")
+	r.HandleFunc("/api/reports/day-close", handlers.GetDayCloseReport).Methods("GET")
 
 	// Customer routes
 	r.HandleFunc("/api/customers", handlers.GetCustomers).Methods("GET")
 	r.HandleFunc("/api/customers", handlers.CreateCustomer).Methods("POST")
 	r.HandleFunc("/api/customers/{id}", handlers.GetCustomer).Methods("GET")
+	r.HandleFunc("/api/customers/{id}", handlers.UpdateCustomer).Methods("PUT")
+	r.HandleFunc("/api/customers/{id}/statement", handlers.GetCustomerStatement).Methods("GET")
+	r.HandleFunc("/api/customers/{id}/settle", handlers.SettleCustomer).Methods("POST")
+	r.HandleFunc("/api/customers/{id}/interest", handlers.GetInterestSettings).Methods("GET")
+	r.HandleFunc("/api/customers/{id}/interest", handlers.UpdateInterestSettings).Methods("PUT")
+	r.HandleFunc("/api/customers/{id}/interest", handlers.DeleteInterestSettings).Methods("DELETE")
+	r.HandleFunc("/api/customers/{id}/interest/preview", handlers.PreviewInterest).Methods("GET")
+	r.HandleFunc("/api/customers/{id}/consent", handlers.GetCustomerConsent).Methods("GET")
+	r.HandleFunc("/api/customers/{id}/consent/{channel}", handlers.UpdateCustomerConsent).Methods("PUT")
+
+	// Supplier routes; suppliers are parties too, so statements and
+	// settlement share the customer handlers
+	r.HandleFunc("/api/suppliers", handlers.GetSuppliers).Methods("GET")
+	r.HandleFunc("/api/suppliers", handlers.CreateSupplier).Methods("POST")
+	r.HandleFunc("/api/suppliers/{id}", handlers.GetSupplier).Methods("GET")
+	r.HandleFunc("/api/suppliers/{id}", handlers.UpdateSupplier).Methods("PUT")
+	r.HandleFunc("/api/suppliers/{id}/statement", handlers.GetCustomerStatement).Methods("GET")
+	r.HandleFunc("/api/suppliers/{id}/settle", handlers.SettleCustomer).Methods("POST")
+
+	// Category and tag routes
+	r.HandleFunc("/api/categories", handlers.GetCategories).Methods("GET")
+	r.HandleFunc("/api/categories", handlers.CreateCategory).Methods("POST")
+	r.HandleFunc("/api/categories/{id}", handlers.UpdateCategory).Methods("PUT")
+	r.HandleFunc("/api/categories/{id}", handlers.DeleteCategory).Methods("DELETE")
+	r.HandleFunc("/api/tags", handlers.GetTags).Methods("GET")
+
+	// Payment method routes
+	r.HandleFunc("/api/payment-methods", handlers.GetPaymentMethods).Methods("GET")
+	r.HandleFunc("/api/payment-methods", handlers.CreatePaymentMethod).Methods("POST")
+	r.HandleFunc("/api/payment-methods/{id}", handlers.UpdatePaymentMethod).Methods("PUT")
+	r.HandleFunc("/api/payment-methods/{id}", handlers.DeletePaymentMethod).Methods("DELETE")
 
 	// Ledger routes
 	r.HandleFunc("/api/ledger", handlers.GetLedgerEntries).Methods("GET")
 	r.HandleFunc("/api/ledger", handlers.CreateLedgerEntry).Methods("POST")
 	r.HandleFunc("/api/ledger/{id}", handlers.GetLedgerEntry).Methods("GET")
+	r.HandleFunc("/api/ledger/{id}/category", handlers.UpdateLedgerEntryCategory).Methods("PUT")
+	r.HandleFunc("/api/ledger/{id}/cheque", handlers.UpdateLedgerCheque).Methods("PUT")
+	r.HandleFunc("/api/ledger/{id}/tags", handlers.UpdateLedgerEntryTags).Methods("PUT")
+	r.HandleFunc("/api/ledger/{id}/attachments", handlers.GetLedgerAttachments).Methods("GET")
+	r.HandleFunc("/api/ledger/{id}/attachments", handlers.UploadLedgerAttachment).Methods("POST")
+	r.HandleFunc("/api/ledger/{id}/attachments/{attachmentId}", handlers.DownloadLedgerAttachment).Methods("GET")
+	r.HandleFunc("/api/ledger/{id}/attachments/{attachmentId}", handlers.DeleteLedgerAttachment).Methods("DELETE")
+
+	// Recurring entry routes
+	r.HandleFunc("/api/recurring-entries", handlers.GetRecurringEntries).Methods("GET")
+	r.HandleFunc("/api/recurring-entries", handlers.CreateRecurringEntry).Methods("POST")
+	r.HandleFunc("/api/recurring-entries/{id}", handlers.GetRecurringEntry).Methods("GET")
+	r.HandleFunc("/api/recurring-entries/{id}", handlers.UpdateRecurringEntry).Methods("PUT")
+	r.HandleFunc("/api/recurring-entries/{id}", handlers.DeleteRecurringEntry).Methods("DELETE")
+	r.HandleFunc("/api/recurring-entries/{id}/pause", handlers.PauseRecurringEntry).Methods("POST")
+	r.HandleFunc("/api/recurring-entries/{id}/resume", handlers.ResumeRecurringEntry).Methods("POST")
+	r.HandleFunc("/api/recurring-entries/{id}/skip", handlers.SkipRecurringEntry).Methods("POST")
+
+	// Invoice and tax routes
+	r.HandleFunc("/api/tax-rates", handlers.GetTaxRates).Methods("GET")
+	r.HandleFunc("/api/tax-rates", handlers.CreateTaxRate).Methods("POST")
+	r.HandleFunc("/api/tax-rates/{id}", handlers.UpdateTaxRate).Methods("PUT")
+	r.HandleFunc("/api/tax-rates/{id}", handlers.DeleteTaxRate).Methods("DELETE")
+	r.HandleFunc("/api/invoice-series", handlers.GetInvoiceSeries).Methods("GET")
+	r.HandleFunc("/api/invoice-series", handlers.CreateInvoiceSeries).Methods("POST")
+	r.HandleFunc("/api/invoice-series/{id}", handlers.UpdateInvoiceSeries).Methods("PUT")
+	r.HandleFunc("/api/invoices", handlers.GetInvoices).Methods("GET")
+	r.HandleFunc("/api/invoices", handlers.CreateInvoice).Methods("POST")
+	r.HandleFunc("/api/invoices/{id}", handlers.GetInvoice).Methods("GET")
+	r.HandleFunc("/api/invoices/{id}/allocations", handlers.AllocateInvoicePayment).Methods("POST")
+	r.HandleFunc("/api/invoices/{id}/allocations/{entryId}", handlers.DeleteInvoiceAllocation).Methods("DELETE")
+
+	// Cashbook routes
+	r.HandleFunc("/api/cashbook", handlers.GetCashbookEntries).Methods("GET")
+	r.HandleFunc("/api/cashbook", handlers.CreateCashbookEntry).Methods("POST")
+	r.HandleFunc("/api/cashbook/balances", handlers.GetCashBalances).Methods("GET")
+	r.HandleFunc("/api/cashbook/{id}", handlers.GetCashbookEntry).Methods("GET")
+	r.HandleFunc("/api/cashbook/{id}", handlers.UpdateCashbookEntry).Methods("PUT")
+	r.HandleFunc("/api/cashbook/{id}", handlers.DeleteCashbookEntry).Methods("DELETE")
+
+	// Day close routes
+	r.HandleFunc("/api/day-close", handlers.GetDayCloses).Methods("GET")
+	r.HandleFunc("/api/day-close", handlers.CloseDay).Methods("POST")
+	r.HandleFunc("/api/day-close/{date}", handlers.GetDayClose).Methods("GET")
+	r.HandleFunc("/api/day-close/{date}/reopen", handlers.ReopenDay).Methods("POST")
+
+	// Reminder routes
 	r.HandleFunc("/api/reminders", handlers.GetReminders).Methods("GET")
 	r.HandleFunc("/api/reminders", handlers.CreateReminder).Methods("POST")
+	r.HandleFunc("/api/reminders/bulk", handlers.BulkReminderAction).Methods("POST")
 	r.HandleFunc("/api/reminders/{id}", handlers.UpdateReminder).Methods("PUT")
 	r.HandleFunc("/api/reminders/{id}", handlers.DeleteReminder).Methods("DELETE")
+	r.HandleFunc("/api/reminders/{id}/history", handlers.GetReminderHistory).Methods("GET")
+	r.HandleFunc("/api/reminders/{id}/snooze", handlers.SnoozeReminder).Methods("POST")
+	r.HandleFunc("/api/reminder-rules", handlers.GetReminderRules).Methods("GET")
+	r.HandleFunc("/api/reminder-rules", handlers.CreateReminderRule).Methods("POST")
+	r.HandleFunc("/api/reminder-rules/{id}", handlers.GetReminderRule).Methods("GET")
+	r.HandleFunc("/api/reminder-rules/{id}", handlers.UpdateReminderRule).Methods("PUT")
+	r.HandleFunc("/api/reminder-rules/{id}", handlers.DeleteReminderRule).Methods("DELETE")
+	r.HandleFunc("/api/reminder-rules/{id}/run", handlers.RunReminderRule).Methods("POST")
+	r.HandleFunc("/api/reminder-rules/{id}/preview", handlers.PreviewReminderRule).Methods("GET")
+	r.HandleFunc("/api/reminder-templates", handlers.GetReminderTemplates).Methods("GET")
+	r.HandleFunc("/api/reminder-templates", handlers.CreateReminderTemplate).Methods("POST")
+	r.HandleFunc("/api/reminder-templates/{id}", handlers.GetReminderTemplate).Methods("GET")
+	r.HandleFunc("/api/reminder-templates/{id}", handlers.UpdateReminderTemplate).Methods("PUT")
+	r.HandleFunc("/api/reminder-templates/{id}", handlers.DeleteReminderTemplate).Methods("DELETE")
+	r.HandleFunc("/api/reminder-templates/{id}/preview", handlers.PreviewReminderTemplate).Methods("POST")
+
+	// Provider webhooks; these are authenticated by signature, not token
+	r.HandleFunc("/api/webhooks/whatsapp", handlers.VerifyWhatsAppWebhook).Methods("GET")
+	r.HandleFunc("/api/webhooks/whatsapp", handlers.WhatsAppWebhook).Methods("POST")
+	r.HandleFunc("/api/webhooks/sms", handlers.SMSWebhook).Methods("POST")
 
 	// Catch-all OPTIONS handler for CORS preflight requests
 	r.PathPrefix("/").Methods("OPTIONS").HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -68,6 +178,10 @@ func main() {
 		port = "8080"
 	}
 
+	// Start scheduled jobs such as recurring entries, interest accrual and
+	// reminder delivery
+	handlers.StartBackgroundJobs()
+
 	logger.L.WithField("port", port).Info("Server starting")
 	logger.L.Fatal(http.ListenAndServe(":"+port, r))
 }
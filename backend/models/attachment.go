@@ -0,0 +1,16 @@
+package models
+
+import (
+	"time"
+)
+
+type LedgerAttachment struct {
+	ID           int       `json:"id"`
+	EntryID      int       `json:"entry_id"`
+	FileName     string    `json:"file_name"`
+	ContentType  string    `json:"content_type"`
+	SizeBytes    int64     `json:"size_bytes"`
+	HasThumbnail bool      `json:"has_thumbnail"`
+	UserID       int       `json:"user_id"`
+	CreatedAt    time.Time `json:"created_at"`
+}
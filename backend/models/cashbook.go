@@ -0,0 +1,46 @@
+package models
+
+import (
+	"time"
+)
+
+type CashbookEntry struct {
+	ID           int       `json:"id"`
+	Type         string    `json:"type"` // "income" or "expense"
+	Amount       float64   `json:"amount"`
+	Method       string    `json:"method"` // payment method code
+	CategoryID   *int      `json:"category_id,omitempty"`
+	CategoryName *string   `json:"category_name,omitempty"`
+	Note         *string   `json:"note,omitempty"`
+	Date         time.Time `json:"date"`
+	UserID       int       `json:"user_id"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+type CashbookEntryRequest struct {
+	Type       string    `json:"type"`
+	Amount     float64   `json:"amount"`
+	Method     string    `json:"method"`
+	CategoryID *int      `json:"category_id,omitempty"`
+	Note       *string   `json:"note,omitempty"`
+	Date       time.Time `json:"date,omitempty"`
+}
+
+// CashBalance is one payment method's position for a day
+type CashBalance struct {
+	Method  string  `json:"method"`
+	Opening float64 `json:"opening"`
+	In      float64 `json:"in"`
+	Out     float64 `json:"out"`
+	Closing float64 `json:"closing"`
+}
+
+type CashDay struct {
+	Date    string        `json:"date"`
+	Methods []CashBalance `json:"methods"`
+	Opening float64       `json:"opening"`
+	In      float64       `json:"in"`
+	Out     float64       `json:"out"`
+	Closing float64       `json:"closing"`
+}
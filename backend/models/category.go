@@ -0,0 +1,18 @@
+package models
+
+import (
+	"time"
+)
+
+type Category struct {
+	ID         int       `json:"id"`
+	Name       string    `json:"name"`
+	UserID     int       `json:"user_id"`
+	EntryCount int       `json:"entry_count"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+type CategoryRequest struct {
+	Name string `json:"name"`
+}
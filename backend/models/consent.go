@@ -0,0 +1,21 @@
+package models
+
+import (
+	"time"
+)
+
+// CustomerConsent is whether a customer agreed to receive reminders on a
+// channel. Status is "unknown" until the customer opts in or out.
+type CustomerConsent struct {
+	Channel   string     `json:"channel"`          // "sms", "whatsapp", "email"
+	Status    string     `json:"status"`           // "opted_in", "opted_out", "unknown"
+	Source    *string    `json:"source,omitempty"` // e.g. "manual", "keyword"
+	Detail    *string    `json:"detail,omitempty"`
+	ChangedAt *time.Time `json:"changed_at,omitempty"`
+}
+
+type ConsentRequest struct {
+	Status string `json:"status"`           // "opted_in" or "opted_out"
+	Source string `json:"source,omitempty"` // defaults to "manual"
+	Detail string `json:"detail,omitempty"`
+}
@@ -7,8 +7,14 @@ import (
 type Customer struct {
 	ID        int       `json:"id"`
 	Name      string    `json:"name"`
+	PartyType string    `json:"party_type"` // "customer" or "supplier"
 	Phone     *string   `json:"phone,omitempty"`
+	Email     *string   `json:"email,omitempty"`
+	Language  *string   `json:"language,omitempty"` // reminder language, e.g. "en" or "hi"
+	Timezone  *string   `json:"timezone,omitempty"` // IANA zone for reminder quiet hours
 	Note      *string   `json:"note,omitempty"`
+	GSTIN     *string   `json:"gstin,omitempty"`
+	StateCode *string   `json:"state_code,omitempty"` // place of supply for unregistered customers
 	UserID    int       `json:"user_id"`
 	Balance   float64   `json:"balance"`
 	CreatedAt time.Time `json:"created_at"`
@@ -16,8 +22,22 @@ type Customer struct {
 }
 
 type CustomerRequest struct {
-	Name    string  `json:"name"`
-	Phone   *string `json:"phone,omitempty"`
-	Note    *string `json:"note,omitempty"`
-	Balance float64 `json:"balance,omitempty"`
+	Name      string  `json:"name"`
+	Phone     *string `json:"phone,omitempty"`
+	Email     *string `json:"email,omitempty"`
+	Language  *string `json:"language,omitempty"`
+	Timezone  *string `json:"timezone,omitempty"`
+	Note      *string `json:"note,omitempty"`
+	GSTIN     *string `json:"gstin,omitempty"`
+	StateCode *string `json:"state_code,omitempty"`
+	Balance   float64 `json:"balance,omitempty"`
+}
+
+type SettleRequest struct {
+	Method   string         `json:"method"`
+	Discount float64        `json:"discount,omitempty"`  // portion forgiven rather than paid
+	WriteOff float64        `json:"write_off,omitempty"` // portion written off as bad debt
+	Note     *string        `json:"note,omitempty"`
+	Cheque   *ChequeRequest `json:"cheque,omitempty"`
+	Date     time.Time      `json:"date,omitempty"`
 }
@@ -0,0 +1,46 @@
+package models
+
+import (
+	"time"
+)
+
+type DayClose struct {
+	ID            int             `json:"id"`
+	Date          string          `json:"date"`
+	Status        string          `json:"status"` // "closed" or "reopened"
+	ExpectedTotal float64         `json:"expected_total"`
+	CountedTotal  float64         `json:"counted_total"`
+	VarianceTotal float64         `json:"variance_total"`
+	Note          *string         `json:"note,omitempty"`
+	ReopenReason  *string         `json:"reopen_reason,omitempty"`
+	ClosedAt      *time.Time      `json:"closed_at,omitempty"`
+	ReopenedAt    *time.Time      `json:"reopened_at,omitempty"`
+	Counts        []DayCloseCount `json:"counts"`
+	UserID        int             `json:"user_id"`
+	CreatedAt     time.Time       `json:"created_at"`
+	UpdatedAt     time.Time       `json:"updated_at"`
+}
+
+// DayCloseCount compares the cash a method should hold at the end of the day
+// with what was counted. Counted and Variance are nil for uncounted methods.
+type DayCloseCount struct {
+	Method   string   `json:"method"`
+	Expected float64  `json:"expected"`
+	Counted  *float64 `json:"counted"`
+	Variance *float64 `json:"variance"`
+}
+
+type DayCloseRequest struct {
+	Date   string                 `json:"date,omitempty"` // YYYY-MM-DD, defaults to today
+	Counts []DayCloseCountRequest `json:"counts"`
+	Note   *string                `json:"note,omitempty"`
+}
+
+type DayCloseCountRequest struct {
+	Method  string  `json:"method"`
+	Counted float64 `json:"counted"`
+}
+
+type DayReopenRequest struct {
+	Reason *string `json:"reason,omitempty"`
+}
@@ -0,0 +1,104 @@
+package models
+
+import (
+	"time"
+)
+
+type TaxRate struct {
+	ID        int       `json:"id"`
+	Name      string    `json:"name"` // e.g. "GST 18%"
+	Rate      float64   `json:"rate"` // percent, split into CGST and SGST or charged as IGST
+	HSNCode   *string   `json:"hsn_code,omitempty"`
+	IsActive  bool      `json:"is_active"`
+	UserID    int       `json:"user_id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type TaxRateRequest struct {
+	Name     string   `json:"name"`
+	Rate     *float64 `json:"rate,omitempty"`
+	HSNCode  *string  `json:"hsn_code,omitempty"`
+	IsActive *bool    `json:"is_active,omitempty"`
+}
+
+// GSTR1 follows the JSON layout of the GSTR-1 return. Amounts are rupees and
+// dates are dd-mm-yyyy as the GST portal expects.
+type GSTR1 struct {
+	GSTIN  string      `json:"gstin"`
+	Period string      `json:"fp"` // MMYYYY
+	B2B    []GSTR1B2B  `json:"b2b"`
+	B2CL   []GSTR1B2CL `json:"b2cl"`
+	B2CS   []GSTR1B2CS `json:"b2cs"`
+	HSN    GSTR1HSN    `json:"hsn"`
+}
+
+// GSTR1B2B groups invoices issued to one registered customer
+type GSTR1B2B struct {
+	CTIN     string         `json:"ctin"`
+	Invoices []GSTR1Invoice `json:"inv"`
+}
+
+// GSTR1B2CL groups large inter-state invoices to unregistered customers by
+// place of supply
+type GSTR1B2CL struct {
+	POS      string         `json:"pos"`
+	Invoices []GSTR1Invoice `json:"inv"`
+}
+
+type GSTR1Invoice struct {
+	Number        string      `json:"inum"`
+	Date          string      `json:"idt"`
+	Value         float64     `json:"val"`
+	POS           string      `json:"pos,omitempty"`
+	ReverseCharge string      `json:"rchrg,omitempty"`
+	Type          string      `json:"inv_typ,omitempty"`
+	Items         []GSTR1Item `json:"itms"`
+}
+
+// GSTR1Item totals an invoice's lines at one tax rate
+type GSTR1Item struct {
+	Num    int             `json:"num"`
+	Detail GSTR1ItemDetail `json:"itm_det"`
+}
+
+type GSTR1ItemDetail struct {
+	Rate         float64 `json:"rt"`
+	TaxableValue float64 `json:"txval"`
+	IGST         float64 `json:"iamt"`
+	CGST         float64 `json:"camt"`
+	SGST         float64 `json:"samt"`
+	Cess         float64 `json:"csamt"`
+}
+
+// GSTR1B2CS totals the remaining sales to unregistered customers by supply
+// type, place of supply and rate
+type GSTR1B2CS struct {
+	SupplyType   string  `json:"sply_ty"` // "INTRA" or "INTER"
+	POS          string  `json:"pos"`
+	Type         string  `json:"typ"`
+	Rate         float64 `json:"rt"`
+	TaxableValue float64 `json:"txval"`
+	IGST         float64 `json:"iamt"`
+	CGST         float64 `json:"camt"`
+	SGST         float64 `json:"samt"`
+	Cess         float64 `json:"csamt"`
+}
+
+type GSTR1HSN struct {
+	Data []GSTR1HSNRow `json:"data"`
+}
+
+type GSTR1HSNRow struct {
+	Num          int     `json:"num"`
+	HSN          string  `json:"hsn_sc"`
+	Description  string  `json:"desc"`
+	UQC          string  `json:"uqc"`
+	Quantity     float64 `json:"qty"`
+	Rate         float64 `json:"rt"`
+	TaxableValue float64 `json:"txval"`
+	IGST         float64 `json:"iamt"`
+	CGST         float64 `json:"camt"`
+	SGST         float64 `json:"samt"`
+	Cess         float64 `json:"csamt"`
+}
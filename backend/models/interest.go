@@ -0,0 +1,36 @@
+package models
+
+import (
+	"time"
+)
+
+type InterestSettings struct {
+	CustomerID     int       `json:"customer_id"`
+	Mode           string    `json:"mode"`         // "simple" or "compound"
+	MonthlyRate    float64   `json:"monthly_rate"` // percent per month, e.g. 2 for 2%
+	GraceDays      int       `json:"grace_days"`
+	LateFee        float64   `json:"late_fee"` // flat fee per overdue month, 0 for none
+	Active         bool      `json:"active"`
+	AccruedThrough time.Time `json:"accrued_through"`
+	UserID         int       `json:"user_id"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+type InterestSettingsRequest struct {
+	Mode        string  `json:"mode"`
+	MonthlyRate float64 `json:"monthly_rate"`
+	GraceDays   int     `json:"grace_days"`
+	LateFee     float64 `json:"late_fee"`
+	Active      *bool   `json:"active,omitempty"`
+}
+
+type InterestAccrual struct {
+	PeriodStart     time.Time `json:"period_start"`
+	PeriodEnd       time.Time `json:"period_end"`
+	OverdueBalance  float64   `json:"overdue_balance"` // at the end of the period
+	Interest        float64   `json:"interest"`
+	LateFee         float64   `json:"late_fee"`
+	InterestEntryID *int      `json:"interest_entry_id,omitempty"`
+	LateFeeEntryID  *int      `json:"late_fee_entry_id,omitempty"`
+}
@@ -0,0 +1,101 @@
+package models
+
+import (
+	"time"
+)
+
+type InvoiceSeries struct {
+	ID         int       `json:"id"`
+	Name       string    `json:"name"`
+	Prefix     string    `json:"prefix"` // e.g. "INV-" gives INV-0001
+	NextNumber int       `json:"next_number"`
+	Padding    int       `json:"padding"`
+	IsDefault  bool      `json:"is_default"`
+	UserID     int       `json:"user_id"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+type InvoiceSeriesRequest struct {
+	Name       string `json:"name"`
+	Prefix     string `json:"prefix"`
+	NextNumber *int   `json:"next_number,omitempty"`
+	Padding    *int   `json:"padding,omitempty"`
+	IsDefault  *bool  `json:"is_default,omitempty"`
+}
+
+type Invoice struct {
+	ID            int                 `json:"id"`
+	CustomerID    int                 `json:"customer_id"`
+	CustomerName  string              `json:"customer_name,omitempty"`
+	SeriesID      *int                `json:"series_id,omitempty"`
+	InvoiceNumber string              `json:"invoice_number"`
+	InvoiceDate   time.Time           `json:"invoice_date"`
+	DueDate       time.Time           `json:"due_date"`
+	Subtotal      float64             `json:"subtotal"`
+	TaxTotal      float64             `json:"tax_total"`
+	CGSTTotal     float64             `json:"cgst_total"`
+	SGSTTotal     float64             `json:"sgst_total"`
+	IGSTTotal     float64             `json:"igst_total"`
+	Total         float64             `json:"total"`
+	AmountPaid    float64             `json:"amount_paid"`
+	AmountDue     float64             `json:"amount_due"`
+	Status        string              `json:"status"` // "open", "partial", "paid" or "overdue"
+	Note          *string             `json:"note,omitempty"`
+	SupplierGSTIN *string             `json:"supplier_gstin,omitempty"`
+	CustomerGSTIN *string             `json:"customer_gstin,omitempty"`
+	PlaceOfSupply *string             `json:"place_of_supply,omitempty"` // state code
+	EntryID       *int                `json:"entry_id,omitempty"`
+	Lines         []InvoiceLine       `json:"lines,omitempty"`
+	Allocations   []InvoiceAllocation `json:"allocations,omitempty"`
+	UserID        int                 `json:"user_id"`
+	CreatedAt     time.Time           `json:"created_at"`
+	UpdatedAt     time.Time           `json:"updated_at"`
+}
+
+type InvoiceLine struct {
+	ID         int     `json:"id"`
+	Item       string  `json:"item"`
+	HSNCode    *string `json:"hsn_code,omitempty"`
+	Quantity   float64 `json:"quantity"`
+	Rate       float64 `json:"rate"`
+	TaxRate    float64 `json:"tax_rate"` // percent
+	Amount     float64 `json:"amount"`   // quantity × rate, the taxable value
+	TaxAmount  float64 `json:"tax_amount"`
+	CGSTAmount float64 `json:"cgst_amount"`
+	SGSTAmount float64 `json:"sgst_amount"`
+	IGSTAmount float64 `json:"igst_amount"`
+	Total      float64 `json:"total"`
+}
+
+type InvoiceRequest struct {
+	CustomerID  int                  `json:"customer_id"`
+	SeriesID    *int                 `json:"series_id,omitempty"`
+	InvoiceDate time.Time            `json:"invoice_date,omitempty"`
+	DueDate     *time.Time           `json:"due_date,omitempty"`
+	Note        *string              `json:"note,omitempty"`
+	Lines       []InvoiceLineRequest `json:"lines"`
+}
+
+type InvoiceLineRequest struct {
+	Item      string  `json:"item"`
+	HSNCode   *string `json:"hsn_code,omitempty"`
+	Quantity  float64 `json:"quantity"`
+	Rate      float64 `json:"rate"`
+	TaxRate   float64 `json:"tax_rate,omitempty"`
+	TaxRateID *int    `json:"tax_rate_id,omitempty"` // a configured rate; overrides tax_rate and supplies a default HSN code
+}
+
+type InvoiceAllocation struct {
+	InvoiceID     int       `json:"invoice_id"`
+	InvoiceNumber string    `json:"invoice_number,omitempty"`
+	EntryID       int       `json:"entry_id"`
+	Amount        float64   `json:"amount"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+type AllocationRequest struct {
+	InvoiceID int     `json:"invoice_id"`
+	EntryID   int     `json:"entry_id,omitempty"`
+	Amount    float64 `json:"amount"`
+}
@@ -7,10 +7,13 @@ import (
 type LedgerEntry struct {
 	ID         int       `json:"id"`
 	CustomerID int       `json:"customer_id"`
-	Type       string    `json:"type"` // "credit" or "debit"
+	Type       string    `json:"type"` // "credit", "debit", "interest", "late_fee", "write_off" or "discount"
 	Amount     float64   `json:"amount"`
-	Method     string    `json:"method"` // "cash", "upi", "bank"
+	Method     string    `json:"method"` // payment method code, "adjustment" for entries that move no money
+	CategoryID *int      `json:"category_id,omitempty"`
+	Tags       []string  `json:"tags,omitempty"`
 	Note       *string   `json:"note,omitempty"`
+	Cheque     *Cheque   `json:"cheque,omitempty"`
 	Date       time.Time `json:"date"`
 	UserID     int       `json:"user_id"`
 	CreatedAt  time.Time `json:"created_at"`
@@ -18,10 +21,16 @@ type LedgerEntry struct {
 }
 
 type LedgerEntryRequest struct {
-	CustomerID int       `json:"customer_id"`
-	Type       string    `json:"type"`
-	Amount     float64   `json:"amount"`
-	Method     string    `json:"method"`
-	Note       *string   `json:"note,omitempty"`
-	Date       time.Time `json:"date,omitempty"`
+	CustomerID int            `json:"customer_id"`
+	Type       string         `json:"type"`
+	Amount     float64        `json:"amount"`
+	Method     string         `json:"method"`
+	CategoryID *int           `json:"category_id,omitempty"`
+	Tags       []string       `json:"tags,omitempty"`
+	Note       *string        `json:"note,omitempty"`
+	Cheque     *ChequeRequest `json:"cheque,omitempty"`
+	Date       time.Time      `json:"date,omitempty"`
+	// Allocations applies a payment to specific invoices; without it the
+	// payment settles the oldest open invoices first
+	Allocations []AllocationRequest `json:"allocations,omitempty"`
 }
@@ -0,0 +1,36 @@
+package models
+
+import (
+	"time"
+)
+
+type PaymentMethod struct {
+	ID      *int   `json:"id,omitempty"` // nil for built-in methods
+	Code    string `json:"code"`
+	Name    string `json:"name"`
+	Kind    string `json:"kind"` // "cash", "upi", "bank", "cheque", "card", "wallet", "other"
+	BuiltIn bool   `json:"built_in"`
+	Active  bool   `json:"active"`
+}
+
+type PaymentMethodRequest struct {
+	Name   string `json:"name"`
+	Kind   string `json:"kind"`
+	Active *bool  `json:"active,omitempty"`
+}
+
+type Cheque struct {
+	EntryID       int        `json:"entry_id"`
+	ChequeNumber  string     `json:"cheque_number"`
+	BankName      *string    `json:"bank_name,omitempty"`
+	ClearingDate  *time.Time `json:"clearing_date,omitempty"`
+	Status        string     `json:"status"` // "pending", "cleared", "bounced"
+	BounceEntryID *int       `json:"bounce_entry_id,omitempty"`
+}
+
+type ChequeRequest struct {
+	ChequeNumber string     `json:"cheque_number"`
+	BankName     *string    `json:"bank_name,omitempty"`
+	ClearingDate *time.Time `json:"clearing_date,omitempty"`
+	Status       string     `json:"status,omitempty"`
+}
@@ -0,0 +1,42 @@
+package models
+
+import (
+	"time"
+)
+
+type RecurringEntry struct {
+	ID          int        `json:"id"`
+	CustomerID  int        `json:"customer_id"`
+	Type        string     `json:"type"` // "credit" or "debit"
+	Amount      float64    `json:"amount"`
+	Method      string     `json:"method"`
+	CategoryID  *int       `json:"category_id,omitempty"`
+	Note        *string    `json:"note,omitempty"`
+	RRule       string     `json:"rrule"` // e.g. "FREQ=MONTHLY;BYMONTHDAY=1"
+	StartDate   time.Time  `json:"start_date"`
+	EndDate     *time.Time `json:"end_date,omitempty"`
+	NextRunDate *time.Time `json:"next_run_date,omitempty"`
+	Status      string     `json:"status"` // "active", "paused", "ended"
+	UserID      int        `json:"user_id"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+type RecurringEntryRequest struct {
+	CustomerID int        `json:"customer_id"`
+	Type       string     `json:"type"`
+	Amount     float64    `json:"amount"`
+	Method     string     `json:"method"`
+	CategoryID *int       `json:"category_id,omitempty"`
+	Note       *string    `json:"note,omitempty"`
+	RRule      string     `json:"rrule"`
+	StartDate  time.Time  `json:"start_date"`
+	EndDate    *time.Time `json:"end_date,omitempty"`
+}
+
+type RecurringRun struct {
+	OccurrenceDate string `json:"occurrence_date"`
+	EntryID        *int   `json:"entry_id,omitempty"`
+	Status         string `json:"status"` // "posted" or "skipped"
+	CreatedAt      string `json:"created_at"`
+}
@@ -10,10 +10,20 @@ type Reminder struct {
 	DueAmount  float64   `json:"due_amount"`
 	DueDate    time.Time `json:"due_date"`
 	Channel    string    `json:"channel"` // "sms", "whatsapp", "email"
-	Status     string    `json:"status"`  // "pending", "sent", "snoozed", "paid"
-	UserID     int       `json:"user_id"`
-	CreatedAt  time.Time `json:"created_at"`
-	UpdatedAt  time.Time `json:"updated_at"`
+	TemplateID *int      `json:"template_id,omitempty"`
+	RuleID     *int      `json:"rule_id,omitempty"`
+	Status     string    `json:"status"` // "pending", "sent", "snoozed", "paid", "failed"
+	// SnoozedUntil is when a snoozed reminder goes back to pending
+	SnoozedUntil *time.Time `json:"snoozed_until,omitempty"`
+	// Delivery state kept by the reminder dispatcher
+	Attempts          int        `json:"attempts"`
+	NextAttemptAt     *time.Time `json:"next_attempt_at,omitempty"`
+	LastError         *string    `json:"last_error,omitempty"`
+	SentAt            *time.Time `json:"sent_at,omitempty"`
+	ProviderMessageID *string    `json:"provider_message_id,omitempty"`
+	UserID            int        `json:"user_id"`
+	CreatedAt         time.Time  `json:"created_at"`
+	UpdatedAt         time.Time  `json:"updated_at"`
 }
 
 type ReminderRequest struct {
@@ -21,4 +31,52 @@ type ReminderRequest struct {
 	DueAmount  float64   `json:"due_amount"`
 	DueDate    time.Time `json:"due_date"`
 	Channel    string    `json:"channel"`
+	TemplateID *int      `json:"template_id,omitempty"`
+}
+
+// ReminderSnoozeRequest snoozes a reminder either for a duration such as
+// "2h" or "3d", or until a date ("2006-01-02") or time (RFC 3339)
+type ReminderSnoozeRequest struct {
+	Duration string `json:"duration,omitempty"`
+	Until    string `json:"until,omitempty"`
+}
+
+// ReminderEvent is one entry in a reminder's history: a delivery attempt,
+// its outcome or a change of status
+type ReminderEvent struct {
+	ID                int64     `json:"id"`
+	ReminderID        int       `json:"reminder_id"`
+	Event             string    `json:"event"` // "created", "sent", "attempt_failed", "failed", "status_changed", "amount_reduced", "snoozed", "woke"
+	FromStatus        *string   `json:"from_status,omitempty"`
+	ToStatus          *string   `json:"to_status,omitempty"`
+	Attempt           *int      `json:"attempt,omitempty"`
+	Channel           *string   `json:"channel,omitempty"`
+	ProviderMessageID *string   `json:"provider_message_id,omitempty"`
+	Detail            *string   `json:"detail,omitempty"` // failure reason or receipt details
+	CreatedAt         time.Time `json:"created_at"`
+}
+
+// ReminderFilter selects reminders the way the reminder list does
+type ReminderFilter struct {
+	Status     string `json:"status,omitempty"`
+	CustomerID int    `json:"customer_id,omitempty"`
+}
+
+// ReminderBulkRequest applies one action to many reminders, chosen either
+// by ID or by filter. Duration and Until are used by the snooze action.
+type ReminderBulkRequest struct {
+	Action   string          `json:"action"` // "send", "snooze", "mark_paid", "delete"
+	IDs      []int           `json:"ids,omitempty"`
+	Filter   *ReminderFilter `json:"filter,omitempty"`
+	Duration string          `json:"duration,omitempty"`
+	Until    string          `json:"until,omitempty"`
+}
+
+// ReminderBulkResult is the outcome of a bulk action for one reminder
+type ReminderBulkResult struct {
+	ID      int    `json:"id"`
+	Success bool   `json:"success"`
+	Status  string `json:"status,omitempty"` // status after the action
+	Error   string `json:"error,omitempty"`
+	Message string `json:"message,omitempty"`
 }
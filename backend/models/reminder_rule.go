@@ -0,0 +1,48 @@
+package models
+
+import (
+	"time"
+)
+
+// ReminderRule creates reminders for every customer owing more than
+// MinBalance who has not paid for DaysSincePayment days, repeating every
+// RepeatEveryDays until the balance is paid
+type ReminderRule struct {
+	ID               int        `json:"id"`
+	Name             string     `json:"name"`
+	MinBalance       float64    `json:"min_balance"`
+	DaysSincePayment int        `json:"days_since_payment"`
+	Channel          string     `json:"channel"` // "sms", "whatsapp", "email"
+	TemplateID       *int       `json:"template_id,omitempty"`
+	RepeatEveryDays  int        `json:"repeat_every_days"`
+	IsActive         bool       `json:"is_active"`
+	LastRunAt        *time.Time `json:"last_run_at,omitempty"`
+	UserID           int        `json:"user_id"`
+	CreatedAt        time.Time  `json:"created_at"`
+	UpdatedAt        time.Time  `json:"updated_at"`
+}
+
+type ReminderRuleRequest struct {
+	Name             string   `json:"name"`
+	MinBalance       *float64 `json:"min_balance,omitempty"`
+	DaysSincePayment *int     `json:"days_since_payment,omitempty"`
+	Channel          string   `json:"channel"`
+	TemplateID       *int     `json:"template_id,omitempty"`
+	RepeatEveryDays  *int     `json:"repeat_every_days,omitempty"`
+	IsActive         *bool    `json:"is_active,omitempty"`
+}
+
+type ReminderRuleRunRequest struct {
+	DryRun bool `json:"dry_run"`
+}
+
+// ReminderRuleMatch is a customer a rule applies to on a run
+type ReminderRuleMatch struct {
+	CustomerID      int               `json:"customer_id"`
+	CustomerName    string            `json:"customer_name"`
+	Balance         float64           `json:"balance"`
+	LastPaymentDate *string           `json:"last_payment_date"`
+	ReminderID      *int              `json:"reminder_id,omitempty"` // the reminder created, unless a dry run
+	Preview         *RenderedReminder `json:"preview,omitempty"`
+	PreviewError    *string           `json:"preview_error,omitempty"`
+}
@@ -0,0 +1,42 @@
+package models
+
+import (
+	"time"
+)
+
+type ReminderTemplate struct {
+	ID        int       `json:"id"`
+	Name      string    `json:"name"`
+	Channel   string    `json:"channel"`  // "sms", "whatsapp", "email"
+	Language  string    `json:"language"` // e.g. "en", "hi"
+	Subject   *string   `json:"subject,omitempty"`
+	Body      string    `json:"body"`
+	IsDefault bool      `json:"is_default"`
+	UserID    int       `json:"user_id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type ReminderTemplateRequest struct {
+	Name      string  `json:"name"`
+	Channel   string  `json:"channel"`
+	Language  string  `json:"language,omitempty"`
+	Subject   *string `json:"subject,omitempty"`
+	Body      string  `json:"body"`
+	IsDefault *bool   `json:"is_default,omitempty"`
+}
+
+// RenderedReminder is a reminder message after its template was filled in
+type RenderedReminder struct {
+	TemplateID *int              `json:"template_id"` // nil for the built-in template
+	Channel    string            `json:"channel"`
+	Language   string            `json:"language"`
+	To         string            `json:"to"`
+	Subject    string            `json:"subject,omitempty"`
+	Body       string            `json:"body"`
+	Variables  map[string]string `json:"variables"`
+}
+
+type ReminderPreviewRequest struct {
+	ReminderID int `json:"reminder_id"`
+}
@@ -1,12 +1,23 @@
 package models
 
 type ReportSummary struct {
-	Month       string             `json:"month"`
-	TotalCredit float64            `json:"total_credit"`
-	TotalDebit  float64            `json:"total_debit"`
-	Balance     float64            `json:"balance"`
-	ByCategory  map[string]float64 `json:"by_category,omitempty"`
-	ByMethod    map[string]float64 `json:"by_method,omitempty"`
+	Month          string  `json:"month"`
+	TotalCredit    float64 `json:"total_credit"`
+	TotalDebit     float64 `json:"total_debit"`
+	TotalInterest  float64 `json:"total_interest"`
+	TotalLateFees  float64 `json:"total_late_fees"`
+	TotalWriteOffs float64 `json:"total_write_offs"`
+	TotalDiscounts float64 `json:"total_discounts"`
+	// Credit and debit totals are for customers; suppliers' purchases on
+	// credit and the payments made to them are kept apart
+	SupplierPurchases float64 `json:"supplier_purchases"`
+	SupplierPayments  float64 `json:"supplier_payments"`
+	Balance           float64 `json:"balance"`
+	// Cashbook income and expenses belong to no party and stay out of Balance
+	CashbookIncome  float64            `json:"cashbook_income"`
+	CashbookExpense float64            `json:"cashbook_expense"`
+	ByCategory      map[string]float64 `json:"by_category,omitempty"`
+	ByMethod        map[string]float64 `json:"by_method,omitempty"`
 }
 
 type DashboardSummary struct {
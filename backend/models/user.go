@@ -5,14 +5,22 @@ import (
 )
 
 type User struct {
-	ID           int       `json:"id"`
-	Name         string    `json:"name,omitempty"`
-	Phone        string    `json:"phone,omitempty"`
-	Email        string    `json:"email"`
-	Address      string    `json:"address,omitempty"`
-	PasswordHash string    `json:"-"`
-	CreatedAt    time.Time `json:"created_at"`
-	UpdatedAt    time.Time `json:"updated_at,omitempty"`
+	ID      int    `json:"id"`
+	Name    string `json:"name,omitempty"`
+	Phone   string `json:"phone,omitempty"`
+	Email   string `json:"email"`
+	Address string `json:"address,omitempty"`
+	GSTIN   string `json:"gstin,omitempty"`
+	UPIID   string `json:"upi_id,omitempty"`
+	// AutoCloseReminders closes or reduces open reminders when a payment
+	// lowers the customer's balance
+	AutoCloseReminders bool      `json:"auto_close_reminders"`
+	Timezone           string    `json:"timezone,omitempty"`          // IANA zone due dates are read in
+	QuietHoursStart    string    `json:"quiet_hours_start,omitempty"` // "HH:MM" in the customer's time zone
+	QuietHoursEnd      string    `json:"quiet_hours_end,omitempty"`
+	PasswordHash       string    `json:"-"`
+	CreatedAt          time.Time `json:"created_at"`
+	UpdatedAt          time.Time `json:"updated_at,omitempty"`
 }
 
 type UserRequest struct {
@@ -21,6 +29,14 @@ type UserRequest struct {
 	Email    string `json:"email"`
 	Password string `json:"password"`
 	Address  string `json:"address,omitempty"`
+	GSTIN    string `json:"gstin,omitempty"`
+	UPIID    string `json:"upi_id,omitempty"`
+	// AutoCloseReminders is only changed when present
+	AutoCloseReminders *bool  `json:"auto_close_reminders,omitempty"`
+	Timezone           string `json:"timezone,omitempty"`
+	// Quiet hours are changed together; empty strings clear them
+	QuietHoursStart *string `json:"quiet_hours_start,omitempty"`
+	QuietHoursEnd   *string `json:"quiet_hours_end,omitempty"`
 }
 
 type LoginResponse struct {
@@ -0,0 +1,78 @@
+// Package gst validates Indian GST registration numbers and splits tax
+// between the central, state and integrated components.
+package gst
+
+import (
+	"math"
+	"regexp"
+	"strings"
+)
+
+const checksumAlphabet = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZ"
+
+// gstinPattern is the 15 character GSTIN layout: state code, PAN, entity
+// number, the letter Z and a check character
+var gstinPattern = regexp.MustCompile(`^[0-9]{2}[A-Z]{5}[0-9]{4}[A-Z][1-9A-Z]Z[0-9A-Z]$`)
+
+var stateCodePattern = regexp.MustCompile(`^(0[1-9]|[1-3][0-9]|97|99)$`)
+
+// Normalize upper-cases a GSTIN and strips surrounding spaces
+func Normalize(gstin string) string {
+	return strings.ToUpper(strings.TrimSpace(gstin))
+}
+
+// ValidGSTIN reports whether gstin has the right layout, a known state code
+// and a correct check character. It expects a normalized value.
+func ValidGSTIN(gstin string) bool {
+	if !gstinPattern.MatchString(gstin) || !ValidStateCode(gstin[:2]) {
+		return false
+	}
+	return gstin[14] == checkChar(gstin[:14])
+}
+
+// ValidStateCode reports whether code is a two digit GST state code
+func ValidStateCode(code string) bool {
+	return stateCodePattern.MatchString(code)
+}
+
+// StateCode returns the state a GSTIN is registered in
+func StateCode(gstin string) string {
+	if len(gstin) < 2 {
+		return ""
+	}
+	return gstin[:2]
+}
+
+// checkChar computes the GSTIN check character: a weighted base-36 sum of
+// the first 14 characters
+func checkChar(body string) byte {
+	sum := 0
+	for i := 0; i < len(body); i++ {
+		factor := 1
+		if i%2 == 1 {
+			factor = 2
+		}
+		product := strings.IndexByte(checksumAlphabet, body[i]) * factor
+		sum += product/36 + product%36
+	}
+	return checksumAlphabet[(36-sum%36)%36]
+}
+
+// Split is the tax on a supply broken into its GST components
+type Split struct {
+	CGST float64
+	SGST float64
+	IGST float64
+}
+
+// SplitTax divides tax between CGST and SGST for a supply within the
+// supplier's state, and charges it all as IGST otherwise. Tax is rounded to
+// the paisa either way, and any odd paisa goes to SGST so the parts always
+// add up to the same total as IGST would.
+func SplitTax(tax float64, supplierState, placeOfSupply string) Split {
+	if supplierState != placeOfSupply {
+		return Split{IGST: math.Round(tax*100) / 100}
+	}
+	cgst := math.Floor(math.Round(tax*100)/2) / 100
+	return Split{CGST: cgst, SGST: math.Round((tax-cgst)*100) / 100}
+}
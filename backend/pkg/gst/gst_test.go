@@ -0,0 +1,112 @@
+package gst
+
+import "testing"
+
+func TestValidGSTIN(t *testing.T) {
+	tests := []struct {
+		gstin string
+		valid bool
+	}{
+		{"27AAPFU0939F1ZV", true},
+		{"07AAGFF2194N1Z1", true},
+		{"33AAACH7409R1Z8", true},
+		{"29AAGCB7383J1Z4", true},
+		{"24AAACC1206D1ZM", true},
+		{"27AAACR5055K1Z7", true},
+		{"97AAAAA0000A1Z0", false}, // well formed, wrong check character
+
+		{"27AAPFU0939F1ZW", false}, // wrong check character
+		{"27AAPFU0939F1Z", false},  // too short
+		{"27AAPFU0939F1ZVX", false},
+		{"27aapfu0939f1zv", false}, // not normalized
+		{"00AAPFU0939F1ZV", false}, // no such state
+		{"40AAPFU0939F1ZV", false},
+		{"27AAPFU0939F0ZV", false}, // entity number 0
+		{"27AAPFU0939F1YV", false}, // 14th character must be Z
+		{"27AAPF10939F1ZV", false}, // PAN layout
+		{"", false},
+	}
+	for _, tt := range tests {
+		if got := ValidGSTIN(tt.gstin); got != tt.valid {
+			t.Errorf("ValidGSTIN(%q) = %v, want %v", tt.gstin, got, tt.valid)
+		}
+	}
+
+	if !ValidGSTIN(Normalize("  27aapfu0939f1zv ")) {
+		t.Error("normalized GSTIN rejected")
+	}
+}
+
+func TestCheckChar(t *testing.T) {
+	// Changing any one character of a valid GSTIN changes its check character
+	const gstin = "27AAPFU0939F1ZV"
+	for i := 0; i < 14; i++ {
+		for _, c := range []byte(checksumAlphabet) {
+			if c == gstin[i] {
+				continue
+			}
+			body := gstin[:i] + string(c) + gstin[i+1:14]
+			if checkChar(body) == gstin[14] {
+				t.Errorf("%s shares the check character of %s", body, gstin[:14])
+			}
+		}
+	}
+}
+
+func TestValidStateCode(t *testing.T) {
+	for _, code := range []string{"01", "09", "10", "27", "38", "97", "99"} {
+		if !ValidStateCode(code) {
+			t.Errorf("ValidStateCode(%q) = false", code)
+		}
+	}
+	for _, code := range []string{"00", "40", "96", "98", "7", "007", "AB"} {
+		if ValidStateCode(code) {
+			t.Errorf("ValidStateCode(%q) = true", code)
+		}
+	}
+}
+
+func TestSplitTax(t *testing.T) {
+	tests := []struct {
+		tax             float64
+		supplier, place string
+		want            Split
+	}{
+		// Within the state: halved, the odd paisa to SGST
+		{18, "27", "27", Split{CGST: 9, SGST: 9}},
+		{0.3, "27", "27", Split{CGST: 0.15, SGST: 0.15}},
+		{1.01, "27", "27", Split{CGST: 0.5, SGST: 0.51}},
+		{99.99, "27", "27", Split{CGST: 49.99, SGST: 50}},
+		{0.05, "27", "27", Split{CGST: 0.02, SGST: 0.03}},
+		{0.01, "27", "27", Split{CGST: 0, SGST: 0.01}},
+		{10.005, "27", "27", Split{CGST: 5, SGST: 5.01}},
+		{0, "27", "27", Split{}},
+
+		// Across states: all IGST, rounded the same way
+		{18, "27", "29", Split{IGST: 18}},
+		{1.01, "27", "29", Split{IGST: 1.01}},
+		{10.005, "27", "07", Split{IGST: 10.01}},
+		{99.994, "27", "07", Split{IGST: 99.99}},
+	}
+	for _, tt := range tests {
+		got := SplitTax(tt.tax, tt.supplier, tt.place)
+		if got != tt.want {
+			t.Errorf("SplitTax(%v, %s, %s) = %+v, want %+v", tt.tax, tt.supplier, tt.place, got, tt.want)
+		}
+	}
+}
+
+func TestSplitTaxAddsUp(t *testing.T) {
+	// Intra-state parts always add up to what IGST would charge
+	for paise := 0; paise <= 100000; paise += 7 {
+		tax := float64(paise) / 100
+		intra := SplitTax(tax, "27", "27")
+		inter := SplitTax(tax, "27", "29")
+		if sum := intra.CGST + intra.SGST; int(sum*100+0.5) != int(inter.IGST*100+0.5) {
+			t.Fatalf("tax %.2f: CGST %.2f + SGST %.2f = %.2f, IGST %.2f", tax, intra.CGST, intra.SGST, sum, inter.IGST)
+		}
+		if diff := intra.SGST - intra.CGST; diff < -0.0001 || diff > 0.0101 {
+			t.Fatalf("tax %.2f: CGST %.2f and SGST %.2f differ by more than a paisa", tax, intra.CGST, intra.SGST)
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"khata-book-backend/pkg/logger"
+)
+
+// Fake accepts messages without sending them anywhere. It records what was
+// sent, and failures can be queued with FailNext to exercise retries.
+type Fake struct {
+	channel string
+
+	mu       sync.Mutex
+	sent     []Message
+	failures []error
+}
+
+// NewFake returns a fake provider for channel
+func NewFake(channel string) *Fake {
+	return &Fake{channel: channel}
+}
+
+func (f *Fake) Send(ctx context.Context, msg Message) (*Receipt, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if len(f.failures) > 0 {
+		err := f.failures[0]
+		f.failures = f.failures[1:]
+		return nil, err
+	}
+
+	f.sent = append(f.sent, msg)
+	receipt := &Receipt{ProviderMessageID: fmt.Sprintf("fake-%s-%d", f.channel, len(f.sent))}
+
+	logger.L.WithFields(map[string]interface{}{
+		"channel":    f.channel,
+		"to":         msg.To,
+		"reference":  msg.Reference,
+		"message_id": receipt.ProviderMessageID,
+	}).Info("Fake notifier accepted message")
+
+	return receipt, nil
+}
+
+// FailNext makes the following Send calls return errs, one per call
+func (f *Fake) FailNext(errs ...error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.failures = append(f.failures, errs...)
+}
+
+// Sent returns a copy of the messages accepted so far
+func (f *Fake) Sent() []Message {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]Message(nil), f.sent...)
+}
+
+// Reset forgets sent messages and queued failures
+func (f *Fake) Reset() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.sent = nil
+	f.failures = nil
+}
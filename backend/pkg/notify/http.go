@@ -0,0 +1,55 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+var httpClient = &http.Client{Timeout: 30 * time.Second}
+
+// postJSON sends body as JSON and decodes a successful response into out.
+// Client errors other than timeouts and rate limits are permanent.
+func postJSON(ctx context.Context, url string, header http.Header, body, out interface{}) error {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return Permanent(err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(payload))
+	if err != nil {
+		return Permanent(err)
+	}
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		text, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		err := fmt.Errorf("provider request failed: %s: %s", resp.Status, strings.TrimSpace(string(text)))
+		if resp.StatusCode >= 400 && resp.StatusCode < 500 &&
+			resp.StatusCode != http.StatusRequestTimeout && resp.StatusCode != http.StatusTooManyRequests {
+			return Permanent(err)
+		}
+		return err
+	}
+
+	if out == nil {
+		return nil
+	}
+	// An accepted message with an unreadable body is still accepted
+	_ = json.NewDecoder(io.LimitReader(resp.Body, 64*1024)).Decode(out)
+	return nil
+}
@@ -0,0 +1,154 @@
+// Package notify delivers reminder messages to customers over SMS, WhatsApp
+// and email. Each channel has a provider that talks to a real service and a
+// Fake that records messages locally.
+package notify
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strings"
+
+	"khata-book-backend/pkg/logger"
+)
+
+// Channels a message can be sent on
+const (
+	ChannelSMS      = "sms"
+	ChannelWhatsApp = "whatsapp"
+	ChannelEmail    = "email"
+)
+
+// Message is one outgoing notification. To is an E.164 phone number for SMS
+// and WhatsApp and an address for email. Reference identifies the message in
+// our system so providers and logs can be correlated with it.
+type Message struct {
+	Channel   string
+	To        string
+	Subject   string
+	Body      string
+	Reference string
+}
+
+// Receipt is returned when a provider accepts a message for delivery
+type Receipt struct {
+	ProviderMessageID string
+}
+
+// Notifier sends messages on one channel. A nil error means the provider
+// accepted the message; it may still fail to reach the recipient later.
+type Notifier interface {
+	Send(ctx context.Context, msg Message) (*Receipt, error)
+}
+
+// PermanentError marks a failure that retrying will not fix, such as an
+// invalid recipient or rejected credentials
+type PermanentError struct {
+	Err error
+}
+
+func (e *PermanentError) Error() string { return e.Err.Error() }
+
+func (e *PermanentError) Unwrap() error { return e.Err }
+
+// Permanent wraps err so IsPermanent reports true for it
+func Permanent(err error) error {
+	return &PermanentError{Err: err}
+}
+
+// IsPermanent reports whether err should not be retried
+func IsPermanent(err error) bool {
+	var permanent *PermanentError
+	return errors.As(err, &permanent)
+}
+
+// ErrNoNotifier is returned by Send when a channel has no provider configured
+var ErrNoNotifier = errors.New("no provider configured for channel")
+
+// Notifiers holds the provider for each channel, selected by InitNotifiers
+var Notifiers = map[string]Notifier{}
+
+// Send delivers msg through the provider for its channel
+func Send(ctx context.Context, msg Message) (*Receipt, error) {
+	notifier, ok := Notifiers[msg.Channel]
+	if !ok {
+		return nil, Permanent(ErrNoNotifier)
+	}
+	return notifier.Send(ctx, msg)
+}
+
+// InitNotifiers configures Notifiers from the environment. NOTIFY_DRIVER
+// selects "fake" (default), which logs and accepts every message, or "live",
+// which sets up each channel whose provider settings are present.
+func InitNotifiers() {
+	driver := os.Getenv("NOTIFY_DRIVER")
+
+	switch driver {
+	case "live":
+		if host := os.Getenv("SMTP_HOST"); host != "" {
+			Notifiers[ChannelEmail] = NewSMTPNotifier(SMTPConfig{
+				Host:     host,
+				Port:     os.Getenv("SMTP_PORT"),
+				Username: os.Getenv("SMTP_USERNAME"),
+				Password: os.Getenv("SMTP_PASSWORD"),
+				From:     os.Getenv("SMTP_FROM"),
+			})
+		}
+		if gatewayURL := os.Getenv("SMS_GATEWAY_URL"); gatewayURL != "" {
+			Notifiers[ChannelSMS] = NewSMSGateway(SMSGatewayConfig{
+				URL:      gatewayURL,
+				APIKey:   os.Getenv("SMS_API_KEY"),
+				SenderID: os.Getenv("SMS_SENDER_ID"),
+			})
+		}
+		if phoneNumberID := os.Getenv("WHATSAPP_PHONE_NUMBER_ID"); phoneNumberID != "" {
+			Notifiers[ChannelWhatsApp] = NewWhatsAppNotifier(WhatsAppConfig{
+				APIURL:        os.Getenv("WHATSAPP_API_URL"),
+				PhoneNumberID: phoneNumberID,
+				AccessToken:   os.Getenv("WHATSAPP_ACCESS_TOKEN"),
+			})
+		}
+		for _, channel := range []string{ChannelSMS, ChannelWhatsApp, ChannelEmail} {
+			if _, ok := Notifiers[channel]; !ok {
+				logger.L.WithField("channel", channel).Warn("No notification provider configured; messages on this channel will fail")
+			}
+		}
+	case "", "fake":
+		for _, channel := range []string{ChannelSMS, ChannelWhatsApp, ChannelEmail} {
+			Notifiers[channel] = NewFake(channel)
+		}
+		driver = "fake"
+	default:
+		logger.L.WithField("driver", driver).Fatal("Unknown NOTIFY_DRIVER")
+	}
+
+	logger.L.WithField("driver", driver).Info("Notifiers configured")
+}
+
+// NormalizePhone turns a stored phone number into E.164 form. Ten digit
+// numbers are taken to be local and get defaultCountryCode prefixed. It
+// returns "" when the number cannot be used.
+func NormalizePhone(phone, defaultCountryCode string) string {
+	var digits strings.Builder
+	for _, c := range phone {
+		if c >= '0' && c <= '9' {
+			digits.WriteRune(c)
+		}
+	}
+	number := digits.String()
+
+	switch {
+	case strings.HasPrefix(strings.TrimSpace(phone), "+"):
+	case strings.HasPrefix(number, "00"):
+		number = number[2:]
+	case len(number) == 11 && number[0] == '0':
+		number = defaultCountryCode + number[1:]
+	case len(number) == 10:
+		number = defaultCountryCode + number
+	}
+
+	if len(number) < 8 || len(number) > 15 {
+		return ""
+	}
+	return "+" + number
+}
@@ -0,0 +1,57 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"net/http"
+)
+
+// SMSGatewayConfig describes an HTTP SMS gateway that accepts
+// {"to", "message", "sender", "reference"} as JSON with a bearer API key
+type SMSGatewayConfig struct {
+	URL      string
+	APIKey   string
+	SenderID string
+}
+
+// SMSGateway sends text messages through an HTTP SMS gateway
+type SMSGateway struct {
+	cfg SMSGatewayConfig
+}
+
+// NewSMSGateway returns a notifier for the gateway
+func NewSMSGateway(cfg SMSGatewayConfig) *SMSGateway {
+	return &SMSGateway{cfg: cfg}
+}
+
+func (g *SMSGateway) Send(ctx context.Context, msg Message) (*Receipt, error) {
+	if msg.To == "" {
+		return nil, Permanent(errors.New("recipient phone number is required"))
+	}
+
+	header := http.Header{}
+	if g.cfg.APIKey != "" {
+		header.Set("Authorization", "Bearer "+g.cfg.APIKey)
+	}
+
+	// Gateways differ in what they call the message ID
+	var resp struct {
+		ID        string `json:"id"`
+		MessageID string `json:"message_id"`
+	}
+	err := postJSON(ctx, g.cfg.URL, header, map[string]string{
+		"to":        msg.To,
+		"message":   msg.Body,
+		"sender":    g.cfg.SenderID,
+		"reference": msg.Reference,
+	}, &resp)
+	if err != nil {
+		return nil, err
+	}
+
+	receipt := &Receipt{ProviderMessageID: resp.ID}
+	if receipt.ProviderMessageID == "" {
+		receipt.ProviderMessageID = resp.MessageID
+	}
+	return receipt, nil
+}
@@ -0,0 +1,115 @@
+package notify
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"mime"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"strings"
+	"time"
+)
+
+// SMTPConfig describes the mail server reminders are sent through. STARTTLS
+// is used whenever the server offers it.
+type SMTPConfig struct {
+	Host     string
+	Port     string
+	Username string
+	Password string
+	From     string
+}
+
+// SMTPNotifier sends reminders as plain text email
+type SMTPNotifier struct {
+	cfg SMTPConfig
+}
+
+// NewSMTPNotifier returns a notifier for the mail server
+func NewSMTPNotifier(cfg SMTPConfig) *SMTPNotifier {
+	if cfg.Port == "" {
+		cfg.Port = "587"
+	}
+	return &SMTPNotifier{cfg: cfg}
+}
+
+func (n *SMTPNotifier) Send(ctx context.Context, msg Message) (*Receipt, error) {
+	if msg.To == "" || strings.ContainsAny(msg.To, "\r\n") {
+		return nil, Permanent(errors.New("a valid recipient address is required"))
+	}
+
+	dialer := &net.Dialer{Timeout: 30 * time.Second}
+	conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(n.cfg.Host, n.cfg.Port))
+	if err != nil {
+		return nil, err
+	}
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(time.Minute)
+	}
+	conn.SetDeadline(deadline)
+
+	client, err := smtp.NewClient(conn, n.cfg.Host)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err := client.StartTLS(&tls.Config{ServerName: n.cfg.Host}); err != nil {
+			return nil, err
+		}
+	}
+	if n.cfg.Username != "" {
+		if err := client.Auth(smtp.PlainAuth("", n.cfg.Username, n.cfg.Password, n.cfg.Host)); err != nil {
+			return nil, smtpError(err)
+		}
+	}
+
+	messageID := fmt.Sprintf("%s.%d@%s", msg.Reference, time.Now().UnixNano(), n.cfg.Host)
+	if err := client.Mail(n.cfg.From); err != nil {
+		return nil, smtpError(err)
+	}
+	if err := client.Rcpt(msg.To); err != nil {
+		return nil, smtpError(err)
+	}
+
+	w, err := client.Data()
+	if err != nil {
+		return nil, smtpError(err)
+	}
+	headers := []string{
+		"From: " + n.cfg.From,
+		"To: " + msg.To,
+		"Subject: " + mime.QEncoding.Encode("utf-8", msg.Subject),
+		"Message-ID: <" + messageID + ">",
+		"Date: " + time.Now().Format(time.RFC1123Z),
+		"MIME-Version: 1.0",
+		"Content-Type: text/plain; charset=UTF-8",
+		"Content-Transfer-Encoding: 8bit",
+	}
+	body := strings.ReplaceAll(msg.Body, "\n", "\r\n")
+	if _, err := w.Write([]byte(strings.Join(headers, "\r\n") + "\r\n\r\n" + body + "\r\n")); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, smtpError(err)
+	}
+
+	client.Quit()
+	return &Receipt{ProviderMessageID: messageID}, nil
+}
+
+// smtpError marks 5xx replies, which the server will not accept on retry, as
+// permanent
+func smtpError(err error) error {
+	var protoErr *textproto.Error
+	if errors.As(err, &protoErr) && protoErr.Code >= 500 {
+		return Permanent(err)
+	}
+	return err
+}
@@ -0,0 +1,78 @@
+package notify
+
+import (
+	"fmt"
+	"strings"
+)
+
+// TemplateVariables are the placeholders a message template may use
+var TemplateVariables = []string{"customerName", "amount", "businessName", "dueDate", "paymentLink"}
+
+// ValidateTemplate checks that every {placeholder} in tmpl is a known
+// variable and that braces are balanced. "{{" and "}}" stand for literal
+// braces.
+func ValidateTemplate(tmpl string) error {
+	_, err := expand(tmpl, nil)
+	return err
+}
+
+// RenderTemplate substitutes vars into tmpl. Values are inserted as plain
+// text and never expanded again, and control characters in them become
+// spaces so a customer name cannot break the layout of an SMS or an email
+// header.
+func RenderTemplate(tmpl string, vars map[string]string) (string, error) {
+	clean := make(map[string]string, len(vars))
+	for name, value := range vars {
+		clean[name] = stripControl(value)
+	}
+	return expand(tmpl, clean)
+}
+
+func expand(tmpl string, vars map[string]string) (string, error) {
+	var out strings.Builder
+	for i := 0; i < len(tmpl); i++ {
+		c := tmpl[i]
+		switch {
+		case c == '{' && i+1 < len(tmpl) && tmpl[i+1] == '{':
+			out.WriteByte('{')
+			i++
+		case c == '}' && i+1 < len(tmpl) && tmpl[i+1] == '}':
+			out.WriteByte('}')
+			i++
+		case c == '{':
+			end := strings.IndexByte(tmpl[i:], '}')
+			if end < 0 {
+				return "", fmt.Errorf("unclosed placeholder at position %d", i)
+			}
+			name := tmpl[i+1 : i+end]
+			if !knownVariable(name) {
+				return "", fmt.Errorf("unknown placeholder {%s}", name)
+			}
+			out.WriteString(vars[name])
+			i += end
+		case c == '}':
+			return "", fmt.Errorf("unmatched } at position %d", i)
+		default:
+			out.WriteByte(c)
+		}
+	}
+	return out.String(), nil
+}
+
+func knownVariable(name string) bool {
+	for _, v := range TemplateVariables {
+		if v == name {
+			return true
+		}
+	}
+	return false
+}
+
+func stripControl(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r < ' ' || r == 0x7f {
+			return ' '
+		}
+		return r
+	}, s)
+}
@@ -0,0 +1,41 @@
+package notify
+
+import "testing"
+
+func TestRenderTemplate(t *testing.T) {
+	vars := map[string]string{
+		"customerName": "Asha",
+		"amount":       "1,250.00",
+		"businessName": "Gupta Stores",
+	}
+
+	tests := []struct {
+		name    string
+		tmpl    string
+		vars    map[string]string
+		want    string
+		wantErr bool
+	}{
+		{"placeholders", "Dear {customerName}, ₹{amount} is due to {businessName}.", vars, "Dear Asha, ₹1,250.00 is due to Gupta Stores.", false},
+		{"repeated placeholder", "{customerName}/{customerName}", vars, "Asha/Asha", false},
+		{"missing value renders empty", "Pay by {dueDate}.", vars, "Pay by .", false},
+		{"literal braces", "{{customerName}} is {customerName}", vars, "{customerName} is Asha", false},
+		{"no placeholders", "Thank you", nil, "Thank you", false},
+		{"values are not expanded", "Hi {customerName}", map[string]string{"customerName": "{amount}"}, "Hi {amount}", false},
+		{"control characters become spaces", "Hi {customerName}", map[string]string{"customerName": "Asha\r\nBcc: x\x7f"}, "Hi Asha  Bcc: x ", false},
+		{"unknown placeholder", "Hi {name}", vars, "", true},
+		{"unclosed placeholder", "Hi {customerName", vars, "", true},
+		{"unmatched close brace", "Hi customerName}", vars, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := RenderTemplate(tt.tmpl, tt.vars)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("RenderTemplate(%q) error = %v, want error %v", tt.tmpl, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("RenderTemplate(%q) = %q, want %q", tt.tmpl, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,234 @@
+package notify
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Webhook errors
+var (
+	ErrWebhookNotConfigured = errors.New("no webhook secret configured for channel")
+	ErrBadSignature         = errors.New("webhook signature does not match")
+)
+
+// webhookSigners names, for each channel, the environment variable holding
+// the provider's signing secret and the header its signature arrives in
+var webhookSigners = map[string]struct{ secretEnv, header string }{
+	ChannelWhatsApp: {"WHATSAPP_APP_SECRET", "X-Hub-Signature-256"},
+	ChannelSMS:      {"SMS_WEBHOOK_SECRET", "X-Signature"},
+}
+
+// VerifyWebhook checks that body was signed by the channel's provider: the
+// signature header holds the hex HMAC-SHA256 of the raw body, optionally
+// prefixed with "sha256=" as Meta sends it
+func VerifyWebhook(channel string, header http.Header, body []byte) error {
+	signer, ok := webhookSigners[channel]
+	if !ok {
+		return ErrWebhookNotConfigured
+	}
+	secret := os.Getenv(signer.secretEnv)
+	if secret == "" {
+		return ErrWebhookNotConfigured
+	}
+
+	signature, err := hex.DecodeString(strings.TrimPrefix(header.Get(signer.header), "sha256="))
+	if err != nil || !hmac.Equal(signature, SignWebhook(secret, body)) {
+		return ErrBadSignature
+	}
+	return nil
+}
+
+// WebhookSigner returns the header a channel's provider sends its signature
+// in and the environment variable holding the signing secret
+func WebhookSigner(channel string) (header, secretEnv string, ok bool) {
+	signer, ok := webhookSigners[channel]
+	return signer.header, signer.secretEnv, ok
+}
+
+// SignWebhook returns the HMAC-SHA256 of body under secret
+func SignWebhook(secret string, body []byte) []byte {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return mac.Sum(nil)
+}
+
+// InboundMessage is a text a customer sent to the business's number. From
+// is the sender's number in E.164 form when the provider gives one.
+type InboundMessage struct {
+	Channel           string
+	From              string
+	Text              string
+	ProviderMessageID string
+}
+
+// Delivery statuses reported by providers, in the order a message moves
+// through them; a failure can be reported at any point
+const (
+	StatusSent      = "sent"
+	StatusDelivered = "delivered"
+	StatusRead      = "read"
+	StatusFailed    = "failed"
+)
+
+// DeliveryStatus is a provider's report on a message it accepted earlier
+type DeliveryStatus struct {
+	Channel           string
+	ProviderMessageID string
+	Status            string
+	Error             string
+	Timestamp         time.Time
+}
+
+// Webhook is what a provider callback carries
+type Webhook struct {
+	Messages []InboundMessage
+	Statuses []DeliveryStatus
+}
+
+// ParseWhatsAppWebhook reads a WhatsApp Business Cloud API callback. Text
+// messages and quick-reply button presses are returned as inbound messages
+// and status updates as delivery statuses.
+func ParseWhatsAppWebhook(body []byte) (*Webhook, error) {
+	var payload struct {
+		Entry []struct {
+			Changes []struct {
+				Value struct {
+					Messages []struct {
+						From string `json:"from"`
+						ID   string `json:"id"`
+						Type string `json:"type"`
+						Text struct {
+							Body string `json:"body"`
+						} `json:"text"`
+						Button struct {
+							Text string `json:"text"`
+						} `json:"button"`
+					} `json:"messages"`
+					Statuses []struct {
+						ID        string `json:"id"`
+						Status    string `json:"status"`
+						Timestamp string `json:"timestamp"`
+						Errors    []struct {
+							Code    int    `json:"code"`
+							Title   string `json:"title"`
+							Message string `json:"message"`
+						} `json:"errors"`
+					} `json:"statuses"`
+				} `json:"value"`
+			} `json:"changes"`
+		} `json:"entry"`
+	}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil, err
+	}
+
+	webhook := &Webhook{}
+	for _, entry := range payload.Entry {
+		for _, change := range entry.Changes {
+			for _, m := range change.Value.Messages {
+				text := m.Text.Body
+				if m.Type == "button" {
+					text = m.Button.Text
+				}
+				webhook.Messages = append(webhook.Messages, InboundMessage{
+					Channel:           ChannelWhatsApp,
+					From:              "+" + strings.TrimPrefix(m.From, "+"),
+					Text:              text,
+					ProviderMessageID: m.ID,
+				})
+			}
+			for _, st := range change.Value.Statuses {
+				status := DeliveryStatus{Channel: ChannelWhatsApp, ProviderMessageID: st.ID, Status: st.Status}
+				if seconds, err := strconv.ParseInt(st.Timestamp, 10, 64); err == nil {
+					status.Timestamp = time.Unix(seconds, 0)
+				}
+				if len(st.Errors) > 0 {
+					status.Error = strings.TrimSpace(fmt.Sprintf("%d %s", st.Errors[0].Code, st.Errors[0].Title))
+					if st.Errors[0].Message != "" && st.Errors[0].Message != st.Errors[0].Title {
+						status.Error += ": " + st.Errors[0].Message
+					}
+				}
+				webhook.Statuses = append(webhook.Statuses, status)
+			}
+		}
+	}
+	return webhook, nil
+}
+
+// ParseSMSWebhook reads an SMS gateway callback. An incoming text is sent
+// as {"from", "text"} and a delivery receipt as {"message_id", "status",
+// "error"}; gateways that say "message" or "id" instead are understood too.
+// Only final failures such as undelivered or expired count as failed;
+// interim receipt statuses such as queued or enroute are ignored.
+func ParseSMSWebhook(body []byte) (*Webhook, error) {
+	var payload struct {
+		From      string `json:"from"`
+		Text      string `json:"text"`
+		Message   string `json:"message"`
+		ID        string `json:"id"`
+		MessageID string `json:"message_id"`
+		Status    string `json:"status"`
+		Error     string `json:"error"`
+		Timestamp string `json:"timestamp"`
+	}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil, err
+	}
+	messageID := payload.ID
+	if messageID == "" {
+		messageID = payload.MessageID
+	}
+
+	webhook := &Webhook{}
+	switch {
+	case payload.Status != "":
+		status := DeliveryStatus{Channel: ChannelSMS, ProviderMessageID: messageID, Error: payload.Error}
+		switch strings.ToLower(payload.Status) {
+		case "delivered", "delivrd":
+			status.Status = StatusDelivered
+		case "sent", "submitted", "accepted":
+			status.Status = StatusSent
+		case "failed", "undelivered", "undeliv", "rejected", "rejectd", "expired":
+			status.Status = StatusFailed
+			if status.Error == "" {
+				status.Error = payload.Status
+			}
+		default:
+			return webhook, nil
+		}
+		if t, err := time.Parse(time.RFC3339, payload.Timestamp); err == nil {
+			status.Timestamp = t
+		}
+		webhook.Statuses = append(webhook.Statuses, status)
+	case payload.From != "":
+		msg := InboundMessage{Channel: ChannelSMS, From: payload.From, Text: payload.Text, ProviderMessageID: messageID}
+		if msg.Text == "" {
+			msg.Text = payload.Message
+		}
+		webhook.Messages = append(webhook.Messages, msg)
+	}
+	return webhook, nil
+}
+
+// Consent keywords customers reply with; matching ignores case and
+// surrounding punctuation
+var (
+	optOutKeywords = map[string]bool{"STOP": true, "STOPALL": true, "STOP ALL": true, "UNSUBSCRIBE": true, "CANCEL": true, "END": true, "QUIT": true, "OPTOUT": true, "OPT OUT": true}
+	optInKeywords  = map[string]bool{"START": true, "UNSTOP": true, "SUBSCRIBE": true, "OPTIN": true, "OPT IN": true}
+)
+
+// ConsentKeyword classifies an inbound text as an opt-out request such as
+// STOP, an opt-in request such as START, or neither
+func ConsentKeyword(text string) (optOut, optIn bool) {
+	keyword := strings.ToUpper(strings.Join(strings.Fields(strings.Trim(text, " \t\r\n.!")), " "))
+	return optOutKeywords[keyword], optInKeywords[keyword]
+}
@@ -0,0 +1,185 @@
+package notify
+
+import (
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestVerifyWebhook(t *testing.T) {
+	body := []byte(`{"message_id":"m-1","status":"delivered"}`)
+	signature := hex.EncodeToString(SignWebhook("s3cret", body))
+
+	tests := []struct {
+		name    string
+		channel string
+		secret  string
+		header  string
+		value   string
+		want    error
+	}{
+		{"signed", ChannelSMS, "s3cret", "X-Signature", signature, nil},
+		{"signed with prefix", ChannelWhatsApp, "s3cret", "X-Hub-Signature-256", "sha256=" + signature, nil},
+		{"wrong secret", ChannelSMS, "other", "X-Signature", signature, ErrBadSignature},
+		{"not hex", ChannelSMS, "s3cret", "X-Signature", "not-a-signature", ErrBadSignature},
+		{"missing signature", ChannelSMS, "s3cret", "X-Signature", "", ErrBadSignature},
+		{"wrong header", ChannelSMS, "s3cret", "X-Hub-Signature-256", signature, ErrBadSignature},
+		{"no secret", ChannelSMS, "", "X-Signature", signature, ErrWebhookNotConfigured},
+		{"unsigned channel", ChannelEmail, "s3cret", "X-Signature", signature, ErrWebhookNotConfigured},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("SMS_WEBHOOK_SECRET", tt.secret)
+			t.Setenv("WHATSAPP_APP_SECRET", tt.secret)
+			header := http.Header{}
+			if tt.value != "" {
+				header.Set(tt.header, tt.value)
+			}
+			if err := VerifyWebhook(tt.channel, header, body); !errors.Is(err, tt.want) {
+				t.Errorf("VerifyWebhook = %v, want %v", err, tt.want)
+			}
+		})
+	}
+
+	t.Run("tampered body", func(t *testing.T) {
+		t.Setenv("SMS_WEBHOOK_SECRET", "s3cret")
+		header := http.Header{"X-Signature": []string{signature}}
+		if err := VerifyWebhook(ChannelSMS, header, append(body, ' ')); err != ErrBadSignature {
+			t.Errorf("VerifyWebhook = %v, want %v", err, ErrBadSignature)
+		}
+	})
+}
+
+func TestParseWhatsAppWebhook(t *testing.T) {
+	body := []byte(`{"entry":[{"changes":[{"value":{
+		"messages":[
+			{"from":"919876543210","id":"wamid.in1","type":"text","text":{"body":"STOP"}},
+			{"from":"919876543210","id":"wamid.in2","type":"button","button":{"text":"Pay now"}}
+		],
+		"statuses":[
+			{"id":"wamid.out1","status":"read","timestamp":"1700000000"},
+			{"id":"wamid.out2","status":"failed","timestamp":"1700000060","errors":[{"code":131026,"title":"Message undeliverable","message":"Message undeliverable"}]}
+		]}}]}]}`)
+
+	webhook, err := ParseWhatsAppWebhook(body)
+	if err != nil {
+		t.Fatalf("ParseWhatsAppWebhook: %v", err)
+	}
+
+	wantMessages := []InboundMessage{
+		{Channel: ChannelWhatsApp, From: "+919876543210", Text: "STOP", ProviderMessageID: "wamid.in1"},
+		{Channel: ChannelWhatsApp, From: "+919876543210", Text: "Pay now", ProviderMessageID: "wamid.in2"},
+	}
+	if len(webhook.Messages) != len(wantMessages) {
+		t.Fatalf("messages = %+v, want %+v", webhook.Messages, wantMessages)
+	}
+	for i, want := range wantMessages {
+		if webhook.Messages[i] != want {
+			t.Errorf("message %d = %+v, want %+v", i, webhook.Messages[i], want)
+		}
+	}
+
+	wantStatuses := []DeliveryStatus{
+		{Channel: ChannelWhatsApp, ProviderMessageID: "wamid.out1", Status: StatusRead, Timestamp: time.Unix(1700000000, 0)},
+		{Channel: ChannelWhatsApp, ProviderMessageID: "wamid.out2", Status: StatusFailed, Error: "131026 Message undeliverable", Timestamp: time.Unix(1700000060, 0)},
+	}
+	if len(webhook.Statuses) != len(wantStatuses) {
+		t.Fatalf("statuses = %+v, want %+v", webhook.Statuses, wantStatuses)
+	}
+	for i, want := range wantStatuses {
+		got := webhook.Statuses[i]
+		if got.Channel != want.Channel || got.ProviderMessageID != want.ProviderMessageID || got.Status != want.Status ||
+			got.Error != want.Error || !got.Timestamp.Equal(want.Timestamp) {
+			t.Errorf("status %d = %+v, want %+v", i, got, want)
+		}
+	}
+
+	if _, err := ParseWhatsAppWebhook([]byte("not json")); err == nil {
+		t.Error("ParseWhatsAppWebhook accepted a body that is not JSON")
+	}
+}
+
+func TestParseSMSWebhookStatuses(t *testing.T) {
+	tests := []struct {
+		body      string
+		wantID    string
+		want      string // empty when the receipt is ignored
+		wantError string
+	}{
+		{`{"message_id":"m-1","status":"DELIVRD"}`, "m-1", StatusDelivered, ""},
+		{`{"id":"m-2","status":"delivered","timestamp":"2026-10-19T10:00:00Z"}`, "m-2", StatusDelivered, ""},
+		{`{"message_id":"m-3","status":"submitted"}`, "m-3", StatusSent, ""},
+		{`{"message_id":"m-4","status":"undelivered"}`, "m-4", StatusFailed, "undelivered"},
+		{`{"message_id":"m-5","status":"failed","error":"DND number"}`, "m-5", StatusFailed, "DND number"},
+		{`{"message_id":"m-6","status":"rejected"}`, "m-6", StatusFailed, "rejected"},
+		{`{"message_id":"m-7","status":"expired"}`, "m-7", StatusFailed, "expired"},
+		{`{"message_id":"m-8","status":"queued"}`, "", "", ""},
+		{`{"message_id":"m-9","status":"enroute"}`, "", "", ""},
+		{`{"message_id":"m-10","status":"buffered"}`, "", "", ""},
+		{`{"message_id":"m-11","status":"pending"}`, "", "", ""},
+	}
+	for _, tt := range tests {
+		webhook, err := ParseSMSWebhook([]byte(tt.body))
+		if err != nil {
+			t.Errorf("ParseSMSWebhook(%s): %v", tt.body, err)
+			continue
+		}
+		if len(webhook.Messages) != 0 {
+			t.Errorf("ParseSMSWebhook(%s) messages = %+v, want none", tt.body, webhook.Messages)
+		}
+		if tt.want == "" {
+			if len(webhook.Statuses) != 0 {
+				t.Errorf("ParseSMSWebhook(%s) statuses = %+v, want the interim receipt ignored", tt.body, webhook.Statuses)
+			}
+			continue
+		}
+		if len(webhook.Statuses) != 1 {
+			t.Errorf("ParseSMSWebhook(%s) statuses = %+v, want one", tt.body, webhook.Statuses)
+			continue
+		}
+		st := webhook.Statuses[0]
+		if st.Channel != ChannelSMS || st.ProviderMessageID != tt.wantID || st.Status != tt.want || st.Error != tt.wantError {
+			t.Errorf("ParseSMSWebhook(%s) = %+v, want %s %s with error %q", tt.body, st, tt.wantID, tt.want, tt.wantError)
+		}
+	}
+}
+
+func TestParseSMSWebhookInbound(t *testing.T) {
+	for _, body := range []string{
+		`{"from":"+919876543210","text":"STOP","id":"in-1"}`,
+		`{"from":"+919876543210","message":"STOP","id":"in-1"}`,
+	} {
+		webhook, err := ParseSMSWebhook([]byte(body))
+		if err != nil {
+			t.Fatalf("ParseSMSWebhook(%s): %v", body, err)
+		}
+		want := InboundMessage{Channel: ChannelSMS, From: "+919876543210", Text: "STOP", ProviderMessageID: "in-1"}
+		if len(webhook.Messages) != 1 || webhook.Messages[0] != want || len(webhook.Statuses) != 0 {
+			t.Errorf("ParseSMSWebhook(%s) = %+v, want one message %+v", body, webhook, want)
+		}
+	}
+}
+
+func TestConsentKeyword(t *testing.T) {
+	tests := []struct {
+		text          string
+		optOut, optIn bool
+	}{
+		{"STOP", true, false},
+		{" stop! ", true, false},
+		{"Opt  Out.", true, false},
+		{"unsubscribe", true, false},
+		{"START", false, true},
+		{"opt in", false, true},
+		{"please stop", false, false},
+		{"", false, false},
+	}
+	for _, tt := range tests {
+		optOut, optIn := ConsentKeyword(tt.text)
+		if optOut != tt.optOut || optIn != tt.optIn {
+			t.Errorf("ConsentKeyword(%q) = %v, %v, want %v, %v", tt.text, optOut, optIn, tt.optOut, tt.optIn)
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+)
+
+const defaultWhatsAppAPIURL = "https://graph.facebook.com/v19.0"
+
+// WhatsAppConfig describes a WhatsApp Business Cloud API sender
+type WhatsAppConfig struct {
+	APIURL        string
+	PhoneNumberID string
+	AccessToken   string
+}
+
+// WhatsAppNotifier sends text messages through the WhatsApp Business Cloud
+// API. Outside a 24 hour customer service window Meta only delivers approved
+// templates and rejects plain text, which surfaces as a permanent error.
+type WhatsAppNotifier struct {
+	cfg WhatsAppConfig
+}
+
+// NewWhatsAppNotifier returns a notifier for the business phone number
+func NewWhatsAppNotifier(cfg WhatsAppConfig) *WhatsAppNotifier {
+	if cfg.APIURL == "" {
+		cfg.APIURL = defaultWhatsAppAPIURL
+	}
+	cfg.APIURL = strings.TrimSuffix(cfg.APIURL, "/")
+	return &WhatsAppNotifier{cfg: cfg}
+}
+
+func (n *WhatsAppNotifier) Send(ctx context.Context, msg Message) (*Receipt, error) {
+	if msg.To == "" {
+		return nil, Permanent(errors.New("recipient phone number is required"))
+	}
+
+	header := http.Header{}
+	header.Set("Authorization", "Bearer "+n.cfg.AccessToken)
+
+	var resp struct {
+		Messages []struct {
+			ID string `json:"id"`
+		} `json:"messages"`
+	}
+	err := postJSON(ctx, n.cfg.APIURL+"/"+n.cfg.PhoneNumberID+"/messages", header, map[string]interface{}{
+		"messaging_product": "whatsapp",
+		"recipient_type":    "individual",
+		"to":                strings.TrimPrefix(msg.To, "+"),
+		"type":              "text",
+		"text":              map[string]interface{}{"body": msg.Body, "preview_url": true},
+	}, &resp)
+	if err != nil {
+		return nil, err
+	}
+
+	receipt := &Receipt{}
+	if len(resp.Messages) > 0 {
+		receipt.ProviderMessageID = resp.Messages[0].ID
+	}
+	return receipt, nil
+}
@@ -0,0 +1,212 @@
+// Package rrule implements the date-only subset of RFC 5545 recurrence rules
+// needed for recurring ledger entries: FREQ, INTERVAL, BYDAY, BYMONTHDAY,
+// COUNT and UNTIL.
+package rrule
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// searchHorizon bounds how far ahead After looks for the next occurrence
+const searchHorizon = 10 * 366
+
+// untilTimeRegex matches the time part of an UNTIL date-time
+var untilTimeRegex = regexp.MustCompile(`^T[0-9]{6}Z?$`)
+
+var weekdays = map[string]time.Weekday{
+	"SU": time.Sunday, "MO": time.Monday, "TU": time.Tuesday, "WE": time.Wednesday,
+	"TH": time.Thursday, "FR": time.Friday, "SA": time.Saturday,
+}
+
+// Rule is a parsed recurrence rule
+type Rule struct {
+	Freq       string // "DAILY", "WEEKLY", "MONTHLY" or "YEARLY"
+	Interval   int
+	ByDay      []time.Weekday
+	ByMonthDay []int     // 1..31, or -1..-31 counted from the end of the month
+	Count      int       // 0 means unbounded
+	Until      time.Time // last possible date; zero means unbounded
+}
+
+// Parse reads a rule such as "FREQ=MONTHLY;BYMONTHDAY=1". A leading "RRULE:"
+// is accepted.
+func Parse(s string) (*Rule, error) {
+	s = strings.TrimPrefix(strings.TrimSpace(s), "RRULE:")
+	if s == "" {
+		return nil, errors.New("empty rule")
+	}
+
+	rule := &Rule{Interval: 1}
+	for _, part := range strings.Split(s, ";") {
+		key, value, ok := strings.Cut(part, "=")
+		if !ok {
+			return nil, fmt.Errorf("malformed rule part %q", part)
+		}
+
+		switch strings.ToUpper(key) {
+		case "FREQ":
+			rule.Freq = strings.ToUpper(value)
+			if rule.Freq != "DAILY" && rule.Freq != "WEEKLY" && rule.Freq != "MONTHLY" && rule.Freq != "YEARLY" {
+				return nil, fmt.Errorf("unsupported FREQ %q", value)
+			}
+		case "INTERVAL":
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 1 || n > 366 {
+				return nil, fmt.Errorf("invalid INTERVAL %q", value)
+			}
+			rule.Interval = n
+		case "BYDAY":
+			for _, day := range strings.Split(value, ",") {
+				wd, ok := weekdays[strings.ToUpper(strings.TrimSpace(day))]
+				if !ok {
+					return nil, fmt.Errorf("invalid BYDAY %q", day)
+				}
+				rule.ByDay = append(rule.ByDay, wd)
+			}
+		case "BYMONTHDAY":
+			for _, day := range strings.Split(value, ",") {
+				n, err := strconv.Atoi(strings.TrimSpace(day))
+				if err != nil || n == 0 || n < -31 || n > 31 {
+					return nil, fmt.Errorf("invalid BYMONTHDAY %q", day)
+				}
+				rule.ByMonthDay = append(rule.ByMonthDay, n)
+			}
+		case "COUNT":
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 1 {
+				return nil, fmt.Errorf("invalid COUNT %q", value)
+			}
+			rule.Count = n
+		case "UNTIL":
+			// A date, or a date-time of which only the date is kept
+			until, err := time.Parse("20060102", value[:min(len(value), 8)])
+			if err != nil || (len(value) > 8 && !untilTimeRegex.MatchString(value[8:])) {
+				return nil, fmt.Errorf("invalid UNTIL %q", value)
+			}
+			rule.Until = until
+		default:
+			return nil, fmt.Errorf("unsupported rule part %q", key)
+		}
+	}
+
+	if rule.Freq == "" {
+		return nil, errors.New("FREQ is required")
+	}
+	if rule.Count > 0 && !rule.Until.IsZero() {
+		return nil, errors.New("COUNT and UNTIL cannot be combined")
+	}
+	if len(rule.ByDay) > 0 && rule.Freq != "WEEKLY" {
+		return nil, errors.New("BYDAY is only supported with FREQ=WEEKLY")
+	}
+	if len(rule.ByMonthDay) > 0 && rule.Freq != "MONTHLY" {
+		return nil, errors.New("BYMONTHDAY is only supported with FREQ=MONTHLY")
+	}
+
+	return rule, nil
+}
+
+// After returns the first occurrence strictly after t for a series starting
+// on start. Only calendar dates are considered. The second result is false
+// when there is no further occurrence within the search horizon or before
+// UNTIL; COUNT is not applied here since it depends on how many occurrences
+// were used.
+func (r *Rule) After(start, t time.Time) (time.Time, bool) {
+	start = dateOf(start)
+	day := dateOf(t).AddDate(0, 0, 1)
+	if day.Before(start) {
+		day = start
+	}
+
+	for i := 0; i < searchHorizon; i++ {
+		if !r.Until.IsZero() && day.After(r.Until) {
+			break
+		}
+		if r.matches(start, day) {
+			return day, true
+		}
+		day = day.AddDate(0, 0, 1)
+	}
+	return time.Time{}, false
+}
+
+// OnOrAfter returns the first occurrence on or after t
+func (r *Rule) OnOrAfter(start, t time.Time) (time.Time, bool) {
+	return r.After(start, dateOf(t).AddDate(0, 0, -1))
+}
+
+func (r *Rule) matches(start, day time.Time) bool {
+	switch r.Freq {
+	case "DAILY":
+		return daysBetween(start, day)%r.Interval == 0
+
+	case "WEEKLY":
+		weeks := daysBetween(weekStart(start), weekStart(day)) / 7
+		if weeks%r.Interval != 0 {
+			return false
+		}
+		if len(r.ByDay) == 0 {
+			return day.Weekday() == start.Weekday()
+		}
+		for _, wd := range r.ByDay {
+			if day.Weekday() == wd {
+				return true
+			}
+		}
+		return false
+
+	case "MONTHLY":
+		months := (day.Year()-start.Year())*12 + int(day.Month()-start.Month())
+		if months%r.Interval != 0 {
+			return false
+		}
+		last := daysIn(day.Year(), day.Month())
+		if len(r.ByMonthDay) == 0 {
+			// A series starting on the 31st falls on the last day of
+			// shorter months rather than skipping them
+			return day.Day() == min(start.Day(), last)
+		}
+		for _, md := range r.ByMonthDay {
+			if md < 0 {
+				md = last + md + 1
+			}
+			if md > last {
+				md = last
+			}
+			if day.Day() == md {
+				return true
+			}
+		}
+		return false
+
+	case "YEARLY":
+		years := day.Year() - start.Year()
+		if years%r.Interval != 0 || day.Month() != start.Month() {
+			return false
+		}
+		return day.Day() == min(start.Day(), daysIn(day.Year(), day.Month()))
+	}
+	return false
+}
+
+func dateOf(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+func daysBetween(a, b time.Time) int {
+	return int(b.Sub(a).Hours() / 24)
+}
+
+// weekStart returns the Monday of the week containing t
+func weekStart(t time.Time) time.Time {
+	offset := (int(t.Weekday()) + 6) % 7
+	return t.AddDate(0, 0, -offset)
+}
+
+func daysIn(year int, month time.Month) int {
+	return time.Date(year, month+1, 0, 0, 0, 0, 0, time.UTC).Day()
+}
@@ -0,0 +1,188 @@
+package rrule
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func date(s string) time.Time {
+	t, err := time.Parse("2006-01-02", s)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+// occurrences lists up to n dates of the series starting on start, applying
+// COUNT the way the recurring entries job does
+func occurrences(r *Rule, start string, n int) []string {
+	if r.Count > 0 && r.Count < n {
+		n = r.Count
+	}
+	var dates []string
+	day, ok := r.OnOrAfter(date(start), date(start))
+	for ok && len(dates) < n {
+		dates = append(dates, day.Format("2006-01-02"))
+		day, ok = r.After(date(start), day)
+	}
+	return dates
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		rule string
+		want Rule
+	}{
+		{"FREQ=DAILY", Rule{Freq: "DAILY", Interval: 1}},
+		{"RRULE:FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,TH", Rule{Freq: "WEEKLY", Interval: 2, ByDay: []time.Weekday{time.Monday, time.Thursday}}},
+		{"freq=monthly;bymonthday=1,-1", Rule{Freq: "MONTHLY", Interval: 1, ByMonthDay: []int{1, -1}}},
+		{"FREQ=MONTHLY;COUNT=12", Rule{Freq: "MONTHLY", Interval: 1, Count: 12}},
+		{"FREQ=YEARLY;UNTIL=20301231", Rule{Freq: "YEARLY", Interval: 1, Until: date("2030-12-31")}},
+		{"FREQ=DAILY;UNTIL=20261031T183000Z", Rule{Freq: "DAILY", Interval: 1, Until: date("2026-10-31")}},
+		{"  FREQ=DAILY;INTERVAL=366  ", Rule{Freq: "DAILY", Interval: 366}},
+	}
+	for _, tt := range tests {
+		got, err := Parse(tt.rule)
+		if err != nil {
+			t.Errorf("Parse(%q): %v", tt.rule, err)
+			continue
+		}
+		if !reflect.DeepEqual(*got, tt.want) {
+			t.Errorf("Parse(%q) = %+v, want %+v", tt.rule, *got, tt.want)
+		}
+	}
+}
+
+func TestParseRejects(t *testing.T) {
+	tests := []struct {
+		rule, wantErr string
+	}{
+		{"", "empty rule"},
+		{"INTERVAL=2", "FREQ is required"},
+		{"FREQ=HOURLY", "unsupported FREQ"},
+		{"FREQ=SECONDLY", "unsupported FREQ"},
+		{"FREQ=DAILY;BYHOUR=9", "unsupported rule part"},
+		{"FREQ=MONTHLY;BYSETPOS=-1;BYDAY=FR", "unsupported rule part"},
+		{"FREQ=YEARLY;BYMONTH=3", "unsupported rule part"},
+		{"FREQ=WEEKLY;WKST=SU", "unsupported rule part"},
+		{"FREQ=DAILY;COUNT", "malformed rule part"},
+		{"FREQ=DAILY;INTERVAL=0", "invalid INTERVAL"},
+		{"FREQ=DAILY;INTERVAL=367", "invalid INTERVAL"},
+		{"FREQ=DAILY;INTERVAL=x", "invalid INTERVAL"},
+		{"FREQ=WEEKLY;BYDAY=1MO", "invalid BYDAY"},
+		{"FREQ=WEEKLY;BYDAY=XX", "invalid BYDAY"},
+		{"FREQ=MONTHLY;BYMONTHDAY=0", "invalid BYMONTHDAY"},
+		{"FREQ=MONTHLY;BYMONTHDAY=32", "invalid BYMONTHDAY"},
+		{"FREQ=MONTHLY;BYMONTHDAY=-32", "invalid BYMONTHDAY"},
+		{"FREQ=DAILY;COUNT=0", "invalid COUNT"},
+		{"FREQ=DAILY;UNTIL=2026-12-31", "invalid UNTIL"},
+		{"FREQ=DAILY;UNTIL=20261331", "invalid UNTIL"},
+		{"FREQ=DAILY;UNTIL=20261231T99", "invalid UNTIL"},
+		{"FREQ=DAILY;COUNT=3;UNTIL=20261231", "cannot be combined"},
+		{"FREQ=MONTHLY;BYDAY=MO", "BYDAY is only supported"},
+		{"FREQ=WEEKLY;BYMONTHDAY=1", "BYMONTHDAY is only supported"},
+	}
+	for _, tt := range tests {
+		_, err := Parse(tt.rule)
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("Parse(%q) error = %v, want %q", tt.rule, err, tt.wantErr)
+		}
+	}
+}
+
+func TestOccurrences(t *testing.T) {
+	tests := []struct {
+		name, rule, start string
+		want              []string
+	}{
+		{"daily", "FREQ=DAILY", "2026-02-27",
+			[]string{"2026-02-27", "2026-02-28", "2026-03-01", "2026-03-02"}},
+		{"every third day", "FREQ=DAILY;INTERVAL=3", "2026-01-30",
+			[]string{"2026-01-30", "2026-02-02", "2026-02-05", "2026-02-08"}},
+		{"weekly on the start weekday", "FREQ=WEEKLY", "2026-10-19",
+			[]string{"2026-10-19", "2026-10-26", "2026-11-02", "2026-11-09"}},
+		{"fortnightly on two days", "FREQ=WEEKLY;INTERVAL=2;BYDAY=TU,FR", "2026-10-19",
+			[]string{"2026-10-20", "2026-10-23", "2026-11-03", "2026-11-06"}},
+		{"weekly across a year end", "FREQ=WEEKLY;BYDAY=SU", "2026-12-20",
+			[]string{"2026-12-20", "2026-12-27", "2027-01-03", "2027-01-10"}},
+		{"monthly on the start day", "FREQ=MONTHLY", "2026-01-15",
+			[]string{"2026-01-15", "2026-02-15", "2026-03-15", "2026-04-15"}},
+		{"quarterly", "FREQ=MONTHLY;INTERVAL=3", "2026-11-05",
+			[]string{"2026-11-05", "2027-02-05", "2027-05-05", "2027-08-05"}},
+		{"monthly from the 31st", "FREQ=MONTHLY", "2026-01-31",
+			[]string{"2026-01-31", "2026-02-28", "2026-03-31", "2026-04-30", "2026-05-31"}},
+		{"monthly from the 31st in a leap year", "FREQ=MONTHLY", "2028-01-31",
+			[]string{"2028-01-31", "2028-02-29", "2028-03-31"}},
+		{"monthly from the 30th", "FREQ=MONTHLY", "2028-01-30",
+			[]string{"2028-01-30", "2028-02-29", "2028-03-30"}},
+		{"BYMONTHDAY=31 clamps to month end", "FREQ=MONTHLY;BYMONTHDAY=31", "2026-01-01",
+			[]string{"2026-01-31", "2026-02-28", "2026-03-31", "2026-04-30"}},
+		{"BYMONTHDAY=29 in February", "FREQ=MONTHLY;BYMONTHDAY=29", "2027-01-10",
+			[]string{"2027-01-29", "2027-02-28", "2027-03-29"}},
+		{"last day of the month", "FREQ=MONTHLY;BYMONTHDAY=-1", "2028-01-15",
+			[]string{"2028-01-31", "2028-02-29", "2028-03-31", "2028-04-30"}},
+		{"first and fifteenth", "FREQ=MONTHLY;BYMONTHDAY=1,15", "2026-10-10",
+			[]string{"2026-10-15", "2026-11-01", "2026-11-15", "2026-12-01"}},
+		{"second last day", "FREQ=MONTHLY;BYMONTHDAY=-2", "2026-02-01",
+			[]string{"2026-02-27", "2026-03-30", "2026-04-29"}},
+		{"yearly", "FREQ=YEARLY", "2026-04-01",
+			[]string{"2026-04-01", "2027-04-01", "2028-04-01"}},
+		{"yearly on 29 February", "FREQ=YEARLY", "2028-02-29",
+			[]string{"2028-02-29", "2029-02-28", "2030-02-28", "2031-02-28", "2032-02-29"}},
+		{"every other year", "FREQ=YEARLY;INTERVAL=2", "2026-07-01",
+			[]string{"2026-07-01", "2028-07-01", "2030-07-01"}},
+		{"COUNT", "FREQ=MONTHLY;COUNT=3", "2026-10-01",
+			[]string{"2026-10-01", "2026-11-01", "2026-12-01"}},
+		{"UNTIL is inclusive", "FREQ=WEEKLY;UNTIL=20261102", "2026-10-19",
+			[]string{"2026-10-19", "2026-10-26", "2026-11-02"}},
+		{"UNTIL between occurrences", "FREQ=MONTHLY;UNTIL=20270115T000000Z", "2026-10-31",
+			[]string{"2026-10-31", "2026-11-30", "2026-12-31"}},
+		{"UNTIL before the start", "FREQ=DAILY;UNTIL=20261001", "2026-10-19", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule, err := Parse(tt.rule)
+			if err != nil {
+				t.Fatalf("Parse(%q): %v", tt.rule, err)
+			}
+			// Bounded series must stop after the listed dates, unbounded
+			// ones go on
+			got := occurrences(rule, tt.start, len(tt.want)+2)
+			if rule.Count == 0 && rule.Until.IsZero() && len(got) > len(tt.want) {
+				got = got[:len(tt.want)]
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("%s from %s = %v, want %v", tt.rule, tt.start, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAfter(t *testing.T) {
+	rule, err := Parse("FREQ=MONTHLY;BYMONTHDAY=10")
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := date("2026-01-10")
+
+	tests := []struct{ after, want string }{
+		{"2025-12-31", "2026-01-10"}, // before the series starts
+		{"2026-01-09", "2026-01-10"},
+		{"2026-01-10", "2026-02-10"}, // strictly after
+		{"2026-03-11", "2026-04-10"},
+	}
+	for _, tt := range tests {
+		// The time of day is ignored
+		got, ok := rule.After(start, date(tt.after).Add(23*time.Hour))
+		if !ok || got.Format("2006-01-02") != tt.want {
+			t.Errorf("After(%s) = %s %v, want %s", tt.after, got.Format("2006-01-02"), ok, tt.want)
+		}
+	}
+
+	got, ok := rule.OnOrAfter(start, date("2026-02-10"))
+	if !ok || !got.Equal(date("2026-02-10")) {
+		t.Errorf("OnOrAfter(2026-02-10) = %s %v, want 2026-02-10", got.Format("2006-01-02"), ok)
+	}
+}
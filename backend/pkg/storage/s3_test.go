@@ -0,0 +1,248 @@
+package storage
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+const (
+	testAccessKey = "AKIDEXAMPLE"
+	testSecretKey = "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY"
+	testRegion    = "ap-south-1"
+	testBucket    = "khata-test"
+)
+
+// fakeS3 is an in-memory bucket that checks every request's SigV4
+// signature the way S3 does before acting on it
+type fakeS3 struct {
+	t *testing.T
+
+	mu      sync.Mutex
+	objects map[string][]byte
+	types   map[string]string
+}
+
+func newFakeS3(t *testing.T) (*fakeS3, *S3Store) {
+	t.Helper()
+	fake := &fakeS3{t: t, objects: map[string][]byte{}, types: map[string]string{}}
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+
+	store, err := NewS3Store(S3Config{
+		Endpoint:  server.URL,
+		Region:    testRegion,
+		Bucket:    testBucket,
+		AccessKey: testAccessKey,
+		SecretKey: testSecretKey,
+	})
+	if err != nil {
+		t.Fatalf("NewS3Store: %v", err)
+	}
+	return fake, store
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := f.verify(r, body); err != nil {
+		f.t.Errorf("%s %s: %v", r.Method, r.URL.EscapedPath(), err)
+		http.Error(w, "SignatureDoesNotMatch", http.StatusForbidden)
+		return
+	}
+
+	prefix := "/" + testBucket + "/"
+	if !strings.HasPrefix(r.URL.Path, prefix) {
+		http.Error(w, "NoSuchBucket", http.StatusNotFound)
+		return
+	}
+	key := strings.TrimPrefix(r.URL.Path, prefix)
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	switch r.Method {
+	case http.MethodPut:
+		f.objects[key] = body
+		f.types[key] = r.Header.Get("Content-Type")
+		w.WriteHeader(http.StatusOK)
+	case http.MethodGet:
+		data, ok := f.objects[key]
+		if !ok {
+			http.Error(w, "NoSuchKey", http.StatusNotFound)
+			return
+		}
+		w.Write(data)
+	case http.MethodDelete:
+		delete(f.objects, key)
+		delete(f.types, key)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		http.Error(w, "MethodNotAllowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// object returns what is stored under key
+func (f *fakeS3) object(key string) (data []byte, contentType string, ok bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	data, ok = f.objects[key]
+	return data, f.types[key], ok
+}
+
+// verify recomputes the request's signature from what arrived on the wire
+func (f *fakeS3) verify(r *http.Request, body []byte) error {
+	amzDate := r.Header.Get("x-amz-date")
+	when, err := time.Parse("20060102T150405Z", amzDate)
+	if err != nil {
+		return errors.New("missing or malformed x-amz-date")
+	}
+	if d := time.Since(when); d > time.Minute || d < -time.Minute {
+		return errors.New("x-amz-date is not current")
+	}
+
+	payloadSum := sha256.Sum256(body)
+	payloadHash := hex.EncodeToString(payloadSum[:])
+	if r.Header.Get("x-amz-content-sha256") != payloadHash {
+		return errors.New("x-amz-content-sha256 does not match the body")
+	}
+
+	scope := when.Format("20060102") + "/" + testRegion + "/s3/aws4_request"
+	canonicalRequest := r.Method + "\n" +
+		r.URL.EscapedPath() + "\n" +
+		r.URL.RawQuery + "\n" +
+		"host:" + r.Host + "\n" +
+		"x-amz-content-sha256:" + payloadHash + "\n" +
+		"x-amz-date:" + amzDate + "\n\n" +
+		"host;x-amz-content-sha256;x-amz-date\n" +
+		payloadHash
+	canonicalSum := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(canonicalSum[:])
+
+	key := []byte("AWS4" + testSecretKey)
+	for _, part := range []string{when.Format("20060102"), testRegion, "s3", "aws4_request"} {
+		mac := hmac.New(sha256.New, key)
+		mac.Write([]byte(part))
+		key = mac.Sum(nil)
+	}
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(stringToSign))
+
+	want := "AWS4-HMAC-SHA256 Credential=" + testAccessKey + "/" + scope +
+		", SignedHeaders=host;x-amz-content-sha256;x-amz-date, Signature=" + hex.EncodeToString(mac.Sum(nil))
+	if got := r.Header.Get("Authorization"); got != want {
+		return errors.New("Authorization is " + got + ", want " + want)
+	}
+	return nil
+}
+
+func TestS3StorePutGetDelete(t *testing.T) {
+	fake, store := newFakeS3(t)
+	ctx := context.Background()
+
+	keys := []string{
+		"attachments/1/42/3f2a9c.jpg",
+		"attachments/1/42/bill of sale (1)+copy.pdf",
+		"attachments/1/42/रसीद.png",
+	}
+	for _, key := range keys {
+		data := []byte("contents of " + key)
+		if err := store.Put(ctx, key, data, "image/jpeg"); err != nil {
+			t.Fatalf("Put(%q): %v", key, err)
+		}
+		stored, contentType, _ := fake.object(key)
+		if string(stored) != string(data) {
+			t.Errorf("stored %q under %q, want %q", stored, key, data)
+		}
+		if contentType != "image/jpeg" {
+			t.Errorf("stored content type %q for %q, want image/jpeg", contentType, key)
+		}
+
+		rc, err := store.Get(ctx, key)
+		if err != nil {
+			t.Fatalf("Get(%q): %v", key, err)
+		}
+		got, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			t.Fatalf("reading %q: %v", key, err)
+		}
+		if string(got) != string(data) {
+			t.Errorf("Get(%q) = %q, want %q", key, got, data)
+		}
+
+		if err := store.Delete(ctx, key); err != nil {
+			t.Fatalf("Delete(%q): %v", key, err)
+		}
+		if _, _, ok := fake.object(key); ok {
+			t.Errorf("%q still stored after Delete", key)
+		}
+	}
+}
+
+func TestS3StoreGetMissing(t *testing.T) {
+	_, store := newFakeS3(t)
+
+	if _, err := store.Get(context.Background(), "attachments/1/1/missing.jpg"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get of a missing key returned %v, want ErrNotFound", err)
+	}
+}
+
+func TestS3StoreDeleteMissing(t *testing.T) {
+	_, store := newFakeS3(t)
+
+	if err := store.Delete(context.Background(), "attachments/1/1/missing.jpg"); err != nil {
+		t.Errorf("Delete of a missing key returned %v, want nil", err)
+	}
+}
+
+func TestS3StoreError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "AccessDenied", http.StatusForbidden)
+	}))
+	defer server.Close()
+
+	store, err := NewS3Store(S3Config{Endpoint: server.URL, Bucket: testBucket, AccessKey: testAccessKey, SecretKey: "wrong"})
+	if err != nil {
+		t.Fatalf("NewS3Store: %v", err)
+	}
+	ctx := context.Background()
+
+	if err := store.Put(ctx, "a.jpg", []byte("x"), "image/jpeg"); err == nil || !strings.Contains(err.Error(), "AccessDenied") {
+		t.Errorf("Put returned %v, want the AccessDenied error", err)
+	}
+	if _, err := store.Get(ctx, "a.jpg"); err == nil || errors.Is(err, ErrNotFound) {
+		t.Errorf("Get returned %v, want a request error", err)
+	}
+	if err := store.Delete(ctx, "a.jpg"); err == nil {
+		t.Error("Delete returned nil, want a request error")
+	}
+}
+
+func TestNewS3StoreConfig(t *testing.T) {
+	if _, err := NewS3Store(S3Config{Bucket: testBucket}); err == nil {
+		t.Error("missing credentials accepted")
+	}
+	if _, err := NewS3Store(S3Config{Endpoint: "localhost:9000", Bucket: testBucket, AccessKey: "a", SecretKey: "b"}); err == nil {
+		t.Error("endpoint without a scheme accepted")
+	}
+
+	store, err := NewS3Store(S3Config{Bucket: testBucket, AccessKey: "a", SecretKey: "b"})
+	if err != nil {
+		t.Fatalf("NewS3Store: %v", err)
+	}
+	if store.cfg.Region != "us-east-1" || store.endpoint.String() != "https://s3.us-east-1.amazonaws.com" {
+		t.Errorf("defaults are region %q endpoint %q", store.cfg.Region, store.endpoint)
+	}
+}
@@ -0,0 +1,59 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+
+	"khata-book-backend/pkg/logger"
+)
+
+// ErrNotFound is returned when a blob does not exist in the store
+var ErrNotFound = errors.New("blob not found")
+
+// BlobStore stores opaque file contents under string keys
+type BlobStore interface {
+	Put(ctx context.Context, key string, data []byte, contentType string) error
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	Delete(ctx context.Context, key string) error
+}
+
+// Blobs is the store used by the API, selected by InitStore
+var Blobs BlobStore
+
+// InitStore configures Blobs from the environment. STORAGE_DRIVER selects
+// "local" (default) or "s3".
+func InitStore() {
+	driver := os.Getenv("STORAGE_DRIVER")
+
+	switch driver {
+	case "s3":
+		store, err := NewS3Store(S3Config{
+			Endpoint:  os.Getenv("S3_ENDPOINT"),
+			Region:    os.Getenv("S3_REGION"),
+			Bucket:    os.Getenv("S3_BUCKET"),
+			AccessKey: os.Getenv("S3_ACCESS_KEY"),
+			SecretKey: os.Getenv("S3_SECRET_KEY"),
+		})
+		if err != nil {
+			logger.L.WithField("error", err).Fatal("Error configuring S3 blob store")
+		}
+		Blobs = store
+	case "", "local":
+		dir := os.Getenv("STORAGE_LOCAL_DIR")
+		if dir == "" {
+			dir = "uploads"
+		}
+		store, err := NewLocalStore(dir)
+		if err != nil {
+			logger.L.WithField("error", err).Fatal("Error configuring local blob store")
+		}
+		Blobs = store
+		driver = "local"
+	default:
+		logger.L.WithField("driver", driver).Fatal("Unknown STORAGE_DRIVER")
+	}
+
+	logger.L.WithField("driver", driver).Info("Blob store configured")
+}